@@ -272,6 +272,7 @@ pub struct YamlConfig {
     pub decap_erspan: bool,
     pub analyzer_ip: String,
     pub ingress_flavour: IngressFlavour,
+    pub kubernetes_custom_workloads: Vec<String>,
     pub grpc_buffer_size: usize,
     #[serde(with = "humantime_serde")]
     pub l7_log_session_aggr_timeout: Duration,
@@ -503,6 +504,11 @@ impl Default for YamlConfig {
             decap_erspan: false,
             analyzer_ip: "".into(),
             ingress_flavour: IngressFlavour::Kubernetes,
+            kubernetes_custom_workloads: vec![
+                "clonesets.apps.kruise.io".into(),
+                "statefulsets.apps.kruise.io".into(),
+                "rollouts.argoproj.io".into(),
+            ],
             grpc_buffer_size: 5,
             l7_log_session_aggr_timeout: Duration::from_secs(120),
             tap_mac_script: "".into(),
@@ -231,6 +231,7 @@ pub struct PlatformConfig {
     pub epc_id: u32,
     pub kubernetes_api_enabled: bool,
     pub namespace: Option<String>,
+    pub custom_workloads: Vec<String>,
     pub thread_threshold: u32,
     pub tap_mode: TapMode,
 }
@@ -824,6 +825,7 @@ impl TryFrom<(Config, RuntimeConfig)> for ModuleConfig {
                 } else {
                     Some(conf.yaml_config.kubernetes_namespace.clone())
                 },
+                custom_workloads: conf.yaml_config.kubernetes_custom_workloads.clone(),
                 thread_threshold: conf.thread_threshold,
                 tap_mode: conf.tap_mode,
             },
@@ -30,7 +30,10 @@ use std::{
 use arc_swap::access::Access;
 use flate2::{write::ZlibEncoder, Compression};
 use k8s_openapi::apimachinery::pkg::version::Info;
-use kube::{Client, Config};
+use kube::{
+    api::{ApiResource, GroupVersionKind},
+    Client, Config,
+};
 use log::{debug, error, info, log_enabled, warn, Level};
 use sysinfo::{System, SystemExt};
 use tokio::{
@@ -91,6 +94,20 @@ const PB_RESOURCES: [&str; 10] = [
     "*v1.Ingress",
 ];
 const PB_INGRESS: &str = "*v1.Ingress";
+/*
+    自定义工作负载(如 OpenKruise CloneSet、Argo Rollout)通过配置的 <资源名>.<API组> 查找，
+    上报类型为 *<API组>/<版本>.<Kind>，例如 *apps.kruise.io/v1alpha1.CloneSet，避免与内置资源冲突
+*/
+/*
+    Gateway API 和 Istio 的路由资源，存在对应 API 组时与自定义工作负载一样通过 DynamicObject 获取，
+    控制器将其作为 Ingress 处理
+*/
+const ROUTE_RESOURCES: [&str; 4] = [
+    "httproutes.gateway.networking.k8s.io",
+    "gateways.gateway.networking.k8s.io",
+    "virtualservices.networking.istio.io",
+    "gateways.networking.istio.io",
+];
 const PB_VERSION_INFO: &str = "*version.Info";
 
 struct Context {
@@ -235,6 +252,7 @@ impl ApiWatcher {
         apiserver_version: &Arc<Mutex<Info>>,
         err_msgs: &Arc<Mutex<Vec<String>>>,
         namespace: Option<&str>,
+        custom_workloads: &[String],
     ) -> Result<(HashMap<String, GenericResourceWatcher>, Vec<JoinHandle<()>>)> {
         let mut config = Config::infer().await.map_err(|e| {
             Error::KubernetesApiWatcher(format!("failed to infer kubernetes config: {}", e))
@@ -332,6 +350,36 @@ impl ApiWatcher {
                     );
 
                     for api_resource in api_resources.unwrap().resources {
+                        let custom_name = format!("{}.{}", api_resource.name, group.name);
+                        if custom_workloads.iter().any(|w| w == &custom_name)
+                            || ROUTE_RESOURCES.iter().any(|&r| r == custom_name)
+                        {
+                            info!(
+                                "found custom resource {} api in group {}",
+                                api_resource.name,
+                                version.group_version.as_str()
+                            );
+                            let kind = format!(
+                                "*{}.{}",
+                                version.group_version.as_str(),
+                                api_resource.kind
+                            );
+                            let ar = ApiResource::from_gvk_with_plural(
+                                &GroupVersionKind::gvk(
+                                    group.name.as_str(),
+                                    version.version.as_str(),
+                                    api_resource.kind.as_str(),
+                                ),
+                                api_resource.name.as_str(),
+                            );
+                            if let Some(watcher) =
+                                watcher_factory.new_custom_watcher(ar, kind, namespace)
+                            {
+                                watchers.insert(custom_name, watcher);
+                            }
+                            continue;
+                        }
+
                         let resource_name = api_resource.name;
                         if !RESOURCES.iter().any(|&r| r == resource_name) {
                             continue;
@@ -646,6 +694,7 @@ impl ApiWatcher {
 
         let namespace = context.config.load().namespace.clone();
         let ns = namespace.as_ref().map(|ns| ns.as_str());
+        let custom_workloads = context.config.load().custom_workloads.clone();
 
         let (resource_watchers, task_handles) = loop {
             match context.runtime.block_on(Self::set_up(
@@ -654,6 +703,7 @@ impl ApiWatcher {
                 &apiserver_version,
                 &err_msgs,
                 ns,
+                &custom_workloads,
             )) {
                 Ok(r) => break r,
                 Err(e) => {
@@ -41,10 +41,9 @@ use k8s_openapi::{
         extensions, networking,
     },
     apimachinery::pkg::apis::meta::v1::ObjectMeta,
-    Metadata,
 };
 use kube::{
-    api::ListParams,
+    api::{ApiResource, DynamicObject, ListParams},
     runtime::{self, watcher::Event},
     Api, Client, Resource,
 };
@@ -91,6 +90,7 @@ pub enum GenericResourceWatcher {
     V1beta1Ingress(ResourceWatcher<networking::v1beta1::Ingress>),
     ExtV1beta1Ingress(ResourceWatcher<extensions::v1beta1::Ingress>),
     Route(ResourceWatcher<Route>),
+    Custom(ResourceWatcher<DynamicObject>),
 }
 
 // 发生错误，需要重新构造实例
@@ -99,7 +99,7 @@ pub struct ResourceWatcher<K> {
     api: Api<K>,
     entries: Arc<Mutex<HashMap<String, Vec<u8>>>>,
     err_msg: Arc<Mutex<Option<String>>>,
-    kind: &'static str,
+    kind: String,
     version: Arc<AtomicU64>,
     runtime: Handle,
     ready: Arc<AtomicBool>,
@@ -108,12 +108,11 @@ pub struct ResourceWatcher<K> {
 impl<K> Watcher for ResourceWatcher<K>
 where
     K: Clone + Debug + DeserializeOwned + Resource + Serialize + Trimmable,
-    K: Metadata<Ty = ObjectMeta>,
 {
     fn start(&self) -> Option<JoinHandle<()>> {
         let entries = self.entries.clone();
         let version = self.version.clone();
-        let kind = self.kind;
+        let kind = self.kind.clone();
         let err_msg = self.err_msg.clone();
         let ready = self.ready.clone();
 
@@ -136,7 +135,7 @@ where
     }
 
     fn kind(&self) -> String {
-        self.kind.to_string()
+        self.kind.clone()
     }
 
     fn entries(&self) -> Vec<Vec<u8>> {
@@ -155,14 +154,13 @@ where
 impl<K> ResourceWatcher<K>
 where
     K: Clone + Debug + DeserializeOwned + Resource + Serialize + Trimmable,
-    K: Metadata<Ty = ObjectMeta>,
 {
-    pub fn new(api: Api<K>, kind: &'static str, runtime: Handle) -> Self {
+    pub fn new(api: Api<K>, kind: impl Into<String>, runtime: Handle) -> Self {
         Self {
             api,
             entries: Arc::new(Mutex::new(HashMap::new())),
             version: Arc::new(AtomicU64::new(0)),
-            kind,
+            kind: kind.into(),
             err_msg: Arc::new(Mutex::new(None)),
             runtime,
             ready: Default::default(),
@@ -173,12 +171,12 @@ where
         entries: Arc<Mutex<HashMap<String, Vec<u8>>>>,
         version: Arc<AtomicU64>,
         api: Api<K>,
-        kind: &'static str,
+        kind: String,
         err_msg: Arc<Mutex<Option<String>>>,
         ready: Arc<AtomicBool>,
     ) {
         let mut encoder = ZlibEncoder::new(Vec::new(), Compression::default());
-        Self::get_list_entry(&mut encoder, &entries, &version, kind, &api, &err_msg).await;
+        Self::get_list_entry(&mut encoder, &entries, &version, &kind, &api, &err_msg).await;
         ready.store(true, Ordering::Relaxed);
         info!("{} watcher ready", kind);
 
@@ -204,7 +202,7 @@ where
                         &mut last_update,
                         &entries,
                         &version,
-                        kind,
+                        &kind,
                         &err_msg,
                         &mut event_counter
                     ).await;
@@ -218,7 +216,7 @@ where
 
                     last_update = SystemTime::now();
                     last_refresh = SystemTime::now();
-                    Self::get_list_entry(&mut encoder, &entries, &version, kind, &api, &err_msg).await;
+                    Self::get_list_entry(&mut encoder, &entries, &version, &kind, &api, &err_msg).await;
                 }
             }
         }
@@ -697,6 +695,44 @@ impl Trimmable for Namespace {
     }
 }
 
+// 自定义工作负载只保留控制器需要的字段，与Deployment等内置工作负载一致
+impl Trimmable for DynamicObject {
+    fn trim(mut self) -> Self {
+        self.metadata = ObjectMeta {
+            uid: self.metadata.uid.take(),
+            name: self.metadata.name.take(),
+            namespace: self.metadata.namespace.take(),
+            labels: self.metadata.labels.take(),
+            ..Default::default()
+        };
+        let mut trim_spec = serde_json::Map::new();
+        if let Some(spec) = self.data.get_mut("spec").and_then(|s| s.as_object_mut()) {
+            // 自定义工作负载只需要 replicas/selector/template，其余为 Gateway API 和 Istio 路由资源需要的字段
+            for key in [
+                "replicas",
+                "selector",
+                "template",
+                "hostnames",
+                "parentRefs",
+                "rules",
+                "listeners",
+                "hosts",
+                "gateways",
+                "http",
+                "tls",
+                "tcp",
+                "servers",
+            ] {
+                if let Some(value) = spec.remove(key) {
+                    trim_spec.insert(key.to_string(), value);
+                }
+            }
+        }
+        self.data = serde_json::json!({ "spec": trim_spec });
+        self
+    }
+}
+
 pub struct ResourceWatcherFactory {
     client: Client,
     runtime: Handle,
@@ -822,4 +858,22 @@ impl ResourceWatcherFactory {
             _ => None,
         }
     }
+
+    pub fn new_custom_watcher(
+        &self,
+        api_resource: ApiResource,
+        kind: String,
+        namespace: Option<&str>,
+    ) -> Option<GenericResourceWatcher> {
+        Some(GenericResourceWatcher::Custom(ResourceWatcher::new(
+            match namespace {
+                Some(namespace) => {
+                    Api::namespaced_with(self.client.clone(), namespace, &api_resource)
+                }
+                None => Api::all_with(self.client.clone(), &api_resource),
+            },
+            kind,
+            self.runtime.clone(),
+        )))
+    }
 }
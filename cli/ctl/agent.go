@@ -90,29 +90,32 @@ func RegisterAgentCommand() *cobra.Command {
 	}
 
 	var typeStr string
+	var dryRun bool
 	rebalanceCmd := &cobra.Command{
 		Use:   "rebalance",
 		Short: "rebalance controller or analyzer",
 		Example: `deepflow-ctl agent rebalance (rebalance controller and analyzer)
 deepflow-ctl agent rebalance --type=controller
-deepflow-ctl agent rebalance --type=analyzer`,
+deepflow-ctl agent rebalance --type=analyzer
+deepflow-ctl agent rebalance --type=analyzer --dry-run`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if typeStr != "" {
-				if err := rebalance(cmd, RebalanceType(typeStr), typeStr); err != nil {
+				if err := rebalance(cmd, RebalanceType(typeStr), typeStr, dryRun); err != nil {
 					fmt.Println(err)
 				}
 				return
 			}
 
-			if err := rebalance(cmd, RebalanceTypeNull, "controller"); err != nil {
+			if err := rebalance(cmd, RebalanceTypeNull, "controller", dryRun); err != nil {
 				fmt.Println(err)
 			}
-			if err := rebalance(cmd, RebalanceTypeNull, "analyzer"); err != nil {
+			if err := rebalance(cmd, RebalanceTypeNull, "analyzer", dryRun); err != nil {
 				fmt.Println(err)
 			}
 		},
 	}
 	rebalanceCmd.Flags().StringVarP(&typeStr, "type", "t", "", "request type controller/analyzer")
+	rebalanceCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the rebalance result without switching agents")
 
 	agent.AddCommand(list)
 	agent.AddCommand(delete)
@@ -127,7 +130,10 @@ func RegisterAgentUpgradeCommand() *cobra.Command {
 		Use:   "agent-upgrade",
 		Short: "agent upgrade operation commands",
 		Example: "deepflow-ctl agent-upgrade list\n" +
-			"deepflow-ctl agent-upgrade vtap-name --package=/usr/sbin/deepflow-agent\n",
+			"deepflow-ctl agent-upgrade vtap-name --package=/usr/sbin/deepflow-agent\n" +
+			"deepflow-ctl agent-upgrade repo upload deepflow-agent-v6.1 --package=/usr/sbin/deepflow-agent --signature-file=deepflow-agent.sig\n" +
+			"deepflow-ctl agent-upgrade vtap-name --package=deepflow-agent-v6.1\n" +
+			"deepflow-ctl agent-upgrade campaign create campaign-name --agent-group=group-name --package=/usr/sbin/deepflow-agent\n",
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 1 {
 				if args[0] == "list" {
@@ -143,7 +149,9 @@ func RegisterAgentUpgradeCommand() *cobra.Command {
 			}
 		},
 	}
-	agentUpgrade.Flags().StringVarP(&upgradePackage, "package", "c", "", "")
+	agentUpgrade.Flags().StringVarP(&upgradePackage, "package", "c", "", "name of package in repo, or package path on deepflow-server")
+	agentUpgrade.AddCommand(registerAgentUpgradeCampaignCommand())
+	agentUpgrade.AddCommand(registerAgentUpgradeRepoCommand())
 
 	return agentUpgrade
 }
@@ -374,17 +382,12 @@ func executeCommand(command string) (string, error) {
 
 var upgradePackage string
 
-func upgadeAgent(cmd *cobra.Command, args []string) {
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "must specify name and package. Examples: \n%s", cmd.Example)
-		return
-	}
-	vtapName := args[0]
+// getPackageRevision 执行升级包获取其版本号
+func getPackageRevision(upgradePackage string) (string, error) {
 	command := upgradePackage + " -v"
 	output, err := executeCommand(command)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return "", err
 	}
 
 	var expectedVersion string
@@ -398,11 +401,24 @@ func upgadeAgent(cmd *cobra.Command, args []string) {
 		expectedVersion = splitStr[0]
 	}
 	if expectedVersion == "" {
-		fmt.Printf("get expectedVersion faild, exec: %s, output: %s", command, output)
-		return
+		return "", fmt.Errorf("get expectedVersion faild, exec: %s, output: %s", command, output)
 	}
+	return expectedVersion, nil
+}
 
+func upgadeAgent(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name and package. Examples: \n%s", cmd.Example)
+		return
+	}
+	vtapName := args[0]
 	server := common.GetServerInfo(cmd)
+	expectedVersion, err := getUpgradePackageRevision(server, upgradePackage)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	serverURL := fmt.Sprintf("http://%s:%d/v1/controllers/", server.IP, server.Port)
 	response, err := common.CURLPerform("GET", serverURL, nil, "")
 	if err != nil {
@@ -470,9 +486,20 @@ func upgadeAgent(cmd *cobra.Command, args []string) {
 	fmt.Printf("set agent %s revision(%s) success\n", vtapName, expectedVersion)
 }
 
-func rebalance(cmd *cobra.Command, rebalanceType RebalanceType, typeVal string) error {
+func rebalance(cmd *cobra.Command, rebalanceType RebalanceType, typeVal string, dryRun bool) error {
 	server := common.GetServerInfo(cmd)
 
+	if dryRun {
+		resp, err := execRebalance(server, typeVal)
+		if err != nil {
+			return err
+		}
+		if rebalanceType == RebalanceTypeNull {
+			fmt.Printf("------------------------ %s (dry run) ------------------------\n", typeVal)
+		}
+		printDetail(resp)
+		return nil
+	}
 	isBalance, err := ifNeedRebalance(server, typeVal)
 	if err != nil {
 		return err
@@ -524,31 +551,67 @@ func execRebalance(server *common.Server, typeStr string) (*simplejson.Json, err
 
 func printDetail(resp *simplejson.Json) {
 	details := resp.Get("DATA").Get("DETAILS")
+	// 按数据量均衡时输出节点负载及采集器迁移明细
+	byLoad := resp.Get("DATA").Get("ALGORITHM").MustString() != ""
 	ipMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "IP")
 	azMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "AZ")
 	stateMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "STATE")
 	beforeVTapNumMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "BEFORE_VTAP_NUM")
 	afertVTapNumMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "AFTER_VTAP_NUM")
 	switchVTapNumMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "SWITCH_VTAP_NUM")
+	beforeVTapLoadMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "BEFORE_VTAP_LOAD")
+	afterVTapLoadMaxSize := jsonparser.GetTheMaxSizeOfAttr(details, "AFTER_VTAP_LOAD")
 
-	cmdFormat := "%-*v %-*v %-*v %-*v %-*v %-*v\n"
-	fmt.Printf(cmdFormat,
+	cmdFormat := "%-*v %-*v %-*v %-*v %-*v %-*v"
+	header := []interface{}{
 		ipMaxSize, "IP",
 		azMaxSize, "AZ",
 		stateMaxSize, "STATE",
 		beforeVTapNumMaxSize, "BEFORE_VTAP_NUM",
 		afertVTapNumMaxSize, "AFTER_VTAP_NUM",
-		switchVTapNumMaxSize, "SWITCH_VTAP_NUM")
+		switchVTapNumMaxSize, "SWITCH_VTAP_NUM",
+	}
+	if byLoad {
+		cmdFormat += " %-*v %-*v"
+		header = append(header, beforeVTapLoadMaxSize, "BEFORE_VTAP_LOAD", afterVTapLoadMaxSize, "AFTER_VTAP_LOAD")
+	}
+	cmdFormat += "\n"
+	fmt.Printf(cmdFormat, header...)
 
 	for i := range details.MustArray() {
 		detail := resp.Get("DATA").Get("DETAILS").GetIndex(i)
-		detail.Get("IP")
-		fmt.Printf(cmdFormat,
+		row := []interface{}{
 			ipMaxSize, detail.Get("IP").MustString(),
 			azMaxSize, detail.Get("AZ").MustString(),
 			stateMaxSize, detail.Get("STATE").MustInt(),
 			beforeVTapNumMaxSize, detail.Get("BEFORE_VTAP_NUM").MustInt(),
 			afertVTapNumMaxSize, detail.Get("AFTER_VTAP_NUM").MustInt(),
-			switchVTapNumMaxSize, detail.Get("SWITCH_VTAP_NUM").MustInt())
+			switchVTapNumMaxSize, detail.Get("SWITCH_VTAP_NUM").MustInt(),
+		}
+		if byLoad {
+			row = append(row,
+				beforeVTapLoadMaxSize, int64(detail.Get("BEFORE_VTAP_LOAD").MustFloat64()),
+				afterVTapLoadMaxSize, int64(detail.Get("AFTER_VTAP_LOAD").MustFloat64()))
+		}
+		fmt.Printf(cmdFormat, row...)
+	}
+
+	migrations := resp.Get("DATA").Get("MIGRATIONS")
+	if len(migrations.MustArray()) == 0 {
+		return
+	}
+	nameMaxSize := jsonparser.GetTheMaxSizeOfAttr(migrations, "NAME")
+	fromMaxSize := jsonparser.GetTheMaxSizeOfAttr(migrations, "FROM")
+	toMaxSize := jsonparser.GetTheMaxSizeOfAttr(migrations, "TO")
+	migrationFormat := "%-*v %-*v %-*v %v\n"
+	fmt.Println()
+	fmt.Printf(migrationFormat, nameMaxSize, "NAME", fromMaxSize, "FROM", toMaxSize, "TO", "LOAD")
+	for i := range migrations.MustArray() {
+		migration := migrations.GetIndex(i)
+		fmt.Printf(migrationFormat,
+			nameMaxSize, migration.Get("NAME").MustString(),
+			fromMaxSize, migration.Get("FROM").MustString(),
+			toMaxSize, migration.Get("TO").MustString(),
+			int64(migration.Get("LOAD").MustFloat64()))
 	}
 }
@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowys/deepflow/cli/ctl/common"
+)
+
+type upgradeCampaignCreateArgs struct {
+	agentGroup       string
+	selector         string
+	upgradePackage   string
+	revision         string
+	rollbackPackage  string
+	rollbackRevision string
+	batches          []int
+	failureThreshold int
+	failureAction    string
+	batchTimeout     int
+}
+
+func registerAgentUpgradeCampaignCommand() *cobra.Command {
+	campaign := &cobra.Command{
+		Use:   "campaign",
+		Short: "staged agent upgrade campaign operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'list | create | pause | resume | cancel | rollback | delete'.\n")
+		},
+	}
+
+	var listOutput string
+	list := &cobra.Command{
+		Use:     "list [name]",
+		Short:   "list upgrade campaign info, show agents of the campaign when name is specified",
+		Example: "deepflow-ctl agent-upgrade campaign list campaign-name",
+		Run: func(cmd *cobra.Command, args []string) {
+			listUpgradeCampaign(cmd, args, listOutput)
+		},
+	}
+	list.Flags().StringVarP(&listOutput, "output", "o", "", "output format")
+
+	createArgs := upgradeCampaignCreateArgs{}
+	create := &cobra.Command{
+		Use:   "create [name]",
+		Short: "create upgrade campaign, agents are upgraded batch by batch",
+		Example: "deepflow-ctl agent-upgrade campaign create campaign-name --agent-group=group-name --package=/usr/sbin/deepflow-agent\n" +
+			"deepflow-ctl agent-upgrade campaign create campaign-name --selector=arch=x86_64,type=K8S_VM " +
+			"--package=/usr/sbin/deepflow-agent --batches=1,10,100 --failure-threshold=10 " +
+			"--failure-action=rollback --rollback-package=/usr/sbin/deepflow-agent.old",
+		Run: func(cmd *cobra.Command, args []string) {
+			createUpgradeCampaign(cmd, args, createArgs)
+		},
+	}
+	create.Flags().StringVarP(&createArgs.agentGroup, "agent-group", "g", "", "name of agent-group to upgrade")
+	create.Flags().StringVarP(&createArgs.selector, "selector", "l", "",
+		"agent selector, e.g. arch=x86_64,os!=windows; supported labels: "+
+			"name, type, ctrl_ip, launch_server, controller_ip, analyzer_ip, region, az, arch, os, kernel_version, revision")
+	create.Flags().StringVarP(&createArgs.upgradePackage, "package", "c", "", "name of package in repo, or package path on deepflow-server")
+	create.Flags().StringVarP(&createArgs.revision, "revision", "", "", "expected revision, get from the package by default")
+	create.Flags().StringVarP(&createArgs.rollbackPackage, "rollback-package", "", "", "name of package in repo, or package path on deepflow-server to roll back to")
+	create.Flags().StringVarP(&createArgs.rollbackRevision, "rollback-revision", "", "", "revision to roll back to, get from the rollback package by default")
+	create.Flags().IntSliceVarP(&createArgs.batches, "batches", "b", []int{1, 10, 100}, "cumulative percentages of agents upgraded by each batch")
+	create.Flags().IntVarP(&createArgs.failureThreshold, "failure-threshold", "t", 0, "failure percentage of a batch to halt the campaign, 0 means any failure")
+	create.Flags().StringVarP(&createArgs.failureAction, "failure-action", "a", "pause", "action when failure threshold exceeded, pause or rollback")
+	create.Flags().IntVarP(&createArgs.batchTimeout, "batch-timeout", "", 600, "seconds to wait for agents to report the expected revision")
+
+	campaign.AddCommand(list)
+	campaign.AddCommand(create)
+	for _, action := range []string{"pause", "resume", "cancel", "rollback"} {
+		action := action
+		campaign.AddCommand(&cobra.Command{
+			Use:     action + " [name]",
+			Short:   action + " upgrade campaign",
+			Example: fmt.Sprintf("deepflow-ctl agent-upgrade campaign %s campaign-name", action),
+			Run: func(cmd *cobra.Command, args []string) {
+				updateUpgradeCampaign(cmd, args, action)
+			},
+		})
+	}
+	campaign.AddCommand(&cobra.Command{
+		Use:     "delete [name]",
+		Short:   "delete upgrade campaign",
+		Example: "deepflow-ctl agent-upgrade campaign delete campaign-name",
+		Run: func(cmd *cobra.Command, args []string) {
+			deleteUpgradeCampaign(cmd, args)
+		},
+	})
+	return campaign
+}
+
+func listUpgradeCampaign(cmd *cobra.Command, args []string, output string) {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-upgrade-campaigns/", server.IP, server.Port)
+	if name != "" {
+		url += fmt.Sprintf("?name=%s", name)
+	}
+
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if output == "yaml" {
+		dataJson, _ := response.Get("DATA").MarshalJSON()
+		dataYaml, _ := yaml.JSONToYAML(dataJson)
+		fmt.Printf(string(dataYaml))
+		return
+	}
+
+	cmdFormat := "%-32s %-14s %-8s %-32s %-8s %-10s %-8s %s\n"
+	fmt.Printf(cmdFormat, "NAME", "STATE", "BATCH", "EXPECTED_REVISION", "AGENTS", "SUCCEEDED", "FAILED", "MESSAGE")
+	for i := range response.Get("DATA").MustArray() {
+		campaign := response.Get("DATA").GetIndex(i)
+		stateCounts := campaign.Get("STATE_COUNTS")
+		fmt.Printf(cmdFormat,
+			campaign.Get("NAME").MustString(),
+			campaign.Get("STATE_NAME").MustString(),
+			fmt.Sprintf("%d/%d", campaign.Get("CURRENT_BATCH").MustInt()+1, len(campaign.Get("BATCHES").MustArray())),
+			campaign.Get("EXPECTED_REVISION").MustString(),
+			fmt.Sprintf("%d", campaign.Get("VTAP_COUNT").MustInt()),
+			fmt.Sprintf("%d", stateCounts.Get("SUCCEEDED").MustInt()),
+			fmt.Sprintf("%d", stateCounts.Get("FAILED").MustInt()),
+			campaign.Get("MESSAGE").MustString(),
+		)
+	}
+
+	if name == "" || len(response.Get("DATA").MustArray()) == 0 {
+		return
+	}
+	fmt.Println()
+	vtapFormat := "%-48s %-6s %-16s %-32s %-32s %s\n"
+	fmt.Printf(vtapFormat, "AGENT", "BATCH", "STATE", "ORIGIN_REVISION", "EXPECTED_REVISION", "MESSAGE")
+	vtaps := response.Get("DATA").GetIndex(0).Get("VTAPS")
+	for i := range vtaps.MustArray() {
+		vtap := vtaps.GetIndex(i)
+		fmt.Printf(vtapFormat,
+			vtap.Get("VTAP_NAME").MustString(),
+			fmt.Sprintf("%d", vtap.Get("BATCH").MustInt()+1),
+			vtap.Get("STATE_NAME").MustString(),
+			vtap.Get("ORIGIN_REVISION").MustString(),
+			vtap.Get("EXPECTED_REVISION").MustString(),
+			vtap.Get("MESSAGE").MustString(),
+		)
+	}
+}
+
+func createUpgradeCampaign(cmd *cobra.Command, args []string, createArgs upgradeCampaignCreateArgs) {
+	if len(args) == 0 || createArgs.upgradePackage == "" {
+		fmt.Fprintf(os.Stderr, "must specify name and package.\nExample: %s\n", cmd.Example)
+		return
+	}
+	if createArgs.agentGroup == "" && createArgs.selector == "" {
+		fmt.Fprintf(os.Stderr, "must specify agent-group or selector.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	var err error
+	server := common.GetServerInfo(cmd)
+	if createArgs.revision == "" {
+		if createArgs.revision, err = getUpgradePackageRevision(server, createArgs.upgradePackage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+	if createArgs.rollbackPackage != "" && createArgs.rollbackRevision == "" {
+		if createArgs.rollbackRevision, err = getUpgradePackageRevision(server, createArgs.rollbackPackage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+
+	body := map[string]interface{}{
+		"NAME":              args[0],
+		"LABEL_SELECTOR":    createArgs.selector,
+		"EXPECTED_REVISION": createArgs.revision,
+		"UPGRADE_PACKAGE":   createArgs.upgradePackage,
+		"ROLLBACK_REVISION": createArgs.rollbackRevision,
+		"ROLLBACK_PACKAGE":  createArgs.rollbackPackage,
+		"BATCHES":           createArgs.batches,
+		"FAILURE_THRESHOLD": createArgs.failureThreshold,
+		"FAILURE_ACTION":    createArgs.failureAction,
+		"BATCH_TIMEOUT":     createArgs.batchTimeout,
+	}
+	if createArgs.agentGroup != "" {
+		// 调用采集器组API，获取lcuuid
+		url := fmt.Sprintf("http://%s:%d/v1/vtap-groups/?name=%s", server.IP, server.Port, createArgs.agentGroup)
+		response, err := common.CURLPerform("GET", url, nil, "")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if len(response.Get("DATA").MustArray()) == 0 {
+			fmt.Fprintf(os.Stderr, "agent-group (%s) not found\n", createArgs.agentGroup)
+			return
+		}
+		body["VTAP_GROUP_LCUUID"] = response.Get("DATA").GetIndex(0).Get("LCUUID").MustString()
+	}
+
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-upgrade-campaigns/", server.IP, server.Port)
+	response, err := common.CURLPerform("POST", url, body, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Printf(
+		"create upgrade campaign %s to upgrade %d agents to revision(%s) in batches %v\n",
+		args[0], response.Get("DATA").Get("VTAP_COUNT").MustInt(), createArgs.revision,
+		response.Get("DATA").Get("BATCHES").MustArray(),
+	)
+}
+
+func getUpgradeCampaignLcuuid(server *common.Server, name string) (string, error) {
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-upgrade-campaigns/?name=%s", server.IP, server.Port, name)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		return "", err
+	}
+	if len(response.Get("DATA").MustArray()) == 0 {
+		return "", fmt.Errorf("upgrade campaign (%s) not found", name)
+	}
+	return response.Get("DATA").GetIndex(0).Get("LCUUID").MustString(), nil
+}
+
+func updateUpgradeCampaign(cmd *cobra.Command, args []string, action string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	lcuuid, err := getUpgradeCampaignLcuuid(server, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-upgrade-campaigns/%s/", server.IP, server.Port, lcuuid)
+	response, err := common.CURLPerform("PATCH", url, map[string]interface{}{"ACTION": action}, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Printf("upgrade campaign %s state: %s\n", args[0], response.Get("DATA").Get("STATE_NAME").MustString())
+}
+
+func deleteUpgradeCampaign(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	lcuuid, err := getUpgradeCampaignLcuuid(server, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-upgrade-campaigns/%s/", server.IP, server.Port, lcuuid)
+	if _, err := common.CURLPerform("DELETE", url, nil, ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowys/deepflow/cli/ctl/common"
+)
+
+type repoUploadArgs struct {
+	upgradePackage string
+	revision       string
+	arch           string
+	os             string
+	agentType      string
+	signature      string
+	signatureFile  string
+}
+
+func registerAgentUpgradeRepoCommand() *cobra.Command {
+	repo := &cobra.Command{
+		Use:   "repo",
+		Short: "agent package repository operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'list | upload | delete'.\n")
+		},
+	}
+
+	var listOutput string
+	list := &cobra.Command{
+		Use:     "list [name]",
+		Short:   "list agent packages in repo",
+		Example: "deepflow-ctl agent-upgrade repo list",
+		Run: func(cmd *cobra.Command, args []string) {
+			listRepo(cmd, args, listOutput)
+		},
+	}
+	list.Flags().StringVarP(&listOutput, "output", "o", "", "output format")
+
+	uploadArgs := repoUploadArgs{}
+	upload := &cobra.Command{
+		Use:   "upload [name]",
+		Short: "upload signed agent package to repo",
+		Example: "deepflow-ctl agent-upgrade repo upload deepflow-agent-v6.1 --package=/usr/sbin/deepflow-agent " +
+			"--signature-file=deepflow-agent.sig --arch=x86_64 --os=linux",
+		Run: func(cmd *cobra.Command, args []string) {
+			uploadRepo(cmd, args, uploadArgs)
+		},
+	}
+	upload.Flags().StringVarP(&uploadArgs.upgradePackage, "package", "c", "", "agent package file path")
+	upload.Flags().StringVarP(&uploadArgs.revision, "revision", "", "", "package revision, get from the package by default")
+	upload.Flags().StringVarP(&uploadArgs.arch, "arch", "", "", "agent arch of the package, empty means not checked")
+	upload.Flags().StringVarP(&uploadArgs.os, "os", "", "", "agent os of the package, empty means not checked")
+	upload.Flags().StringVarP(&uploadArgs.agentType, "type", "", "", "agent type of the package, e.g. K8S_VM, empty means not checked")
+	upload.Flags().StringVarP(&uploadArgs.signature, "signature", "s", "", "base64 encoded ed25519 signature of the package")
+	upload.Flags().StringVarP(&uploadArgs.signatureFile, "signature-file", "f", "", "file of ed25519 signature, raw or base64 encoded")
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete [name]",
+		Short:   "delete agent package from repo",
+		Example: "deepflow-ctl agent-upgrade repo delete deepflow-agent-v6.1",
+		Run: func(cmd *cobra.Command, args []string) {
+			deleteRepo(cmd, args)
+		},
+	}
+
+	repo.AddCommand(list)
+	repo.AddCommand(upload)
+	repo.AddCommand(deleteCmd)
+	return repo
+}
+
+// getUpgradePackageRevision 升级包为仓库中的升级包时使用仓库记录的版本，否则执行升级包获取版本
+func getUpgradePackageRevision(server *common.Server, upgradePackage string) (string, error) {
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-repo/?name=%s", server.IP, server.Port, upgradePackage)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err == nil && len(response.Get("DATA").MustArray()) > 0 {
+		return response.Get("DATA").GetIndex(0).Get("REVISION").MustString(), nil
+	}
+	return getPackageRevision(upgradePackage)
+}
+
+func readPackageSignature(uploadArgs repoUploadArgs) (string, error) {
+	if uploadArgs.signature != "" {
+		return uploadArgs.signature, nil
+	}
+	if uploadArgs.signatureFile == "" {
+		return "", fmt.Errorf("must specify signature or signature-file")
+	}
+	content, err := ioutil.ReadFile(uploadArgs.signatureFile)
+	if err != nil {
+		return "", err
+	}
+	if len(content) == ed25519.SignatureSize {
+		return base64.StdEncoding.EncodeToString(content), nil
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func listRepo(cmd *cobra.Command, args []string, output string) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-repo/", server.IP, server.Port)
+	if len(args) > 0 {
+		url += fmt.Sprintf("?name=%s", args[0])
+	}
+
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if output == "yaml" {
+		dataJson, _ := response.Get("DATA").MarshalJSON()
+		dataYaml, _ := yaml.JSONToYAML(dataJson)
+		fmt.Printf(string(dataYaml))
+		return
+	}
+
+	cmdFormat := "%-32s %-32s %-10s %-10s %-12s %-10s %-64s %s\n"
+	fmt.Printf(cmdFormat, "NAME", "REVISION", "ARCH", "OS", "TYPE", "SIZE", "SHA256", "CREATED_AT")
+	for i := range response.Get("DATA").MustArray() {
+		repo := response.Get("DATA").GetIndex(i)
+		fmt.Printf(cmdFormat,
+			repo.Get("NAME").MustString(),
+			repo.Get("REVISION").MustString(),
+			repo.Get("ARCH").MustString(),
+			repo.Get("OS").MustString(),
+			repo.Get("VTAP_TYPE").MustString(),
+			fmt.Sprintf("%d", repo.Get("SIZE").MustInt()),
+			repo.Get("SHA256").MustString(),
+			repo.Get("CREATED_AT").MustString(),
+		)
+	}
+}
+
+func uploadRepo(cmd *cobra.Command, args []string, uploadArgs repoUploadArgs) {
+	if len(args) == 0 || uploadArgs.upgradePackage == "" {
+		fmt.Fprintf(os.Stderr, "must specify name and package.\nExample: %s\n", cmd.Example)
+		return
+	}
+	signature, err := readPackageSignature(uploadArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if uploadArgs.revision == "" {
+		if uploadArgs.revision, err = getPackageRevision(uploadArgs.upgradePackage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-repo/", server.IP, server.Port)
+	fields := map[string]string{
+		"NAME":      args[0],
+		"REVISION":  uploadArgs.revision,
+		"ARCH":      uploadArgs.arch,
+		"OS":        uploadArgs.os,
+		"VTAP_TYPE": uploadArgs.agentType,
+		"SIGNATURE": signature,
+	}
+	response, err := common.CURLPostFormData(url, fields, "IMAGE", uploadArgs.upgradePackage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Printf(
+		"upload package %s revision(%s) success, sha256: %s\n",
+		args[0], uploadArgs.revision, response.Get("DATA").Get("SHA256").MustString(),
+	)
+}
+
+func deleteRepo(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-repo/?name=%s", server.IP, server.Port, args[0])
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if len(response.Get("DATA").MustArray()) == 0 {
+		fmt.Fprintf(os.Stderr, "package (%s) not found in repo\n", args[0])
+		return
+	}
+	lcuuid := response.Get("DATA").GetIndex(0).Get("LCUUID").MustString()
+	url = fmt.Sprintf("http://%s:%d/v1/vtap-repo/%s/", server.IP, server.Port, lcuuid)
+	if _, err := common.CURLPerform("DELETE", url, nil, ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
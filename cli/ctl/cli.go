@@ -56,6 +56,8 @@ func Execute(version string) {
 	root.AddCommand(RegisterSubDomainCommand())
 	root.AddCommand(RegisterGenesisCommand())
 	root.AddCommand(RegisterCloudCommand())
+	root.AddCommand(RegisterPolicyCommand())
+	root.AddCommand(RegisterResourceGroupCommand())
 	root.AddCommand(RegisterRecorderCommand())
 	root.AddCommand(RegisterTrisolarisCommand())
 	root.AddCommand(RegisterVPCCommend())
@@ -44,6 +44,7 @@ const (
 	DOMAIN_TYPE_KUBERNETES DomainType = 11 // kubernetes
 	DOMAIN_TYPE_HUAWEI     DomainType = 13 // huawei
 	DOMAIN_TYPE_QINGCLOUD  DomainType = 14 // qingcloud
+	DOMAIN_TYPE_AZURE      DomainType = 18 // azure
 	DOMAIN_TYPE_AGENT_SYNC DomainType = 23 // agent-sync
 	DOMAIN_TYPE_BAIDU_BCE  DomainType = 25 // baidu_bce
 )
@@ -55,6 +56,7 @@ var DomainTypes []DomainType = []DomainType{
 	DOMAIN_TYPE_KUBERNETES,
 	DOMAIN_TYPE_HUAWEI,
 	DOMAIN_TYPE_QINGCLOUD,
+	DOMAIN_TYPE_AZURE,
 	DOMAIN_TYPE_AGENT_SYNC,
 	DOMAIN_TYPE_BAIDU_BCE,
 }
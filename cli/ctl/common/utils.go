@@ -21,10 +21,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -65,6 +68,53 @@ func CURLPerform(method string, url string, body map[string]interface{}, strBody
 	req.Header.Set("X-User-Id", "1")
 	req.Header.Set("X-User-Type", "1")
 
+	return doRequest(client, req, url)
+}
+
+// 功能：以multipart/form-data格式上传文件
+func CURLPostFormData(url string, fields map[string]string, fileField, filePath string) (*simplejson.Json, error) {
+	errResponse, _ := simplejson.NewJson([]byte("{}"))
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errResponse, err
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return errResponse, err
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, filepath.Base(filePath))
+	if err != nil {
+		return errResponse, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return errResponse, err
+	}
+	if err := writer.Close(); err != nil {
+		return errResponse, err
+	}
+
+	// 升级包较大，适当延长超时时间
+	client := &http.Client{Timeout: time.Second * 300}
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return errResponse, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json, text/plain")
+	req.Header.Set("X-User-Id", "1")
+	req.Header.Set("X-User-Type", "1")
+	return doRequest(client, req, url)
+}
+
+func doRequest(client *http.Client, req *http.Request, url string) (*simplejson.Json, error) {
+	errResponse, _ := simplejson.NewJson([]byte("{}"))
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return errResponse, errors.New(fmt.Sprintf("curl (%s) failed, (%v)", url, err))
@@ -259,6 +259,8 @@ func exampleDomainConfig(cmd *cobra.Command, args []string) {
 		fmt.Printf(string(example.YamlDomainHuawei))
 	case common.DOMAIN_TYPE_QINGCLOUD:
 		fmt.Printf(string(example.YamlDomainQingCloud))
+	case common.DOMAIN_TYPE_AZURE:
+		fmt.Printf(string(example.YamlDomainAzure))
 	case common.DOMAIN_TYPE_BAIDU_BCE:
 		fmt.Printf(string(example.YamlDomainBaiduBce))
 	case common.DOMAIN_TYPE_AGENT_SYNC:
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package example
+
+var YamlDomainAzure = []byte(`
+# 名称
+name: azure
+# 云平台类型
+type: azure
+config:
+  # 所属区域标识 [按需指定]
+  region_uuid: ffffffff-ffff-ffff-ffff-ffffffffffff
+  # 资源同步控制器 [按需指定,不指定时随机分配]
+  # controller_ip: 127.0.0.1
+  # 租户 ID [必需参数], 在Azure门户-Microsoft Entra ID-概述 获取
+  tenant_id: xxxxxxxx
+  # 应用程序(客户端) ID [必需参数], 在Azure门户-应用注册-概述 获取
+  client_id: xxxxxxxx
+  # 客户端密码 [必需参数], 在Azure门户-应用注册-证书和密码 获取
+  client_secret: xxxxxxx
+  # 订阅 ID [必需参数], 应用需在该订阅中被授予读者角色
+  subscription_id: xxxxxxxx
+  # 区域白名单, 多个区域名称之间以英文逗号分隔 [按需指定]
+  include_regions:
+  # 区域黑名单, 多个区域名称之间以英文逗号分隔 [按需指定]
+  exclude_regions:
+  # 认证服务地址 [按需指定], 默认为Azure全球版, 世纪互联版请配置为 https://login.chinacloudapi.cn
+  # login_endpoint: https://login.microsoftonline.com
+  # 资源管理服务地址 [按需指定], 世纪互联版请配置为 https://management.chinacloudapi.cn
+  # resource_manager_endpoint: https://management.azure.com
+`)
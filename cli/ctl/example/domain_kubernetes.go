@@ -32,4 +32,6 @@ config:
   pod_net_ipv6_cidr_max_mask: 64
   # 额外对接路由接口
   port_name_regex: ^(cni|flannel|cali|vxlan.calico|tunl)
+  # 自定义工作负载类型, 需与采集器 kubernetes-custom-workloads 配置对应
+  #custom_workload_kinds: [CloneSet, StatefulSet, Rollout]
 `)
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package example
+
+var YamlACL = []byte(`
+# 名称
+name: npb-acl
+# 应用, 可选: npb, pcap
+application: npb
+# 采集点, 默认3(云网络)
+tap_type: 3
+# 0-禁用; 1-启用, 默认1
+state: 1
+# 源/目的资源组ID, 为空表示任意, 目前最多支持一个
+src_group_ids: [1]
+dst_group_ids: []
+# 协议号, 0表示任意, 如6(TCP), 17(UDP)
+protocol: 6
+# 端口, 协议为任意、TCP或UDP时可指定, 多个端口或范围以英文逗号分隔
+src_ports: ""
+dst_ports: 80,8000-8080
+# VLAN, 0表示任意
+vlan: 0
+`)
+
+var YamlNpbTunnel = []byte(`
+# 名称
+name: npb-tunnel
+# 隧道目的IP
+ip: 10.1.1.1
+# 隧道类型, 0-VXLAN; 1-ERSPAN
+type: 0
+`)
+
+var YamlNpbPolicy = []byte(`
+# 名称
+name: npb-policy
+# 0-禁用; 1-启用, 默认1
+state: 1
+# 流量过滤(ACL)ID, ACL的应用需为npb
+acl_id: 1
+# 分发隧道ID
+npb_tunnel_id: 1
+# VXLAN为VNI, 范围[0, 16777215]; ERSPAN为Session ID, 范围[0, 1023]
+vni: 100
+# 0-丢弃; 1-分发, 默认1
+distribute: 1
+# 包长截取, 单位字节, 为空表示不截取
+payload_slice: 128
+# 生效的采集器ID, 为空表示所有采集器
+vtap_ids: []
+`)
+
+var YamlPcapPolicy = []byte(`
+# 名称
+name: pcap-policy
+# 0-禁用; 1-启用, 默认1
+state: 1
+# 流量过滤(ACL)ID, ACL的应用需为pcap
+acl_id: 2
+# 包长截取, 单位字节, 为空表示不截取
+payload_slice: 128
+# 生效的采集器ID, 为空表示所有采集器
+vtap_ids: []
+`)
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package example
+
+var YamlResourceGroupIP = []byte(`
+# 名称
+name: ip-group
+# 类型, 可选: ip, subnet, pod_group, pod_service, pod_label
+type: ip
+# 所属VPC ID, 可选
+vpc_id: 0
+# 支持IP、CIDR及IP范围
+ips:
+- 10.1.1.1
+- 10.1.2.0/24
+- 10.1.3.1-10.1.3.100
+`)
+
+var YamlResourceGroupSubnet = []byte(`
+# 名称
+name: subnet-group
+type: subnet
+# 所属VPC ID, 必填
+vpc_id: 1
+# 子网ID, 需属于上述VPC
+subnet_ids: [1, 2]
+`)
+
+var YamlResourceGroupPodGroup = []byte(`
+# 名称
+name: pod-group-group
+type: pod_group
+# 容器工作负载ID
+pod_group_ids: [1, 2]
+`)
+
+var YamlResourceGroupPodService = []byte(`
+# 名称
+name: pod-service-group
+type: pod_service
+# 容器服务ID, 有ClusterIP时解析为ClusterIP, 否则解析为后端容器IP
+pod_service_ids: [1]
+`)
+
+var YamlResourceGroupPodLabel = []byte(`
+# 名称
+name: pod-label-group
+type: pod_label
+# 容器集群ID, 0表示所有集群
+pod_cluster_id: 0
+# 容器标签, 需匹配所有标签
+pod_labels:
+- app:nginx
+- tier:web
+`)
@@ -14,4 +14,6 @@ config:
   #pod_net_ipv6_cidr_max_mask: 64
   # 输入正则表达式，指定需要额外对接路由接口 [选填]
   #port_name_regex: ^(cni|flannel|cali|vxlan.calico|tunl|en[ospx])
+  # 自定义工作负载类型, 需与采集器 kubernetes-custom-workloads 配置对应 [选填]
+  #custom_workload_kinds: [CloneSet, StatefulSet, Rollout]
 `)
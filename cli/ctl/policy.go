@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowys/deepflow/cli/ctl/common"
+	"github.com/deepflowys/deepflow/cli/ctl/example"
+)
+
+// policyResource 描述一类策略资源, 各类资源的增删改查命令结构相同
+type policyResource struct {
+	use     string
+	name    string
+	path    string
+	example []byte
+	format  string
+	columns []string
+}
+
+var policyResources = []policyResource{
+	{
+		use:     "acl",
+		name:    "flow acl",
+		path:    "acls",
+		example: example.YamlACL,
+		format:  "%-32s %-6s %-12s %-9s %-6s %-14s %-14s %-9s %-16s %s\n",
+		columns: []string{"NAME", "ID", "APPLICATION", "TAP_TYPE", "STATE", "SRC_GROUP_IDS", "DST_GROUP_IDS", "PROTOCOL", "SRC_PORTS", "DST_PORTS"},
+	},
+	{
+		use:     "npb-tunnel",
+		name:    "npb tunnel",
+		path:    "npb-tunnels",
+		example: example.YamlNpbTunnel,
+		format:  "%-32s %-6s %-40s %s\n",
+		columns: []string{"NAME", "ID", "IP", "TYPE_NAME"},
+	},
+	{
+		use:     "npb-policy",
+		name:    "npb policy",
+		path:    "npb-policies",
+		example: example.YamlNpbPolicy,
+		format:  "%-32s %-6s %-6s %-7s %-14s %-9s %-11s %-14s %s\n",
+		columns: []string{"NAME", "ID", "STATE", "ACL_ID", "NPB_TUNNEL_ID", "VNI", "DISTRIBUTE", "PAYLOAD_SLICE", "VTAP_IDS"},
+	},
+	{
+		use:     "pcap-policy",
+		name:    "pcap policy",
+		path:    "pcap-policies",
+		example: example.YamlPcapPolicy,
+		format:  "%-32s %-6s %-6s %-7s %-14s %s\n",
+		columns: []string{"NAME", "ID", "STATE", "ACL_ID", "PAYLOAD_SLICE", "VTAP_IDS"},
+	},
+}
+
+func RegisterPolicyCommand() *cobra.Command {
+	policy := &cobra.Command{
+		Use:   "policy",
+		Short: "flow acl, npb and pcap policy operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'acl | npb-tunnel | npb-policy | pcap-policy'.\n")
+		},
+	}
+
+	for i := range policyResources {
+		policy.AddCommand(registerPolicyResourceCommand(policyResources[i]))
+	}
+	return policy
+}
+
+func registerPolicyResourceCommand(resource policyResource) *cobra.Command {
+	resourceCmd := &cobra.Command{
+		Use:   resource.use,
+		Short: resource.name + " operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'list | create | update | delete | example'.\n")
+		},
+	}
+
+	var listOutput string
+	list := &cobra.Command{
+		Use:     "list [name]",
+		Short:   "list " + resource.name,
+		Example: fmt.Sprintf("deepflow-ctl policy %s list", resource.use),
+		Run: func(cmd *cobra.Command, args []string) {
+			listPolicyResource(cmd, args, resource, listOutput)
+		},
+	}
+	list.Flags().StringVarP(&listOutput, "output", "o", "", "output format")
+
+	var createFilename string
+	var createDryRun bool
+	create := &cobra.Command{
+		Use:     "create",
+		Short:   "create " + resource.name,
+		Example: fmt.Sprintf("deepflow-ctl policy %s create -f %s.yaml", resource.use, resource.use),
+		Run: func(cmd *cobra.Command, args []string) {
+			createPolicyResource(cmd, resource, createFilename, createDryRun)
+		},
+	}
+	create.Flags().StringVarP(&createFilename, "filename", "f", "", "create "+resource.name+" from file or stdin")
+	create.Flags().BoolVarP(&createDryRun, "dry-run", "", false, "only show agents whose policy version would change")
+	create.MarkFlagRequired("filename")
+
+	var updateFilename string
+	var updateDryRun bool
+	update := &cobra.Command{
+		Use:     "update [name]",
+		Short:   "update " + resource.name,
+		Example: fmt.Sprintf("deepflow-ctl policy %s update %s -f %s.yaml --dry-run", resource.use, resource.use, resource.use),
+		Run: func(cmd *cobra.Command, args []string) {
+			updatePolicyResource(cmd, args, resource, updateFilename, updateDryRun)
+		},
+	}
+	update.Flags().StringVarP(&updateFilename, "filename", "f", "", "update "+resource.name+" from file or stdin")
+	update.Flags().BoolVarP(&updateDryRun, "dry-run", "", false, "only show agents whose policy version would change")
+	update.MarkFlagRequired("filename")
+
+	var deleteDryRun bool
+	deleteCmd := &cobra.Command{
+		Use:     "delete [name]",
+		Short:   "delete " + resource.name,
+		Example: fmt.Sprintf("deepflow-ctl policy %s delete %s", resource.use, resource.use),
+		Run: func(cmd *cobra.Command, args []string) {
+			deletePolicyResource(cmd, args, resource, deleteDryRun)
+		},
+	}
+	deleteCmd.Flags().BoolVarP(&deleteDryRun, "dry-run", "", false, "only show agents whose policy version would change")
+
+	exampleCmd := &cobra.Command{
+		Use:     "example",
+		Short:   "example " + resource.name + " create yaml",
+		Example: fmt.Sprintf("deepflow-ctl policy %s example", resource.use),
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf(string(resource.example))
+		},
+	}
+
+	resourceCmd.AddCommand(list)
+	resourceCmd.AddCommand(create)
+	resourceCmd.AddCommand(update)
+	resourceCmd.AddCommand(deleteCmd)
+	resourceCmd.AddCommand(exampleCmd)
+	return resourceCmd
+}
+
+func listPolicyResource(cmd *cobra.Command, args []string, resource policyResource, output string) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/%s/", server.IP, server.Port, resource.path)
+	if len(args) > 0 {
+		url += fmt.Sprintf("?name=%s", args[0])
+	}
+
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if output == "yaml" {
+		jData, _ := response.Get("DATA").MarshalJSON()
+		yData, _ := yaml.JSONToYAML(jData)
+		fmt.Printf(string(yData))
+		return
+	}
+	columns := make([]interface{}, len(resource.columns))
+	for i, column := range resource.columns {
+		columns[i] = column
+	}
+	fmt.Printf(resource.format, columns...)
+	for i := range response.Get("DATA").MustArray() {
+		d := response.Get("DATA").GetIndex(i)
+		values := make([]interface{}, len(resource.columns))
+		for j, column := range resource.columns {
+			values[j] = formatPolicyValue(d.Get(column))
+		}
+		fmt.Printf(resource.format, values...)
+	}
+}
+
+func formatPolicyValue(value *simplejson.Json) string {
+	switch v := value.Interface().(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(v))
+		for i := range v {
+			items[i] = fmt.Sprint(v[i])
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func getPolicyResourceLcuuid(server *common.Server, resource policyResource, name string) (string, error) {
+	url := fmt.Sprintf("http://%s:%d/v1/%s/?name=%s", server.IP, server.Port, resource.path, name)
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		return "", err
+	}
+	if len(response.Get("DATA").MustArray()) == 0 {
+		return "", fmt.Errorf("%s (%s) not found", resource.name, name)
+	}
+	return response.Get("DATA").GetIndex(0).Get("LCUUID").MustString(), nil
+}
+
+func policyDryRunQuery(dryRun bool) string {
+	if dryRun {
+		return "?dry_run=true"
+	}
+	return ""
+}
+
+func createPolicyResource(cmd *cobra.Command, resource policyResource, filename string, dryRun bool) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/%s/%s", server.IP, server.Port, resource.path, policyDryRunQuery(dryRun))
+
+	body, err := formatBody(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	response, err := common.CURLPerform("POST", url, body, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	printPolicyResponse(response, dryRun)
+}
+
+func updatePolicyResource(cmd *cobra.Command, args []string, resource policyResource, filename string, dryRun bool) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	lcuuid, err := getPolicyResourceLcuuid(server, resource, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	body, err := formatBody(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	url := fmt.Sprintf(
+		"http://%s:%d/v1/%s/%s/%s", server.IP, server.Port, resource.path, lcuuid, policyDryRunQuery(dryRun),
+	)
+	response, err := common.CURLPerform("PATCH", url, body, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	printPolicyResponse(response, dryRun)
+}
+
+func deletePolicyResource(cmd *cobra.Command, args []string, resource policyResource, dryRun bool) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	lcuuid, err := getPolicyResourceLcuuid(server, resource, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	url := fmt.Sprintf(
+		"http://%s:%d/v1/%s/%s/%s", server.IP, server.Port, resource.path, lcuuid, policyDryRunQuery(dryRun),
+	)
+	response, err := common.CURLPerform("DELETE", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	printPolicyResponse(response, dryRun)
+}
+
+// printPolicyResponse dry-run时列出策略版本将变化的采集器
+func printPolicyResponse(response *simplejson.Json, dryRun bool) {
+	if !dryRun {
+		fmt.Println(response)
+		return
+	}
+
+	data := response.Get("DATA")
+	vtaps := data.Get("VTAPS").MustArray()
+	fmt.Printf("%d agent(s) policy version would change, analyzer policy changed: %t\n",
+		len(vtaps), data.Get("ANALYZER_POLICY_CHANGED").MustBool())
+	if len(vtaps) == 0 {
+		return
+	}
+	format := "%-6s %-46s %s\n"
+	fmt.Printf(format, "ID", "NAME", "LCUUID")
+	for i := range vtaps {
+		vtap := data.Get("VTAPS").GetIndex(i)
+		fmt.Printf(format, formatPolicyValue(vtap.Get("ID")), vtap.Get("NAME").MustString(), vtap.Get("LCUUID").MustString())
+	}
+}
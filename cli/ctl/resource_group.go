@@ -0,0 +1,281 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowys/deepflow/cli/ctl/common"
+	"github.com/deepflowys/deepflow/cli/ctl/example"
+)
+
+var resourceGroupExamples = map[string][]byte{
+	"ip":          example.YamlResourceGroupIP,
+	"subnet":      example.YamlResourceGroupSubnet,
+	"pod_group":   example.YamlResourceGroupPodGroup,
+	"pod_service": example.YamlResourceGroupPodService,
+	"pod_label":   example.YamlResourceGroupPodLabel,
+}
+
+var resourceGroupTypes = []string{"ip", "subnet", "pod_group", "pod_service", "pod_label"}
+
+func RegisterResourceGroupCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "group",
+		Short: "resource group operation commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("please run with 'list | ips | create | update | delete | example'.\n")
+		},
+	}
+
+	var listOutput string
+	var listType string
+	list := &cobra.Command{
+		Use:     "list [name]",
+		Short:   "list resource group info",
+		Example: "deepflow-ctl group list --type pod_label",
+		Run: func(cmd *cobra.Command, args []string) {
+			listResourceGroup(cmd, args, listType, listOutput)
+		},
+	}
+	list.Flags().StringVarP(&listOutput, "output", "o", "", "output format")
+	list.Flags().StringVarP(&listType, "type", "t", "", "resource group type, supported types: "+strings.Join(resourceGroupTypes, ", "))
+
+	ips := &cobra.Command{
+		Use:     "ips name",
+		Short:   "show resolved member ips of resource group",
+		Example: "deepflow-ctl group ips pod-label-group",
+		Run: func(cmd *cobra.Command, args []string) {
+			showResourceGroupIPs(cmd, args)
+		},
+	}
+
+	var createFilename string
+	create := &cobra.Command{
+		Use:     "create",
+		Short:   "create resource group",
+		Example: "deepflow-ctl group create -f ip-group.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			createResourceGroup(cmd, createFilename)
+		},
+	}
+	create.Flags().StringVarP(&createFilename, "filename", "f", "", "create resource group from file or stdin")
+	create.MarkFlagRequired("filename")
+
+	var updateFilename string
+	update := &cobra.Command{
+		Use:     "update name",
+		Short:   "update resource group, type can not be changed",
+		Example: "deepflow-ctl group update ip-group -f ip-group.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			updateResourceGroup(cmd, args, updateFilename)
+		},
+	}
+	update.Flags().StringVarP(&updateFilename, "filename", "f", "", "update resource group from file or stdin")
+	update.MarkFlagRequired("filename")
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete name",
+		Short:   "delete resource group",
+		Example: "deepflow-ctl group delete ip-group",
+		Run: func(cmd *cobra.Command, args []string) {
+			deleteResourceGroup(cmd, args)
+		},
+	}
+
+	exampleCmd := &cobra.Command{
+		Use:     "example type",
+		Short:   "example resource group create yaml",
+		Long:    "supported types: " + strings.Join(resourceGroupTypes, ", "),
+		Example: "deepflow-ctl group example pod_label",
+		Run: func(cmd *cobra.Command, args []string) {
+			exampleResourceGroup(cmd, args)
+		},
+	}
+
+	group.AddCommand(list)
+	group.AddCommand(ips)
+	group.AddCommand(create)
+	group.AddCommand(update)
+	group.AddCommand(deleteCmd)
+	group.AddCommand(exampleCmd)
+	return group
+}
+
+func listResourceGroup(cmd *cobra.Command, args []string, groupType, output string) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/resource-groups/", server.IP, server.Port)
+	params := []string{}
+	if len(args) > 0 {
+		params = append(params, "name="+args[0])
+	}
+	if groupType != "" {
+		params = append(params, "type="+groupType)
+	}
+	if len(params) > 0 {
+		url += "?" + strings.Join(params, "&")
+	}
+
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if output == "yaml" {
+		jData, _ := response.Get("DATA").MarshalJSON()
+		yData, _ := yaml.JSONToYAML(jData)
+		fmt.Printf(string(yData))
+		return
+	}
+	format := "%-32s %-6s %-12s %-8s %-10s %s\n"
+	fmt.Printf(format, "NAME", "ID", "TYPE", "VPC_ID", "IP_COUNT", "MEMBERS")
+	for i := range response.Get("DATA").MustArray() {
+		d := response.Get("DATA").GetIndex(i)
+		ipCount := len(d.Get("RESOLVED_IPS").MustArray()) + len(d.Get("RESOLVED_IP_RANGES").MustArray())
+		fmt.Printf(
+			format, d.Get("NAME").MustString(), formatPolicyValue(d.Get("ID")), d.Get("TYPE").MustString(),
+			formatPolicyValue(d.Get("VPC_ID")), fmt.Sprint(ipCount), getResourceGroupMembers(d.Get("TYPE").MustString(), d),
+		)
+	}
+}
+
+// getResourceGroupMembers 按资源组类型展示其定义的成员
+func getResourceGroupMembers(groupType string, d *simplejson.Json) string {
+	switch groupType {
+	case "ip":
+		return formatPolicyValue(d.Get("IPS"))
+	case "subnet":
+		return "subnets: " + formatPolicyValue(d.Get("SUBNET_IDS"))
+	case "pod_group":
+		return "pod_groups: " + formatPolicyValue(d.Get("POD_GROUP_IDS"))
+	case "pod_service":
+		return "pod_services: " + formatPolicyValue(d.Get("POD_SERVICE_IDS"))
+	case "pod_label":
+		return "labels: " + formatPolicyValue(d.Get("POD_LABELS"))
+	}
+	return ""
+}
+
+func getResourceGroup(cmd *cobra.Command, args []string) (*simplejson.Json, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("must specify name.\nExample: %s", cmd.Example)
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/resource-groups/?name=%s", server.IP, server.Port, args[0])
+	response, err := common.CURLPerform("GET", url, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Get("DATA").MustArray()) == 0 {
+		return nil, fmt.Errorf("resource group (%s) not found", args[0])
+	}
+	return response.Get("DATA").GetIndex(0), nil
+}
+
+func showResourceGroupIPs(cmd *cobra.Command, args []string) {
+	group, err := getResourceGroup(cmd, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for i := range group.Get("RESOLVED_IPS").MustArray() {
+		fmt.Println(group.Get("RESOLVED_IPS").GetIndex(i).MustString())
+	}
+	for i := range group.Get("RESOLVED_IP_RANGES").MustArray() {
+		fmt.Println(group.Get("RESOLVED_IP_RANGES").GetIndex(i).MustString())
+	}
+}
+
+func createResourceGroup(cmd *cobra.Command, filename string) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/resource-groups/", server.IP, server.Port)
+
+	body, err := formatBody(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	resp, err := common.CURLPerform("POST", url, body, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(resp)
+}
+
+func updateResourceGroup(cmd *cobra.Command, args []string, filename string) {
+	group, err := getResourceGroup(cmd, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	body, err := formatBody(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	// 资源组类型不可修改
+	delete(body, "TYPE")
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/resource-groups/%s/", server.IP, server.Port, group.Get("LCUUID").MustString())
+	resp, err := common.CURLPerform("PATCH", url, body, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(resp)
+}
+
+func deleteResourceGroup(cmd *cobra.Command, args []string) {
+	group, err := getResourceGroup(cmd, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/resource-groups/%s/", server.IP, server.Port, group.Get("LCUUID").MustString())
+	resp, err := common.CURLPerform("DELETE", url, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(resp)
+}
+
+func exampleResourceGroup(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify type.\n%s\nExample: %s\n", cmd.Long, cmd.Example)
+		return
+	}
+	groupExample, ok := resourceGroupExamples[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "type (%s) not supported, %s\n", args[0], cmd.Long)
+		return
+	}
+	fmt.Printf(string(groupExample))
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"sort"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+// Azure没有可用区列表API，可用区由资源的location和zones生成，
+// 未指定可用区的资源(如子网、非可用区部署的云服务器)归属于以location命名的可用区
+func (a *Azure) getAZLcuuid(location, regionLcuuid string, jZones *simplejson.Json) string {
+	name := location
+	if zones := jZones.MustStringArray(); len(zones) > 0 {
+		name = location + "-" + zones[0]
+	}
+	lcuuid := common.GenerateUUID(name + "_" + a.lcuuidGenerate)
+	if _, ok := a.toolDataSet.lcuuidToAZ[lcuuid]; !ok {
+		a.toolDataSet.lcuuidToAZ[lcuuid] = model.AZ{
+			Lcuuid:       lcuuid,
+			Name:         name,
+			RegionLcuuid: regionLcuuid,
+		}
+	}
+	return lcuuid
+}
+
+func (a *Azure) getAZs() []model.AZ {
+	lcuuids := make([]string, 0, len(a.toolDataSet.lcuuidToAZ))
+	for lcuuid := range a.toolDataSet.lcuuidToAZ {
+		lcuuids = append(lcuuids, lcuuid)
+	}
+	sort.Strings(lcuuids)
+	azs := make([]model.AZ, 0, len(lcuuids))
+	for _, lcuuid := range lcuuids {
+		azs = append(azs, a.toolDataSet.lcuuidToAZ[lcuuid])
+	}
+	return azs
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.azure")
+
+const (
+	API_VERSION_SUBSCRIPTION      = "2020-01-01"
+	API_VERSION_NETWORK           = "2022-07-01"
+	API_VERSION_COMPUTE           = "2022-08-01"
+	API_VERSION_CONTAINER_SERVICE = "2022-09-01"
+)
+
+type Azure struct {
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	config         *Config
+	token          *Token
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewAzure(domain mysql.Domain) (*Azure, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newAzure(domain, conf), nil
+}
+
+func newAzure(domain mysql.Domain, conf *Config) *Azure {
+	return &Azure{
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (a *Azure) ClearDebugLog() {
+	a.debugger.Clear()
+}
+
+func (a *Azure) CheckAuth() error {
+	token, err := a.createToken()
+	if err != nil {
+		return err
+	}
+	a.token = token
+	return nil
+}
+
+func (a *Azure) GetCloudData() (model.Resource, error) {
+	a.cloudStatsd = statsd.NewCloudStatsd()
+	a.toolDataSet = NewToolDataSet()
+
+	var resource model.Resource
+
+	regions, err := a.getRegions()
+	if err != nil {
+		return resource, err
+	}
+
+	vpcs, networks, subnets, peers, err := a.getVPCs()
+	if err != nil {
+		return resource, err
+	}
+	resource.VPCs = append(resource.VPCs, vpcs...)
+	resource.Networks = append(resource.Networks, networks...)
+	resource.Subnets = append(resource.Subnets, subnets...)
+	resource.PeerConnections = append(resource.PeerConnections, peers...)
+
+	err = a.getPublicIPs()
+	if err != nil {
+		return resource, err
+	}
+
+	vifs, ips, err := a.getVInterfaces()
+	if err != nil {
+		return resource, err
+	}
+	resource.VInterfaces = append(resource.VInterfaces, vifs...)
+	resource.IPs = append(resource.IPs, ips...)
+
+	vms, err := a.getVMs()
+	if err != nil {
+		return resource, err
+	}
+	resource.VMs = append(resource.VMs, vms...)
+
+	ngws, natVMs, vifs, ips, err := a.getNATGateways()
+	if err != nil {
+		return resource, err
+	}
+	resource.NATGateways = append(resource.NATGateways, ngws...)
+	resource.NATVMConnections = append(resource.NATVMConnections, natVMs...)
+	resource.VInterfaces = append(resource.VInterfaces, vifs...)
+	resource.IPs = append(resource.IPs, ips...)
+
+	lbs, listeners, targetServers, vifs, ips, err := a.getLBs()
+	if err != nil {
+		return resource, err
+	}
+	resource.LBs = append(resource.LBs, lbs...)
+	resource.LBListeners = append(resource.LBListeners, listeners...)
+	resource.LBTargetServers = append(resource.LBTargetServers, targetServers...)
+	resource.VInterfaces = append(resource.VInterfaces, vifs...)
+	resource.IPs = append(resource.IPs, ips...)
+
+	subDomains, err := a.getSubDomains()
+	if err != nil {
+		return resource, err
+	}
+	resource.SubDomains = append(resource.SubDomains, subDomains...)
+
+	log.Debugf("region resource num info: %v", a.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", a.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, a.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(a.getAZs(), a.toolDataSet.azLcuuidToResourceNum)
+
+	a.cloudStatsd.RefreshResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(a)
+
+	a.debugger.Refresh()
+	return resource, nil
+}
+
+func (a *Azure) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": a.name,
+		"domain":      a.lcuuid,
+		"platform":    common.AZURE_EN,
+	}
+
+	return statsd.StatsdStatter{
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(a.cloudStatsd),
+	}
+}
+
+// 获取订阅下provider的全部资源，按nextLink分页，params为额外的查询参数，格式为key=value
+func (a *Azure) getRawData(provider, apiVersion, resultKey string, params ...string) (jsonList []*simplejson.Json, err error) {
+	statsdAPIStartTime := time.Now()
+
+	reqURL := fmt.Sprintf(
+		"%s/subscriptions/%s%s?api-version=%s", a.config.ResourceManagerEndpoint, a.config.SubscriptionID, provider, apiVersion,
+	)
+	for _, param := range params {
+		reqURL += "&" + param
+	}
+	firstURL := reqURL
+	for reqURL != "" {
+		resp, err := a.requestGet(reqURL)
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get("value")
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+		reqURL = resp.Get("nextLink").MustString()
+	}
+
+	a.cloudStatsd.RefreshAPICost(resultKey, statsdAPIStartTime)
+	a.cloudStatsd.RefreshAPICount(resultKey, len(jsonList))
+
+	a.debugger.WriteJson(resultKey, firstURL, jsonList)
+	return
+}
+
+// Azure资源ID不区分大小写，不同资源中引用的ID大小写可能不一致，统一转为小写后使用
+func resourceKey(id string) string {
+	return strings.ToLower(id)
+}
+
+func generateLcuuid(id string) string {
+	return common.GenerateUUID(resourceKey(id))
+}
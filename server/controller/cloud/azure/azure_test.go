@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	cloudconfig "github.com/deepflowys/deepflow/server/controller/cloud/config"
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/statsd"
+	statsdconfig "github.com/deepflowys/deepflow/server/controller/statsd/config"
+)
+
+// 使用testfiles中录制的API响应模拟认证及资源管理API，
+// 请求路径的最后一段作为文件名，分页请求的$skiptoken作为文件名后缀
+func newFixtureServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		if r.Method == http.MethodPost {
+			if r.ParseForm() != nil || r.PostForm.Get("client_secret") != "test-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if skipToken := r.URL.Query().Get("$skiptoken"); skipToken != "" {
+			name += "_" + skipToken
+		}
+		data, err := ioutil.ReadFile("testfiles/" + name + ".json")
+		if err != nil {
+			t.Logf("fixture %s not found", name)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.ReplaceAll(string(data), "{{server}}", server.URL)))
+	}))
+	return server
+}
+
+func TestAzure(t *testing.T) {
+	Convey("TestAzure", t, func() {
+		cloudconfig.SetCloudGlobalConfig(cloudconfig.CloudConfig{HTTPTimeout: 30})
+		statsd.NewStatsdMonitor(statsdconfig.StatsdConfig{})
+		server := newFixtureServer(t)
+		defer server.Close()
+
+		domain := mysql.Domain{
+			Name:        "test_azure",
+			DisplayName: "test_azure",
+		}
+		conf := &Config{
+			TenantID:                "tenant-id",
+			ClientID:                "client-id",
+			ClientSecret:            "test-secret",
+			SubscriptionID:          "sub-id",
+			ExcludeRegions:          []string{"northeurope"},
+			LoginEndpoint:           server.URL,
+			ResourceManagerEndpoint: server.URL,
+		}
+		azure := newAzure(domain, conf)
+		So(azure.CheckAuth(), ShouldBeNil)
+
+		data, err := azure.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("region, az and network resources", func() {
+			So(len(data.Regions), ShouldEqual, 2)
+			So(len(data.AZs), ShouldEqual, 3)
+			So(len(data.VPCs), ShouldEqual, 2)
+			So(len(data.Networks), ShouldEqual, 3)
+			So(len(data.Subnets), ShouldEqual, 3)
+			So(len(data.PeerConnections), ShouldEqual, 1)
+			So(data.PeerConnections[0].LocalRegionLcuuid, ShouldNotEqual, data.PeerConnections[0].RemoteRegionLcuuid)
+		})
+
+		Convey("vm and vinterface resources", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			So(data.VMs[0].State, ShouldEqual, common.VM_STATE_RUNNING)
+			So(data.VMs[1].State, ShouldEqual, common.VM_STATE_STOPPED)
+			So(data.VMs[0].VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+			So(data.VMs[1].VPCLcuuid, ShouldEqual, data.VPCs[1].Lcuuid)
+			So(data.VInterfaces[0].Mac, ShouldEqual, "00:0d:3a:00:00:01")
+			So(data.VInterfaces[0].DeviceLcuuid, ShouldEqual, data.VMs[0].Lcuuid)
+			So(len(data.VInterfaces), ShouldEqual, 5)
+			So(len(data.IPs), ShouldEqual, 5)
+		})
+
+		Convey("nat gateway and lb resources", func() {
+			So(len(data.NATGateways), ShouldEqual, 1)
+			So(data.NATGateways[0].FloatingIPs, ShouldEqual, "20.1.1.3")
+			So(len(data.NATVMConnections), ShouldEqual, 1)
+			So(len(data.LBs), ShouldEqual, 1)
+			So(data.LBs[0].Model, ShouldEqual, 2)
+			So(data.LBs[0].VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+			So(len(data.LBListeners), ShouldEqual, 1)
+			So(data.LBListeners[0].IPs, ShouldEqual, "20.1.1.2")
+			So(data.LBListeners[0].Protocol, ShouldEqual, "TCP")
+			So(len(data.LBTargetServers), ShouldEqual, 1)
+			So(data.LBTargetServers[0].IP, ShouldEqual, "10.0.0.4")
+			So(data.LBTargetServers[0].Port, ShouldEqual, 8080)
+			So(data.LBTargetServers[0].VMLcuuid, ShouldEqual, data.VMs[0].Lcuuid)
+		})
+
+		Convey("aks sub domains", func() {
+			So(len(data.SubDomains), ShouldEqual, 1)
+			So(data.SubDomains[0].VpcUUID, ShouldEqual, data.VPCs[0].Lcuuid)
+			So(data.SubDomains[0].ClusterID, ShouldStartWith, "d-")
+		})
+	})
+
+	Convey("TestAzureAuthFailed", t, func() {
+		server := newFixtureServer(t)
+		defer server.Close()
+
+		azure := newAzure(mysql.Domain{Name: "test_azure"}, &Config{
+			TenantID:                "tenant-id",
+			ClientSecret:            "wrong-secret",
+			LoginEndpoint:           server.URL,
+			ResourceManagerEndpoint: server.URL,
+		})
+		So(azure.CheckAuth(), ShouldNotBeNil)
+	})
+}
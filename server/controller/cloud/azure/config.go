@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+const (
+	DEFAULT_LOGIN_ENDPOINT            = "https://login.microsoftonline.com"
+	DEFAULT_RESOURCE_MANAGER_ENDPOINT = "https://management.azure.com"
+)
+
+type Config struct {
+	RegionLcuuid   string
+	TenantID       string
+	ClientID       string
+	ClientSecret   string
+	SubscriptionID string
+	ExcludeRegions []string
+	IncludeRegions []string
+
+	// 认证及资源管理API地址，中国区等非公有云环境需修改
+	LoginEndpoint           string
+	ResourceManagerEndpoint string
+}
+
+func (c *Config) LoadFromString(sConf string) (err error) {
+	jConf, err := simplejson.NewJson([]byte(sConf))
+	if err != nil {
+		log.Errorf("convert config string: %s to json failed: %v", sConf, err)
+		return
+	}
+	c.TenantID, err = jConf.Get("tenant_id").String()
+	if err != nil {
+		log.Error("tenant_id must be specified")
+		return
+	}
+	c.ClientID, err = jConf.Get("client_id").String()
+	if err != nil {
+		log.Error("client_id must be specified")
+		return
+	}
+	secret, err := jConf.Get("client_secret").String()
+	if err != nil {
+		log.Error("client_secret must be specified")
+		return
+	}
+	c.ClientSecret, err = common.DecryptSecretKey(secret)
+	if err != nil {
+		log.Error("decrypt client_secret failed")
+		return
+	}
+	c.SubscriptionID, err = jConf.Get("subscription_id").String()
+	if err != nil {
+		log.Error("subscription_id must be specified")
+		return
+	}
+	c.RegionLcuuid = jConf.Get("region_uuid").MustString()
+	eRegions := jConf.Get("exclude_regions").MustString()
+	if eRegions != "" {
+		c.ExcludeRegions = strings.Split(eRegions, ",")
+	}
+	iRegions := jConf.Get("include_regions").MustString()
+	if iRegions != "" {
+		c.IncludeRegions = strings.Split(iRegions, ",")
+	}
+	c.LoginEndpoint = strings.TrimSuffix(jConf.Get("login_endpoint").MustString(DEFAULT_LOGIN_ENDPOINT), "/")
+	c.ResourceManagerEndpoint = strings.TrimSuffix(jConf.Get("resource_manager_endpoint").MustString(DEFAULT_RESOURCE_MANAGER_ENDPOINT), "/")
+	return
+}
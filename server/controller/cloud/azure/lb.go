@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+// 负载均衡的前端IP配置对应负载均衡的接口，负载均衡规则对应监听器，规则关联的后端池中的网卡IP配置对应后端主机
+func (a *Azure) getLBs() (
+	lbs []model.LB, lbListeners []model.LBListener, lbTargetServers []model.LBTargetServer, vifs []model.VInterface, ips []model.IP, err error,
+) {
+	jLBs, err := a.getRawData("/providers/Microsoft.Network/loadBalancers", API_VERSION_NETWORK, "loadBalancers")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return
+	}
+
+	requiredAttrs := []string{"id", "name", "location", "properties"}
+	for i := range jLBs {
+		jLB := jLBs[i]
+		name := jLB.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jLB, requiredAttrs) {
+			log.Infof("exclude lb: %s, missing attr", name)
+			continue
+		}
+		regionLcuuid, ok := a.getRegionLcuuid(jLB.Get("location").MustString())
+		if !ok {
+			continue
+		}
+		properties := jLB.Get("properties")
+		lbLcuuid := generateLcuuid(jLB.Get("id").MustString())
+
+		lbModel := cloudcommon.LB_MODEL_INTERNAL
+		var vpcLcuuid string
+		var lbVIFs []model.VInterface
+		var lbIPs []model.IP
+		var vips []string
+		frontendIDToIP := map[string]string{}
+		jFrontends := properties.Get("frontendIPConfigurations")
+		for f := range jFrontends.MustArray() {
+			jf := jFrontends.GetIndex(f)
+			vif := model.VInterface{
+				Lcuuid:       generateLcuuid(jf.Get("id").MustString()),
+				Name:         jf.Get("name").MustString(),
+				DeviceType:   common.VIF_DEVICE_TYPE_LB,
+				DeviceLcuuid: lbLcuuid,
+				RegionLcuuid: regionLcuuid,
+			}
+			var ip, subnetLcuuid string
+			if publicIP, ok := a.toolDataSet.publicIPIDToIP[resourceKey(jf.Get("properties").Get("publicIPAddress").Get("id").MustString())]; ok {
+				lbModel = cloudcommon.LB_MODEL_EXTERNAL
+				vif.Type = common.VIF_TYPE_WAN
+				vif.Mac = common.VIF_DEFAULT_MAC
+				vif.NetworkLcuuid = common.NETWORK_ISP_LCUUID
+				ip, subnetLcuuid = publicIP, common.SUBNET_ISP_LCUUID
+			} else {
+				network, ok := a.toolDataSet.subnetIDToNetwork[resourceKey(jf.Get("properties").Get("subnet").Get("id").MustString())]
+				ip = jf.Get("properties").Get("privateIPAddress").MustString()
+				if !ok || ip == "" {
+					log.Infof("lb: %s frontend ip configuration missing ip info", name)
+					continue
+				}
+				vpcLcuuid = network.VPCLcuuid
+				vif.Type = common.VIF_TYPE_LAN
+				vif.Mac = common.VIF_DEFAULT_MAC
+				vif.NetworkLcuuid = network.Lcuuid
+				subnetLcuuid = a.toolDataSet.networkLcuuidToSubnetLcuuid[network.Lcuuid]
+			}
+			frontendIDToIP[resourceKey(jf.Get("id").MustString())] = ip
+			vips = append(vips, ip)
+			lbVIFs = append(lbVIFs, vif)
+			lbIPs = append(
+				lbIPs,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(vif.Lcuuid + ip),
+					VInterfaceLcuuid: vif.Lcuuid,
+					IP:               ip,
+					SubnetLcuuid:     subnetLcuuid,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+		}
+
+		// 公网负载均衡没有内网前端，通过后端主机确定所属VPC
+		poolIDToIPConfigIDs := map[string][]string{}
+		jPools := properties.Get("backendAddressPools")
+		for p := range jPools.MustArray() {
+			jp := jPools.GetIndex(p)
+			poolID := resourceKey(jp.Get("id").MustString())
+			jConfigs := jp.Get("properties").Get("backendIPConfigurations")
+			for c := range jConfigs.MustArray() {
+				configID := resourceKey(jConfigs.GetIndex(c).Get("id").MustString())
+				poolIDToIPConfigIDs[poolID] = append(poolIDToIPConfigIDs[poolID], configID)
+				if ipConfig, ok := a.toolDataSet.ipConfigIDToIPConfig[configID]; ok && vpcLcuuid == "" {
+					vpcLcuuid = ipConfig.VPCLcuuid
+				}
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude lb: %s, missing vpc info", name)
+			continue
+		}
+		lb := model.LB{
+			Lcuuid:       lbLcuuid,
+			Name:         name,
+			Label:        name,
+			Model:        lbModel,
+			VIP:          strings.Join(vips, common.STRINGS_JOIN_COMMA),
+			VPCLcuuid:    vpcLcuuid,
+			RegionLcuuid: regionLcuuid,
+		}
+		lbs = append(lbs, lb)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+		for i := range lbVIFs {
+			lbVIFs[i].VPCLcuuid = vpcLcuuid
+		}
+		vifs = append(vifs, lbVIFs...)
+		ips = append(ips, lbIPs...)
+
+		jRules := properties.Get("loadBalancingRules")
+		for r := range jRules.MustArray() {
+			listener, targetServers := a.formatListenerAndTargetServers(lb, jRules.GetIndex(r), frontendIDToIP, poolIDToIPConfigIDs)
+			lbListeners = append(lbListeners, listener)
+			lbTargetServers = append(lbTargetServers, targetServers...)
+		}
+	}
+	return
+}
+
+func (a *Azure) formatListenerAndTargetServers(
+	lb model.LB, jRule *simplejson.Json, frontendIDToIP map[string]string, poolIDToIPConfigIDs map[string][]string,
+) (listener model.LBListener, targetServers []model.LBTargetServer) {
+	ruleProperties := jRule.Get("properties")
+	protocol := strings.ToUpper(ruleProperties.Get("protocol").MustString())
+	if protocol == "ALL" {
+		protocol = cloudcommon.PROTOCOL_ALL
+	}
+	listener = model.LBListener{
+		Lcuuid:   generateLcuuid(jRule.Get("id").MustString()),
+		LBLcuuid: lb.Lcuuid,
+		Name:     jRule.Get("name").MustString(),
+		IPs:      frontendIDToIP[resourceKey(ruleProperties.Get("frontendIPConfiguration").Get("id").MustString())],
+		Protocol: protocol,
+		Port:     ruleProperties.Get("frontendPort").MustInt(),
+	}
+
+	poolIDs := []string{}
+	if poolID := ruleProperties.Get("backendAddressPool").Get("id").MustString(); poolID != "" {
+		poolIDs = append(poolIDs, resourceKey(poolID))
+	}
+	jPools := ruleProperties.Get("backendAddressPools")
+	for p := range jPools.MustArray() {
+		if poolID := resourceKey(jPools.GetIndex(p).Get("id").MustString()); !common.Contains(poolIDs, poolID) {
+			poolIDs = append(poolIDs, poolID)
+		}
+	}
+	for _, poolID := range poolIDs {
+		for _, configID := range poolIDToIPConfigIDs[poolID] {
+			ipConfig, ok := a.toolDataSet.ipConfigIDToIPConfig[configID]
+			if !ok {
+				log.Infof("lb_target_server: %s vm not found", configID)
+				continue
+			}
+			targetServers = append(
+				targetServers,
+				model.LBTargetServer{
+					Lcuuid:           common.GenerateUUID(listener.Lcuuid + configID),
+					LBLcuuid:         lb.Lcuuid,
+					LBListenerLcuuid: listener.Lcuuid,
+					Type:             common.LB_SERVER_TYPE_VM,
+					VMLcuuid:         ipConfig.VMLcuuid,
+					VPCLcuuid:        lb.VPCLcuuid,
+					IP:               ipConfig.IP,
+					Port:             ruleProperties.Get("backendPort").MustInt(),
+					Protocol:         protocol,
+				},
+			)
+		}
+	}
+	return
+}
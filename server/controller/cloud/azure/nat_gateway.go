@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+// NAT网关关联的子网中的云服务器通过该NAT网关访问公网
+func (a *Azure) getNATGateways() (
+	natGateways []model.NATGateway, natVMConnections []model.NATVMConnection, vifs []model.VInterface, ips []model.IP, err error,
+) {
+	jNGs, err := a.getRawData("/providers/Microsoft.Network/natGateways", API_VERSION_NETWORK, "natGateways")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return
+	}
+
+	requiredAttrs := []string{"id", "name", "location", "properties"}
+	for i := range jNGs {
+		jNG := jNGs[i]
+		name := jNG.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNG, requiredAttrs) {
+			log.Infof("exclude nat_gateway: %s, missing attr", name)
+			continue
+		}
+		regionLcuuid, ok := a.getRegionLcuuid(jNG.Get("location").MustString())
+		if !ok {
+			continue
+		}
+		var vpcLcuuid string
+		var vmLcuuids []string
+		jSubnets := jNG.Get("properties").Get("subnets")
+		for s := range jSubnets.MustArray() {
+			subnetID := resourceKey(jSubnets.GetIndex(s).Get("id").MustString())
+			network, ok := a.toolDataSet.subnetIDToNetwork[subnetID]
+			if !ok {
+				continue
+			}
+			vpcLcuuid = network.VPCLcuuid
+			for _, vmLcuuid := range a.toolDataSet.subnetIDToVMLcuuids[subnetID] {
+				if !common.Contains(vmLcuuids, vmLcuuid) {
+					vmLcuuids = append(vmLcuuids, vmLcuuid)
+				}
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude nat_gateway: %s, missing vpc info", name)
+			continue
+		}
+		var floatingIPs []string
+		jPublicIPs := jNG.Get("properties").Get("publicIpAddresses")
+		for p := range jPublicIPs.MustArray() {
+			if ip, ok := a.toolDataSet.publicIPIDToIP[resourceKey(jPublicIPs.GetIndex(p).Get("id").MustString())]; ok {
+				floatingIPs = append(floatingIPs, ip)
+			}
+		}
+
+		id := jNG.Get("id").MustString()
+		natGateway := model.NATGateway{
+			Lcuuid:       generateLcuuid(id),
+			Name:         name,
+			Label:        name,
+			FloatingIPs:  strings.Join(floatingIPs, common.STRINGS_JOIN_COMMA),
+			VPCLcuuid:    vpcLcuuid,
+			RegionLcuuid: regionLcuuid,
+		}
+		natGateways = append(natGateways, natGateway)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		for _, vmLcuuid := range vmLcuuids {
+			natVMConnections = append(
+				natVMConnections,
+				model.NATVMConnection{
+					Lcuuid:           common.GenerateUUID(natGateway.Lcuuid + vmLcuuid),
+					NATGatewayLcuuid: natGateway.Lcuuid,
+					VMLcuuid:         vmLcuuid,
+				},
+			)
+		}
+
+		if len(floatingIPs) == 0 {
+			continue
+		}
+		vifLcuuid := common.GenerateUUID(natGateway.Lcuuid)
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        vifLcuuid,
+				Type:          common.VIF_TYPE_WAN,
+				Mac:           common.VIF_DEFAULT_MAC,
+				DeviceLcuuid:  natGateway.Lcuuid,
+				DeviceType:    common.VIF_DEVICE_TYPE_NAT_GATEWAY,
+				NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+				VPCLcuuid:     vpcLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+		for _, fip := range floatingIPs {
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(vifLcuuid + fip),
+					VInterfaceLcuuid: vifLcuuid,
+					IP:               fip,
+					SubnetLcuuid:     common.SUBNET_ISP_LCUUID,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+		}
+	}
+	return
+}
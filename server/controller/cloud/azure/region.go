@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+func (a *Azure) getRegions() ([]model.Region, error) {
+	jLocations, err := a.getRawData("/locations", API_VERSION_SUBSCRIPTION, "locations")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return nil, err
+	}
+
+	var regions []model.Region
+	for i := range jLocations {
+		jl := jLocations[i]
+		if !cloudcommon.CheckJsonAttributes(jl, []string{"name"}) {
+			continue
+		}
+		name := jl.Get("name").MustString()
+		// 逻辑区域(如 global、us)不包含资源
+		if jl.Get("metadata").Get("regionType").MustString() == "Logical" {
+			continue
+		}
+		if len(a.config.IncludeRegions) > 0 && !common.Contains(a.config.IncludeRegions, name) {
+			log.Infof("exclude region: %s, not included", name)
+			continue
+		}
+		if common.Contains(a.config.ExcludeRegions, name) {
+			log.Infof("exclude region: %s", name)
+			continue
+		}
+
+		region := model.Region{
+			Lcuuid: common.GenerateUUID(name + "_" + a.lcuuidGenerate),
+			Name:   jl.Get("displayName").MustString(name),
+		}
+		regions = append(regions, region)
+		a.toolDataSet.locationToRegionLcuuid[name] = region.Lcuuid
+	}
+	return regions, nil
+}
+
+// 资源所在location不在对接的区域中时返回false，该资源不做同步
+func (a *Azure) getRegionLcuuid(location string) (string, bool) {
+	regionLcuuid, ok := a.toolDataSet.locationToRegionLcuuid[location]
+	if !ok {
+		return "", false
+	}
+	if a.config.RegionLcuuid != "" {
+		return a.config.RegionLcuuid, true
+	}
+	return regionLcuuid, true
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+// AKS集群对应附属容器集群，集群ID由AKS资源ID生成，部署在该集群中的采集器需配置相同的kubernetes-cluster-id
+func (a *Azure) getSubDomains() ([]model.SubDomain, error) {
+	var subDomains []model.SubDomain
+	jClusters, err := a.getRawData("/providers/Microsoft.ContainerService/managedClusters", API_VERSION_CONTAINER_SERVICE, "managedClusters")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location", "properties"}
+	for i := range jClusters {
+		jc := jClusters[i]
+		name := jc.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jc, requiredAttrs) {
+			log.Infof("exclude sub_domain: %s, missing attr", name)
+			continue
+		}
+		regionLcuuid, ok := a.getRegionLcuuid(jc.Get("location").MustString())
+		if !ok {
+			continue
+		}
+		vpcLcuuid := a.getClusterVPCLcuuid(jc.Get("properties").Get("agentPoolProfiles").MustArray(), jc.Get("properties").Get("nodeResourceGroup").MustString())
+		if vpcLcuuid == "" {
+			log.Infof("exclude sub_domain: %s, missing vpc info", name)
+			continue
+		}
+		id := resourceKey(jc.Get("id").MustString())
+		md5Sum := md5.Sum([]byte(id))
+		clusterID, err := common.GenerateKuberneteClusterIDByMD5(hex.EncodeToString(md5Sum[:]))
+		if err != nil {
+			log.Errorf("generate sub_domain: %s cluster_id failed: %s", name, err.Error())
+			continue
+		}
+
+		config := map[string]string{
+			"vpc_uuid":        vpcLcuuid,
+			"cluster_id":      clusterID,
+			"port_name_regex": common.DEFAULT_PORT_NAME_REGEX,
+			"vtap_id":         "",
+			"controller_ip":   "",
+			"region_uuid":     regionLcuuid,
+		}
+		configJson, _ := json.Marshal(config)
+		subDomains = append(subDomains, model.SubDomain{
+			Lcuuid:      generateLcuuid(id),
+			Name:        name,
+			DisplayName: clusterID,
+			ClusterID:   clusterID,
+			VpcUUID:     vpcLcuuid,
+			Config:      string(configJson),
+		})
+	}
+	return subDomains, nil
+}
+
+// 节点池使用自定义VNet时通过子网确定VPC，否则使用节点资源组中AKS自动创建的VNet
+func (a *Azure) getClusterVPCLcuuid(agentPools []interface{}, nodeResourceGroup string) string {
+	for _, pool := range agentPools {
+		poolMap, ok := pool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		subnetID, _ := poolMap["vnetSubnetID"].(string)
+		if network, ok := a.toolDataSet.subnetIDToNetwork[resourceKey(subnetID)]; ok {
+			return network.VPCLcuuid
+		}
+	}
+	if nodeResourceGroup == "" {
+		return ""
+	}
+	vnetIDs := []string{}
+	for vnetID := range a.toolDataSet.vnetIDToVPC {
+		if strings.Contains(vnetID, "/resourcegroups/"+resourceKey(nodeResourceGroup)+"/") {
+			vnetIDs = append(vnetIDs, vnetID)
+		}
+	}
+	if len(vnetIDs) == 0 {
+		return ""
+	}
+	sort.Strings(vnetIDs)
+	return a.toolDataSet.vnetIDToVPC[vnetIDs[0]].Lcuuid
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+)
+
+type Token struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// 离失效时间小于5m时认为token已过期
+func (t *Token) isExpired() bool {
+	return t == nil || time.Now().Add(5*time.Minute).After(t.expiresAt)
+}
+
+func (a *Azure) getToken() (string, error) {
+	if a.token.isExpired() {
+		token, err := a.createToken()
+		if err != nil {
+			return "", err
+		}
+		a.token = token
+	}
+	return a.token.accessToken, nil
+}
+
+// 使用服务主体的client credentials获取资源管理API的token
+func (a *Azure) createToken() (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.config.ClientID)
+	form.Set("client_secret", a.config.ClientSecret)
+	form.Set("scope", a.config.ResourceManagerEndpoint+"/.default")
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.config.LoginEndpoint, a.config.TenantID)
+	log.Infof("url: %s", tokenURL)
+
+	client := &http.Client{Timeout: time.Second * 60}
+	resp, err := client.PostForm(tokenURL, form)
+	if err != nil {
+		log.Errorf("request failed: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("read failed: %s", err.Error())
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("request token failed: %s", string(respBody))
+		return nil, errors.New(fmt.Sprintf("request token failed: %s %s", resp.Status, string(respBody)))
+	}
+	jResp, err := simplejson.NewJson(respBody)
+	if err != nil {
+		log.Errorf("jsonify failed: %s", err.Error())
+		return nil, err
+	}
+	accessToken := jResp.Get("access_token").MustString()
+	if accessToken == "" {
+		return nil, errors.New("access_token not found in response")
+	}
+	return &Token{
+		accessToken: accessToken,
+		expiresAt:   time.Now().Add(time.Duration(jResp.Get("expires_in").MustInt(3600)) * time.Second),
+	}, nil
+}
+
+func (a *Azure) requestGet(reqURL string) (*simplejson.Json, error) {
+	token, err := a.getToken()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("url: %s", reqURL)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		log.Errorf("new request failed: %s", err.Error())
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: time.Second * 60}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Errorf("request failed: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("read failed: %s", err.Error())
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("request failed: %s", string(respBody))
+		return nil, errors.New(fmt.Sprintf("request failed: %s %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+	return simplejson.NewJson(respBody)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+)
+
+// 以下map的key均为小写的Azure资源ID
+type ToolDataSet struct {
+	locationToRegionLcuuid      map[string]string
+	lcuuidToAZ                  map[string]model.AZ
+	vnetIDToVPC                 map[string]model.VPC
+	subnetIDToNetwork           map[string]model.Network
+	networkLcuuidToSubnetLcuuid map[string]string
+	publicIPIDToIP              map[string]string
+	ipConfigIDToIPConfig        map[string]IPConfig
+	vmIDToVPCLcuuid             map[string]string
+	subnetIDToVMLcuuids         map[string][]string
+
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		locationToRegionLcuuid:      make(map[string]string),
+		lcuuidToAZ:                  make(map[string]model.AZ),
+		vnetIDToVPC:                 make(map[string]model.VPC),
+		subnetIDToNetwork:           make(map[string]model.Network),
+		networkLcuuidToSubnetLcuuid: make(map[string]string),
+		publicIPIDToIP:              make(map[string]string),
+		ipConfigIDToIPConfig:        make(map[string]IPConfig),
+		vmIDToVPCLcuuid:             make(map[string]string),
+		subnetIDToVMLcuuids:         make(map[string][]string),
+		regionLcuuidToResourceNum:   make(map[string]int),
+		azLcuuidToResourceNum:       make(map[string]int),
+	}
+}
+
+// 网卡的IP配置，负载均衡后端池通过IP配置ID关联云服务器
+type IPConfig struct {
+	VMLcuuid  string
+	VPCLcuuid string
+	IP        string
+}
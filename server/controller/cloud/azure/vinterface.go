@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+func (a *Azure) getPublicIPs() error {
+	jPublicIPs, err := a.getRawData("/providers/Microsoft.Network/publicIPAddresses", API_VERSION_NETWORK, "publicIPAddresses")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return err
+	}
+	for i := range jPublicIPs {
+		jp := jPublicIPs[i]
+		ip := jp.Get("properties").Get("ipAddress").MustString()
+		if ip == "" {
+			log.Debugf("public ip: %s not allocated", jp.Get("name").MustString())
+			continue
+		}
+		a.toolDataSet.publicIPIDToIP[resourceKey(jp.Get("id").MustString())] = ip
+	}
+	return nil
+}
+
+// 仅同步挂载到云服务器的网卡，网卡的公网IP生成单独的WAN接口
+func (a *Azure) getVInterfaces() (vifs []model.VInterface, ips []model.IP, err error) {
+	jNICs, err := a.getRawData("/providers/Microsoft.Network/networkInterfaces", API_VERSION_NETWORK, "networkInterfaces")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return
+	}
+
+	requiredAttrs := []string{"id", "name", "location", "properties"}
+	for i := range jNICs {
+		jn := jNICs[i]
+		name := jn.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jn, requiredAttrs) {
+			log.Infof("exclude vinterface: %s, missing attr", name)
+			continue
+		}
+		regionLcuuid, ok := a.getRegionLcuuid(jn.Get("location").MustString())
+		if !ok {
+			continue
+		}
+		properties := jn.Get("properties")
+		vmID := resourceKey(properties.Get("virtualMachine").Get("id").MustString())
+		if vmID == "" {
+			log.Infof("exclude vinterface: %s, not attached to vm", name)
+			continue
+		}
+		vmLcuuid := generateLcuuid(vmID)
+		mac := common.VIF_DEFAULT_MAC
+		if m := properties.Get("macAddress").MustString(); m != "" {
+			mac = strings.ToLower(strings.ReplaceAll(m, "-", ":"))
+		}
+
+		vifLcuuid := generateLcuuid(jn.Get("id").MustString())
+		var vif model.VInterface
+		var publicIPs []string
+		jConfigs := properties.Get("ipConfigurations")
+		for c := range jConfigs.MustArray() {
+			jc := jConfigs.GetIndex(c)
+			subnetID := resourceKey(jc.Get("properties").Get("subnet").Get("id").MustString())
+			network, ok := a.toolDataSet.subnetIDToNetwork[subnetID]
+			if !ok {
+				log.Infof("vinterface: %s ip configuration subnet not found", name)
+				continue
+			}
+			if vif.Lcuuid == "" {
+				vif = model.VInterface{
+					Lcuuid:        vifLcuuid,
+					Name:          name,
+					Type:          common.VIF_TYPE_LAN,
+					Mac:           mac,
+					DeviceLcuuid:  vmLcuuid,
+					DeviceType:    common.VIF_DEVICE_TYPE_VM,
+					NetworkLcuuid: network.Lcuuid,
+					VPCLcuuid:     network.VPCLcuuid,
+					RegionLcuuid:  regionLcuuid,
+				}
+			}
+			if _, ok := a.toolDataSet.vmIDToVPCLcuuid[vmID]; !ok || properties.Get("primary").MustBool() {
+				a.toolDataSet.vmIDToVPCLcuuid[vmID] = network.VPCLcuuid
+			}
+			if !common.Contains(a.toolDataSet.subnetIDToVMLcuuids[subnetID], vmLcuuid) {
+				a.toolDataSet.subnetIDToVMLcuuids[subnetID] = append(a.toolDataSet.subnetIDToVMLcuuids[subnetID], vmLcuuid)
+			}
+
+			privateIP := jc.Get("properties").Get("privateIPAddress").MustString()
+			a.toolDataSet.ipConfigIDToIPConfig[resourceKey(jc.Get("id").MustString())] = IPConfig{
+				VMLcuuid:  vmLcuuid,
+				VPCLcuuid: network.VPCLcuuid,
+				IP:        privateIP,
+			}
+			if privateIP != "" {
+				ips = append(
+					ips,
+					model.IP{
+						Lcuuid:           common.GenerateUUID(vifLcuuid + privateIP),
+						VInterfaceLcuuid: vifLcuuid,
+						IP:               privateIP,
+						SubnetLcuuid:     a.toolDataSet.networkLcuuidToSubnetLcuuid[network.Lcuuid],
+						RegionLcuuid:     regionLcuuid,
+					},
+				)
+			}
+			if publicIP, ok := a.toolDataSet.publicIPIDToIP[resourceKey(jc.Get("properties").Get("publicIPAddress").Get("id").MustString())]; ok {
+				publicIPs = append(publicIPs, publicIP)
+			}
+		}
+		if vif.Lcuuid == "" {
+			log.Infof("exclude vinterface: %s, missing network info", name)
+			continue
+		}
+		vifs = append(vifs, vif)
+
+		if len(publicIPs) == 0 {
+			continue
+		}
+		wanVIFLcuuid := common.GenerateUUID(vifLcuuid + "_wan")
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        wanVIFLcuuid,
+				Name:          name,
+				Type:          common.VIF_TYPE_WAN,
+				Mac:           cloudcommon.GenerateWANVInterfaceMac(mac),
+				DeviceLcuuid:  vmLcuuid,
+				DeviceType:    common.VIF_DEVICE_TYPE_VM,
+				NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+				VPCLcuuid:     vif.VPCLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+		for _, publicIP := range publicIPs {
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(wanVIFLcuuid + publicIP),
+					VInterfaceLcuuid: wanVIFLcuuid,
+					IP:               publicIP,
+					SubnetLcuuid:     common.SUBNET_ISP_LCUUID,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"time"
+
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[string]int{
+	"PowerState/running":     common.VM_STATE_RUNNING,
+	"PowerState/stopped":     common.VM_STATE_STOPPED,
+	"PowerState/deallocated": common.VM_STATE_STOPPED,
+}
+
+func (a *Azure) getVMs() ([]model.VM, error) {
+	var vms []model.VM
+	// statusOnly=true时返回instanceView，用于获取运行状态
+	jVMs, err := a.getRawData("/providers/Microsoft.Compute/virtualMachines", API_VERSION_COMPUTE, "virtualMachines", "statusOnly=true")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, requiredAttrs) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		location := jVM.Get("location").MustString()
+		regionLcuuid, ok := a.getRegionLcuuid(location)
+		if !ok {
+			continue
+		}
+		id := resourceKey(jVM.Get("id").MustString())
+		vpcLcuuid, ok := a.toolDataSet.vmIDToVPCLcuuid[id]
+		if !ok {
+			log.Infof("exclude vm: %s, missing vpc info", name)
+			continue
+		}
+
+		state := common.VM_STATE_EXCEPTION
+		jStatuses := jVM.Get("properties").Get("instanceView").Get("statuses")
+		for s := range jStatuses.MustArray() {
+			if st, ok := STATE_CONVERTION[jStatuses.GetIndex(s).Get("code").MustString()]; ok {
+				state = st
+				break
+			}
+		}
+		azLcuuid := a.getAZLcuuid(location, regionLcuuid, jVM.Get("zones"))
+		vm := model.VM{
+			Lcuuid:       generateLcuuid(id),
+			Name:         name,
+			Label:        jVM.Get("properties").Get("vmId").MustString(name),
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+			VPCLcuuid:    vpcLcuuid,
+		}
+		if created := jVM.Get("properties").Get("timeCreated").MustString(); created != "" {
+			createdAt, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				log.Errorf("parse created failed: %s", created)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		a.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return vms, nil
+}
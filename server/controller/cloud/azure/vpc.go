@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+)
+
+// VNet对应VPC，VNet中的子网对应网络和子网，VNet对等连接对应对等连接
+func (a *Azure) getVPCs() (
+	vpcs []model.VPC, networks []model.Network, subnets []model.Subnet, peers []model.PeerConnection, err error,
+) {
+	jVNets, err := a.getRawData("/providers/Microsoft.Network/virtualNetworks", API_VERSION_NETWORK, "virtualNetworks")
+	if err != nil {
+		log.Errorf("request failed: %v", err)
+		return
+	}
+
+	requiredAttrs := []string{"id", "name", "location", "properties"}
+	for i := range jVNets {
+		jv := jVNets[i]
+		name := jv.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jv, requiredAttrs) {
+			log.Infof("exclude vpc: %s, missing attr", name)
+			continue
+		}
+		regionLcuuid, ok := a.getRegionLcuuid(jv.Get("location").MustString())
+		if !ok {
+			log.Infof("exclude vpc: %s, region not included", name)
+			continue
+		}
+		id := jv.Get("id").MustString()
+		vpc := model.VPC{
+			Lcuuid:       generateLcuuid(id),
+			Name:         name,
+			RegionLcuuid: regionLcuuid,
+		}
+		if prefixes := jv.Get("properties").Get("addressSpace").Get("addressPrefixes").MustStringArray(); len(prefixes) > 0 {
+			vpc.CIDR = prefixes[0]
+		}
+		vpcs = append(vpcs, vpc)
+		a.toolDataSet.vnetIDToVPC[resourceKey(id)] = vpc
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		azLcuuid := a.getAZLcuuid(jv.Get("location").MustString(), regionLcuuid, jv.Get("zones"))
+		jSubnets := jv.Get("properties").Get("subnets")
+		for s := range jSubnets.MustArray() {
+			js := jSubnets.GetIndex(s)
+			subnetID := js.Get("id").MustString()
+			subnetName := js.Get("name").MustString()
+			cidr := js.Get("properties").Get("addressPrefix").MustString()
+			if cidr == "" {
+				if prefixes := js.Get("properties").Get("addressPrefixes").MustStringArray(); len(prefixes) > 0 {
+					cidr = prefixes[0]
+				}
+			}
+			if subnetID == "" || cidr == "" {
+				log.Infof("exclude network: %s, missing attr", subnetName)
+				continue
+			}
+			network := model.Network{
+				Lcuuid:         generateLcuuid(subnetID),
+				Name:           subnetName,
+				SegmentationID: 1,
+				Shared:         false,
+				External:       false,
+				NetType:        common.NETWORK_TYPE_LAN,
+				VPCLcuuid:      vpc.Lcuuid,
+				AZLcuuid:       azLcuuid,
+				RegionLcuuid:   regionLcuuid,
+			}
+			networks = append(networks, network)
+			a.toolDataSet.subnetIDToNetwork[resourceKey(subnetID)] = network
+			a.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+			a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+			subnetLcuuid := common.GenerateUUID(network.Lcuuid)
+			subnets = append(
+				subnets,
+				model.Subnet{
+					Lcuuid:        subnetLcuuid,
+					Name:          subnetName,
+					CIDR:          cidr,
+					NetworkLcuuid: network.Lcuuid,
+					VPCLcuuid:     vpc.Lcuuid,
+				},
+			)
+			a.toolDataSet.networkLcuuidToSubnetLcuuid[network.Lcuuid] = subnetLcuuid
+		}
+	}
+
+	// 对等连接在两端VNet中各有一条记录，仅保留两端VNet均已同步且处于连接状态的记录，并按VNet对去重
+	peerKeys := map[string]bool{}
+	for i := range jVNets {
+		jv := jVNets[i]
+		localVPC, ok := a.toolDataSet.vnetIDToVPC[resourceKey(jv.Get("id").MustString())]
+		if !ok {
+			continue
+		}
+		jPeerings := jv.Get("properties").Get("virtualNetworkPeerings")
+		for p := range jPeerings.MustArray() {
+			jp := jPeerings.GetIndex(p)
+			name := jp.Get("name").MustString()
+			if jp.Get("properties").Get("peeringState").MustString() != "Connected" {
+				log.Infof("exclude peer_connection: %s, not connected", name)
+				continue
+			}
+			remoteVPC, ok := a.toolDataSet.vnetIDToVPC[resourceKey(jp.Get("properties").Get("remoteVirtualNetwork").Get("id").MustString())]
+			if !ok {
+				log.Infof("exclude peer_connection: %s, remote vpc not found", name)
+				continue
+			}
+			key := localVPC.Lcuuid + remoteVPC.Lcuuid
+			if remoteVPC.Lcuuid < localVPC.Lcuuid {
+				key = remoteVPC.Lcuuid + localVPC.Lcuuid
+			}
+			if peerKeys[key] {
+				continue
+			}
+			peerKeys[key] = true
+			lcuuid := common.GenerateUUID(key)
+			peers = append(
+				peers,
+				model.PeerConnection{
+					Lcuuid:             lcuuid,
+					Name:               name,
+					Label:              lcuuid,
+					LocalVPCLcuuid:     localVPC.Lcuuid,
+					RemoteVPCLcuuid:    remoteVPC.Lcuuid,
+					LocalRegionLcuuid:  localVPC.RegionLcuuid,
+					RemoteRegionLcuuid: remoteVPC.RegionLcuuid,
+				},
+			)
+		}
+	}
+	return
+}
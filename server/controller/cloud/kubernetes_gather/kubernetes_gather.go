@@ -38,6 +38,9 @@ const (
 	K8S_VERSION_PREFIX        = "Kubernetes"
 )
 
+// 默认按工作负载处理的自定义资源类型: OpenKruise CloneSet/Advanced StatefulSet, Argo Rollouts
+var K8S_CUSTOM_WORKLOAD_KINDS = []string{"CloneSet", "StatefulSet", "Rollout"}
+
 var log = logging.MustGetLogger("cloud.kubernetes_gather")
 
 type KubernetesGather struct {
@@ -50,6 +53,7 @@ type KubernetesGather struct {
 	PortNameRegex                string
 	PodNetIPv4CIDRMaxMask        int
 	PodNetIPv6CIDRMaxMask        int
+	CustomWorkloadKinds          []string
 	isSubDomain                  bool
 	azLcuuid                     string
 	podGroupLcuuids              mapset.Set
@@ -123,6 +127,15 @@ func NewKubernetesGather(domain *mysql.Domain, subDomain *mysql.SubDomain, isSub
 		podNetIPv6CIDRMaxMask = K8S_POD_IPV6_NETMASK
 	}
 
+	customWorkloadKinds := K8S_CUSTOM_WORKLOAD_KINDS
+	if _, ok := configJson.CheckGet("custom_workload_kinds"); ok {
+		customWorkloadKinds, err = configJson.Get("custom_workload_kinds").StringArray()
+		if err != nil {
+			log.Errorf("newkubernetesgather custom_workload_kinds (%v) error: (%s)", configJson.Get("custom_workload_kinds").Interface(), err.Error())
+			return nil
+		}
+	}
+
 	return &KubernetesGather{
 		// TODO: display_name后期需要修改为uuid_generate
 		Name:                  name,
@@ -134,6 +147,7 @@ func NewKubernetesGather(domain *mysql.Domain, subDomain *mysql.SubDomain, isSub
 		PodNetIPv4CIDRMaxMask: podNetIPv4CIDRMaxMask,
 		PodNetIPv6CIDRMaxMask: podNetIPv6CIDRMaxMask,
 		PortNameRegex:         portNameRegex,
+		CustomWorkloadKinds:   customWorkloadKinds,
 
 		// 以下属性为获取资源所用的关联关系
 		azLcuuid:                     "",
@@ -273,6 +287,13 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	if err != nil {
 		return model.KubernetesGatherResource{}, err
 	}
+	routes, routeRules, routeBackends, err := k.getPodRoutes()
+	if err != nil {
+		return model.KubernetesGatherResource{}, err
+	}
+	ingresses = append(ingresses, routes...)
+	ingressRules = append(ingressRules, routeRules...)
+	ingressRuleBackends = append(ingressRuleBackends, routeBackends...)
 	for index, s := range podServices {
 		if ingressLcuuid, ok := k.serviceLcuuidToIngressLcuuid[s.Lcuuid]; ok {
 			podServices[index].PodIngressLcuuid = ingressLcuuid
@@ -115,3 +115,100 @@ func TestKubernetes(t *testing.T) {
 		})
 	})
 }
+
+func TestCustomWorkload(t *testing.T) {
+	Convey("TestCustomWorkload", t, func() {
+		k8sConfig := mysql.SubDomain{
+			Name:        "test_k8s",
+			DisplayName: "test_k8s",
+			ClusterID:   "d-01LMvvfQPZ",
+			Config:      fmt.Sprintf(`{"region_uuid": "%s","vpc_uuid": ""}`, common.DEFAULT_REGION),
+		}
+		k8s := NewKubernetesGather(nil, &k8sConfig, false)
+		So(k8s.CustomWorkloadKinds, ShouldResemble, K8S_CUSTOM_WORKLOAD_KINDS)
+
+		k8s.namespaceToLcuuid = map[string]string{"default": "ns-lcuuid"}
+		k8s.k8sInfo = map[string][]string{
+			"*apps.kruise.io/v1alpha1.CloneSet": {
+				`{"metadata":{"name":"web","namespace":"default","uid":"cloneset-uid","labels":{"app":"web"}},"spec":{"replicas":3,"selector":{"matchLabels":{"app":"web"}},"template":{"spec":{"containers":[{"name":"web","ports":[{"name":"http","containerPort":8080}]}]}}}}`,
+			},
+			"*argoproj.io/v1alpha1.Rollout": {
+				`{"metadata":{"name":"api","namespace":"default","uid":"rollout-uid"},"spec":{"replicas":2,"selector":{"matchLabels":{"app":"api"}}}}`,
+			},
+			"*example.com/v1.Unknown": {
+				`{"metadata":{"name":"other","namespace":"default","uid":"unknown-uid"},"spec":{"replicas":1}}`,
+			},
+		}
+		podGroups, err := k8s.getPodGroups()
+		So(err, ShouldBeNil)
+		So(len(podGroups), ShouldEqual, 2)
+		So(podGroups[0].Lcuuid, ShouldEqual, "cloneset-uid")
+		So(podGroups[0].Type, ShouldEqual, common.POD_GROUP_CUSTOM)
+		So(podGroups[0].PodNum, ShouldEqual, 3)
+		So(podGroups[1].Lcuuid, ShouldEqual, "rollout-uid")
+		So(k8s.podGroupLcuuids.Contains("rollout-uid"), ShouldBeTrue)
+		So(k8s.pgLcuuidTopodTargetPorts["cloneset-uid"]["http"], ShouldEqual, 8080)
+		So(k8s.nsLabelToGroupLcuuids["defaultapp_web"].Contains("cloneset-uid"), ShouldBeTrue)
+
+		k8sConfig.Config = `{"custom_workload_kinds": ["Rollout"]}`
+		k8s = NewKubernetesGather(nil, &k8sConfig, false)
+		So(k8s.CustomWorkloadKinds, ShouldResemble, []string{"Rollout"})
+		k8s.namespaceToLcuuid = map[string]string{"default": "ns-lcuuid"}
+		k8s.k8sInfo = map[string][]string{
+			"*apps.kruise.io/v1alpha1.CloneSet": {`{"metadata":{"name":"web","namespace":"default","uid":"cloneset-uid"}}`},
+		}
+		podGroups, _ = k8s.getPodGroups()
+		So(len(podGroups), ShouldEqual, 0)
+	})
+}
+
+func TestPodRoutes(t *testing.T) {
+	Convey("TestPodRoutes", t, func() {
+		k8sConfig := mysql.SubDomain{
+			Name:        "test_k8s",
+			DisplayName: "test_k8s",
+			ClusterID:   "d-01LMvvfQPZ",
+			Config:      fmt.Sprintf(`{"region_uuid": "%s","vpc_uuid": ""}`, common.DEFAULT_REGION),
+		}
+		k8s := NewKubernetesGather(nil, &k8sConfig, false)
+		k8s.namespaceToLcuuid = map[string]string{"default": "ns-lcuuid", "shop": "shop-lcuuid"}
+		k8s.nsServiceNameToService = map[string]map[string]map[string]int{
+			"defaultweb":     {"web-svc": {"http": 80}},
+			"shopreviews":    {"reviews-svc": {"http": 9080, "grpc": 9090, "admin": 19080}, "reviews-svc-2": {"http": 80}},
+			"defaultgateway": {"gateway-svc": {"http": 8080}},
+		}
+		k8s.serviceLcuuidToIngressLcuuid = map[string]string{"gateway-svc": "ingress-uid"}
+		k8s.k8sInfo = map[string][]string{
+			"*gateway.networking.k8s.io/v1beta1.HTTPRoute": {
+				`{"metadata":{"name":"web-route","namespace":"default","uid":"httproute-uid"},"spec":{"hostnames":["web.example.com"],"rules":[{"matches":[{"path":{"type":"PathPrefix","value":"/api"}}],"backendRefs":[{"name":"web","port":80},{"kind":"Bucket","name":"web"}]}]}}`,
+			},
+			"*gateway.networking.k8s.io/v1.Gateway": {
+				`{"metadata":{"name":"gw","namespace":"default","uid":"gateway-api-uid"},"spec":{"listeners":[{"name":"http","hostname":"*.example.com","protocol":"HTTP","port":80}]}}`,
+			},
+			"*networking.istio.io/v1beta1.VirtualService": {
+				`{"metadata":{"name":"reviews","namespace":"default","uid":"vs-uid"},"spec":{"hosts":["reviews.example.com"],"http":[{"match":[{"uri":{"prefix":"/reviews"}}],"route":[{"destination":{"host":"reviews.shop.svc.cluster.local"}}]},{"route":[{"destination":{"host":"gateway","port":{"number":8080}}}]}]}}`,
+			},
+			"*networking.istio.io/v1beta1.Gateway": {
+				`{"metadata":{"name":"istio-gw","namespace":"default","uid":"istio-gateway-uid"},"spec":{"servers":[{"port":{"number":443,"name":"https","protocol":"HTTPS"},"hosts":["a.example.com","b.example.com"]}]}}`,
+			},
+		}
+		ingresses, rules, backends, err := k8s.getPodRoutes()
+		So(err, ShouldBeNil)
+		So(len(ingresses), ShouldEqual, 4)
+		So(ingresses[0].Lcuuid, ShouldEqual, "httproute-uid")
+		So(len(rules), ShouldEqual, 5)
+		So(rules[0].Host, ShouldEqual, "web.example.com")
+		So(rules[1].Protocol, ShouldEqual, "HTTP")
+		So(rules[2].Protocol, ShouldEqual, "HTTP")
+		So(rules[3].Protocol, ShouldEqual, "HTTPS")
+		So(len(backends), ShouldEqual, 3)
+		So(backends[0].Path, ShouldEqual, "/api")
+		So(backends[0].PodServiceLcuuid, ShouldEqual, "web-svc")
+		So(backends[1].Port, ShouldEqual, 9080)
+		So(backends[1].PodServiceLcuuid, ShouldEqual, "reviews-svc")
+		So(backends[2].Port, ShouldEqual, 8080)
+		So(k8s.serviceLcuuidToIngressLcuuid["web-svc"], ShouldEqual, "httproute-uid")
+		So(k8s.serviceLcuuidToIngressLcuuid["reviews-svc"], ShouldEqual, "vs-uid")
+		So(k8s.serviceLcuuidToIngressLcuuid["gateway-svc"], ShouldEqual, "ingress-uid")
+	})
+}
@@ -30,7 +30,8 @@ import (
 
 func (k *KubernetesGather) getPods() (pods []model.Pod, nodes []model.PodNode, err error) {
 	log.Debug("get pods starting")
-	podTypes := [4]string{"StatefulSet", "ReplicaSet", "ReplicationController", "Deployment"}
+	podTypes := []string{"StatefulSet", "ReplicaSet", "ReplicationController", "Deployment"}
+	podTypes = append(podTypes, k.CustomWorkloadKinds...)
 	abstractNodes := map[string]int{}
 	for _, p := range k.k8sInfo["*v1.Pod"] {
 		pData, pErr := simplejson.NewJson([]byte(p))
@@ -20,6 +20,7 @@ import (
 	cloudcommon "github.com/deepflowys/deepflow/server/controller/cloud/common"
 	"github.com/deepflowys/deepflow/server/controller/cloud/model"
 	"github.com/deepflowys/deepflow/server/controller/common"
+	"sort"
 	"strings"
 
 	"github.com/bitly/go-simplejson"
@@ -29,11 +30,17 @@ import (
 
 func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error) {
 	log.Debug("get podgroups starting")
-	podControllers := [4][]string{}
+	podControllers := make([][]string, 4)
 	podControllers[0] = k.k8sInfo["*v1.Deployment"]
 	podControllers[1] = k.k8sInfo["*v1.StatefulSet"]
 	podControllers[2] = k.k8sInfo["*v1.DaemonSet"]
 	podControllers[3] = k.k8sInfo["*v1.Pod"]
+	// 自定义工作负载追加在内置类型之后, 记录下标对应的kind
+	customKinds := map[int]string{}
+	for _, key := range k.getCustomWorkloadKeys() {
+		customKinds[len(podControllers)] = customWorkloadKind(key)
+		podControllers = append(podControllers, k.k8sInfo[key])
+	}
 	pgNameToTypeID := map[string]int{
 		"deployment":            common.POD_GROUP_DEPLOYMENT,
 		"statefulset":           common.POD_GROUP_STATEFULSET,
@@ -115,6 +122,11 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 				typeName := strings.ToLower(abstractPGType)
 				serviceType = pgNameToTypeID[typeName]
 				label = typeName + ":" + namespace + ":" + abstractPGName
+			default:
+				if kind, ok := customKinds[t]; ok {
+					serviceType = common.POD_GROUP_CUSTOM
+					label = strings.ToLower(kind) + namespace + ":" + name
+				}
 			}
 
 			_, ok = k.nsLabelToGroupLcuuids[namespace+label]
@@ -176,6 +188,30 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 	return
 }
 
+// getCustomWorkloadKeys 返回k8sInfo中需要作为工作负载的自定义资源
+// 采集器上报的自定义资源key格式为 *group/version.Kind, 例如 *apps.kruise.io/v1alpha1.CloneSet
+func (k *KubernetesGather) getCustomWorkloadKeys() []string {
+	keys := []string{}
+	for key := range k.k8sInfo {
+		if !strings.Contains(key, "/") {
+			continue
+		}
+		kind := customWorkloadKind(key)
+		for _, customKind := range k.CustomWorkloadKinds {
+			if customKind == kind {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func customWorkloadKind(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
 func (k *KubernetesGather) getPodReplicationControllers() (podRCs []model.PodGroup, err error) {
 	log.Debug("get replicationcontrollers starting")
 	for _, r := range k.k8sInfo["*v1.ReplicationController"] {
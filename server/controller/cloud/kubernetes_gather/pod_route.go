@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_gather
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowys/deepflow/server/controller/cloud/model"
+	"github.com/deepflowys/deepflow/server/controller/common"
+
+	"github.com/bitly/go-simplejson"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Gateway API 及 Istio 的路由资源，采集器上报类型为 *<API组>/<版本>.<Kind>，与 Ingress 一样作为容器 Ingress 处理
+const (
+	GATEWAY_API_GROUP      = "gateway.networking.k8s.io"
+	ISTIO_NETWORKING_GROUP = "networking.istio.io"
+)
+
+type routeParser func(k *KubernetesGather, uID, namespace string, spec *simplejson.Json) ([]model.PodIngressRule, []model.PodIngressRuleBackend)
+
+var routeResources = []struct {
+	group  string
+	kind   string
+	parser routeParser
+}{
+	{GATEWAY_API_GROUP, "HTTPRoute", (*KubernetesGather).parseHTTPRoute},
+	{GATEWAY_API_GROUP, "Gateway", (*KubernetesGather).parseGatewayAPIGateway},
+	{ISTIO_NETWORKING_GROUP, "VirtualService", (*KubernetesGather).parseVirtualService},
+	{ISTIO_NETWORKING_GROUP, "Gateway", (*KubernetesGather).parseIstioGateway},
+}
+
+func (k *KubernetesGather) getPodRoutes() (ingresses []model.PodIngress, ingressRules []model.PodIngressRule, ingressRuleBackends []model.PodIngressRuleBackend, err error) {
+	log.Debug("get routes starting")
+	for _, r := range routeResources {
+		for _, key := range k.getRouteKeys(r.group, r.kind) {
+			for _, i := range k.k8sInfo[key] {
+				rData, rErr := simplejson.NewJson([]byte(i))
+				if rErr != nil {
+					err = rErr
+					log.Errorf("route initialization simplejson error: (%s)", rErr.Error())
+					return
+				}
+				metaData, ok := rData.CheckGet("metadata")
+				if !ok {
+					log.Infof("%s metadata not found", r.kind)
+					continue
+				}
+				uID := metaData.Get("uid").MustString()
+				if uID == "" {
+					log.Infof("%s uid not found", r.kind)
+					continue
+				}
+				name := metaData.Get("name").MustString()
+				if name == "" {
+					log.Infof("%s (%s) name not found", r.kind, uID)
+					continue
+				}
+				namespace := metaData.Get("namespace").MustString()
+				namespaceLcuuid, ok := k.namespaceToLcuuid[namespace]
+				if !ok {
+					log.Infof("%s (%s) namespace not found", r.kind, name)
+					continue
+				}
+				ingresses = append(ingresses, model.PodIngress{
+					Lcuuid:             uID,
+					Name:               name,
+					PodNamespaceLcuuid: namespaceLcuuid,
+					AZLcuuid:           k.azLcuuid,
+					RegionLcuuid:       k.RegionUuid,
+					PodClusterLcuuid:   common.GetUUID(k.UuidGenerate, uuid.Nil),
+				})
+				rules, backends := r.parser(k, uID, namespace, rData.Get("spec"))
+				ingressRules = append(ingressRules, rules...)
+				ingressRuleBackends = append(ingressRuleBackends, backends...)
+			}
+		}
+	}
+	log.Debug("get routes complete")
+	return
+}
+
+// 匹配任意版本的 *<group>/<version>.<kind>
+func (k *KubernetesGather) getRouteKeys(group, kind string) []string {
+	keys := []string{}
+	for key := range k.k8sInfo {
+		if strings.HasPrefix(key, "*"+group+"/") && strings.HasSuffix(key, "."+kind) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HTTPRoute 每个 hostname 对应一条规则，rules 中 matches 的 path 与 backendRefs 组合为后端
+func (k *KubernetesGather) parseHTTPRoute(uID, namespace string, spec *simplejson.Json) (rules []model.PodIngressRule, backends []model.PodIngressRuleBackend) {
+	hosts := spec.Get("hostnames").MustStringArray()
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	for h, host := range hosts {
+		ruleLcuuid := common.GetUUID(uID+host+"_"+strconv.Itoa(h), uuid.Nil)
+		rules = append(rules, model.PodIngressRule{
+			Lcuuid:           ruleLcuuid,
+			Host:             host,
+			Protocol:         "HTTP",
+			PodIngressLcuuid: uID,
+		})
+		routeRules := spec.Get("rules")
+		for r := range routeRules.MustArray() {
+			routeRule := routeRules.GetIndex(r)
+			paths := []string{}
+			for m := range routeRule.Get("matches").MustArray() {
+				if path := routeRule.Get("matches").GetIndex(m).Get("path").Get("value").MustString(); path != "" {
+					paths = append(paths, path)
+				}
+			}
+			if len(paths) == 0 {
+				paths = []string{""}
+			}
+			backendRefs := routeRule.Get("backendRefs")
+			for b := range backendRefs.MustArray() {
+				backendRef := backendRefs.GetIndex(b)
+				if kind := backendRef.Get("kind").MustString(); kind != "" && kind != "Service" {
+					continue
+				}
+				backendNamespace := backendRef.Get("namespace").MustString(namespace)
+				for _, path := range paths {
+					backend, ok := k.getRouteBackend(uID, ruleLcuuid, backendNamespace, backendRef.Get("name").MustString(), path, backendRef.Get("port").MustInt(), r)
+					if ok {
+						backends = append(backends, backend)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// Gateway API Gateway 每个 listener 对应一条规则，没有后端
+func (k *KubernetesGather) parseGatewayAPIGateway(uID, namespace string, spec *simplejson.Json) (rules []model.PodIngressRule, backends []model.PodIngressRuleBackend) {
+	listeners := spec.Get("listeners")
+	for l := range listeners.MustArray() {
+		listener := listeners.GetIndex(l)
+		name := listener.Get("name").MustString()
+		rules = append(rules, model.PodIngressRule{
+			Lcuuid:           common.GetUUID(uID+name+"_"+strconv.Itoa(l), uuid.Nil),
+			Name:             name,
+			Host:             listener.Get("hostname").MustString(),
+			Protocol:         strings.ToUpper(listener.Get("protocol").MustString()),
+			PodIngressLcuuid: uID,
+		})
+	}
+	return
+}
+
+// VirtualService 每个 host 与 http/tls/tcp 路由组合为一条规则，route 中的 destination 为后端
+func (k *KubernetesGather) parseVirtualService(uID, namespace string, spec *simplejson.Json) (rules []model.PodIngressRule, backends []model.PodIngressRuleBackend) {
+	hosts := spec.Get("hosts").MustStringArray()
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	for _, protocol := range []string{"http", "tls", "tcp"} {
+		routes := spec.Get(protocol)
+		if len(routes.MustArray()) == 0 {
+			continue
+		}
+		for h, host := range hosts {
+			ruleLcuuid := common.GetUUID(uID+host+"_"+protocol+"_"+strconv.Itoa(h), uuid.Nil)
+			rules = append(rules, model.PodIngressRule{
+				Lcuuid:           ruleLcuuid,
+				Host:             host,
+				Protocol:         strings.ToUpper(protocol),
+				PodIngressLcuuid: uID,
+			})
+			for r := range routes.MustArray() {
+				route := routes.GetIndex(r)
+				paths := []string{}
+				for m := range route.Get("match").MustArray() {
+					uri := route.Get("match").GetIndex(m).Get("uri")
+					for _, matchType := range []string{"exact", "prefix", "regex"} {
+						if path := uri.Get(matchType).MustString(); path != "" {
+							paths = append(paths, path)
+						}
+					}
+				}
+				if len(paths) == 0 {
+					paths = []string{""}
+				}
+				destinations := route.Get("route")
+				for d := range destinations.MustArray() {
+					destination := destinations.GetIndex(d).Get("destination")
+					// host 可以是短域名 <service> 或 <service>.<namespace>.svc.cluster.local
+					hostParts := strings.Split(destination.Get("host").MustString(), ".")
+					serviceName, serviceNamespace := hostParts[0], namespace
+					if len(hostParts) > 1 {
+						serviceNamespace = hostParts[1]
+					}
+					for _, path := range paths {
+						backend, ok := k.getRouteBackend(uID, ruleLcuuid, serviceNamespace, serviceName, path, destination.Get("port").Get("number").MustInt(), r)
+						if ok {
+							backends = append(backends, backend)
+						}
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// Istio Gateway 每个 server 的每个 host 对应一条规则，没有后端
+func (k *KubernetesGather) parseIstioGateway(uID, namespace string, spec *simplejson.Json) (rules []model.PodIngressRule, backends []model.PodIngressRuleBackend) {
+	servers := spec.Get("servers")
+	for s := range servers.MustArray() {
+		server := servers.GetIndex(s)
+		port := server.Get("port")
+		for h, host := range server.Get("hosts").MustStringArray() {
+			rules = append(rules, model.PodIngressRule{
+				Lcuuid:           common.GetUUID(uID+host+"_"+strconv.Itoa(s)+"_"+strconv.Itoa(h), uuid.Nil),
+				Name:             port.Get("name").MustString(),
+				Host:             host,
+				Protocol:         strings.ToUpper(port.Get("protocol").MustString()),
+				PodIngressLcuuid: uID,
+			})
+		}
+	}
+	return
+}
+
+// index 为后端所在路由的下标，避免不同路由中相同的后端生成相同的 lcuuid
+func (k *KubernetesGather) getRouteBackend(ingressLcuuid, ruleLcuuid, namespace, serviceName, path string, port, index int) (model.PodIngressRuleBackend, bool) {
+	service, ok := k.nsServiceNameToService[namespace+serviceName]
+	if !ok {
+		log.Infof("route (%s) backend service (%s) not found", ingressLcuuid, serviceName)
+		return model.PodIngressRuleBackend{}, false
+	}
+	// 同名服务存在多个时取 lcuuid 最小的，保证每次同步结果一致
+	serviceLcuuids := make([]string, 0, len(service))
+	for key := range service {
+		serviceLcuuids = append(serviceLcuuids, key)
+	}
+	if len(serviceLcuuids) == 0 {
+		log.Infof("route (%s) backend service (%s) not found", ingressLcuuid, serviceName)
+		return model.PodIngressRuleBackend{}, false
+	}
+	sort.Strings(serviceLcuuids)
+	serviceLcuuid := serviceLcuuids[0]
+	ports := service[serviceLcuuid]
+	if associated, ok := k.serviceLcuuidToIngressLcuuid[serviceLcuuid]; ok && associated != ingressLcuuid {
+		log.Infof("ingress (%s) is already associated with the service (%s), and route (%s) cannot be associated", associated, serviceLcuuid, ingressLcuuid)
+	} else {
+		k.serviceLcuuidToIngressLcuuid[serviceLcuuid] = ingressLcuuid
+	}
+	// 未指定端口时使用最小的服务端口
+	if port == 0 {
+		servicePorts := make([]int, 0, len(ports))
+		for _, p := range ports {
+			servicePorts = append(servicePorts, p)
+		}
+		sort.Ints(servicePorts)
+		for _, p := range servicePorts {
+			if p != 0 {
+				port = p
+				break
+			}
+		}
+	}
+	if port == 0 {
+		log.Infof("route (%s) backend service (%s) no servicePort", ingressLcuuid, serviceName)
+		return model.PodIngressRuleBackend{}, false
+	}
+	return model.PodIngressRuleBackend{
+		Lcuuid:               common.GetUUID(ruleLcuuid+strconv.Itoa(index)+serviceName+"_"+strconv.Itoa(port)+path, uuid.Nil),
+		Path:                 path,
+		Port:                 port,
+		PodServiceLcuuid:     serviceLcuuid,
+		PodIngressRuleLcuuid: ruleLcuuid,
+		PodIngressLcuuid:     ingressLcuuid,
+	}, true
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/deepflowys/deepflow/server/controller/cloud/aliyun"
 	"github.com/deepflowys/deepflow/server/controller/cloud/aws"
+	"github.com/deepflowys/deepflow/server/controller/cloud/azure"
 	"github.com/deepflowys/deepflow/server/controller/cloud/baidubce"
 	"github.com/deepflowys/deepflow/server/controller/cloud/config"
 	"github.com/deepflowys/deepflow/server/controller/cloud/filereader"
@@ -68,6 +69,8 @@ func NewPlatform(domain mysql.Domain, cfg config.CloudConfig) (Platform, error)
 		platform, err = huawei.NewHuaWei(domain, &cfg)
 	case common.FILEREADER:
 		platform, err = filereader.NewFileReader(domain)
+	case common.AZURE:
+		platform, err = azure.NewAzure(domain)
 	// TODO: other platform
 	default:
 		return nil, errors.New(fmt.Sprintf("domain type (%d) not supported", domain.Type))
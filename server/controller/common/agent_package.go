@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+func GetAgentPackageSHA256(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// VerifyAgentPackage 校验采集器升级包的SHA-256及Ed25519签名
+// publicKey和signature均为base64编码，签名内容为升级包原始数据
+func VerifyAgentPackage(content []byte, sha256Sum, signature, publicKey string) error {
+	if publicKey == "" {
+		return errors.New("agent package public key is not configured")
+	}
+	if sum := GetAgentPackageSHA256(content); sum != sha256Sum {
+		return fmt.Errorf("agent package sha256 mismatch, expected: %s, actual: %s", sha256Sum, sum)
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return fmt.Errorf("decode agent package public key failed: %s", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("agent package public key size(%d) is invalid", len(key))
+	}
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("decode agent package signature failed: %s", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), content, sign) {
+		return errors.New("agent package signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifyAgentPackage(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey)
+	content := []byte("deepflow-agent")
+	sha256Sum := GetAgentPackageSHA256(content)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, content))
+
+	if err := VerifyAgentPackage(content, sha256Sum, signature, encodedKey); err != nil {
+		t.Errorf("verify signed package failed: %s", err)
+	}
+	if err := VerifyAgentPackage(content, sha256Sum, signature, ""); err == nil {
+		t.Error("verify without public key should fail")
+	}
+	if err := VerifyAgentPackage([]byte("deepflow-agent-modified"), sha256Sum, signature, encodedKey); err == nil {
+		t.Error("verify modified package should fail")
+	}
+
+	otherPublicKey, _, _ := ed25519.GenerateKey(nil)
+	if err := VerifyAgentPackage(content, sha256Sum, signature, base64.StdEncoding.EncodeToString(otherPublicKey)); err == nil {
+		t.Error("verify with another public key should fail")
+	}
+}
@@ -113,6 +113,54 @@ const (
 	VTAP_STATE_PENDING_STR       = "PENDING"
 )
 
+// 采集器升级任务状态
+const (
+	UPGRADE_CAMPAIGN_STATE_RUNNING = 1 + iota
+	UPGRADE_CAMPAIGN_STATE_PAUSED
+	UPGRADE_CAMPAIGN_STATE_COMPLETED
+	UPGRADE_CAMPAIGN_STATE_ROLLING_BACK
+	UPGRADE_CAMPAIGN_STATE_ROLLED_BACK
+	UPGRADE_CAMPAIGN_STATE_CANCELLED
+)
+
+var UpgradeCampaignStateName = map[int]string{
+	UPGRADE_CAMPAIGN_STATE_RUNNING:      "RUNNING",
+	UPGRADE_CAMPAIGN_STATE_PAUSED:       "PAUSED",
+	UPGRADE_CAMPAIGN_STATE_COMPLETED:    "COMPLETED",
+	UPGRADE_CAMPAIGN_STATE_ROLLING_BACK: "ROLLING_BACK",
+	UPGRADE_CAMPAIGN_STATE_ROLLED_BACK:  "ROLLED_BACK",
+	UPGRADE_CAMPAIGN_STATE_CANCELLED:    "CANCELLED",
+}
+
+// 升级任务中单个采集器的状态
+const (
+	UPGRADE_CAMPAIGN_VTAP_STATE_PENDING = 1 + iota
+	UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING
+	UPGRADE_CAMPAIGN_VTAP_STATE_SUCCEEDED
+	UPGRADE_CAMPAIGN_VTAP_STATE_FAILED
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLED_BACK
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLBACK_FAILED
+	UPGRADE_CAMPAIGN_VTAP_STATE_CANCELLED
+)
+
+var UpgradeCampaignVTapStateName = map[int]string{
+	UPGRADE_CAMPAIGN_VTAP_STATE_PENDING:         "PENDING",
+	UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING:       "UPGRADING",
+	UPGRADE_CAMPAIGN_VTAP_STATE_SUCCEEDED:       "SUCCEEDED",
+	UPGRADE_CAMPAIGN_VTAP_STATE_FAILED:          "FAILED",
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK:    "ROLLING_BACK",
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLED_BACK:     "ROLLED_BACK",
+	UPGRADE_CAMPAIGN_VTAP_STATE_ROLLBACK_FAILED: "ROLLBACK_FAILED",
+	UPGRADE_CAMPAIGN_VTAP_STATE_CANCELLED:       "CANCELLED",
+}
+
+// 失败比例超过阈值后的处理方式
+const (
+	UPGRADE_CAMPAIGN_FAILURE_ACTION_PAUSE    = "pause"
+	UPGRADE_CAMPAIGN_FAILURE_ACTION_ROLLBACK = "rollback"
+)
+
 const (
 	VTAP_TYPE_KVM = 1 + iota
 	VTAP_TYPE_ESXI
@@ -180,14 +228,66 @@ const (
 )
 
 const (
-	ACL_STATE_ENABLE = 1
+	ACL_STATE_DISABLE = 0
+	ACL_STATE_ENABLE  = 1
+
+	ACL_TYPE_CUSTOM = 2
+
+	ACL_APPLICATION_PCAP = 4
+	ACL_APPLICATION_NPB  = 6
+
+	// 与trisolaris下发采集器资源组时使用的业务ID一致
+	ACL_BUSINESS_ID_NPB  = 1
+	ACL_BUSINESS_ID_PCAP = -3
+)
+
+var ACLApplicationName = map[int]string{
+	ACL_APPLICATION_PCAP: "pcap",
+	ACL_APPLICATION_NPB:  "npb",
+}
+
+const (
+	NPB_TUNNEL_TYPE_VXLAN  = 0
+	NPB_TUNNEL_TYPE_ERSPAN = 1
 )
 
+var NpbTunnelTypeName = map[int]string{
+	NPB_TUNNEL_TYPE_VXLAN:  "VXLAN",
+	NPB_TUNNEL_TYPE_ERSPAN: "ERSPAN",
+}
+
 const (
 	NPB_POLICY_FLOW_DROP       = 0
 	NPB_POLICY_FLOW_DISTRIBUTE = 1
 )
 
+// 通过API管理的具名资源组类型, 匿名资源组类型见trisolaris/common
+const (
+	RESOURCE_GROUP_TYPE_IP          = 2
+	RESOURCE_GROUP_TYPE_POD_SERVICE = 7
+	RESOURCE_GROUP_TYPE_VL2         = 13
+	RESOURCE_GROUP_TYPE_POD_GROUP   = 15
+	RESOURCE_GROUP_TYPE_POD_LABEL   = 16
+
+	RESOURCE_GROUP_IP_TYPE_SINGLE = 1
+	RESOURCE_GROUP_IP_TYPE_RANGE  = 2
+	RESOURCE_GROUP_IP_TYPE_CIDR   = 3
+	RESOURCE_GROUP_IP_TYPE_MIX    = 4
+
+	RESOURCE_GROUP_EXTRA_INFO_TYPE_POD_SERVICE = 3
+	RESOURCE_GROUP_EXTRA_INFO_TYPE_POD_GROUP   = 4
+
+	RESOURCE_GROUP_DEFAULT_BUSINESS_ID = 1
+)
+
+var ResourceGroupTypeName = map[int]string{
+	RESOURCE_GROUP_TYPE_IP:          "ip",
+	RESOURCE_GROUP_TYPE_VL2:         "subnet",
+	RESOURCE_GROUP_TYPE_POD_GROUP:   "pod_group",
+	RESOURCE_GROUP_TYPE_POD_SERVICE: "pod_service",
+	RESOURCE_GROUP_TYPE_POD_LABEL:   "pod_label",
+}
+
 const (
 	DEFAULT_ENCRYPTION_PASSWORD = "******"
 	DEFAULT_PORT_NAME_REGEX     = "(cni|flannel|vxlan.calico|tunl|en[ospx])"
@@ -409,6 +509,7 @@ const (
 	POD_GROUP_RC                    = 3
 	POD_GROUP_DAEMON_SET            = 4
 	POD_GROUP_REPLICASET_CONTROLLER = 5
+	POD_GROUP_CUSTOM                = 6
 )
 
 const (
@@ -435,6 +536,11 @@ const (
 	NAME_SPACE_KEY = "K8S_NAMESPACE_FOR_DEEPFLOW"
 )
 
+const (
+	ELECTION_BACKEND_KUBERNETES = "kubernetes"
+	ELECTION_BACKEND_MYSQL      = "mysql"
+)
+
 const (
 	DEEPFLOW_STATSD_PREFIX            = "deepflow.server.controller"
 	CLOUD_METRIC_NAME_TASK_COST       = "cloud.task.cost"
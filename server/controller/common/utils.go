@@ -47,6 +47,19 @@ func init() {
 	PodName = os.Getenv(POD_NAME_KEY)
 	PodIP = os.Getenv(POD_IP_KEY)
 	NameSpace = os.Getenv(NAME_SPACE_KEY)
+	// 非Kubernetes环境（如虚拟机部署）下未设置上述环境变量，使用主机名和本机IP代替
+	if NodeName == "" {
+		NodeName, _ = os.Hostname()
+	}
+	if PodName == "" {
+		PodName = NodeName
+	}
+	if NodeIP == "" {
+		NodeIP = getLocalIP()
+	}
+	if PodIP == "" {
+		PodIP = NodeIP
+	}
 	log.Infof("ENV %s=%s; %s=%s; %s=%s; %s=%s; %s=%s",
 		NODE_NAME_KEY, NodeName,
 		NODE_IP_KEY, NodeIP,
@@ -55,6 +68,29 @@ func init() {
 		NAME_SPACE_KEY, NameSpace)
 }
 
+// getLocalIP 获取本机第一个非回环地址，优先IPv4
+func getLocalIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Error(err)
+		return ""
+	}
+	var ipv6 string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+		if ipv6 == "" {
+			ipv6 = ipNet.IP.String()
+		}
+	}
+	return ipv6
+}
+
 func GetNodeName() string {
 	return NodeName
 }
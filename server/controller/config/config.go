@@ -17,6 +17,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -24,6 +25,7 @@ import (
 	logging "github.com/op/go-logging"
 	"gopkg.in/yaml.v2"
 
+	"github.com/deepflowys/deepflow/server/controller/common"
 	"github.com/deepflowys/deepflow/server/controller/db/clickhouse"
 	mysql "github.com/deepflowys/deepflow/server/controller/db/mysql/config"
 	"github.com/deepflowys/deepflow/server/controller/db/redis"
@@ -33,6 +35,7 @@ import (
 	statsd "github.com/deepflowys/deepflow/server/controller/statsd/config"
 	tagrecorder "github.com/deepflowys/deepflow/server/controller/tagrecorder/config"
 	trisolaris "github.com/deepflowys/deepflow/server/controller/trisolaris/config"
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
 )
 
 var log = logging.MustGetLogger("config")
@@ -69,6 +72,7 @@ type ControllerConfig struct {
 	GrpcNodePort         string `default:"30035" yaml:"grpc-node-port"`
 	Kubeconfig           string `yaml:"kubeconfig"`
 	ElectionName         string `default:"deepflow-server" yaml:"election-name"`
+	ElectionBackend      string `default:"kubernetes" yaml:"election-backend"`
 	ReportingDisabled    bool   `default:"false" yaml:"reporting-disabled"`
 
 	DFWebService DFWebService `yaml:"df-web-service"`
@@ -93,6 +97,14 @@ type Config struct {
 }
 
 func (c *Config) Validate() error {
+	switch c.ControllerConfig.ElectionBackend {
+	case common.ELECTION_BACKEND_KUBERNETES, common.ELECTION_BACKEND_MYSQL:
+	default:
+		return fmt.Errorf("election-backend (%s) is not supported", c.ControllerConfig.ElectionBackend)
+	}
+	if !ckdb.IsValidDiscovery(c.ControllerConfig.ClickHouseCfg.Discovery) {
+		return fmt.Errorf("clickhouse discovery (%s) is not supported", c.ControllerConfig.ClickHouseCfg.Discovery)
+	}
 	return nil
 }
 
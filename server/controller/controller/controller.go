@@ -160,8 +160,12 @@ func Start(ctx context.Context, configPath string, shared *servercommon.Controll
 	router.DebugRouter(r, m, g)
 	router.ControllerRouter(r, controllerCheck, cfg)
 	router.AnalyzerRouter(r, analyzerCheck, cfg)
-	router.VtapRouter(r)
+	router.VtapRouter(r, cfg)
 	router.VtapGroupRouter(r, cfg)
+	router.VTapUpgradeCampaignRouter(r)
+	router.VTapRepoRouter(r, cfg)
+	router.PolicyRouter(r, trisolaris.GetMetaData())
+	router.ResourceGroupRouter(r, trisolaris.GetMetaData())
 	router.DataSourceRouter(r, cfg)
 	router.DomainRouter(r, cfg)
 	router.VTapGroupConfigRouter(r)
@@ -88,7 +88,8 @@ func checkAndStartMasterFunctions(
 	}
 
 	vtapCheck := vtap.NewVTapCheck(cfg.MonitorCfg, ctx)
-	vtapRebalanceCheck := vtap.NewRebalanceCheck(cfg.MonitorCfg, ctx)
+	vtapRebalanceCheck := vtap.NewRebalanceCheck(cfg, ctx)
+	vtapUpgradeCampaignCheck := vtap.NewUpgradeCampaignCheck(cfg.MonitorCfg, ctx)
 	vtapLicenseAllocation := license.NewVTapLicenseAllocation(cfg.MonitorCfg, ctx)
 	resourceCleaner := recorder.NewResourceCleaner(&cfg.ManagerCfg.TaskCfg.RecorderCfg, ctx)
 	domainChecker := service.NewDomainCheck(ctx)
@@ -130,6 +131,9 @@ func checkAndStartMasterFunctions(
 				// rebalance vtap check
 				vtapRebalanceCheck.Start()
 
+				// vtap分批升级任务
+				vtapUpgradeCampaignCheck.Start()
+
 				// license分配和检查
 				vtapLicenseAllocation.Start()
 
@@ -154,6 +158,9 @@ func checkAndStartMasterFunctions(
 				// stop vtap check
 				vtapCheck.Stop()
 
+				// stop vtap upgrade campaign check
+				vtapUpgradeCampaignCheck.Stop()
+
 				// stop vtap license allocation and check
 				vtapLicenseAllocation.Stop()
 
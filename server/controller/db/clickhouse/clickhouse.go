@@ -33,6 +33,9 @@ type ClickHouseConfig struct {
 	UserName     string `default:"default" yaml:"user-name"`
 	UserPassword string `default:"" yaml:"user-password"`
 	TimeOut      uint32 `default:"30" yaml:"timeout"`
+	// 节点发现方式：kubernetes/static/dns
+	Discovery string   `default:"kubernetes" yaml:"discovery"`
+	Endpoints []string `yaml:"endpoints"` // static方式下的节点地址列表，格式为host[:port]
 }
 
 func Connect(cfg ClickHouseConfig) (*sqlx.DB, error) {
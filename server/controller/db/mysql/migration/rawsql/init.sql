@@ -733,7 +733,7 @@ CREATE TABLE IF NOT EXISTS pod_group (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name                VARCHAR(256) DEFAULT '',
     alias               CHAR(64),
-    type                INTEGER DEFAULT NULL COMMENT '1: Deployment 2: StatefulSet 3: ReplicationController',
+    type                INTEGER DEFAULT NULL COMMENT '1: Deployment 2: StatefulSet 3: ReplicationController 4: DaemonSet 5: ReplicaSetController 6: Custom Workload',
     pod_num             INTEGER DEFAULT 1,
     label               TEXT COMMENT 'separated by ,',
     pod_namespace_id    INTEGER DEFAULT NULL,
@@ -932,6 +932,64 @@ CREATE TABLE IF NOT EXISTS vtap_group (
 ) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
 TRUNCATE TABLE vtap_group;
 
+CREATE TABLE IF NOT EXISTS vtap_upgrade_campaign (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name                    VARCHAR(256) NOT NULL,
+    vtap_group_lcuuid       CHAR(64) DEFAULT '',
+    label_selector          TEXT,
+    expected_revision       VARCHAR(256) NOT NULL,
+    upgrade_package         TEXT NOT NULL,
+    rollback_revision       VARCHAR(256) DEFAULT '',
+    rollback_package        TEXT,
+    batches                 VARCHAR(256) NOT NULL COMMENT 'cumulative percentages, separated by ,',
+    current_batch           INTEGER DEFAULT 0,
+    failure_threshold       INTEGER DEFAULT 0 COMMENT 'unit: %',
+    failure_action          CHAR(16) DEFAULT 'pause' COMMENT 'pause, rollback',
+    batch_timeout           INTEGER DEFAULT 600 COMMENT 'unit: s',
+    state                   INTEGER DEFAULT 1 COMMENT '1.running 2.paused 3.completed 4.rolling-back 5.rolled-back 6.cancelled',
+    message                 TEXT,
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    lcuuid                  CHAR(64) NOT NULL
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+TRUNCATE TABLE vtap_upgrade_campaign;
+
+CREATE TABLE IF NOT EXISTS vtap_upgrade_campaign_vtap (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    campaign_lcuuid         CHAR(64) NOT NULL,
+    vtap_lcuuid             CHAR(64) NOT NULL,
+    vtap_name               VARCHAR(256) DEFAULT '',
+    batch                   INTEGER DEFAULT 0,
+    state                   INTEGER DEFAULT 1 COMMENT '1.pending 2.upgrading 3.succeeded 4.failed 5.rolling-back 6.rolled-back 7.rollback-failed 8.cancelled',
+    origin_revision         VARCHAR(256) DEFAULT '',
+    expected_revision       VARCHAR(256) DEFAULT '',
+    upgrade_package         TEXT,
+    message                 TEXT,
+    started_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX campaign_lcuuid_index(campaign_lcuuid),
+    INDEX state_index(state)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+TRUNCATE TABLE vtap_upgrade_campaign_vtap;
+
+CREATE TABLE IF NOT EXISTS vtap_repo (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name                    VARCHAR(256) NOT NULL,
+    revision                VARCHAR(256) NOT NULL,
+    arch                    VARCHAR(64) DEFAULT '',
+    os                      VARCHAR(64) DEFAULT '',
+    vtap_type               VARCHAR(64) DEFAULT '' COMMENT 'empty means all types',
+    image                   LONGBLOB NOT NULL,
+    size                    INTEGER DEFAULT 0 COMMENT 'unit: byte',
+    sha256                  CHAR(64) NOT NULL,
+    signature               VARCHAR(256) NOT NULL COMMENT 'ed25519 signature, base64 encoded',
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    lcuuid                  CHAR(64) NOT NULL,
+    UNIQUE INDEX name_index(name)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+TRUNCATE TABLE vtap_repo;
+
 CREATE TABLE IF NOT EXISTS topo_position (
     id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     type                    INTEGER DEFAULT 1 COMMENT '3-link topo',
@@ -966,13 +1024,16 @@ CREATE TABLE IF NOT EXISTS `resource_group` (
   `business_id`             INTEGER NOT NULL,
   `lcuuid`                  VARCHAR(64) NOT NULL,
   `name`                    VARCHAR(200) NOT NULL DEFAULT '',
-  `type`                    INTEGER NOT NULL COMMENT '3: anonymous vm, 4: anonymous ip, 5: anonymous pod, 6: anonymous pod_group, 8: anonymous pod_service, 81: anonymous pod_service as pod_group, 14: anonymous vl2',
+  `type`                    INTEGER NOT NULL COMMENT '2: ip, 3: anonymous vm, 4: anonymous ip, 5: anonymous pod, 6: anonymous pod_group, 7: pod_service, 8: anonymous pod_service, 81: anonymous pod_service as pod_group, 13: vl2, 14: anonymous vl2, 15: pod_group, 16: pod_label',
   `ip_type`                 INTEGER COMMENT '1: single ip, 2: ip range, 3: cidr, 4.mix [1, 2, 3]',
   `ips`                     TEXT COMMENT 'ips separated by ,',
   `vm_ids`                  TEXT COMMENT 'vm ids separated by ,',
   `vl2_ids`                 TEXT COMMENT 'vl2 ids separated by ,',
   `epc_id`                  INTEGER,
   `pod_cluster_id`          INTEGER,
+  `pod_labels`              TEXT COMMENT 'pod labels separated by , e.g. app:nginx',
+  `pod_group_ids`           TEXT COMMENT 'pod group ids separated by ,',
+  `pod_service_ids`         TEXT COMMENT 'pod service ids separated by ,',
   `extra_info_ids`          TEXT COMMENT 'resource group extra info ids separated by ,',
   `lb_id`                   INTEGER,
   `lb_listener_id`          INTEGER,
@@ -1724,6 +1785,7 @@ TRUNCATE TABLE ch_pod_ns;
 CREATE TABLE IF NOT EXISTS ch_pod_group (
     id                      INTEGER NOT NULL PRIMARY KEY,
     name                    VARCHAR(256),
+    pod_group_type          INTEGER DEFAULT NULL,
     icon_id                 INTEGER,
     updated_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 )ENGINE=innodb DEFAULT CHARSET=utf8;
@@ -2080,3 +2142,9 @@ CREATE TABLE IF NOT EXISTS ch_int_enum (
     PRIMARY KEY  (tag_name,value)
 )ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
 TRUNCATE TABLE ch_int_enum;
+
+CREATE TABLE IF NOT EXISTS election_lease (
+    name                    VARCHAR(64) NOT NULL PRIMARY KEY,
+    holder                  VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip',
+    renew_time              DATETIME(3) NOT NULL DEFAULT '1970-01-02 00:00:00.000'
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
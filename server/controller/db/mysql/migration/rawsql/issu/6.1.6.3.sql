@@ -0,0 +1,5 @@
+USE deepflow;
+
+ALTER TABLE ch_pod_group ADD COLUMN pod_group_type INTEGER DEFAULT NULL AFTER name;
+
+UPDATE db_version SET version = '6.1.6.3';
@@ -0,0 +1,41 @@
+USE deepflow;
+
+CREATE TABLE IF NOT EXISTS vtap_upgrade_campaign (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name                    VARCHAR(256) NOT NULL,
+    vtap_group_lcuuid       CHAR(64) DEFAULT '',
+    label_selector          TEXT,
+    expected_revision       VARCHAR(256) NOT NULL,
+    upgrade_package         TEXT NOT NULL,
+    rollback_revision       VARCHAR(256) DEFAULT '',
+    rollback_package        TEXT,
+    batches                 VARCHAR(256) NOT NULL COMMENT 'cumulative percentages, separated by ,',
+    current_batch           INTEGER DEFAULT 0,
+    failure_threshold       INTEGER DEFAULT 0 COMMENT 'unit: %',
+    failure_action          CHAR(16) DEFAULT 'pause' COMMENT 'pause, rollback',
+    batch_timeout           INTEGER DEFAULT 600 COMMENT 'unit: s',
+    state                   INTEGER DEFAULT 1 COMMENT '1.running 2.paused 3.completed 4.rolling-back 5.rolled-back 6.cancelled',
+    message                 TEXT,
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    lcuuid                  CHAR(64) NOT NULL
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS vtap_upgrade_campaign_vtap (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    campaign_lcuuid         CHAR(64) NOT NULL,
+    vtap_lcuuid             CHAR(64) NOT NULL,
+    vtap_name               VARCHAR(256) DEFAULT '',
+    batch                   INTEGER DEFAULT 0,
+    state                   INTEGER DEFAULT 1 COMMENT '1.pending 2.upgrading 3.succeeded 4.failed 5.rolling-back 6.rolled-back 7.rollback-failed 8.cancelled',
+    origin_revision         VARCHAR(256) DEFAULT '',
+    expected_revision       VARCHAR(256) DEFAULT '',
+    upgrade_package         TEXT,
+    message                 TEXT,
+    started_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX campaign_lcuuid_index(campaign_lcuuid),
+    INDEX state_index(state)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+
+UPDATE db_version SET version = '6.1.6.4';
@@ -0,0 +1,20 @@
+USE deepflow;
+
+CREATE TABLE IF NOT EXISTS vtap_repo (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name                    VARCHAR(256) NOT NULL,
+    revision                VARCHAR(256) NOT NULL,
+    arch                    VARCHAR(64) DEFAULT '',
+    os                      VARCHAR(64) DEFAULT '',
+    vtap_type               VARCHAR(64) DEFAULT '' COMMENT 'empty means all types',
+    image                   LONGBLOB NOT NULL,
+    size                    INTEGER DEFAULT 0 COMMENT 'unit: byte',
+    sha256                  CHAR(64) NOT NULL,
+    signature               VARCHAR(256) NOT NULL COMMENT 'ed25519 signature, base64 encoded',
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    lcuuid                  CHAR(64) NOT NULL,
+    UNIQUE INDEX name_index(name)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+
+UPDATE db_version SET version = '6.1.6.5';
@@ -0,0 +1,8 @@
+USE deepflow;
+
+ALTER TABLE resource_group MODIFY COLUMN type INTEGER NOT NULL COMMENT '2: ip, 3: anonymous vm, 4: anonymous ip, 5: anonymous pod, 6: anonymous pod_group, 7: pod_service, 8: anonymous pod_service, 81: anonymous pod_service as pod_group, 13: vl2, 14: anonymous vl2, 15: pod_group, 16: pod_label';
+ALTER TABLE resource_group ADD COLUMN pod_labels TEXT COMMENT 'pod labels separated by , e.g. app:nginx' AFTER pod_cluster_id;
+ALTER TABLE resource_group ADD COLUMN pod_group_ids TEXT COMMENT 'pod group ids separated by ,' AFTER pod_labels;
+ALTER TABLE resource_group ADD COLUMN pod_service_ids TEXT COMMENT 'pod service ids separated by ,' AFTER pod_group_ids;
+
+UPDATE db_version SET version = '6.1.6.6';
@@ -0,0 +1,9 @@
+USE deepflow;
+
+CREATE TABLE IF NOT EXISTS election_lease (
+    name                    VARCHAR(64) NOT NULL PRIMARY KEY,
+    holder                  VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip',
+    renew_time              DATETIME(3) NOT NULL DEFAULT '1970-01-02 00:00:00.000'
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+UPDATE db_version SET version = '6.1.6.7';
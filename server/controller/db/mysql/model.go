@@ -686,7 +686,7 @@ type PodGroup struct {
 	SoftDeleteBase `gorm:"embedded"`
 	Name           string `gorm:"column:name;type:varchar(256);default:''" json:"NAME"`
 	Alias          string `gorm:"column:alias;type:char(64);default:''" json:"ALIAS"`
-	Type           int    `gorm:"column:type;type:int;default:null" json:"TYPE"` // 1: Deployment 2: StatefulSet 3: ReplicationController
+	Type           int    `gorm:"column:type;type:int;default:null" json:"TYPE"` // 1: Deployment 2: StatefulSet 3: ReplicationController 4: DaemonSet 5: ReplicaSetController 6: Custom Workload
 	PodNum         int    `gorm:"column:pod_num;type:int;default:1" json:"POD_NUM"`
 	Label          string `gorm:"column:label;type:text;default:''" json:"LABEL"` // separated by ,
 	PodNamespaceID int    `gorm:"column:pod_namespace_id;type:int;default:null" json:"POD_NAMESPACE_ID"`
@@ -764,13 +764,14 @@ type ResourceGroup struct {
 	BusinessID    int       `gorm:"column:business_id;type:int;not null" json:"BUSINESS_ID"`
 	Lcuuid        string    `gorm:"column:lcuuid;type:varchar(64);not null" json:"LCUUID"`
 	Name          string    `gorm:"column:name;type:varchar(200);not null;default:''" json:"NAME"`
-	Type          int       `gorm:"column:type;type:int;not null" json:"TYPE"`            // 1:vm, 2:ip, 3: anonymous vm, 4: anonymous ip, 5: reserved for pod_group, 6: anonymous pod_group, 7: reserved for pod_service, 8: anonymous pod_service, 81: anonymous pod_service as pod_group, 9：lb_bk_rule, 10：reserved for anonymous lb_bk_rule, 11: tmp vm, 21: tmp ip, 13: reserve for vl2, 14: anonymous vl2
+	Type          int       `gorm:"column:type;type:int;not null" json:"TYPE"`            // 1:vm, 2:ip, 3: anonymous vm, 4: anonymous ip, 5: anonymous pod, 6: anonymous pod_group, 7: pod_service, 8: anonymous pod_service, 81: anonymous pod_service as pod_group, 9：lb_bk_rule, 10：reserved for anonymous lb_bk_rule, 11: tmp vm, 21: tmp ip, 13: vl2, 14: anonymous vl2, 15: pod_group, 16: pod_label
 	IPType        int       `gorm:"column:ip_type;type:int;default:null" json:"IP_TYPE"`  // 1: single ip, 2: ip range, 3: cidr, 4.mix [1, 2, 3]
 	IPs           string    `gorm:"column:ips;type:text;default:null" json:"IPS"`         // ips separated by ,
 	VMIDs         string    `gorm:"column:vm_ids;type:text;default:null" json:"VM_IDS"`   // vm ids separated by ,
 	NetworkIDs    string    `gorm:"column:vl2_ids;type:text;default:null" json:"VL2_IDS"` // vl2 ids separated by ,
 	VPCID         int       `gorm:"column:epc_id;type:int;default:null" json:"VPC_ID"`
 	PodClusterID  int       `gorm:"column:pod_cluster_id;type:int;default:null" json:"POD_CLUSTER_ID"`
+	PodLabels     string    `gorm:"column:pod_labels;type:text;default:null" json:"POD_LABELS"`           // pod labels separated by , e.g. app:nginx
 	PodGroupIDs   string    `gorm:"column:pod_group_ids;type:text;default:null" json:"POD_GROUP_IDS"`     // pod group ids separated by ,
 	PodServiceIDs string    `gorm:"column:pod_service_ids;type:text;default:null" json:"POD_SERVICE_IDS"` // pod service ids separated by ,
 	LBID          int       `gorm:"column:lb_id;type:int;default:null" json:"LB_ID"`
@@ -935,6 +936,70 @@ func (VTapGroup) TableName() string {
 	return "vtap_group"
 }
 
+type VTapUpgradeCampaign struct {
+	ID               int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	Name             string    `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
+	VTapGroupLcuuid  string    `gorm:"column:vtap_group_lcuuid;type:char(64);default:''" json:"VTAP_GROUP_LCUUID"`
+	LabelSelector    string    `gorm:"column:label_selector;type:text;default:null" json:"LABEL_SELECTOR"`
+	ExpectedRevision string    `gorm:"column:expected_revision;type:varchar(256);not null" json:"EXPECTED_REVISION"`
+	UpgradePackage   string    `gorm:"column:upgrade_package;type:text;not null" json:"UPGRADE_PACKAGE"`
+	RollbackRevision string    `gorm:"column:rollback_revision;type:varchar(256);default:''" json:"ROLLBACK_REVISION"`
+	RollbackPackage  string    `gorm:"column:rollback_package;type:text;default:null" json:"ROLLBACK_PACKAGE"`
+	Batches          string    `gorm:"column:batches;type:varchar(256);not null" json:"BATCHES"` // 各批次累计升级比例(%), separated by ,
+	CurrentBatch     int       `gorm:"column:current_batch;type:int;default:0" json:"CURRENT_BATCH"`
+	FailureThreshold int       `gorm:"column:failure_threshold;type:int;default:0" json:"FAILURE_THRESHOLD"`      // 失败比例阈值(%)
+	FailureAction    string    `gorm:"column:failure_action;type:char(16);default:'pause'" json:"FAILURE_ACTION"` // pause, rollback
+	BatchTimeout     int       `gorm:"column:batch_timeout;type:int;default:600" json:"BATCH_TIMEOUT"`            // unit: s
+	State            int       `gorm:"column:state;type:int;default:1" json:"STATE"`                              // 1.running 2.paused 3.completed 4.rolling-back 5.rolled-back 6.cancelled
+	Message          string    `gorm:"column:message;type:text;default:null" json:"MESSAGE"`
+	CreatedAt        time.Time `gorm:"column:created_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"CREATED_AT"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"UPDATED_AT"`
+	Lcuuid           string    `gorm:"column:lcuuid;type:char(64);not null" json:"LCUUID"`
+}
+
+func (VTapUpgradeCampaign) TableName() string {
+	return "vtap_upgrade_campaign"
+}
+
+type VTapUpgradeCampaignVTap struct {
+	ID               int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	CampaignLcuuid   string    `gorm:"column:campaign_lcuuid;type:char(64);not null" json:"CAMPAIGN_LCUUID"`
+	VTapLcuuid       string    `gorm:"column:vtap_lcuuid;type:char(64);not null" json:"VTAP_LCUUID"`
+	VTapName         string    `gorm:"column:vtap_name;type:varchar(256);default:''" json:"VTAP_NAME"`
+	Batch            int       `gorm:"column:batch;type:int;default:0" json:"BATCH"`
+	State            int       `gorm:"column:state;type:int;default:1" json:"STATE"` // 1.pending 2.upgrading 3.succeeded 4.failed 5.rolling-back 6.rolled-back 7.rollback-failed 8.cancelled
+	OriginRevision   string    `gorm:"column:origin_revision;type:varchar(256);default:''" json:"ORIGIN_REVISION"`
+	ExpectedRevision string    `gorm:"column:expected_revision;type:varchar(256);default:''" json:"EXPECTED_REVISION"`
+	UpgradePackage   string    `gorm:"column:upgrade_package;type:text;default:null" json:"UPGRADE_PACKAGE"`
+	Message          string    `gorm:"column:message;type:text;default:null" json:"MESSAGE"`
+	StartedAt        time.Time `gorm:"column:started_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"STARTED_AT"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"UPDATED_AT"`
+}
+
+func (VTapUpgradeCampaignVTap) TableName() string {
+	return "vtap_upgrade_campaign_vtap"
+}
+
+type VTapRepo struct {
+	ID        int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	Name      string    `gorm:"column:name;type:varchar(256);not null" json:"NAME"`
+	Revision  string    `gorm:"column:revision;type:varchar(256);not null" json:"REVISION"`
+	Arch      string    `gorm:"column:arch;type:varchar(64);default:''" json:"ARCH"`
+	OS        string    `gorm:"column:os;type:varchar(64);default:''" json:"OS"`
+	VTapType  string    `gorm:"column:vtap_type;type:varchar(64);default:''" json:"VTAP_TYPE"` // 为空表示适用于所有类型
+	Image     []byte    `gorm:"column:image;type:longblob;not null" json:"-"`
+	Size      int       `gorm:"column:size;type:int;default:0" json:"SIZE"` // unit: byte
+	SHA256    string    `gorm:"column:sha256;type:char(64);not null" json:"SHA256"`
+	Signature string    `gorm:"column:signature;type:varchar(256);not null" json:"SIGNATURE"` // Ed25519签名, base64编码
+	CreatedAt time.Time `gorm:"column:created_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"CREATED_AT"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"UPDATED_AT"`
+	Lcuuid    string    `gorm:"column:lcuuid;type:char(64);not null" json:"LCUUID"`
+}
+
+func (VTapRepo) TableName() string {
+	return "vtap_repo"
+}
+
 type DataSource struct {
 	ID                        int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	Name                      string    `gorm:"column:name;type:char(64);default:''" json:"NAME"`
@@ -1150,7 +1215,7 @@ func (ACL) TableName() string {
 // ResourceGroupExtraInfo [...]
 type ResourceGroupExtraInfo struct {
 	ID           int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
-	ResourceType int    `gorm:"column:resource_type;type:int;not null" json:"RESOURCE_TYPE"` // 1: epc, 2: vm, 3: pod_group, 4: pod_service
+	ResourceType int    `gorm:"column:resource_type;type:int;not null" json:"RESOURCE_TYPE"` // 1: epc, 2: vm, 3: pod_service, 4: pod_group, 5: vl2, 6: pod_cluster, 7: pod
 	ResourceID   int    `gorm:"column:resource_id;type:int;not null" json:"RESOURCE_ID"`
 	ResourceName string `gorm:"column:resource_name;type:char(64);not null" json:"RESOURCE_NAME"`
 }
@@ -1197,6 +1262,16 @@ func (NpbTunnel) TableName() string {
 }
 
 // PcapPolicy [...]
+type PolicyAclGroup struct {
+	ID     int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	ACLIDs string `gorm:"column:acl_ids;type:text;not null" json:"ACL_IDS"` // separated by ,
+	Count  int    `gorm:"column:count;type:int;not null" json:"COUNT"`
+}
+
+func (PolicyAclGroup) TableName() string {
+	return "policy_acl_group"
+}
+
 type PcapPolicy struct {
 	ID               int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	Name             string    `gorm:"column:name;type:char(64);default:null" json:"NAME"`
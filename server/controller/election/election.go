@@ -152,6 +152,11 @@ func checkLeaderValid(ctx context.Context, lock *resourcelock.LeaseLock) {
 }
 
 func Start(ctx context.Context, cfg *config.ControllerConfig) {
+	if cfg.ElectionBackend == common.ELECTION_BACKEND_MYSQL {
+		startMySQLElection(ctx, cfg)
+		return
+	}
+
 	kubeconfig := cfg.Kubeconfig
 	electionName := cfg.ElectionName
 	electionNamespace := common.GetNameSpace()
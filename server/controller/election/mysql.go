@@ -0,0 +1,232 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package election
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/deepflowys/deepflow/server/controller/config"
+	mysqlcommon "github.com/deepflowys/deepflow/server/controller/db/mysql/common"
+	mysqlcfg "github.com/deepflowys/deepflow/server/controller/db/mysql/config"
+)
+
+// 基于MySQL行租约的选举，用于非Kubernetes环境（如虚拟机）部署
+// 租约时长、续约期限及重试周期与Kubernetes LeaseLock保持一致，
+// 租约是否过期均以MySQL服务器时间判断，避免各控制器时钟不一致
+const (
+	ELECTION_LEASE_TABLE = "election_lease"
+
+	LEASE_DURATION = 60 * time.Second
+	RENEW_DEADLINE = 15 * time.Second
+	RETRY_PERIOD   = 5 * time.Second
+)
+
+type leaseLock interface {
+	tryAcquireOrRenew() (string, error)
+	release()
+}
+
+type mysqlLeaseLock struct {
+	cfg      mysqlcfg.MySqlConfig
+	name     string
+	identity string
+	db       *gorm.DB
+	// 获取判断租约的当前时间，默认为MySQL服务器时间
+	now func(db *gorm.DB) (time.Time, error)
+}
+
+func mysqlNow(db *gorm.DB) (time.Time, error) {
+	var now time.Time
+	err := db.Raw("SELECT NOW(3)").Row().Scan(&now)
+	return now, err
+}
+
+func (l *mysqlLeaseLock) init() error {
+	db := mysqlcommon.GetConnectionWithoudDatabase(l.cfg)
+	if db == nil {
+		return fmt.Errorf("connect mysql %s:%d failed", l.cfg.Host, l.cfg.Port)
+	}
+	_, err := mysqlcommon.CreateDatabaseIfNotExists(db, l.cfg.Database)
+	closeDB(db)
+	if err != nil {
+		return err
+	}
+
+	db = mysqlcommon.GetGormDB(mysqlcommon.GetDSN(l.cfg, l.cfg.Database, l.cfg.TimeOut, false))
+	if db == nil {
+		return fmt.Errorf("connect mysql database %s failed", l.cfg.Database)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetMaxOpenConns(2)
+	}
+	// 数据库可能尚未由master控制器初始化，此处自行创建租约表
+	err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name       VARCHAR(64) NOT NULL PRIMARY KEY,
+		holder     VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip',
+		renew_time DATETIME(3) NOT NULL DEFAULT '1970-01-02 00:00:00.000'
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8`, ELECTION_LEASE_TABLE)).Error
+	if err == nil {
+		err = db.Exec(fmt.Sprintf("INSERT IGNORE INTO %s (name) VALUES (?)", ELECTION_LEASE_TABLE), l.name).Error
+	}
+	if err != nil {
+		closeDB(db)
+		return err
+	}
+	l.db = db
+	return nil
+}
+
+func (l *mysqlLeaseLock) close() {
+	if l.db != nil {
+		closeDB(l.db)
+		l.db = nil
+	}
+}
+
+// tryAcquireOrRenew 当自己持有租约或租约已过期时获取/续约，返回当前有效的租约持有者
+func (l *mysqlLeaseLock) tryAcquireOrRenew() (string, error) {
+	if l.db == nil {
+		if err := l.init(); err != nil {
+			return "", err
+		}
+	}
+	now, err := l.now(l.db)
+	if err != nil {
+		// 连接或表异常时重新初始化
+		l.close()
+		return "", err
+	}
+	expireTime := now.Add(-LEASE_DURATION)
+	err = l.db.Exec(
+		fmt.Sprintf(
+			"UPDATE %s SET holder = ?, renew_time = ? WHERE name = ? AND (holder = ? OR renew_time < ?)",
+			ELECTION_LEASE_TABLE,
+		),
+		l.identity, now, l.name, l.identity, expireTime,
+	).Error
+	if err != nil {
+		l.close()
+		return "", err
+	}
+	var holders []string
+	err = l.db.Raw(
+		fmt.Sprintf("SELECT holder FROM %s WHERE name = ? AND renew_time >= ?", ELECTION_LEASE_TABLE),
+		l.name, expireTime,
+	).Scan(&holders).Error
+	if err != nil {
+		l.close()
+		return "", err
+	}
+	if len(holders) == 0 {
+		return "", nil
+	}
+	return holders[0], nil
+}
+
+// release 退出时主动释放租约，使其他控制器无需等待租约过期
+func (l *mysqlLeaseLock) release() {
+	if l.db == nil {
+		return
+	}
+	err := l.db.Exec(
+		fmt.Sprintf("UPDATE %s SET renew_time = '1970-01-02 00:00:00.000' WHERE name = ? AND holder = ?", ELECTION_LEASE_TABLE),
+		l.name, l.identity,
+	).Error
+	if err != nil {
+		log.Error(err)
+	}
+	l.close()
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// mysqlElection 记录本控制器的leader状态，每个重试周期执行一次step
+type mysqlElection struct {
+	id            string
+	lock          leaseLock
+	isLeader      bool
+	lastRenewTime time.Time
+}
+
+// step 获取或续约租约并更新leader，now为本地时间，仅用于判断续约期限
+func (e *mysqlElection) step(now time.Time) {
+	holder, err := e.lock.tryAcquireOrRenew()
+	if err != nil {
+		log.Errorf("acquire or renew election lease failed: %s", err.Error())
+		// 超过续约期限仍未续约成功，主动放弃leader身份，
+		// 保证在租约过期、其他控制器当选之前停止leader工作
+		if e.isLeader && now.Sub(e.lastRenewTime) > RENEW_DEADLINE {
+			e.isLeader = false
+			log.Infof("leader lost: %s", e.id)
+			leaderData.SetLeader("")
+		}
+		return
+	}
+	if holder == e.id {
+		if !e.isLeader {
+			log.Infof("%s is the leader", e.id)
+		}
+		e.isLeader = true
+		e.lastRenewTime = now
+	} else {
+		if e.isLeader {
+			log.Infof("leader lost: %s", e.id)
+		}
+		e.isLeader = false
+	}
+	if holder != leaderData.GetLeader() {
+		log.Infof("new leader elected: %s", holder)
+	}
+	leaderData.SetLeader(holder)
+	leaderData.setValide()
+}
+
+func startMySQLElection(ctx context.Context, cfg *config.ControllerConfig) {
+	id := getID()
+	log.Infof("election id is %s, backend is mysql", id)
+	election := &mysqlElection{
+		id: id,
+		lock: &mysqlLeaseLock{
+			cfg:      cfg.MySqlCfg,
+			name:     cfg.ElectionName,
+			identity: id,
+			now:      mysqlNow,
+		},
+	}
+	defer election.lock.release()
+
+	ticker := time.NewTicker(RETRY_PERIOD)
+	defer ticker.Stop()
+	for {
+		election.step(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
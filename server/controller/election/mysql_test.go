@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package election
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const TEST_DB_FILE = "election_test.db"
+
+func initTestLeaseDB(t *testing.T) *gorm.DB {
+	os.Remove(TEST_DB_FILE)
+	db, err := gorm.Open(sqlite.Open(TEST_DB_FILE), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(fmt.Sprintf(`CREATE TABLE %s (
+		name       VARCHAR(64) NOT NULL PRIMARY KEY,
+		holder     VARCHAR(512) NOT NULL DEFAULT '',
+		renew_time DATETIME(3) NOT NULL DEFAULT '1970-01-02 00:00:00.000'
+	)`, ELECTION_LEASE_TABLE)).Error
+	if err == nil {
+		err = db.Exec(fmt.Sprintf("INSERT INTO %s (name) VALUES (?)", ELECTION_LEASE_TABLE), "deepflow-server").Error
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMySQLLeaseLock(t *testing.T) {
+	db := initTestLeaseDB(t)
+	defer os.Remove(TEST_DB_FILE)
+	now := time.Unix(1700000000, 0).UTC()
+	newLock := func(identity string) *mysqlLeaseLock {
+		return &mysqlLeaseLock{
+			name:     "deepflow-server",
+			identity: identity,
+			db:       db,
+			now:      func(*gorm.DB) (time.Time, error) { return now, nil },
+		}
+	}
+	lockA, lockB := newLock("a"), newLock("b")
+	check := func(lock *mysqlLeaseLock, want string) {
+		t.Helper()
+		holder, err := lock.tryAcquireOrRenew()
+		if err != nil || holder != want {
+			t.Fatalf("%s tryAcquireOrRenew() at %s = %q, %v, want %q", lock.identity, now, holder, err, want)
+		}
+	}
+
+	// 租约未被持有时获取, 其他控制器在租约有效期内无法获取
+	check(lockA, "a")
+	check(lockB, "a")
+	// 持有者续约后租约从续约时间起重新计算
+	now = now.Add(LEASE_DURATION - time.Second)
+	check(lockA, "a")
+	now = now.Add(LEASE_DURATION - time.Second)
+	check(lockB, "a")
+	// 持有者停止续约, 租约过期后被其他控制器获取
+	now = now.Add(2 * time.Second)
+	check(lockB, "b")
+	check(lockA, "b")
+}
+
+type fakeLeaseLock struct {
+	holder string
+	err    error
+}
+
+func (l *fakeLeaseLock) tryAcquireOrRenew() (string, error) {
+	return l.holder, l.err
+}
+
+func (l *fakeLeaseLock) release() {}
+
+func TestMySQLElectionStep(t *testing.T) {
+	lock := &fakeLeaseLock{holder: "a"}
+	election := &mysqlElection{id: "a", lock: lock}
+	now := time.Unix(1700000000, 0)
+	check := func(wantLeader bool, want string) {
+		t.Helper()
+		if election.isLeader != wantLeader || leaderData.GetLeader() != want {
+			t.Fatalf("step at %s: isLeader = %v, leader = %q, want %v, %q", now, election.isLeader, leaderData.GetLeader(), wantLeader, want)
+		}
+	}
+
+	election.step(now)
+	check(true, "a")
+	// 续约失败但未超过续约期限时保持leader身份
+	lock.err = errors.New("connection refused")
+	now = now.Add(RENEW_DEADLINE)
+	election.step(now)
+	check(true, "a")
+	// 超过续约期限仍未续约成功时主动放弃leader身份
+	now = now.Add(RETRY_PERIOD)
+	election.step(now)
+	check(false, "")
+	// 恢复后租约已被其他控制器获取
+	lock.holder, lock.err = "b", nil
+	now = now.Add(RETRY_PERIOD)
+	election.step(now)
+	check(false, "b")
+}
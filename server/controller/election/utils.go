@@ -19,7 +19,6 @@ package election
 import (
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/deepflowys/deepflow/server/controller/common"
@@ -28,7 +27,7 @@ import (
 // 功能：判断当前控制器是否为masterController
 func IsMasterController() (bool, error) {
 	// get self host_ip
-	hostIP := os.Getenv(common.POD_IP_KEY)
+	hostIP := common.GetPodIP()
 	if len(hostIP) == 0 {
 		log.Error("pod_ip is null")
 		return false, errors.New("pod_ip is null")
@@ -46,7 +45,7 @@ func IsMasterController() (bool, error) {
 
 func IsMasterControllerAndReturnIP() (bool, string, error) {
 	// get self host_ip
-	hostIP := os.Getenv(common.POD_IP_KEY)
+	hostIP := common.GetPodIP()
 	if len(hostIP) == 0 {
 		log.Error("pod_ip is null")
 		return false, "", errors.New("pod_ip is null")
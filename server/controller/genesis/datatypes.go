@@ -17,7 +17,6 @@
 package genesis
 
 import (
-	"os"
 	"reflect"
 	"sync"
 	"time"
@@ -151,7 +150,7 @@ func (g *GenesisSyncTypeOperation[T]) Load(timestamp time.Time, timeout time.Dur
 	defer g.mutex.Unlock()
 
 	var items []T
-	mysql.Db.Where("node_ip = ?", os.Getenv(common.NODE_IP_KEY)).Find(&items)
+	mysql.Db.Where("node_ip = ?", common.GetNodeIP()).Find(&items)
 	for _, data := range items {
 		iData := reflect.ValueOf(&data).Elem()
 		dataLcuuid := iData.FieldByName("Lcuuid").String()
@@ -174,7 +173,7 @@ func (g *GenesisSyncTypeOperation[T]) Save() {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	nodeIP := os.Getenv(common.NODE_IP_KEY)
+	nodeIP := common.GetNodeIP()
 	// delete db old data
 	var dataType T
 	mysql.Db.Where("node_ip = ?", nodeIP).Delete(&dataType)
@@ -184,7 +183,7 @@ func (g *GenesisSyncTypeOperation[T]) Save() {
 	for _, data := range g.dataDict {
 		tData := reflect.ValueOf(&data).Elem()
 		nodeIP := tData.FieldByName("NodeIP")
-		nodeIPString := os.Getenv(common.NODE_IP_KEY)
+		nodeIPString := common.GetNodeIP()
 		nodeIP.SetString(nodeIPString)
 		items = append(items, data)
 	}
@@ -22,7 +22,6 @@ import (
 	"context"
 	"errors"
 	"net"
-	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -128,7 +127,7 @@ func (g *Genesis) GetGenesisSyncResponse() (GenesisSyncData, error) {
 
 	controllerIPToRegion := make(map[string]string)
 	for _, conn := range azControllerConns {
-		if os.Getenv(common.NODE_IP_KEY) == conn.ControllerIP {
+		if common.GetNodeIP() == conn.ControllerIP {
 			currentRegion = conn.Region
 		}
 		controllerIPToRegion[conn.ControllerIP] = conn.Region
@@ -339,7 +338,7 @@ func (g *Genesis) GetKubernetesResponse(clusterID string) (map[string][]string,
 	k8sInfo, ok := localK8sDatas[clusterID]
 	if !ok {
 		var controllers []mysql.Controller
-		mysql.Db.Where("ip <> ?", os.Getenv(common.NODE_IP_KEY)).Find(&controllers)
+		mysql.Db.Where("ip <> ?", common.GetNodeIP()).Find(&controllers)
 		retFlag := false
 		for _, controller := range controllers {
 
@@ -18,7 +18,6 @@ package genesis
 
 import (
 	"context"
-	"os"
 	"sync"
 	"time"
 
@@ -126,9 +125,9 @@ func (s *SyncStorage) Update(data GenesisSyncDataOperation, vtapID uint32) {
 		var storages []model.GenesisStorage
 		mysql.Db.Where("vtap_id = ?", vtapID).Find(&storages)
 		if len(storages) > 0 {
-			mysql.Db.Model(&model.GenesisStorage{}).Where("vtap_id = ?", vtapID).Update("node_ip", os.Getenv(common.NODE_IP_KEY))
+			mysql.Db.Model(&model.GenesisStorage{}).Where("vtap_id = ?", vtapID).Update("node_ip", common.GetNodeIP())
 		} else {
-			mysql.Db.Create(&model.GenesisStorage{VtapID: vtapID, NodeIP: os.Getenv(common.NODE_IP_KEY)})
+			mysql.Db.Create(&model.GenesisStorage{VtapID: vtapID, NodeIP: common.GetNodeIP()})
 		}
 	}
 	s.dirty = true
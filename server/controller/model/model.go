@@ -173,22 +173,35 @@ type VtapUpdateTapMode struct {
 }
 
 type HostVTapRebalanceResult struct {
-	IP            string `json:"IP"`
-	AZ            string `json:"AZ"`
-	State         int    `json:"STATE"`
-	BeforeVTapNum int    `json:"BEFORE_VTAP_NUM"`
-	AfterVTapNum  int    `json:"AFTER_VTAP_NUM"`
-	SwitchVTapNum int    `json:"SWITCH_VTAP_NUM"`
+	IP             string  `json:"IP"`
+	AZ             string  `json:"AZ"`
+	State          int     `json:"STATE"`
+	BeforeVTapNum  int     `json:"BEFORE_VTAP_NUM"`
+	AfterVTapNum   int     `json:"AFTER_VTAP_NUM"`
+	SwitchVTapNum  int     `json:"SWITCH_VTAP_NUM"`
+	BeforeVTapLoad float64 `json:"BEFORE_VTAP_LOAD,omitempty"` // 按数据量均衡时，采集器发送的数据量之和(字节)
+	AfterVTapLoad  float64 `json:"AFTER_VTAP_LOAD,omitempty"`
+}
+
+type VTapMigration struct {
+	Name string  `json:"NAME"`
+	AZ   string  `json:"AZ"`
+	From string  `json:"FROM"`
+	To   string  `json:"TO"`
+	Load float64 `json:"LOAD"`
 }
 
 type AZVTapRebalanceResult struct {
 	TotalSwitchVTapNum int                       `json:"TOTAL_SWITCH_VTAP_NUM"`
 	Details            []HostVTapRebalanceResult `json:"DETAILS"`
+	Migrations         []VTapMigration           `json:"MIGRATIONS,omitempty"`
 }
 
 type VTapRebalanceResult struct {
+	Algorithm          string                    `json:"ALGORITHM,omitempty"`
 	TotalSwitchVTapNum int                       `json:"TOTAL_SWITCH_VTAP_NUM"`
 	Details            []HostVTapRebalanceResult `json:"DETAILS"`
+	Migrations         []VTapMigration           `json:"MIGRATIONS,omitempty"`
 }
 
 type VtapGroup struct {
@@ -216,6 +229,272 @@ type VtapGroupUpdate struct {
 	VtapLcuuids []string `json:"VTAP_LCUUIDS"`
 }
 
+type VTapUpgradeCampaignVTap struct {
+	VTapLcuuid       string `json:"VTAP_LCUUID"`
+	VTapName         string `json:"VTAP_NAME"`
+	Batch            int    `json:"BATCH"`
+	State            int    `json:"STATE"`
+	StateName        string `json:"STATE_NAME"`
+	OriginRevision   string `json:"ORIGIN_REVISION"`
+	ExpectedRevision string `json:"EXPECTED_REVISION"`
+	Message          string `json:"MESSAGE"`
+	StartedAt        string `json:"STARTED_AT"`
+}
+
+type VTapUpgradeCampaign struct {
+	ID               int                       `json:"ID"`
+	Name             string                    `json:"NAME"`
+	Lcuuid           string                    `json:"LCUUID"`
+	VTapGroupLcuuid  string                    `json:"VTAP_GROUP_LCUUID"`
+	LabelSelector    string                    `json:"LABEL_SELECTOR"`
+	ExpectedRevision string                    `json:"EXPECTED_REVISION"`
+	UpgradePackage   string                    `json:"UPGRADE_PACKAGE"`
+	RollbackRevision string                    `json:"ROLLBACK_REVISION"`
+	RollbackPackage  string                    `json:"ROLLBACK_PACKAGE"`
+	Batches          []int                     `json:"BATCHES"`
+	CurrentBatch     int                       `json:"CURRENT_BATCH"`
+	FailureThreshold int                       `json:"FAILURE_THRESHOLD"`
+	FailureAction    string                    `json:"FAILURE_ACTION"`
+	BatchTimeout     int                       `json:"BATCH_TIMEOUT"`
+	State            int                       `json:"STATE"`
+	StateName        string                    `json:"STATE_NAME"`
+	Message          string                    `json:"MESSAGE"`
+	VTapCount        int                       `json:"VTAP_COUNT"`
+	StateCounts      map[string]int            `json:"STATE_COUNTS"`
+	VTaps            []VTapUpgradeCampaignVTap `json:"VTAPS"`
+	CreatedAt        string                    `json:"CREATED_AT"`
+	UpdatedAt        string                    `json:"UPDATED_AT"`
+}
+
+type VTapUpgradeCampaignCreate struct {
+	Name             string `json:"NAME" binding:"required"`
+	VTapGroupLcuuid  string `json:"VTAP_GROUP_LCUUID"`
+	LabelSelector    string `json:"LABEL_SELECTOR"`
+	ExpectedRevision string `json:"EXPECTED_REVISION" binding:"required"`
+	UpgradePackage   string `json:"UPGRADE_PACKAGE" binding:"required"`
+	RollbackRevision string `json:"ROLLBACK_REVISION"`
+	RollbackPackage  string `json:"ROLLBACK_PACKAGE"`
+	Batches          []int  `json:"BATCHES"`           // 各批次累计升级比例(%), 默认[1, 10, 100]
+	FailureThreshold int    `json:"FAILURE_THRESHOLD"` // 失败比例阈值(%), 默认0即任一采集器升级失败即触发
+	FailureAction    string `json:"FAILURE_ACTION"`    // pause, rollback, 默认pause
+	BatchTimeout     int    `json:"BATCH_TIMEOUT"`     // unit: s, 默认600
+}
+
+type VTapUpgradeCampaignUpdate struct {
+	Action string `json:"ACTION" binding:"required"` // pause, resume, cancel, rollback
+}
+
+type VTapRepo struct {
+	ID        int    `json:"ID"`
+	Name      string `json:"NAME"`
+	Revision  string `json:"REVISION"`
+	Arch      string `json:"ARCH"`
+	OS        string `json:"OS"`
+	VTapType  string `json:"VTAP_TYPE"`
+	Size      int    `json:"SIZE"`
+	SHA256    string `json:"SHA256"`
+	Signature string `json:"SIGNATURE"`
+	CreatedAt string `json:"CREATED_AT"`
+	UpdatedAt string `json:"UPDATED_AT"`
+	Lcuuid    string `json:"LCUUID"`
+}
+
+// VTapRepoCreate 升级包通过multipart/form-data上传，升级包文件字段为IMAGE
+type VTapRepoCreate struct {
+	Name      string `form:"NAME" binding:"required"`
+	Revision  string `form:"REVISION" binding:"required"`
+	Arch      string `form:"ARCH"`
+	OS        string `form:"OS"`
+	VTapType  string `form:"VTAP_TYPE"` // 为空表示适用于所有类型
+	SHA256    string `form:"SHA256"`    // 非空时校验上传的升级包
+	Signature string `form:"SIGNATURE" binding:"required"`
+}
+
+type ACL struct {
+	ID          int    `json:"ID"`
+	Name        string `json:"NAME"`
+	Application string `json:"APPLICATION"`
+	TapType     int    `json:"TAP_TYPE"`
+	State       int    `json:"STATE"`
+	SrcGroupIDs []int  `json:"SRC_GROUP_IDS"`
+	DstGroupIDs []int  `json:"DST_GROUP_IDS"`
+	Protocol    int    `json:"PROTOCOL"`
+	SrcPorts    string `json:"SRC_PORTS"`
+	DstPorts    string `json:"DST_PORTS"`
+	Vlan        int    `json:"VLAN"`
+	CreatedAt   string `json:"CREATED_AT"`
+	UpdatedAt   string `json:"UPDATED_AT"`
+	Lcuuid      string `json:"LCUUID"`
+}
+
+type ACLCreate struct {
+	Name        string `json:"NAME" binding:"required"`
+	Application string `json:"APPLICATION" binding:"required"` // npb, pcap
+	TapType     *int   `json:"TAP_TYPE"`                       // 默认3
+	State       *int   `json:"STATE"`                          // 0-disable; 1-enable, 默认1
+	SrcGroupIDs []int  `json:"SRC_GROUP_IDS"`                  // 资源组ID, 目前最多支持一个
+	DstGroupIDs []int  `json:"DST_GROUP_IDS"`                  // 资源组ID, 目前最多支持一个
+	Protocol    int    `json:"PROTOCOL"`                       // 0表示任意协议
+	SrcPorts    string `json:"SRC_PORTS"`                      // e.g. 80,8000-8080
+	DstPorts    string `json:"DST_PORTS"`                      // e.g. 80,8000-8080
+	Vlan        int    `json:"VLAN"`
+}
+
+type ACLUpdate struct {
+	Name        *string `json:"NAME"`
+	TapType     *int    `json:"TAP_TYPE"`
+	State       *int    `json:"STATE"`
+	SrcGroupIDs *[]int  `json:"SRC_GROUP_IDS"`
+	DstGroupIDs *[]int  `json:"DST_GROUP_IDS"`
+	Protocol    *int    `json:"PROTOCOL"`
+	SrcPorts    *string `json:"SRC_PORTS"`
+	DstPorts    *string `json:"DST_PORTS"`
+	Vlan        *int    `json:"VLAN"`
+}
+
+type NpbTunnel struct {
+	ID        int    `json:"ID"`
+	Name      string `json:"NAME"`
+	IP        string `json:"IP"`
+	Type      int    `json:"TYPE"`
+	TypeName  string `json:"TYPE_NAME"`
+	CreatedAt string `json:"CREATED_AT"`
+	UpdatedAt string `json:"UPDATED_AT"`
+	Lcuuid    string `json:"LCUUID"`
+}
+
+type NpbTunnelCreate struct {
+	Name string `json:"NAME" binding:"required"`
+	IP   string `json:"IP" binding:"required"`
+	Type int    `json:"TYPE"` // 0-VXLAN; 1-ERSPAN
+}
+
+type NpbTunnelUpdate struct {
+	Name *string `json:"NAME"`
+	IP   *string `json:"IP"`
+	Type *int    `json:"TYPE"`
+}
+
+type NpbPolicy struct {
+	ID               int    `json:"ID"`
+	Name             string `json:"NAME"`
+	State            int    `json:"STATE"`
+	ACLID            int    `json:"ACL_ID"`
+	NpbTunnelID      int    `json:"NPB_TUNNEL_ID"`
+	Vni              int    `json:"VNI"`
+	Distribute       int    `json:"DISTRIBUTE"`
+	PayloadSlice     *int   `json:"PAYLOAD_SLICE"`
+	VTapIDs          []int  `json:"VTAP_IDS"`
+	PolicyACLGroupID int    `json:"POLICY_ACL_GROUP_ID"`
+	CreatedAt        string `json:"CREATED_AT"`
+	UpdatedAt        string `json:"UPDATED_AT"`
+	Lcuuid           string `json:"LCUUID"`
+}
+
+type NpbPolicyCreate struct {
+	Name         string `json:"NAME" binding:"required"`
+	State        *int   `json:"STATE"` // 0-disable; 1-enable, 默认1
+	ACLID        int    `json:"ACL_ID" binding:"required"`
+	NpbTunnelID  int    `json:"NPB_TUNNEL_ID" binding:"required"`
+	Vni          int    `json:"VNI"`           // VXLAN为VNI, ERSPAN为Session ID
+	Distribute   *int   `json:"DISTRIBUTE"`    // 0-drop, 1-distribute, 默认1
+	PayloadSlice *int   `json:"PAYLOAD_SLICE"` // 为空表示不截断
+	VTapIDs      []int  `json:"VTAP_IDS"`      // 为空表示所有采集器
+}
+
+type NpbPolicyUpdate struct {
+	Name         *string `json:"NAME"`
+	State        *int    `json:"STATE"`
+	ACLID        *int    `json:"ACL_ID"`
+	NpbTunnelID  *int    `json:"NPB_TUNNEL_ID"`
+	Vni          *int    `json:"VNI"`
+	Distribute   *int    `json:"DISTRIBUTE"`
+	PayloadSlice *int    `json:"PAYLOAD_SLICE"`
+	VTapIDs      *[]int  `json:"VTAP_IDS"`
+}
+
+type PcapPolicy struct {
+	ID               int    `json:"ID"`
+	Name             string `json:"NAME"`
+	State            int    `json:"STATE"`
+	ACLID            int    `json:"ACL_ID"`
+	PayloadSlice     *int   `json:"PAYLOAD_SLICE"`
+	VTapIDs          []int  `json:"VTAP_IDS"`
+	PolicyACLGroupID int    `json:"POLICY_ACL_GROUP_ID"`
+	CreatedAt        string `json:"CREATED_AT"`
+	UpdatedAt        string `json:"UPDATED_AT"`
+	Lcuuid           string `json:"LCUUID"`
+}
+
+type PcapPolicyCreate struct {
+	Name         string `json:"NAME" binding:"required"`
+	State        *int   `json:"STATE"` // 0-disable; 1-enable, 默认1
+	ACLID        int    `json:"ACL_ID" binding:"required"`
+	PayloadSlice *int   `json:"PAYLOAD_SLICE"` // 为空表示不截断
+	VTapIDs      []int  `json:"VTAP_IDS"`      // 为空表示所有采集器
+}
+
+type PcapPolicyUpdate struct {
+	Name         *string `json:"NAME"`
+	State        *int    `json:"STATE"`
+	ACLID        *int    `json:"ACL_ID"`
+	PayloadSlice *int    `json:"PAYLOAD_SLICE"`
+	VTapIDs      *[]int  `json:"VTAP_IDS"`
+}
+
+// PolicyDryRun 预览策略变更, 列出策略版本将变化的采集器
+type PolicyDryRun struct {
+	VTaps                 []PolicyDryRunVTap `json:"VTAPS"`
+	AnalyzerPolicyChanged bool               `json:"ANALYZER_POLICY_CHANGED"`
+}
+
+type PolicyDryRunVTap struct {
+	ID     int    `json:"ID"`
+	Name   string `json:"NAME"`
+	Lcuuid string `json:"LCUUID"`
+}
+
+type ResourceGroup struct {
+	ID               int      `json:"ID"`
+	Name             string   `json:"NAME"`
+	Type             string   `json:"TYPE"`
+	VPCID            int      `json:"VPC_ID"`
+	PodClusterID     int      `json:"POD_CLUSTER_ID"`
+	IPs              []string `json:"IPS"`
+	SubnetIDs        []int    `json:"SUBNET_IDS"`
+	PodGroupIDs      []int    `json:"POD_GROUP_IDS"`
+	PodServiceIDs    []int    `json:"POD_SERVICE_IDS"`
+	PodLabels        []string `json:"POD_LABELS"`
+	ResolvedIPs      []string `json:"RESOLVED_IPS"`       // trisolaris最近一次解析得到的CIDR
+	ResolvedIPRanges []string `json:"RESOLVED_IP_RANGES"` // trisolaris最近一次解析得到的IP范围
+	CreatedAt        string   `json:"CREATED_AT"`
+	UpdatedAt        string   `json:"UPDATED_AT"`
+	Lcuuid           string   `json:"LCUUID"`
+}
+
+type ResourceGroupCreate struct {
+	Name          string   `json:"NAME" binding:"required"`
+	Type          string   `json:"TYPE" binding:"required"` // ip, subnet, pod_group, pod_service, pod_label
+	VPCID         int      `json:"VPC_ID"`                  // subnet类型必填
+	PodClusterID  int      `json:"POD_CLUSTER_ID"`          // pod_label类型可选, 限定容器集群
+	IPs           []string `json:"IPS"`                     // ip类型, 支持IP、CIDR及IP范围, e.g. 10.1.1.1-10.1.1.100
+	SubnetIDs     []int    `json:"SUBNET_IDS"`              // subnet类型
+	PodGroupIDs   []int    `json:"POD_GROUP_IDS"`           // pod_group类型
+	PodServiceIDs []int    `json:"POD_SERVICE_IDS"`         // pod_service类型
+	PodLabels     []string `json:"POD_LABELS"`              // pod_label类型, e.g. app:nginx, 需匹配所有标签
+}
+
+type ResourceGroupUpdate struct {
+	Name          *string   `json:"NAME"`
+	VPCID         *int      `json:"VPC_ID"`
+	PodClusterID  *int      `json:"POD_CLUSTER_ID"`
+	IPs           *[]string `json:"IPS"`
+	SubnetIDs     *[]int    `json:"SUBNET_IDS"`
+	PodGroupIDs   *[]int    `json:"POD_GROUP_IDS"`
+	PodServiceIDs *[]int    `json:"POD_SERVICE_IDS"`
+	PodLabels     *[]string `json:"POD_LABELS"`
+}
+
 type DataSource struct {
 	ID                        int    `json:"ID"`
 	Name                      string `json:"NAME"`
@@ -56,6 +56,7 @@ type StaticConfig struct {
 	AnalyzerPort                     *uint16                            `yaml:"analyzer-port,omitempty"`
 	KubernetesNamespace              *string                            `yaml:"kubernetes-namespace,omitempty"`
 	IngressFlavour                   *string                            `yaml:"ingress-flavour,omitempty"`
+	KubernetesCustomWorkloads        []string                           `yaml:"kubernetes-custom-workloads,omitempty"`
 	GrpcBufferSize                   *int                               `yaml:"grpc-buffer-size,omitempty"`            // 单位：M
 	L7LogSessionAggrTimeout          *int                               `yaml:"l7-log-session-aggr-timeout,omitempty"` // 单位: s
 	TapMacScript                     *string                            `yaml:"tap-mac-script,omitempty"`
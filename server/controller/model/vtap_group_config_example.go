@@ -402,6 +402,13 @@ vtap_group_id: g-xxxxxx
   #kubernetes-namespace:
   ## ingress的类型，填写为kubernetes or openshift，默认kubernetes
   #ingress-flavour: kubernetes
+  ## 作为工作负载同步的自定义资源，格式为<资源名>.<API组>，集群中不存在的资源会被忽略
+  ## 控制器按domain配置中的custom_workload_kinds将其关联为自定义工作负载类型的pod_group
+  ## 采集器的ClusterRole需要包含每个资源的get/list/watch权限，默认列表的权限已包含在deepflow-agent-ds.yaml中
+  #kubernetes-custom-workloads:
+  #- clonesets.apps.kruise.io
+  #- statefulsets.apps.kruise.io
+  #- rollouts.argoproj.io
   ## 配置后会使用配置文件中的analyzer-ip分别替换控制器下发的analyzer-ip
   #analyzer-ip: ""
   ## loglevel: "debug/info/warn/error"
@@ -25,6 +25,7 @@ import (
 
 	"github.com/deepflowys/deepflow/server/controller/common"
 	"github.com/deepflowys/deepflow/server/controller/config"
+	"github.com/deepflowys/deepflow/server/controller/db/clickhouse"
 	"github.com/deepflowys/deepflow/server/controller/db/mysql"
 	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
 	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
@@ -34,6 +35,7 @@ type AnalyzerCheck struct {
 	cCtx                  context.Context
 	cCancel               context.CancelFunc
 	cfg                   mconfig.MonitorConfig
+	ckCfg                 clickhouse.ClickHouseConfig
 	healthCheckPort       int
 	healthCheckNodePort   int
 	ch                    chan string
@@ -47,6 +49,7 @@ func NewAnalyzerCheck(cfg *config.ControllerConfig, ctx context.Context) *Analyz
 		cCtx:                  cCtx,
 		cCancel:               cCancel,
 		cfg:                   cfg.MonitorCfg,
+		ckCfg:                 cfg.ClickHouseCfg,
 		healthCheckPort:       cfg.ListenPort,
 		healthCheckNodePort:   cfg.ListenNodePort,
 		ch:                    make(chan string, cfg.MonitorCfg.HealthCheckHandleChannelLen),
@@ -223,9 +226,13 @@ func (c *AnalyzerCheck) vtapAnalyzerAlloc(excludeIP string) {
 	azToNoAnalyzerVTaps := make(map[string][]*mysql.VTap)
 	analyzerIPToUsedVTapNum := make(map[string]int)
 	azLcuuids := mapset.NewSet()
+	// 按数据量分配时，获取采集器及数据节点的负载
+	vtapNameToLoad := getVTapLoads(c.cfg.VTapLoadBalancing, c.ckCfg)
+	analyzerIPToLoad := make(map[string]float64)
 	for i, vtap := range vtaps {
 		if vtap.AnalyzerIP != "" && vtap.AnalyzerIP != excludeIP {
 			analyzerIPToUsedVTapNum[vtap.AnalyzerIP] += 1
+			analyzerIPToLoad[vtap.AnalyzerIP] += vtapNameToLoad[vtap.Name]
 			continue
 		}
 		azToNoAnalyzerVTaps[vtap.AZ] = append(azToNoAnalyzerVTaps[vtap.AZ], &vtaps[i])
@@ -287,11 +294,17 @@ func (c *AnalyzerCheck) vtapAnalyzerAlloc(excludeIP string) {
 				mysql.Db.Model(&vtap).Update("exceptions", exceptions)
 				continue
 			}
-			sort.Slice(analyzerAvailableVTapNum, func(i, j int) bool {
-				return analyzerAvailableVTapNum[i].Value > analyzerAvailableVTapNum[j].Value
-			})
+			if vtapNameToLoad != nil {
+				// 优先分配负载最低的数据节点
+				sortHostsByLoad(analyzerAvailableVTapNum, analyzerIPToLoad)
+			} else {
+				sort.Slice(analyzerAvailableVTapNum, func(i, j int) bool {
+					return analyzerAvailableVTapNum[i].Value > analyzerAvailableVTapNum[j].Value
+				})
+			}
 			analyzerAvailableVTapNum[0].Value -= 1
 			analyzerIPToAvailableVTapNum[analyzerAvailableVTapNum[0].Key] -= 1
+			analyzerIPToLoad[analyzerAvailableVTapNum[0].Key] += vtapNameToLoad[vtap.Name]
 
 			// 分配数据节点成功，更新数据节点IP + 清空数据节点分配失败的错误码
 			log.Infof("alloc analyzer (%s) for vtap (%s)", analyzerAvailableVTapNum[0].Key, vtap.Name)
@@ -22,14 +22,29 @@ type Warrant struct {
 	Timeout int    `default:"30" yaml:"timeout"`
 }
 
+const (
+	VTAP_LOAD_BALANCING_BY_AGENT_COUNT   = "by-agent-count"
+	VTAP_LOAD_BALANCING_BY_INGESTED_DATA = "by-ingested-data"
+)
+
+// 采集器分配/均衡策略, by-ingested-data时按采集器近期发送的数据量计算控制器/数据节点的负载
+type VTapLoadBalancing struct {
+	Algorithm         string  `default:"by-agent-count" yaml:"algorithm"`
+	DataDuration      int     `default:"86400" yaml:"data_duration"`     // unit: second
+	RebalanceInterval int     `default:"3600" yaml:"rebalance_interval"` // unit: second
+	MaxSkew           float64 `default:"0.2" yaml:"max_skew"`            // 节点负载允许超出平均负载的比例
+}
+
 type MonitorConfig struct {
-	HealthCheckInterval         int     `default:"60" yaml:"health_check_interval"`
-	HealthCheckPort             int     `default:"30417" yaml:"health_check_port"`
-	HealthCheckHandleChannelLen int     `default:"1000" yaml:"health_check_handle_channel_len"`
-	LicenseCheckInterval        int     `default:"60" yaml:"license_check_interval"`
-	VTapCheckInterval           int     `default:"60" yaml:"vtap_check_interval"`
-	ExceptionTimeFrame          int     `default:"3600" yaml:"exception_time_frame"`
-	AutoRebalanceVTap           bool    `default:"true" yaml:"auto_rebalance_vtap"`
-	RebalanceCheckInterval      int     `default:"300" yaml:"rebalance_check_interval"` // unit: second
-	Warrant                     Warrant `yaml:"warrant"`
+	HealthCheckInterval          int               `default:"60" yaml:"health_check_interval"`
+	HealthCheckPort              int               `default:"30417" yaml:"health_check_port"`
+	HealthCheckHandleChannelLen  int               `default:"1000" yaml:"health_check_handle_channel_len"`
+	LicenseCheckInterval         int               `default:"60" yaml:"license_check_interval"`
+	VTapCheckInterval            int               `default:"60" yaml:"vtap_check_interval"`
+	ExceptionTimeFrame           int               `default:"3600" yaml:"exception_time_frame"`
+	AutoRebalanceVTap            bool              `default:"true" yaml:"auto_rebalance_vtap"`
+	RebalanceCheckInterval       int               `default:"300" yaml:"rebalance_check_interval"`       // unit: second
+	UpgradeCampaignCheckInterval int               `default:"30" yaml:"upgrade_campaign_check_interval"` // unit: second
+	VTapLoadBalancing            VTapLoadBalancing `yaml:"vtap_load_balancing"`
+	Warrant                      Warrant           `yaml:"warrant"`
 }
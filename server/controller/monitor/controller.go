@@ -25,6 +25,7 @@ import (
 
 	"github.com/deepflowys/deepflow/server/controller/common"
 	"github.com/deepflowys/deepflow/server/controller/config"
+	"github.com/deepflowys/deepflow/server/controller/db/clickhouse"
 	"github.com/deepflowys/deepflow/server/controller/db/mysql"
 	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
 	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
@@ -34,6 +35,7 @@ type ControllerCheck struct {
 	cCtx                    context.Context
 	cCancel                 context.CancelFunc
 	cfg                     mconfig.MonitorConfig
+	ckCfg                   clickhouse.ClickHouseConfig
 	healthCheckPort         int
 	healthCheckNodePort     int
 	ch                      chan string
@@ -47,6 +49,7 @@ func NewControllerCheck(cfg *config.ControllerConfig, ctx context.Context) *Cont
 		cCtx:                    cCtx,
 		cCancel:                 cCancel,
 		cfg:                     cfg.MonitorCfg,
+		ckCfg:                   cfg.ClickHouseCfg,
 		healthCheckPort:         cfg.ListenPort,
 		healthCheckNodePort:     cfg.ListenNodePort,
 		ch:                      make(chan string, cfg.MonitorCfg.HealthCheckHandleChannelLen),
@@ -236,9 +239,13 @@ func (c *ControllerCheck) vtapControllerAlloc(excludeIP string) {
 	azToNoControllerVTaps := make(map[string][]*mysql.VTap)
 	controllerIPToUsedVTapNum := make(map[string]int)
 	azLcuuids := mapset.NewSet()
+	// 按数据量分配时，获取采集器及控制器的负载
+	vtapNameToLoad := getVTapLoads(c.cfg.VTapLoadBalancing, c.ckCfg)
+	controllerIPToLoad := make(map[string]float64)
 	for i, vtap := range vtaps {
 		if vtap.ControllerIP != "" && vtap.ControllerIP != excludeIP {
 			controllerIPToUsedVTapNum[vtap.ControllerIP] += 1
+			controllerIPToLoad[vtap.ControllerIP] += vtapNameToLoad[vtap.Name]
 			continue
 		}
 		azToNoControllerVTaps[vtap.AZ] = append(azToNoControllerVTaps[vtap.AZ], &vtaps[i])
@@ -300,11 +307,17 @@ func (c *ControllerCheck) vtapControllerAlloc(excludeIP string) {
 				mysql.Db.Model(&vtap).Update("exceptions", exceptions)
 				continue
 			}
-			sort.Slice(controllerAvailableVTapNum, func(i, j int) bool {
-				return controllerAvailableVTapNum[i].Value > controllerAvailableVTapNum[j].Value
-			})
+			if vtapNameToLoad != nil {
+				// 优先分配负载最低的控制器
+				sortHostsByLoad(controllerAvailableVTapNum, controllerIPToLoad)
+			} else {
+				sort.Slice(controllerAvailableVTapNum, func(i, j int) bool {
+					return controllerAvailableVTapNum[i].Value > controllerAvailableVTapNum[j].Value
+				})
+			}
 			controllerAvailableVTapNum[0].Value -= 1
 			controllerIPToAvailableVTapNum[controllerAvailableVTapNum[0].Key] -= 1
+			controllerIPToLoad[controllerAvailableVTapNum[0].Key] += vtapNameToLoad[vtap.Name]
 
 			// 分配控制器成功，更新控制器IP + 清空控制器分配失败的错误码
 			log.Infof("alloc controller (%s) for vtap (%s)", controllerAvailableVTapNum[0].Key, vtap.Name)
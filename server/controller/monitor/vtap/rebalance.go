@@ -22,34 +22,57 @@ import (
 	"time"
 
 	"github.com/deepflowys/deepflow/server/controller/common"
-	"github.com/deepflowys/deepflow/server/controller/monitor/config"
+	"github.com/deepflowys/deepflow/server/controller/config"
+	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
 	"github.com/deepflowys/deepflow/server/controller/service"
 )
 
 type RebalanceCheck struct {
-	vCtx    context.Context
-	vCancel context.CancelFunc
-	cfg     config.MonitorConfig
+	vCtx          context.Context
+	vCancel       context.CancelFunc
+	cfg           mconfig.MonitorConfig
+	controllerCfg *config.ControllerConfig
 }
 
-func NewRebalanceCheck(cfg config.MonitorConfig, ctx context.Context) *RebalanceCheck {
+func NewRebalanceCheck(cfg *config.ControllerConfig, ctx context.Context) *RebalanceCheck {
 	vCtx, vCancel := context.WithCancel(ctx)
 	return &RebalanceCheck{
-		vCtx:    vCtx,
-		vCancel: vCancel,
-		cfg:     cfg,
+		vCtx:          vCtx,
+		vCancel:       vCancel,
+		cfg:           cfg.MonitorCfg,
+		controllerCfg: cfg,
 	}
 }
 
 func (r *RebalanceCheck) Start() {
 	log.Info("rebalance check start")
+	byIngestedData := r.cfg.VTapLoadBalancing.Algorithm == mconfig.VTAP_LOAD_BALANCING_BY_INGESTED_DATA
+	if !byIngestedData && !r.cfg.AutoRebalanceVTap {
+		return
+	}
 	go func() {
-		if !r.cfg.AutoRebalanceVTap {
-			return
+		ticker := time.NewTicker(time.Duration(r.cfg.RebalanceCheckInterval) * time.Second)
+		defer ticker.Stop()
+		// 按数据量均衡时，另外按RebalanceInterval检查各节点的负载
+		var loadTickerC <-chan time.Time
+		if byIngestedData {
+			loadTicker := time.NewTicker(time.Duration(r.cfg.VTapLoadBalancing.RebalanceInterval) * time.Second)
+			defer loadTicker.Stop()
+			loadTickerC = loadTicker.C
 		}
-		for range time.Tick(time.Duration(r.cfg.RebalanceCheckInterval) * time.Second) {
-			r.controllerRebalance()
-			r.analyzerRebalance()
+		for {
+			select {
+			case <-r.vCtx.Done():
+				return
+			case <-ticker.C:
+				if r.cfg.AutoRebalanceVTap {
+					r.controllerRebalance()
+					r.analyzerRebalance()
+				}
+			case <-loadTickerC:
+				r.loadRebalance("controller")
+				r.loadRebalance("analyzer")
+			}
 		}
 	}()
 }
@@ -72,11 +95,13 @@ func (r *RebalanceCheck) controllerRebalance() {
 		// check if need rebalance
 		if controller.VtapCount == 0 && controller.State == common.HOST_STATE_COMPLETE && len(controller.Azs) != 0 {
 			log.Info("need rebalance vtap for controller (%s)", controller.IP)
+			// 节点上没有采集器时按采集器数量均衡，按数据量均衡时新节点的负载可能仍在允许的偏差内
 			args := map[string]interface{}{
-				"check": false,
-				"type":  "controller",
+				"check":     false,
+				"type":      "controller",
+				"algorithm": mconfig.VTAP_LOAD_BALANCING_BY_AGENT_COUNT,
 			}
-			if result, err := service.VTapRebalance(args); err != nil {
+			if result, err := service.VTapRebalance(args, r.controllerCfg); err != nil {
 				log.Error(err)
 			} else {
 				data, _ := json.Marshal(result)
@@ -98,11 +123,13 @@ func (r *RebalanceCheck) analyzerRebalance() {
 	for _, analyzer := range analyzers {
 		if analyzer.VtapCount == 0 && analyzer.State == common.HOST_STATE_COMPLETE && len(analyzer.Azs) != 0 {
 			log.Info("need rebalance vtap for analyzer (%s)", analyzer.IP)
+			// 节点上没有采集器时按采集器数量均衡，按数据量均衡时新节点的负载可能仍在允许的偏差内
 			args := map[string]interface{}{
-				"check": false,
-				"type":  "analyzer",
+				"check":     false,
+				"type":      "analyzer",
+				"algorithm": mconfig.VTAP_LOAD_BALANCING_BY_AGENT_COUNT,
 			}
-			if result, err := service.VTapRebalance(args); err != nil {
+			if result, err := service.VTapRebalance(args, r.controllerCfg); err != nil {
 				log.Error(err)
 			} else {
 				data, _ := json.Marshal(result)
@@ -112,3 +139,25 @@ func (r *RebalanceCheck) analyzerRebalance() {
 		}
 	}
 }
+
+// loadRebalance 按数据量检查各节点负载是否超出允许的偏差，未开启自动均衡时仅输出均衡方案
+func (r *RebalanceCheck) loadRebalance(hostType string) {
+	args := map[string]interface{}{
+		"check": !r.cfg.AutoRebalanceVTap,
+		"type":  hostType,
+	}
+	result, err := service.VTapRebalance(args, r.controllerCfg)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if result.TotalSwitchVTapNum == 0 {
+		return
+	}
+	data, _ := json.Marshal(result)
+	if r.cfg.AutoRebalanceVTap {
+		log.Infof("exec %s rebalance by ingested data: %s", hostType, string(data))
+	} else {
+		log.Infof("propose %s rebalance by ingested data: %s", hostType, string(data))
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vtap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
+	"github.com/deepflowys/deepflow/server/controller/service"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
+)
+
+// UpgradeCampaignCheck 推进采集器分批升级任务:
+// 逐批次下发升级, 等待采集器上报目标版本且状态正常, 批次失败比例超过阈值时暂停或回滚
+type UpgradeCampaignCheck struct {
+	ctx     context.Context
+	vCtx    context.Context
+	vCancel context.CancelFunc
+	cfg     mconfig.MonitorConfig
+}
+
+func NewUpgradeCampaignCheck(cfg mconfig.MonitorConfig, ctx context.Context) *UpgradeCampaignCheck {
+	return &UpgradeCampaignCheck{
+		ctx: ctx,
+		cfg: cfg,
+	}
+}
+
+func (u *UpgradeCampaignCheck) Start() {
+	log.Info("vtap upgrade campaign check start")
+	u.vCtx, u.vCancel = context.WithCancel(u.ctx)
+	go func() {
+		ticker := time.NewTicker(time.Duration(u.cfg.UpgradeCampaignCheckInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-u.vCtx.Done():
+				return
+			case <-ticker.C:
+				u.check()
+			}
+		}
+	}()
+}
+
+func (u *UpgradeCampaignCheck) Stop() {
+	if u.vCancel != nil {
+		u.vCancel()
+	}
+	log.Info("vtap upgrade campaign check stopped")
+}
+
+func (u *UpgradeCampaignCheck) check() {
+	var campaigns []mysql.VTapUpgradeCampaign
+	mysql.Db.Where("state IN (?)", []int{
+		common.UPGRADE_CAMPAIGN_STATE_RUNNING, common.UPGRADE_CAMPAIGN_STATE_PAUSED, common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK,
+	}).Find(&campaigns)
+	if len(campaigns) == 0 {
+		return
+	}
+
+	var vtaps []mysql.VTap
+	mysql.Db.Find(&vtaps)
+	lcuuidToVTap := make(map[string]*mysql.VTap, len(vtaps))
+	for i, vtap := range vtaps {
+		lcuuidToVTap[vtap.Lcuuid] = &vtaps[i]
+	}
+
+	changed := false
+	for i := range campaigns {
+		if u.checkCampaign(&campaigns[i], lcuuidToVTap) {
+			changed = true
+		}
+	}
+	// 通知trisolaris更新采集器的预期版本
+	if changed {
+		refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	}
+}
+
+func (u *UpgradeCampaignCheck) checkCampaign(campaign *mysql.VTapUpgradeCampaign, lcuuidToVTap map[string]*mysql.VTap) bool {
+	var campaignVTaps []*mysql.VTapUpgradeCampaignVTap
+	mysql.Db.Where("campaign_lcuuid = ?", campaign.Lcuuid).Find(&campaignVTaps)
+
+	changed := u.updateCampaignVTaps(campaign, campaignVTaps, lcuuidToVTap)
+	switch campaign.State {
+	case common.UPGRADE_CAMPAIGN_STATE_RUNNING:
+		if u.advanceCampaign(campaign, campaignVTaps) {
+			changed = true
+		}
+	case common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK:
+		rolledBack, rollbackFailed := 0, 0
+		for _, campaignVTap := range campaignVTaps {
+			switch campaignVTap.State {
+			case common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK:
+				return changed
+			case common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLED_BACK:
+				rolledBack += 1
+			case common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLBACK_FAILED:
+				rollbackFailed += 1
+			}
+		}
+		message := fmt.Sprintf("%s; %d vtaps rolled back, %d vtaps failed to roll back", campaign.Message, rolledBack, rollbackFailed)
+		u.updateCampaign(campaign, common.UPGRADE_CAMPAIGN_STATE_ROLLED_BACK, campaign.CurrentBatch, message)
+	}
+	return changed
+}
+
+// updateCampaignVTaps 检查升级中/回滚中的采集器是否已上报目标版本且状态正常, 超时未完成的记为失败
+func (u *UpgradeCampaignCheck) updateCampaignVTaps(
+	campaign *mysql.VTapUpgradeCampaign, campaignVTaps []*mysql.VTapUpgradeCampaignVTap, lcuuidToVTap map[string]*mysql.VTap,
+) bool {
+	changed := false
+	timeout := time.Duration(campaign.BatchTimeout) * time.Second
+	for _, campaignVTap := range campaignVTaps {
+		var succeededState, failedState int
+		switch campaignVTap.State {
+		case common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING:
+			succeededState, failedState = common.UPGRADE_CAMPAIGN_VTAP_STATE_SUCCEEDED, common.UPGRADE_CAMPAIGN_VTAP_STATE_FAILED
+		case common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK:
+			succeededState, failedState = common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLED_BACK, common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLBACK_FAILED
+		default:
+			continue
+		}
+
+		state, message := 0, ""
+		vtap, ok := lcuuidToVTap[campaignVTap.VTapLcuuid]
+		if !ok {
+			state, message = failedState, "vtap not found"
+		} else if service.GetVTapRealRevision(vtap.Revision) == campaignVTap.ExpectedRevision &&
+			vtap.State == common.VTAP_STATE_NORMAL {
+			state = succeededState
+		} else if time.Since(campaignVTap.StartedAt) > timeout {
+			state = failedState
+			message = fmt.Sprintf(
+				"timeout after %ds, revision: %s, state: %d", campaign.BatchTimeout, vtap.Revision, vtap.State,
+			)
+		} else {
+			continue
+		}
+		log.Infof(
+			"vtap_upgrade_campaign (%s) vtap (%s) %s %s",
+			campaign.Name, campaignVTap.VTapName, common.UpgradeCampaignVTapStateName[state], message,
+		)
+		campaignVTap.State = state
+		campaignVTap.Message = message
+		mysql.Db.Model(campaignVTap).Updates(map[string]interface{}{"state": state, "message": message})
+		changed = true
+	}
+	return changed
+}
+
+// advanceCampaign 当前批次全部完成后检查批次失败比例, 未超过阈值时开始下一批次
+func (u *UpgradeCampaignCheck) advanceCampaign(
+	campaign *mysql.VTapUpgradeCampaign, campaignVTaps []*mysql.VTapUpgradeCampaignVTap,
+) bool {
+	var batchVTaps []*mysql.VTapUpgradeCampaignVTap
+	hasNextBatch := false
+	for _, campaignVTap := range campaignVTaps {
+		if campaignVTap.Batch == campaign.CurrentBatch {
+			batchVTaps = append(batchVTaps, campaignVTap)
+		} else if campaignVTap.Batch > campaign.CurrentBatch {
+			hasNextBatch = true
+		}
+	}
+	if len(batchVTaps) == 0 {
+		if hasNextBatch {
+			u.updateCampaign(campaign, campaign.State, campaign.CurrentBatch+1, campaign.Message)
+			return false
+		}
+		succeeded := 0
+		for _, campaignVTap := range campaignVTaps {
+			if campaignVTap.State == common.UPGRADE_CAMPAIGN_VTAP_STATE_SUCCEEDED {
+				succeeded += 1
+			}
+		}
+		message := fmt.Sprintf("%d/%d vtaps upgraded", succeeded, len(campaignVTaps))
+		u.updateCampaign(campaign, common.UPGRADE_CAMPAIGN_STATE_COMPLETED, campaign.CurrentBatch, message)
+		return false
+	}
+
+	pending, upgrading, failed := 0, 0, 0
+	for _, campaignVTap := range batchVTaps {
+		switch campaignVTap.State {
+		case common.UPGRADE_CAMPAIGN_VTAP_STATE_PENDING:
+			pending += 1
+		case common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING:
+			upgrading += 1
+		case common.UPGRADE_CAMPAIGN_VTAP_STATE_FAILED:
+			failed += 1
+		}
+	}
+	if pending > 0 {
+		u.startBatch(campaign, batchVTaps)
+		return true
+	}
+	if upgrading > 0 {
+		return false
+	}
+
+	// 失败比例阈值为0时任一采集器失败即触发
+	if failed > 0 && failed*100 > campaign.FailureThreshold*len(batchVTaps) {
+		message := fmt.Sprintf(
+			"batch %d: %d/%d vtaps failed, exceeds failure threshold %d%%",
+			campaign.CurrentBatch+1, failed, len(batchVTaps), campaign.FailureThreshold,
+		)
+		log.Warningf("vtap_upgrade_campaign (%s) %s", campaign.Name, message)
+		if campaign.FailureAction == common.UPGRADE_CAMPAIGN_FAILURE_ACTION_ROLLBACK {
+			service.RollbackVTapUpgradeCampaign(campaign, message)
+			return false
+		}
+		// 暂停后恢复时从下一批次继续
+		u.updateCampaign(campaign, common.UPGRADE_CAMPAIGN_STATE_PAUSED, campaign.CurrentBatch+1, message)
+		return false
+	}
+
+	u.updateCampaign(campaign, campaign.State, campaign.CurrentBatch+1, campaign.Message)
+	return u.advanceCampaign(campaign, campaignVTaps)
+}
+
+func (u *UpgradeCampaignCheck) startBatch(campaign *mysql.VTapUpgradeCampaign, batchVTaps []*mysql.VTapUpgradeCampaignVTap) {
+	now := time.Now()
+	ids := []int{}
+	for _, campaignVTap := range batchVTaps {
+		if campaignVTap.State != common.UPGRADE_CAMPAIGN_VTAP_STATE_PENDING {
+			continue
+		}
+		campaignVTap.State = common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING
+		campaignVTap.StartedAt = now
+		ids = append(ids, campaignVTap.ID)
+	}
+	mysql.Db.Model(&mysql.VTapUpgradeCampaignVTap{}).Where("id IN (?)", ids).Updates(map[string]interface{}{
+		"state": common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, "started_at": now,
+	})
+	log.Infof(
+		"vtap_upgrade_campaign (%s) start batch %d, upgrade %d vtaps to revision (%s)",
+		campaign.Name, campaign.CurrentBatch+1, len(ids), campaign.ExpectedRevision,
+	)
+}
+
+func (u *UpgradeCampaignCheck) updateCampaign(campaign *mysql.VTapUpgradeCampaign, state, currentBatch int, message string) {
+	if state != campaign.State {
+		log.Infof(
+			"vtap_upgrade_campaign (%s) state changes from %s to %s: %s", campaign.Name,
+			common.UpgradeCampaignStateName[campaign.State], common.UpgradeCampaignStateName[state], message,
+		)
+	}
+	campaign.State = state
+	campaign.CurrentBatch = currentBatch
+	campaign.Message = message
+	mysql.Db.Model(campaign).Updates(map[string]interface{}{
+		"state": state, "current_batch": currentBatch, "message": message,
+	})
+}
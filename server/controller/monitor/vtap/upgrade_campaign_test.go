@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vtap
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/service"
+)
+
+const TEST_DB_FILE = "upgrade_campaign_test.db"
+
+func initTestDB(t *testing.T) {
+	os.Remove(TEST_DB_FILE)
+	db, err := gorm.Open(
+		sqlite.Open(TEST_DB_FILE),
+		&gorm.Config{NamingStrategy: schema.NamingStrategy{SingularTable: true}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mysql.Db = db
+	for _, m := range []interface{}{
+		&mysql.VTap{}, &mysql.VTapGroup{}, &mysql.Region{}, &mysql.AZ{},
+		&mysql.VTapUpgradeCampaign{}, &mysql.VTapUpgradeCampaignVTap{},
+	} {
+		if err := mysql.Db.AutoMigrate(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		arch := "x86_64"
+		if i == 9 {
+			arch = "aarch64"
+		}
+		mysql.Db.Create(&mysql.VTap{
+			Name: fmt.Sprintf("vtap-%d", i), State: common.VTAP_STATE_NORMAL, Enable: common.VTAP_ENABLE_TRUE,
+			Arch: arch, Revision: "main 100-aaa", Lcuuid: fmt.Sprintf("vtap-lcuuid-%d", i),
+		})
+	}
+	t.Cleanup(func() { os.Remove(TEST_DB_FILE) })
+}
+
+// 模拟采集器升级至revision并上报
+func reportRevision(campaign model.VTapUpgradeCampaign, state int, revision string, count int) {
+	var campaignVTaps []mysql.VTapUpgradeCampaignVTap
+	mysql.Db.Where("campaign_lcuuid = ? AND state = ?", campaign.Lcuuid, state).Order("id").Limit(count).Find(&campaignVTaps)
+	for _, campaignVTap := range campaignVTaps {
+		mysql.Db.Model(&mysql.VTap{}).Where("lcuuid = ?", campaignVTap.VTapLcuuid).Update("revision", revision)
+	}
+}
+
+// 模拟升级超时
+func expireUpgrading(campaign model.VTapUpgradeCampaign) {
+	mysql.Db.Model(&mysql.VTapUpgradeCampaignVTap{}).Where(
+		"campaign_lcuuid = ? AND state IN (?)", campaign.Lcuuid,
+		[]int{common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK},
+	).Update("started_at", time.Now().Add(-time.Hour))
+}
+
+func getCampaign(t *testing.T, lcuuid string) model.VTapUpgradeCampaign {
+	campaigns, _ := service.GetVTapUpgradeCampaigns(map[string]interface{}{"lcuuid": lcuuid})
+	if len(campaigns) != 1 {
+		t.Fatalf("campaign %s not found", lcuuid)
+	}
+	return campaigns[0]
+}
+
+func TestUpgradeCampaignPause(t *testing.T) {
+	initTestDB(t)
+	check := &UpgradeCampaignCheck{}
+
+	campaign, err := service.CreateVTapUpgradeCampaign(model.VTapUpgradeCampaignCreate{
+		Name: "canary", LabelSelector: "arch=x86_64", ExpectedRevision: "101-bbb",
+		UpgradePackage: "/usr/sbin/deepflow-agent", Batches: []int{10, 50}, FailureThreshold: 25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if campaign.VTapCount != 9 || len(campaign.Batches) != 3 {
+		t.Fatalf("CreateVTapUpgradeCampaign() = %+v", campaign)
+	}
+	if _, err := service.CreateVTapUpgradeCampaign(model.VTapUpgradeCampaignCreate{
+		Name: "conflict", LabelSelector: "name=vtap-0", ExpectedRevision: "101-bbb", UpgradePackage: "/usr/sbin/deepflow-agent",
+	}); err == nil {
+		t.Error("vtap in an active campaign should not be added to another campaign")
+	}
+
+	// 第一批次: 9 * 10% 向上取整为1个采集器
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.StateCounts["UPGRADING"] != 1 || campaign.StateCounts["PENDING"] != 8 {
+		t.Fatalf("batch 1 state counts = %v", campaign.StateCounts)
+	}
+
+	// 第一批次成功后立即开始第二批次: 9 * 50% 向上取整为5, 即4个采集器
+	reportRevision(campaign, common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, "main 101-bbb", 1)
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.CurrentBatch != 1 || campaign.StateCounts["SUCCEEDED"] != 1 || campaign.StateCounts["UPGRADING"] != 4 {
+		t.Fatalf("batch 2 campaign = %d %v", campaign.CurrentBatch, campaign.StateCounts)
+	}
+
+	// 第二批次2个采集器超时, 失败比例50%超过阈值, 暂停
+	reportRevision(campaign, common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, "main 101-bbb", 2)
+	check.check()
+	expireUpgrading(campaign)
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.State != common.UPGRADE_CAMPAIGN_STATE_PAUSED || campaign.CurrentBatch != 2 ||
+		campaign.StateCounts["FAILED"] != 2 || campaign.StateCounts["PENDING"] != 4 {
+		t.Fatalf("paused campaign = %s %d %v", campaign.StateName, campaign.CurrentBatch, campaign.StateCounts)
+	}
+
+	// 恢复后从第三批次继续
+	if _, err := service.UpdateVTapUpgradeCampaign(campaign.Lcuuid, model.VTapUpgradeCampaignUpdate{Action: "resume"}); err != nil {
+		t.Fatal(err)
+	}
+	check.check()
+	reportRevision(campaign, common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, "main 101-bbb", 4)
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.State != common.UPGRADE_CAMPAIGN_STATE_COMPLETED || campaign.StateCounts["SUCCEEDED"] != 7 {
+		t.Fatalf("completed campaign = %s %v", campaign.StateName, campaign.StateCounts)
+	}
+	if _, err := service.DeleteVTapUpgradeCampaign(campaign.Lcuuid); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpgradeCampaignRollback(t *testing.T) {
+	initTestDB(t)
+	check := &UpgradeCampaignCheck{}
+
+	if _, err := service.CreateVTapUpgradeCampaign(model.VTapUpgradeCampaignCreate{
+		Name: "rollback", LabelSelector: "arch=x86_64", ExpectedRevision: "101-bbb",
+		UpgradePackage: "/usr/sbin/deepflow-agent", FailureAction: "rollback",
+	}); err == nil {
+		t.Error("rollback campaign without rollback package should fail")
+	}
+	campaign, err := service.CreateVTapUpgradeCampaign(model.VTapUpgradeCampaignCreate{
+		Name: "rollback", LabelSelector: "arch!=aarch64", ExpectedRevision: "101-bbb",
+		UpgradePackage: "/usr/sbin/deepflow-agent", Batches: []int{20, 100},
+		FailureAction: "rollback", RollbackRevision: "100-aaa", RollbackPackage: "/usr/sbin/deepflow-agent.old",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一批次2个采集器, 1个成功1个超时, 阈值为0时回滚已升级的采集器
+	check.check()
+	reportRevision(campaign, common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, "main 101-bbb", 1)
+	check.check()
+	expireUpgrading(campaign)
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.State != common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK ||
+		campaign.StateCounts["ROLLING_BACK"] != 2 || campaign.StateCounts["CANCELLED"] != 7 {
+		t.Fatalf("rolling back campaign = %s %v", campaign.StateName, campaign.StateCounts)
+	}
+	for _, vtap := range campaign.VTaps {
+		if vtap.State == common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK && vtap.ExpectedRevision != "100-aaa" {
+			t.Errorf("rolling back vtap = %+v", vtap)
+		}
+	}
+
+	reportRevision(campaign, common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK, "main 100-aaa", 2)
+	check.check()
+	campaign = getCampaign(t, campaign.Lcuuid)
+	if campaign.State != common.UPGRADE_CAMPAIGN_STATE_ROLLED_BACK || campaign.StateCounts["ROLLED_BACK"] != 2 {
+		t.Fatalf("rolled back campaign = %s %v", campaign.StateName, campaign.StateCounts)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/clickhouse"
+	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
+)
+
+// 采集器发送数据的统计, host为采集器名称
+const VTAP_INGESTED_DATA_SQL = "SELECT tag_values[indexOf(tag_names, 'host')] AS host, " +
+	"sum(metrics_float_values[indexOf(metrics_float_names, 'tx-bytes')]) AS bytes " +
+	"FROM deepflow_system.deepflow_agent_collect_sender WHERE time >= now() - %d GROUP BY host"
+
+type vtapIngestedData struct {
+	Host  string  `db:"host"`
+	Bytes float64 `db:"bytes"`
+}
+
+// GetVTapIngestedLoads 获取各采集器最近duration秒内发送的数据量(字节), key为采集器名称
+func GetVTapIngestedLoads(cfg clickhouse.ClickHouseConfig, duration int) (map[string]float64, error) {
+	connect, err := clickhouse.Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer connect.Close()
+
+	var rows []vtapIngestedData
+	if err := connect.Select(&rows, fmt.Sprintf(VTAP_INGESTED_DATA_SQL, duration)); err != nil {
+		return nil, err
+	}
+	vtapNameToLoad := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		vtapNameToLoad[row.Host] += row.Bytes
+	}
+	return vtapNameToLoad, nil
+}
+
+// getVTapLoads 按数据量分配时返回各采集器的负载, 否则或查询失败时返回nil, 此时按采集器个数分配
+func getVTapLoads(cfg mconfig.VTapLoadBalancing, ckCfg clickhouse.ClickHouseConfig) map[string]float64 {
+	if cfg.Algorithm != mconfig.VTAP_LOAD_BALANCING_BY_INGESTED_DATA {
+		return nil
+	}
+	vtapNameToLoad, err := GetVTapIngestedLoads(ckCfg, cfg.DataDuration)
+	if err != nil {
+		log.Warningf("get vtap ingested data failed, alloc by vtap count, (%v)", err)
+		return nil
+	}
+	return vtapNameToLoad
+}
+
+// sortHostsByLoad 将有剩余采集器个数的控制器/数据节点按负载升序排列, 负载相同时剩余个数多的优先
+func sortHostsByLoad(hostAvailableVTapNum []common.KVPair, hostIPToLoad map[string]float64) {
+	sort.Slice(hostAvailableVTapNum, func(i, j int) bool {
+		iAvailable, jAvailable := hostAvailableVTapNum[i].Value > 0, hostAvailableVTapNum[j].Value > 0
+		if iAvailable != jAvailable {
+			return iAvailable
+		}
+		iLoad, jLoad := hostIPToLoad[hostAvailableVTapNum[i].Key], hostIPToLoad[hostAvailableVTapNum[j].Key]
+		if iLoad != jLoad {
+			return iLoad < jLoad
+		}
+		return hostAvailableVTapNum[i].Value > hostAvailableVTapNum[j].Value
+	})
+}
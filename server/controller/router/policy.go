@@ -0,0 +1,267 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/service"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+// PolicyRouter 流量过滤(ACL)、分发策略及PCAP策略
+// 变更类接口支持dry_run=true, 仅返回策略版本将变化的采集器, 不写入数据库
+func PolicyRouter(e *gin.Engine, m *metadata.MetaData) {
+	e.GET("/v1/acls/:lcuuid/", getACL)
+	e.GET("/v1/acls/", getACLs)
+	e.POST("/v1/acls/", createACL(m))
+	e.PATCH("/v1/acls/:lcuuid/", updateACL(m))
+	e.DELETE("/v1/acls/:lcuuid/", deleteACL(m))
+
+	e.GET("/v1/npb-tunnels/:lcuuid/", getNpbTunnel)
+	e.GET("/v1/npb-tunnels/", getNpbTunnels)
+	e.POST("/v1/npb-tunnels/", createNpbTunnel)
+	e.PATCH("/v1/npb-tunnels/:lcuuid/", updateNpbTunnel(m))
+	e.DELETE("/v1/npb-tunnels/:lcuuid/", deleteNpbTunnel)
+
+	e.GET("/v1/npb-policies/:lcuuid/", getNpbPolicy)
+	e.GET("/v1/npb-policies/", getNpbPolicies)
+	e.POST("/v1/npb-policies/", createNpbPolicy(m))
+	e.PATCH("/v1/npb-policies/:lcuuid/", updateNpbPolicy(m))
+	e.DELETE("/v1/npb-policies/:lcuuid/", deleteNpbPolicy(m))
+
+	e.GET("/v1/pcap-policies/:lcuuid/", getPcapPolicy)
+	e.GET("/v1/pcap-policies/", getPcapPolicies)
+	e.POST("/v1/pcap-policies/", createPcapPolicy(m))
+	e.PATCH("/v1/pcap-policies/:lcuuid/", updatePcapPolicy(m))
+	e.DELETE("/v1/pcap-policies/:lcuuid/", deletePcapPolicy(m))
+}
+
+func isDryRun(c *gin.Context) bool {
+	return c.Query("dry_run") == "true"
+}
+
+func getPolicyFilter(c *gin.Context) map[string]interface{} {
+	args := make(map[string]interface{})
+	if value, ok := c.GetQuery("name"); ok {
+		args["name"] = value
+	}
+	return args
+}
+
+func getACL(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetACLs(args)
+	JsonResponse(c, data, err)
+}
+
+func getACLs(c *gin.Context) {
+	data, err := service.GetACLs(getPolicyFilter(c))
+	JsonResponse(c, data, err)
+}
+
+func createACL(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var aclCreate model.ACLCreate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&aclCreate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+
+		data, err := service.CreateACL(aclCreate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func updateACL(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var aclUpdate model.ACLUpdate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&aclUpdate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+
+		data, err := service.UpdateACL(c.Param("lcuuid"), aclUpdate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func deleteACL(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		data, err := service.DeleteACL(c.Param("lcuuid"), m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func getNpbTunnel(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetNpbTunnels(args)
+	JsonResponse(c, data, err)
+}
+
+func getNpbTunnels(c *gin.Context) {
+	data, err := service.GetNpbTunnels(getPolicyFilter(c))
+	JsonResponse(c, data, err)
+}
+
+func createNpbTunnel(c *gin.Context) {
+	var npbTunnelCreate model.NpbTunnelCreate
+
+	// 参数校验
+	err := c.ShouldBindBodyWith(&npbTunnelCreate, binding.JSON)
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+		return
+	}
+
+	data, err := service.CreateNpbTunnel(npbTunnelCreate)
+	JsonResponse(c, data, err)
+}
+
+func updateNpbTunnel(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var npbTunnelUpdate model.NpbTunnelUpdate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&npbTunnelUpdate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+
+		data, err := service.UpdateNpbTunnel(c.Param("lcuuid"), npbTunnelUpdate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func deleteNpbTunnel(c *gin.Context) {
+	data, err := service.DeleteNpbTunnel(c.Param("lcuuid"))
+	JsonResponse(c, data, err)
+}
+
+func getNpbPolicy(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetNpbPolicies(args)
+	JsonResponse(c, data, err)
+}
+
+func getNpbPolicies(c *gin.Context) {
+	data, err := service.GetNpbPolicies(getPolicyFilter(c))
+	JsonResponse(c, data, err)
+}
+
+func createNpbPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var npbPolicyCreate model.NpbPolicyCreate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&npbPolicyCreate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+
+		data, err := service.CreateNpbPolicy(npbPolicyCreate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func updateNpbPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var npbPolicyUpdate model.NpbPolicyUpdate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&npbPolicyUpdate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+
+		data, err := service.UpdateNpbPolicy(c.Param("lcuuid"), npbPolicyUpdate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func deleteNpbPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		data, err := service.DeleteNpbPolicy(c.Param("lcuuid"), m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func getPcapPolicy(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetPcapPolicies(args)
+	JsonResponse(c, data, err)
+}
+
+func getPcapPolicies(c *gin.Context) {
+	data, err := service.GetPcapPolicies(getPolicyFilter(c))
+	JsonResponse(c, data, err)
+}
+
+func createPcapPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var pcapPolicyCreate model.PcapPolicyCreate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&pcapPolicyCreate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+
+		data, err := service.CreatePcapPolicy(pcapPolicyCreate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func updatePcapPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var pcapPolicyUpdate model.PcapPolicyUpdate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&pcapPolicyUpdate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+
+		data, err := service.UpdatePcapPolicy(c.Param("lcuuid"), pcapPolicyUpdate, m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
+
+func deletePcapPolicy(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		data, err := service.DeletePcapPolicy(c.Param("lcuuid"), m, isDryRun(c))
+		JsonResponse(c, data, err)
+	})
+}
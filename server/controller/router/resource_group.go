@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/service"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+func ResourceGroupRouter(e *gin.Engine, m *metadata.MetaData) {
+	e.GET("/v1/resource-groups/:lcuuid/", getResourceGroup(m))
+	e.GET("/v1/resource-groups/", getResourceGroups(m))
+	e.POST("/v1/resource-groups/", createResourceGroup(m))
+	e.PATCH("/v1/resource-groups/:lcuuid/", updateResourceGroup(m))
+	e.DELETE("/v1/resource-groups/:lcuuid/", deleteResourceGroup)
+}
+
+func getResourceGroup(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := make(map[string]interface{})
+		args["lcuuid"] = c.Param("lcuuid")
+		data, err := service.GetResourceGroups(args, m)
+		JsonResponse(c, data, err)
+	})
+}
+
+func getResourceGroups(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := make(map[string]interface{})
+		if value, ok := c.GetQuery("name"); ok {
+			args["name"] = value
+		}
+		if value, ok := c.GetQuery("type"); ok {
+			args["type"] = value
+		}
+		data, err := service.GetResourceGroups(args, m)
+		JsonResponse(c, data, err)
+	})
+}
+
+func createResourceGroup(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var groupCreate model.ResourceGroupCreate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&groupCreate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+
+		data, err := service.CreateResourceGroup(groupCreate, m)
+		JsonResponse(c, data, err)
+	})
+}
+
+func updateResourceGroup(m *metadata.MetaData) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var groupUpdate model.ResourceGroupUpdate
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&groupUpdate, binding.JSON)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			return
+		}
+
+		data, err := service.UpdateResourceGroup(c.Param("lcuuid"), groupUpdate, m)
+		JsonResponse(c, data, err)
+	})
+}
+
+func deleteResourceGroup(c *gin.Context) {
+	data, err := service.DeleteResourceGroup(c.Param("lcuuid"))
+	JsonResponse(c, data, err)
+}
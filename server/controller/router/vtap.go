@@ -24,11 +24,12 @@ import (
 	"github.com/gin-gonic/gin/binding"
 
 	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/config"
 	"github.com/deepflowys/deepflow/server/controller/model"
 	"github.com/deepflowys/deepflow/server/controller/service"
 )
 
-func VtapRouter(e *gin.Engine) {
+func VtapRouter(e *gin.Engine, cfg *config.ControllerConfig) {
 	e.GET("/v1/vtaps/:lcuuid/", getVtap)
 	e.GET("/v1/vtaps/", getVtaps)
 	e.POST("/v1/vtaps/", createVtap)
@@ -38,7 +39,7 @@ func VtapRouter(e *gin.Engine) {
 	e.POST("/v1/vtaps/batch/", batchUpdateVtap)
 	e.DELETE("/v1/vtaps/batch/", batchDeleteVtap)
 
-	e.POST("/v1/rebalance-vtap/", rebalanceVtap)
+	e.POST("/v1/rebalance-vtap/", rebalanceVtap(cfg))
 
 	e.PATCH("/v1/vtaps-license-type/:lcuuid/", updateVtapLicenseType)
 	e.PATCH("/v1/vtaps-license-type/", batchUpdateVtapLicenseType)
@@ -202,27 +203,29 @@ func batchDeleteVtap(c *gin.Context) {
 	JsonResponse(c, data, err)
 }
 
-func rebalanceVtap(c *gin.Context) {
-	args := make(map[string]interface{})
-	args["check"] = false
-	if value, ok := c.GetQuery("check"); ok {
-		args["check"] = (strings.ToLower(value) == "true")
-	}
-	if value, ok := c.GetQuery("type"); ok {
-		args["type"] = value
-		if args["type"] != "controller" && args["type"] != "analyzer" {
-			BadRequestResponse(
-				c, common.INVALID_PARAMETERS,
-				fmt.Sprintf("type (%s) is not supported", args["type"]),
-			)
+func rebalanceVtap(cfg *config.ControllerConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := make(map[string]interface{})
+		args["check"] = false
+		if value, ok := c.GetQuery("check"); ok {
+			args["check"] = (strings.ToLower(value) == "true")
+		}
+		if value, ok := c.GetQuery("type"); ok {
+			args["type"] = value
+			if args["type"] != "controller" && args["type"] != "analyzer" {
+				BadRequestResponse(
+					c, common.INVALID_PARAMETERS,
+					fmt.Sprintf("type (%s) is not supported", args["type"]),
+				)
+				return
+			}
+		} else {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, "must specify type")
 			return
 		}
-	} else {
-		BadRequestResponse(c, common.INVALID_PARAMETERS, "must specify type")
-		return
-	}
-	data, err := service.VTapRebalance(args)
-	JsonResponse(c, data, err)
+		data, err := service.VTapRebalance(args, cfg)
+		JsonResponse(c, data, err)
+	})
 }
 
 func batchUpdateVtapTapMode(c *gin.Context) {
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/config"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/service"
+)
+
+const VTAP_REPO_FORM_RESERVED_SIZE = 1 << 20
+
+func VTapRepoRouter(e *gin.Engine, cfg *config.ControllerConfig) {
+	e.GET("/v1/vtap-repo/:lcuuid/", getVTapRepo)
+	e.GET("/v1/vtap-repo/", getVTapRepos)
+	e.POST("/v1/vtap-repo/", createVTapRepo(cfg))
+	e.DELETE("/v1/vtap-repo/:lcuuid/", deleteVTapRepo)
+}
+
+func getVTapRepo(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetVTapRepos(args)
+	JsonResponse(c, data, err)
+}
+
+func getVTapRepos(c *gin.Context) {
+	args := make(map[string]interface{})
+	if value, ok := c.GetQuery("name"); ok {
+		args["name"] = value
+	}
+	data, err := service.GetVTapRepos(args)
+	JsonResponse(c, data, err)
+}
+
+// createVTapRepo 上传升级包
+func createVTapRepo(cfg *config.ControllerConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var err error
+		var vtapRepoCreate model.VTapRepoCreate
+
+		// 限制请求大小, 避免超大升级包占满磁盘及内存, 预留1MB给其他表单字段
+		maxSize := cfg.TrisolarisCfg.AgentPackageMaxSize
+		if maxSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+VTAP_REPO_FORM_RESERVED_SIZE)
+		}
+		sizeExceeded := fmt.Sprintf("vtap_repo image exceeds agent-package-max-size (%d bytes)", maxSize)
+
+		// 参数校验
+		err = c.ShouldBindWith(&vtapRepoCreate, binding.FormMultipart)
+		if err != nil {
+			if err.Error() == "http: request body too large" {
+				BadRequestResponse(c, common.INVALID_POST_DATA, sizeExceeded)
+				return
+			}
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		fileHeader, err := c.FormFile("IMAGE")
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		if maxSize > 0 && fileHeader.Size > maxSize {
+			BadRequestResponse(c, common.INVALID_POST_DATA, fmt.Sprintf("%s, actual: %d bytes", sizeExceeded, fileHeader.Size))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		defer file.Close()
+		image, err := ioutil.ReadAll(file)
+		if err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+
+		data, err := service.CreateVTapRepo(vtapRepoCreate, image, cfg)
+		JsonResponse(c, data, err)
+	})
+}
+
+func deleteVTapRepo(c *gin.Context) {
+	data, err := service.DeleteVTapRepo(c.Param("lcuuid"))
+	JsonResponse(c, data, err)
+}
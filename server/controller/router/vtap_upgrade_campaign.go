@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/service"
+)
+
+func VTapUpgradeCampaignRouter(e *gin.Engine) {
+	e.GET("/v1/vtap-upgrade-campaigns/:lcuuid/", getVTapUpgradeCampaign)
+	e.GET("/v1/vtap-upgrade-campaigns/", getVTapUpgradeCampaigns)
+	e.POST("/v1/vtap-upgrade-campaigns/", createVTapUpgradeCampaign)
+	e.PATCH("/v1/vtap-upgrade-campaigns/:lcuuid/", updateVTapUpgradeCampaign)
+	e.DELETE("/v1/vtap-upgrade-campaigns/:lcuuid/", deleteVTapUpgradeCampaign)
+}
+
+func getVTapUpgradeCampaign(c *gin.Context) {
+	args := make(map[string]interface{})
+	args["lcuuid"] = c.Param("lcuuid")
+	data, err := service.GetVTapUpgradeCampaigns(args)
+	JsonResponse(c, data, err)
+}
+
+func getVTapUpgradeCampaigns(c *gin.Context) {
+	args := make(map[string]interface{})
+	if value, ok := c.GetQuery("name"); ok {
+		args["name"] = value
+	}
+	data, err := service.GetVTapUpgradeCampaigns(args)
+	JsonResponse(c, data, err)
+}
+
+func createVTapUpgradeCampaign(c *gin.Context) {
+	var err error
+	var campaignCreate model.VTapUpgradeCampaignCreate
+
+	// 参数校验
+	err = c.ShouldBindBodyWith(&campaignCreate, binding.JSON)
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+		return
+	}
+
+	data, err := service.CreateVTapUpgradeCampaign(campaignCreate)
+	JsonResponse(c, data, err)
+}
+
+// updateVTapUpgradeCampaign 暂停、恢复、取消或回滚升级任务
+func updateVTapUpgradeCampaign(c *gin.Context) {
+	var err error
+	var campaignUpdate model.VTapUpgradeCampaignUpdate
+
+	// 参数校验
+	err = c.ShouldBindBodyWith(&campaignUpdate, binding.JSON)
+	if err != nil {
+		BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+		return
+	}
+
+	data, err := service.UpdateVTapUpgradeCampaign(c.Param("lcuuid"), campaignUpdate)
+	JsonResponse(c, data, err)
+}
+
+func deleteVTapUpgradeCampaign(c *gin.Context) {
+	data, err := service.DeleteVTapUpgradeCampaign(c.Param("lcuuid"))
+	JsonResponse(c, data, err)
+}
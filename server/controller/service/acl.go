@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+const ACL_DEFAULT_TAP_TYPE = 3
+
+var aclApplicationBusinessID = map[int]int{
+	common.ACL_APPLICATION_NPB:  common.ACL_BUSINESS_ID_NPB,
+	common.ACL_APPLICATION_PCAP: common.ACL_BUSINESS_ID_PCAP,
+}
+
+func aclToModel(acl *mysql.ACL) model.ACL {
+	return model.ACL{
+		ID:          acl.ID,
+		Name:        acl.Name,
+		Application: aclApplicationName(acl.Applications),
+		TapType:     acl.TapType,
+		State:       acl.State,
+		SrcGroupIDs: stringToInts(acl.SrcGroupIDs),
+		DstGroupIDs: stringToInts(acl.DstGroupIDs),
+		Protocol:    acl.Protocol,
+		SrcPorts:    acl.SrcPorts,
+		DstPorts:    acl.DstPorts,
+		Vlan:        acl.Vlan,
+		CreatedAt:   acl.CreatedAt.Format(common.GO_BIRTHDAY),
+		UpdatedAt:   acl.UpdatedAt.Format(common.GO_BIRTHDAY),
+		Lcuuid:      acl.Lcuuid,
+	}
+}
+
+func GetACLs(filter map[string]interface{}) (resp []model.ACL, err error) {
+	var response []model.ACL
+	var acls []mysql.ACL
+
+	Db := mysql.Db.Where(
+		"applications IN (?)",
+		[]string{strconv.Itoa(common.ACL_APPLICATION_NPB), strconv.Itoa(common.ACL_APPLICATION_PCAP)},
+	)
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("id").Find(&acls)
+
+	for i := range acls {
+		response = append(response, aclToModel(&acls[i]))
+	}
+	return response, nil
+}
+
+// buildACL 校验参数并生成ACL, 不写入数据库
+func buildACL(aclCreate model.ACLCreate) (*mysql.ACL, error) {
+	application := -1
+	for app, name := range common.ACLApplicationName {
+		if name == aclCreate.Application {
+			application = app
+		}
+	}
+	if application < 0 {
+		return nil, fmt.Errorf("application (%s) must be npb or pcap", aclCreate.Application)
+	}
+	state, err := checkPolicyState(aclCreate.State)
+	if err != nil {
+		return nil, err
+	}
+	tapType := ACL_DEFAULT_TAP_TYPE
+	if aclCreate.TapType != nil {
+		tapType = *aclCreate.TapType
+	}
+	if err := checkTapType(tapType); err != nil {
+		return nil, err
+	}
+	if err := checkResourceGroups(aclCreate.SrcGroupIDs); err != nil {
+		return nil, err
+	}
+	if err := checkResourceGroups(aclCreate.DstGroupIDs); err != nil {
+		return nil, err
+	}
+	if aclCreate.Protocol < 0 || aclCreate.Protocol > 255 {
+		return nil, fmt.Errorf("protocol (%d) must be in [0, 255]", aclCreate.Protocol)
+	}
+	if aclCreate.SrcPorts != "" || aclCreate.DstPorts != "" {
+		if aclCreate.Protocol != PROTOCOL_ANY && aclCreate.Protocol != PROTOCOL_TCP && aclCreate.Protocol != PROTOCOL_UDP {
+			return nil, fmt.Errorf("ports are only supported when protocol is any, TCP or UDP, got %d", aclCreate.Protocol)
+		}
+	}
+	if err := checkPorts(aclCreate.SrcPorts); err != nil {
+		return nil, err
+	}
+	if err := checkPorts(aclCreate.DstPorts); err != nil {
+		return nil, err
+	}
+	if aclCreate.Vlan < 0 || aclCreate.Vlan > MAX_VLAN {
+		return nil, fmt.Errorf("vlan (%d) must be in [0, %d]", aclCreate.Vlan, MAX_VLAN)
+	}
+
+	return &mysql.ACL{
+		BusinessID:   aclApplicationBusinessID[application],
+		Name:         aclCreate.Name,
+		Type:         common.ACL_TYPE_CUSTOM,
+		TapType:      tapType,
+		State:        state,
+		Applications: strconv.Itoa(application),
+		SrcGroupIDs:  intsToString(aclCreate.SrcGroupIDs),
+		DstGroupIDs:  intsToString(aclCreate.DstGroupIDs),
+		Protocol:     aclCreate.Protocol,
+		SrcPorts:     aclCreate.SrcPorts,
+		DstPorts:     aclCreate.DstPorts,
+		Vlan:         aclCreate.Vlan,
+	}, nil
+}
+
+func checkACLName(name string, excludeLcuuid string) error {
+	var aclCount int64
+	mysql.Db.Model(&mysql.ACL{}).Where("name = ? AND lcuuid != ?", name, excludeLcuuid).Count(&aclCount)
+	if aclCount > 0 {
+		return NewError(common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("acl (%s) already exist", name))
+	}
+	return nil
+}
+
+// CreateACL dryRun为true时仅返回策略版本将变化的采集器
+func CreateACL(aclCreate model.ACLCreate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	if err := checkACLName(aclCreate.Name, ""); err != nil {
+		return model.ACL{}, err
+	}
+	acl, err := buildACL(aclCreate)
+	if err != nil {
+		return model.ACL{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			data.ACLs = append(data.ACLs, acl)
+		})
+	}
+
+	acl.Lcuuid = uuid.New().String()
+	if ret := mysql.Db.Create(acl); ret.Error != nil {
+		return model.ACL{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof("create acl (%s)", acl.Name)
+	refreshPolicy()
+
+	response, _ := GetACLs(map[string]interface{}{"lcuuid": acl.Lcuuid})
+	return response[0], nil
+}
+
+func UpdateACL(lcuuid string, aclUpdate model.ACLUpdate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var oldACL mysql.ACL
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&oldACL); ret.Error != nil {
+		return model.ACL{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("acl (%s) not found", lcuuid))
+	}
+
+	aclCreate := model.ACLCreate{
+		Name:        oldACL.Name,
+		Application: aclApplicationName(oldACL.Applications),
+		TapType:     &oldACL.TapType,
+		State:       &oldACL.State,
+		SrcGroupIDs: stringToInts(oldACL.SrcGroupIDs),
+		DstGroupIDs: stringToInts(oldACL.DstGroupIDs),
+		Protocol:    oldACL.Protocol,
+		SrcPorts:    oldACL.SrcPorts,
+		DstPorts:    oldACL.DstPorts,
+		Vlan:        oldACL.Vlan,
+	}
+	if aclUpdate.Name != nil {
+		if err := checkACLName(*aclUpdate.Name, lcuuid); err != nil {
+			return model.ACL{}, err
+		}
+		aclCreate.Name = *aclUpdate.Name
+	}
+	if aclUpdate.TapType != nil {
+		aclCreate.TapType = aclUpdate.TapType
+	}
+	if aclUpdate.State != nil {
+		aclCreate.State = aclUpdate.State
+	}
+	if aclUpdate.SrcGroupIDs != nil {
+		aclCreate.SrcGroupIDs = *aclUpdate.SrcGroupIDs
+	}
+	if aclUpdate.DstGroupIDs != nil {
+		aclCreate.DstGroupIDs = *aclUpdate.DstGroupIDs
+	}
+	if aclUpdate.Protocol != nil {
+		aclCreate.Protocol = *aclUpdate.Protocol
+	}
+	if aclUpdate.SrcPorts != nil {
+		aclCreate.SrcPorts = *aclUpdate.SrcPorts
+	}
+	if aclUpdate.DstPorts != nil {
+		aclCreate.DstPorts = *aclUpdate.DstPorts
+	}
+	if aclUpdate.Vlan != nil {
+		aclCreate.Vlan = *aclUpdate.Vlan
+	}
+	acl, err := buildACL(aclCreate)
+	if err != nil {
+		return model.ACL{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	acl.ID = oldACL.ID
+	acl.Lcuuid = oldACL.Lcuuid
+	acl.CreatedAt = oldACL.CreatedAt
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			for i := range data.ACLs {
+				if data.ACLs[i].ID == acl.ID {
+					data.ACLs[i] = acl
+				}
+			}
+		})
+	}
+
+	if ret := mysql.Db.Save(acl); ret.Error != nil {
+		return model.ACL{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof("update acl (%s) %+v", acl.Name, aclCreate)
+	refreshPolicy()
+
+	response, _ := GetACLs(map[string]interface{}{"lcuuid": acl.Lcuuid})
+	return response[0], nil
+}
+
+func DeleteACL(lcuuid string, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var acl mysql.ACL
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&acl); ret.Error != nil {
+		return map[string]string{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("acl (%s) not found", lcuuid))
+	}
+
+	var npbPolicyCount, pcapPolicyCount int64
+	mysql.Db.Model(&mysql.NpbPolicy{}).Where("acl_id = ?", acl.ID).Count(&npbPolicyCount)
+	mysql.Db.Model(&mysql.PcapPolicy{}).Where("acl_id = ?", acl.ID).Count(&pcapPolicyCount)
+	if npbPolicyCount+pcapPolicyCount > 0 {
+		return map[string]string{}, NewError(
+			common.INVALID_PARAMETERS,
+			fmt.Sprintf("acl (%s) is used by %d npb policies and %d pcap policies", acl.Name, npbPolicyCount, pcapPolicyCount),
+		)
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			acls := data.ACLs[:0:0]
+			for _, item := range data.ACLs {
+				if item.ID != acl.ID {
+					acls = append(acls, item)
+				}
+			}
+			data.ACLs = acls
+		})
+	}
+
+	log.Infof("delete acl (%s)", acl.Name)
+	mysql.Db.Delete(&acl)
+	refreshPolicy()
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
@@ -22,7 +22,6 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"os"
 	"strings"
 	"time"
 
@@ -39,12 +38,12 @@ import (
 )
 
 var DOMAIN_PASSWORD_KEYS = []string{
-	"admin_password", "secret_key", "password", "boss_secret_key", "manage_one_password",
+	"admin_password", "secret_key", "password", "boss_secret_key", "manage_one_password", "client_secret",
 }
 
 func getGrpcServerAndPort(controllerIP string, cfg *config.ControllerConfig) (string, string) {
 	// get local controller ip
-	localControllerIP := os.Getenv(common.NODE_IP_KEY)
+	localControllerIP := common.GetNodeIP()
 	if localControllerIP == "" {
 		log.Errorf("get env(%s) data failed", common.NODE_IP_KEY)
 		return controllerIP, cfg.GrpcNodePort
@@ -68,7 +67,7 @@ func getGrpcServerAndPort(controllerIP string, cfg *config.ControllerConfig) (st
 	if region != localRegion {
 		return controllerIP, cfg.GrpcNodePort
 	} else {
-		localPodIP := os.Getenv(common.POD_IP_KEY)
+		localPodIP := common.GetPodIP()
 		if localPodIP == "" {
 			log.Errorf("get env(%s) data failed", common.POD_IP_KEY)
 			return controllerIP, cfg.GrpcNodePort
@@ -736,6 +735,7 @@ func DeleteSubDomain(lcuuid string) (map[string]string, error) {
 		mysql.Db.Unscoped().Where("sub_domain = ?", lcuuid).Delete(&mysql.PodNamespace{})
 		mysql.Db.Unscoped().Where("sub_domain = ?", lcuuid).Delete(&mysql.PodNode{})
 		mysql.Db.Unscoped().Where("sub_domain = ?", lcuuid).Delete(&mysql.PodCluster{})
+		mysql.Db.Unscoped().Delete(&podCluster)
 	}
 
 	mysql.Db.Delete(&subDomain)
@@ -17,6 +17,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
@@ -444,7 +445,7 @@ func (t *SuiteTest) TestCheckAndAllocateDomainController() {
 	t.db.Create(&mysql.Controller{IP: normalControllerIP, State: 2, Lcuuid: uuid.NewString()})
 	t.db.Create(&mysql.Controller{IP: unnormalControllerIP, State: 1, Lcuuid: uuid.NewString()})
 	t.db.Create(&mysql.Domain{Base: mysql.Base{Lcuuid: domainLcuuid}, ControllerIP: unnormalControllerIP, Config: `{"region_uuid": "ffffffff-ffff-ffff-ffff-ffffffffffff"}`})
-	CheckAndAllocateDomainController()
+	NewDomainCheck(context.Background()).checkAndAllocateController()
 	var domain mysql.Domain
 	t.db.Where("lcuuid = ?", domainLcuuid).Find(&domain)
 	assert.Equal(t.T(), normalControllerIP, domain.ControllerIP)
@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+func npbPolicyToModel(npbPolicy *mysql.NpbPolicy) model.NpbPolicy {
+	return model.NpbPolicy{
+		ID:               npbPolicy.ID,
+		Name:             npbPolicy.Name,
+		State:            npbPolicy.State,
+		ACLID:            npbPolicy.ACLID,
+		NpbTunnelID:      npbPolicy.NpbTunnelID,
+		Vni:              npbPolicy.Vni,
+		Distribute:       npbPolicy.Distribute,
+		PayloadSlice:     npbPolicy.PayloadSlice,
+		VTapIDs:          stringToInts(npbPolicy.VtapIDs),
+		PolicyACLGroupID: npbPolicy.PolicyACLGroupID,
+		CreatedAt:        npbPolicy.CreatedAt.Format(common.GO_BIRTHDAY),
+		UpdatedAt:        npbPolicy.UpdatedAt.Format(common.GO_BIRTHDAY),
+		Lcuuid:           npbPolicy.Lcuuid,
+	}
+}
+
+func GetNpbPolicies(filter map[string]interface{}) (resp []model.NpbPolicy, err error) {
+	var response []model.NpbPolicy
+	var npbPolicies []mysql.NpbPolicy
+
+	Db := mysql.Db
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("id").Find(&npbPolicies)
+
+	for i := range npbPolicies {
+		response = append(response, npbPolicyToModel(&npbPolicies[i]))
+	}
+	return response, nil
+}
+
+// buildNpbPolicy 校验参数并生成分发策略, 不写入数据库
+func buildNpbPolicy(npbPolicyCreate model.NpbPolicyCreate) (*mysql.NpbPolicy, error) {
+	state, err := checkPolicyState(npbPolicyCreate.State)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkPolicyACL(npbPolicyCreate.ACLID, common.ACL_APPLICATION_NPB); err != nil {
+		return nil, err
+	}
+	var npbTunnel mysql.NpbTunnel
+	if ret := mysql.Db.Where("id = ?", npbPolicyCreate.NpbTunnelID).First(&npbTunnel); ret.Error != nil {
+		return nil, fmt.Errorf("npb_tunnel (%d) not found", npbPolicyCreate.NpbTunnelID)
+	}
+	maxVni := MAX_VXLAN_VNI
+	if npbTunnel.Type == common.NPB_TUNNEL_TYPE_ERSPAN {
+		maxVni = MAX_ERSPAN_ID
+	}
+	if npbPolicyCreate.Vni < 0 || npbPolicyCreate.Vni > maxVni {
+		return nil, fmt.Errorf(
+			"vni (%d) must be in [0, %d] for %s tunnel",
+			npbPolicyCreate.Vni, maxVni, common.NpbTunnelTypeName[npbTunnel.Type],
+		)
+	}
+	distribute := common.NPB_POLICY_FLOW_DISTRIBUTE
+	if npbPolicyCreate.Distribute != nil {
+		distribute = *npbPolicyCreate.Distribute
+	}
+	if distribute != common.NPB_POLICY_FLOW_DROP && distribute != common.NPB_POLICY_FLOW_DISTRIBUTE {
+		return nil, fmt.Errorf("distribute (%d) must be 0(drop) or 1(distribute)", distribute)
+	}
+	if err := checkPayloadSlice(npbPolicyCreate.PayloadSlice); err != nil {
+		return nil, err
+	}
+	if err := checkPolicyVTaps(npbPolicyCreate.VTapIDs); err != nil {
+		return nil, err
+	}
+
+	return &mysql.NpbPolicy{
+		Name:         npbPolicyCreate.Name,
+		State:        state,
+		BusinessID:   common.ACL_BUSINESS_ID_NPB,
+		Vni:          npbPolicyCreate.Vni,
+		NpbTunnelID:  npbPolicyCreate.NpbTunnelID,
+		Distribute:   distribute,
+		PayloadSlice: npbPolicyCreate.PayloadSlice,
+		ACLID:        npbPolicyCreate.ACLID,
+		VtapIDs:      intsToString(npbPolicyCreate.VTapIDs),
+	}, nil
+}
+
+func checkNpbPolicyName(name string, excludeLcuuid string) error {
+	var npbPolicyCount int64
+	mysql.Db.Model(&mysql.NpbPolicy{}).Where("name = ? AND lcuuid != ?", name, excludeLcuuid).Count(&npbPolicyCount)
+	if npbPolicyCount > 0 {
+		return NewError(common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("npb_policy (%s) already exist", name))
+	}
+	return nil
+}
+
+// CreateNpbPolicy dryRun为true时仅返回策略版本将变化的采集器
+func CreateNpbPolicy(npbPolicyCreate model.NpbPolicyCreate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	if err := checkNpbPolicyName(npbPolicyCreate.Name, ""); err != nil {
+		return model.NpbPolicy{}, err
+	}
+	npbPolicy, err := buildNpbPolicy(npbPolicyCreate)
+	if err != nil {
+		return model.NpbPolicy{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			data.NpbPolicies = append(data.NpbPolicies, npbPolicy)
+		})
+	}
+
+	npbPolicy.Lcuuid = uuid.New().String()
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		// 每条策略使用独立的策略组, 采集器据此区分分发动作
+		policyACLGroup := mysql.PolicyAclGroup{ACLIDs: strconv.Itoa(npbPolicy.ACLID), Count: 1}
+		if err := tx.Create(&policyACLGroup).Error; err != nil {
+			return err
+		}
+		npbPolicy.PolicyACLGroupID = policyACLGroup.ID
+		return tx.Create(npbPolicy).Error
+	})
+	if err != nil {
+		return model.NpbPolicy{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("create npb_policy (%s)", npbPolicy.Name)
+	refreshPolicy()
+
+	response, _ := GetNpbPolicies(map[string]interface{}{"lcuuid": npbPolicy.Lcuuid})
+	return response[0], nil
+}
+
+func UpdateNpbPolicy(lcuuid string, npbPolicyUpdate model.NpbPolicyUpdate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var oldNpbPolicy mysql.NpbPolicy
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&oldNpbPolicy); ret.Error != nil {
+		return model.NpbPolicy{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("npb_policy (%s) not found", lcuuid))
+	}
+
+	npbPolicyCreate := model.NpbPolicyCreate{
+		Name:         oldNpbPolicy.Name,
+		State:        &oldNpbPolicy.State,
+		ACLID:        oldNpbPolicy.ACLID,
+		NpbTunnelID:  oldNpbPolicy.NpbTunnelID,
+		Vni:          oldNpbPolicy.Vni,
+		Distribute:   &oldNpbPolicy.Distribute,
+		PayloadSlice: oldNpbPolicy.PayloadSlice,
+		VTapIDs:      stringToInts(oldNpbPolicy.VtapIDs),
+	}
+	if npbPolicyUpdate.Name != nil {
+		if err := checkNpbPolicyName(*npbPolicyUpdate.Name, lcuuid); err != nil {
+			return model.NpbPolicy{}, err
+		}
+		npbPolicyCreate.Name = *npbPolicyUpdate.Name
+	}
+	if npbPolicyUpdate.State != nil {
+		npbPolicyCreate.State = npbPolicyUpdate.State
+	}
+	if npbPolicyUpdate.ACLID != nil {
+		npbPolicyCreate.ACLID = *npbPolicyUpdate.ACLID
+	}
+	if npbPolicyUpdate.NpbTunnelID != nil {
+		npbPolicyCreate.NpbTunnelID = *npbPolicyUpdate.NpbTunnelID
+	}
+	if npbPolicyUpdate.Vni != nil {
+		npbPolicyCreate.Vni = *npbPolicyUpdate.Vni
+	}
+	if npbPolicyUpdate.Distribute != nil {
+		npbPolicyCreate.Distribute = npbPolicyUpdate.Distribute
+	}
+	if npbPolicyUpdate.PayloadSlice != nil {
+		npbPolicyCreate.PayloadSlice = npbPolicyUpdate.PayloadSlice
+	}
+	if npbPolicyUpdate.VTapIDs != nil {
+		npbPolicyCreate.VTapIDs = *npbPolicyUpdate.VTapIDs
+	}
+	npbPolicy, err := buildNpbPolicy(npbPolicyCreate)
+	if err != nil {
+		return model.NpbPolicy{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	npbPolicy.ID = oldNpbPolicy.ID
+	npbPolicy.PolicyACLGroupID = oldNpbPolicy.PolicyACLGroupID
+	npbPolicy.Lcuuid = oldNpbPolicy.Lcuuid
+	npbPolicy.CreatedAt = oldNpbPolicy.CreatedAt
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			for i := range data.NpbPolicies {
+				if data.NpbPolicies[i].ID == npbPolicy.ID {
+					data.NpbPolicies[i] = npbPolicy
+				}
+			}
+		})
+	}
+
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if npbPolicy.ACLID != oldNpbPolicy.ACLID {
+			err := tx.Model(&mysql.PolicyAclGroup{}).Where("id = ?", npbPolicy.PolicyACLGroupID).
+				Update("acl_ids", strconv.Itoa(npbPolicy.ACLID)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(npbPolicy).Error
+	})
+	if err != nil {
+		return model.NpbPolicy{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("update npb_policy (%s) %+v", npbPolicy.Name, npbPolicyCreate)
+	refreshPolicy()
+
+	response, _ := GetNpbPolicies(map[string]interface{}{"lcuuid": lcuuid})
+	return response[0], nil
+}
+
+func DeleteNpbPolicy(lcuuid string, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var npbPolicy mysql.NpbPolicy
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&npbPolicy); ret.Error != nil {
+		return map[string]string{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("npb_policy (%s) not found", lcuuid))
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			npbPolicies := data.NpbPolicies[:0:0]
+			for _, item := range data.NpbPolicies {
+				if item.ID != npbPolicy.ID {
+					npbPolicies = append(npbPolicies, item)
+				}
+			}
+			data.NpbPolicies = npbPolicies
+		})
+	}
+
+	log.Infof("delete npb_policy (%s)", npbPolicy.Name)
+	mysql.Db.Transaction(func(tx *gorm.DB) error {
+		tx.Where("id = ?", npbPolicy.PolicyACLGroupID).Delete(&mysql.PolicyAclGroup{})
+		return tx.Delete(&npbPolicy).Error
+	})
+	refreshPolicy()
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+func GetNpbTunnels(filter map[string]interface{}) (resp []model.NpbTunnel, err error) {
+	var response []model.NpbTunnel
+	var npbTunnels []mysql.NpbTunnel
+
+	Db := mysql.Db
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("id").Find(&npbTunnels)
+
+	for _, npbTunnel := range npbTunnels {
+		response = append(response, model.NpbTunnel{
+			ID:        npbTunnel.ID,
+			Name:      npbTunnel.Name,
+			IP:        npbTunnel.IP,
+			Type:      npbTunnel.Type,
+			TypeName:  common.NpbTunnelTypeName[npbTunnel.Type],
+			CreatedAt: npbTunnel.CreatedAt.Format(common.GO_BIRTHDAY),
+			UpdatedAt: npbTunnel.UpdatedAt.Format(common.GO_BIRTHDAY),
+			Lcuuid:    npbTunnel.Lcuuid,
+		})
+	}
+	return response, nil
+}
+
+func checkNpbTunnel(name, ip string, tunnelType int, excludeLcuuid string) error {
+	var npbTunnelCount int64
+	mysql.Db.Model(&mysql.NpbTunnel{}).Where("name = ? AND lcuuid != ?", name, excludeLcuuid).Count(&npbTunnelCount)
+	if npbTunnelCount > 0 {
+		return NewError(common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("npb_tunnel (%s) already exist", name))
+	}
+	if net.ParseIP(ip) == nil {
+		return NewError(common.INVALID_PARAMETERS, fmt.Sprintf("npb_tunnel ip (%s) is invalid", ip))
+	}
+	if _, ok := common.NpbTunnelTypeName[tunnelType]; !ok {
+		return NewError(common.INVALID_PARAMETERS, fmt.Sprintf("npb_tunnel type (%d) must be 0(VXLAN) or 1(ERSPAN)", tunnelType))
+	}
+	return nil
+}
+
+func CreateNpbTunnel(npbTunnelCreate model.NpbTunnelCreate) (resp model.NpbTunnel, err error) {
+	if err := checkNpbTunnel(npbTunnelCreate.Name, npbTunnelCreate.IP, npbTunnelCreate.Type, ""); err != nil {
+		return model.NpbTunnel{}, err
+	}
+
+	npbTunnel := mysql.NpbTunnel{
+		Name:   npbTunnelCreate.Name,
+		IP:     npbTunnelCreate.IP,
+		Type:   npbTunnelCreate.Type,
+		Lcuuid: uuid.New().String(),
+	}
+	if ret := mysql.Db.Create(&npbTunnel); ret.Error != nil {
+		return model.NpbTunnel{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof("create npb_tunnel (%s)", npbTunnel.Name)
+
+	response, _ := GetNpbTunnels(map[string]interface{}{"lcuuid": npbTunnel.Lcuuid})
+	return response[0], nil
+}
+
+// UpdateNpbTunnel dryRun为true时仅返回策略版本将变化的采集器
+func UpdateNpbTunnel(lcuuid string, npbTunnelUpdate model.NpbTunnelUpdate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var npbTunnel mysql.NpbTunnel
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&npbTunnel); ret.Error != nil {
+		return model.NpbTunnel{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("npb_tunnel (%s) not found", lcuuid))
+	}
+
+	if npbTunnelUpdate.Name != nil {
+		npbTunnel.Name = *npbTunnelUpdate.Name
+	}
+	if npbTunnelUpdate.IP != nil {
+		npbTunnel.IP = *npbTunnelUpdate.IP
+	}
+	if npbTunnelUpdate.Type != nil {
+		npbTunnel.Type = *npbTunnelUpdate.Type
+	}
+	if err := checkNpbTunnel(npbTunnel.Name, npbTunnel.IP, npbTunnel.Type, lcuuid); err != nil {
+		return model.NpbTunnel{}, err
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			for i := range data.NpbTunnels {
+				if data.NpbTunnels[i].ID == npbTunnel.ID {
+					data.NpbTunnels[i] = &npbTunnel
+				}
+			}
+		})
+	}
+
+	if ret := mysql.Db.Save(&npbTunnel); ret.Error != nil {
+		return model.NpbTunnel{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof("update npb_tunnel (%s) %+v", npbTunnel.Name, npbTunnelUpdate)
+	refreshPolicy()
+
+	response, _ := GetNpbTunnels(map[string]interface{}{"lcuuid": lcuuid})
+	return response[0], nil
+}
+
+func DeleteNpbTunnel(lcuuid string) (resp map[string]string, err error) {
+	var npbTunnel mysql.NpbTunnel
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&npbTunnel); ret.Error != nil {
+		return map[string]string{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("npb_tunnel (%s) not found", lcuuid))
+	}
+
+	var npbPolicyCount int64
+	mysql.Db.Model(&mysql.NpbPolicy{}).Where("npb_tunnel_id = ?", npbTunnel.ID).Count(&npbPolicyCount)
+	if npbPolicyCount > 0 {
+		return map[string]string{}, NewError(
+			common.INVALID_PARAMETERS,
+			fmt.Sprintf("npb_tunnel (%s) is used by %d npb policies", npbTunnel.Name, npbPolicyCount),
+		)
+	}
+
+	log.Infof("delete npb_tunnel (%s)", npbTunnel.Name)
+	mysql.Db.Delete(&npbTunnel)
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+)
+
+func pcapPolicyToModel(pcapPolicy *mysql.PcapPolicy) model.PcapPolicy {
+	return model.PcapPolicy{
+		ID:               pcapPolicy.ID,
+		Name:             pcapPolicy.Name,
+		State:            pcapPolicy.State,
+		ACLID:            pcapPolicy.ACLID,
+		PayloadSlice:     pcapPolicy.PayloadSlice,
+		VTapIDs:          stringToInts(pcapPolicy.VtapIDs),
+		PolicyACLGroupID: pcapPolicy.PolicyACLGroupID,
+		CreatedAt:        pcapPolicy.CreatedAt.Format(common.GO_BIRTHDAY),
+		UpdatedAt:        pcapPolicy.UpdatedAt.Format(common.GO_BIRTHDAY),
+		Lcuuid:           pcapPolicy.Lcuuid,
+	}
+}
+
+func GetPcapPolicies(filter map[string]interface{}) (resp []model.PcapPolicy, err error) {
+	var response []model.PcapPolicy
+	var pcapPolicies []mysql.PcapPolicy
+
+	Db := mysql.Db
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("id").Find(&pcapPolicies)
+
+	for i := range pcapPolicies {
+		response = append(response, pcapPolicyToModel(&pcapPolicies[i]))
+	}
+	return response, nil
+}
+
+// buildPcapPolicy 校验参数并生成PCAP策略, 不写入数据库
+func buildPcapPolicy(pcapPolicyCreate model.PcapPolicyCreate) (*mysql.PcapPolicy, error) {
+	state, err := checkPolicyState(pcapPolicyCreate.State)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkPolicyACL(pcapPolicyCreate.ACLID, common.ACL_APPLICATION_PCAP); err != nil {
+		return nil, err
+	}
+	if err := checkPayloadSlice(pcapPolicyCreate.PayloadSlice); err != nil {
+		return nil, err
+	}
+	if err := checkPolicyVTaps(pcapPolicyCreate.VTapIDs); err != nil {
+		return nil, err
+	}
+
+	return &mysql.PcapPolicy{
+		Name:         pcapPolicyCreate.Name,
+		State:        state,
+		BusinessID:   common.ACL_BUSINESS_ID_PCAP,
+		ACLID:        pcapPolicyCreate.ACLID,
+		PayloadSlice: pcapPolicyCreate.PayloadSlice,
+		VtapIDs:      intsToString(pcapPolicyCreate.VTapIDs),
+	}, nil
+}
+
+func checkPcapPolicyName(name string, excludeLcuuid string) error {
+	var pcapPolicyCount int64
+	mysql.Db.Model(&mysql.PcapPolicy{}).Where("name = ? AND lcuuid != ?", name, excludeLcuuid).Count(&pcapPolicyCount)
+	if pcapPolicyCount > 0 {
+		return NewError(common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("pcap_policy (%s) already exist", name))
+	}
+	return nil
+}
+
+// CreatePcapPolicy dryRun为true时仅返回策略版本将变化的采集器
+func CreatePcapPolicy(pcapPolicyCreate model.PcapPolicyCreate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	if err := checkPcapPolicyName(pcapPolicyCreate.Name, ""); err != nil {
+		return model.PcapPolicy{}, err
+	}
+	pcapPolicy, err := buildPcapPolicy(pcapPolicyCreate)
+	if err != nil {
+		return model.PcapPolicy{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			data.PcapPolicies = append(data.PcapPolicies, pcapPolicy)
+		})
+	}
+
+	pcapPolicy.Lcuuid = uuid.New().String()
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		policyACLGroup := mysql.PolicyAclGroup{ACLIDs: strconv.Itoa(pcapPolicy.ACLID), Count: 1}
+		if err := tx.Create(&policyACLGroup).Error; err != nil {
+			return err
+		}
+		pcapPolicy.PolicyACLGroupID = policyACLGroup.ID
+		return tx.Create(pcapPolicy).Error
+	})
+	if err != nil {
+		return model.PcapPolicy{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("create pcap_policy (%s)", pcapPolicy.Name)
+	refreshPolicy()
+
+	response, _ := GetPcapPolicies(map[string]interface{}{"lcuuid": pcapPolicy.Lcuuid})
+	return response[0], nil
+}
+
+func UpdatePcapPolicy(lcuuid string, pcapPolicyUpdate model.PcapPolicyUpdate, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var oldPcapPolicy mysql.PcapPolicy
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&oldPcapPolicy); ret.Error != nil {
+		return model.PcapPolicy{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("pcap_policy (%s) not found", lcuuid))
+	}
+
+	pcapPolicyCreate := model.PcapPolicyCreate{
+		Name:         oldPcapPolicy.Name,
+		State:        &oldPcapPolicy.State,
+		ACLID:        oldPcapPolicy.ACLID,
+		PayloadSlice: oldPcapPolicy.PayloadSlice,
+		VTapIDs:      stringToInts(oldPcapPolicy.VtapIDs),
+	}
+	if pcapPolicyUpdate.Name != nil {
+		if err := checkPcapPolicyName(*pcapPolicyUpdate.Name, lcuuid); err != nil {
+			return model.PcapPolicy{}, err
+		}
+		pcapPolicyCreate.Name = *pcapPolicyUpdate.Name
+	}
+	if pcapPolicyUpdate.State != nil {
+		pcapPolicyCreate.State = pcapPolicyUpdate.State
+	}
+	if pcapPolicyUpdate.ACLID != nil {
+		pcapPolicyCreate.ACLID = *pcapPolicyUpdate.ACLID
+	}
+	if pcapPolicyUpdate.PayloadSlice != nil {
+		pcapPolicyCreate.PayloadSlice = pcapPolicyUpdate.PayloadSlice
+	}
+	if pcapPolicyUpdate.VTapIDs != nil {
+		pcapPolicyCreate.VTapIDs = *pcapPolicyUpdate.VTapIDs
+	}
+	pcapPolicy, err := buildPcapPolicy(pcapPolicyCreate)
+	if err != nil {
+		return model.PcapPolicy{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	pcapPolicy.ID = oldPcapPolicy.ID
+	pcapPolicy.PolicyACLGroupID = oldPcapPolicy.PolicyACLGroupID
+	pcapPolicy.UserID = oldPcapPolicy.UserID
+	pcapPolicy.Lcuuid = oldPcapPolicy.Lcuuid
+	pcapPolicy.CreatedAt = oldPcapPolicy.CreatedAt
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			for i := range data.PcapPolicies {
+				if data.PcapPolicies[i].ID == pcapPolicy.ID {
+					data.PcapPolicies[i] = pcapPolicy
+				}
+			}
+		})
+	}
+
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if pcapPolicy.ACLID != oldPcapPolicy.ACLID {
+			err := tx.Model(&mysql.PolicyAclGroup{}).Where("id = ?", pcapPolicy.PolicyACLGroupID).
+				Update("acl_ids", strconv.Itoa(pcapPolicy.ACLID)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(pcapPolicy).Error
+	})
+	if err != nil {
+		return model.PcapPolicy{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("update pcap_policy (%s) %+v", pcapPolicy.Name, pcapPolicyCreate)
+	refreshPolicy()
+
+	response, _ := GetPcapPolicies(map[string]interface{}{"lcuuid": lcuuid})
+	return response[0], nil
+}
+
+func DeletePcapPolicy(lcuuid string, m *metadata.MetaData, dryRun bool) (resp interface{}, err error) {
+	var pcapPolicy mysql.PcapPolicy
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&pcapPolicy); ret.Error != nil {
+		return map[string]string{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("pcap_policy (%s) not found", lcuuid))
+	}
+	if dryRun {
+		return dryRunPolicyChange(m, func(data *metadata.PolicySourceData) {
+			pcapPolicies := data.PcapPolicies[:0:0]
+			for _, item := range data.PcapPolicies {
+				if item.ID != pcapPolicy.ID {
+					pcapPolicies = append(pcapPolicies, item)
+				}
+			}
+			data.PcapPolicies = pcapPolicies
+		})
+	}
+
+	log.Infof("delete pcap_policy (%s)", pcapPolicy.Name)
+	mysql.Db.Transaction(func(tx *gorm.DB) error {
+		tx.Where("id = ?", pcapPolicy.PolicyACLGroupID).Delete(&mysql.PolicyAclGroup{})
+		return tx.Delete(&pcapPolicy).Error
+	})
+	refreshPolicy()
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
+)
+
+const (
+	PROTOCOL_ANY = 0
+	PROTOCOL_TCP = 6
+	PROTOCOL_UDP = 17
+
+	MAX_PORT          = 65535
+	MAX_VLAN          = 4095
+	MAX_PAYLOAD_SLICE = 65535
+	MAX_VXLAN_VNI     = 1<<24 - 1
+	MAX_ERSPAN_ID     = 1<<10 - 1
+)
+
+func intsToString(ints []int) string {
+	strs := make([]string, 0, len(ints))
+	for _, i := range ints {
+		strs = append(strs, strconv.Itoa(i))
+	}
+	return strings.Join(strs, ",")
+}
+
+func stringToInts(str string) []int {
+	ints := []int{}
+	for _, item := range strings.Split(str, ",") {
+		if i, err := strconv.Atoi(strings.TrimSpace(item)); err == nil {
+			ints = append(ints, i)
+		}
+	}
+	return ints
+}
+
+func checkPolicyState(state *int) (int, error) {
+	if state == nil {
+		return common.ACL_STATE_ENABLE, nil
+	}
+	if *state != common.ACL_STATE_ENABLE && *state != common.ACL_STATE_DISABLE {
+		return 0, fmt.Errorf("state (%d) must be %d or %d", *state, common.ACL_STATE_DISABLE, common.ACL_STATE_ENABLE)
+	}
+	return *state, nil
+}
+
+// checkPorts 校验形如`80,8000-8080`的端口列表
+func checkPorts(ports string) error {
+	if ports == "" {
+		return nil
+	}
+	for _, item := range strings.Split(ports, ",") {
+		bounds := strings.Split(strings.TrimSpace(item), "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("port range (%s) is invalid", item)
+		}
+		values := make([]int, 0, len(bounds))
+		for _, bound := range bounds {
+			value, err := strconv.Atoi(strings.TrimSpace(bound))
+			if err != nil || value < 0 || value > MAX_PORT {
+				return fmt.Errorf("port (%s) must be an integer in [0, %d]", bound, MAX_PORT)
+			}
+			values = append(values, value)
+		}
+		if len(values) == 2 && values[0] > values[1] {
+			return fmt.Errorf("port range (%s) start is greater than end", item)
+		}
+	}
+	return nil
+}
+
+// checkResourceGroups 采集器策略目前每个方向最多支持一个资源组
+func checkResourceGroups(groupIDs []int) error {
+	if len(groupIDs) > 1 {
+		return fmt.Errorf("only one resource group is supported, got %v", groupIDs)
+	}
+	for _, groupID := range groupIDs {
+		var group mysql.ResourceGroup
+		if ret := mysql.Db.Where("id = ?", groupID).First(&group); ret.Error != nil {
+			return fmt.Errorf("resource_group (%d) not found", groupID)
+		}
+	}
+	return nil
+}
+
+func checkTapType(tapType int) error {
+	var tapTypeCount int64
+	mysql.Db.Model(&mysql.TapType{}).Where("value = ?", tapType).Count(&tapTypeCount)
+	if tapTypeCount == 0 {
+		return fmt.Errorf("tap_type (%d) not found", tapType)
+	}
+	return nil
+}
+
+func checkPolicyVTaps(vtapIDs []int) error {
+	if len(vtapIDs) == 0 {
+		return nil
+	}
+	var vtapCount int64
+	mysql.Db.Model(&mysql.VTap{}).Where("id IN (?)", vtapIDs).Count(&vtapCount)
+	if int(vtapCount) != len(vtapIDs) {
+		return fmt.Errorf("some of vtaps %v not found", vtapIDs)
+	}
+	return nil
+}
+
+func checkPayloadSlice(payloadSlice *int) error {
+	if payloadSlice != nil && (*payloadSlice < 0 || *payloadSlice > MAX_PAYLOAD_SLICE) {
+		return fmt.Errorf("payload slice (%d) must be in [0, %d]", *payloadSlice, MAX_PAYLOAD_SLICE)
+	}
+	return nil
+}
+
+// checkPolicyACL 校验策略引用的ACL存在且用途与策略一致
+func checkPolicyACL(aclID int, application int) (*mysql.ACL, error) {
+	var acl mysql.ACL
+	if ret := mysql.Db.Where("id = ?", aclID).First(&acl); ret.Error != nil {
+		return nil, fmt.Errorf("acl (%d) not found", aclID)
+	}
+	if acl.Applications != strconv.Itoa(application) {
+		return nil, fmt.Errorf(
+			"acl (%s) application is %s, expected %s",
+			acl.Name, aclApplicationName(acl.Applications), common.ACLApplicationName[application],
+		)
+	}
+	return &acl, nil
+}
+
+func aclApplicationName(applications string) string {
+	application, _ := strconv.Atoi(applications)
+	if name, ok := common.ACLApplicationName[application]; ok {
+		return name
+	}
+	return applications
+}
+
+func loadPolicySourceData() *metadata.PolicySourceData {
+	data := &metadata.PolicySourceData{}
+	mysql.Db.Find(&data.ACLs)
+	mysql.Db.Find(&data.NpbTunnels)
+	mysql.Db.Find(&data.NpbPolicies)
+	mysql.Db.Find(&data.PcapPolicies)
+	return data
+}
+
+// dryRunPolicyChange 在当前数据库数据上应用变更并生成策略, 返回策略版本将变化的采集器, 不修改数据库
+func dryRunPolicyChange(m *metadata.MetaData, change func(data *metadata.PolicySourceData)) (model.PolicyDryRun, error) {
+	if m == nil {
+		return model.PolicyDryRun{}, NewError(common.SERVER_ERROR, "policy metadata is not ready")
+	}
+	before := loadPolicySourceData()
+	after := &metadata.PolicySourceData{
+		ACLs:         append(before.ACLs[:0:0], before.ACLs...),
+		NpbTunnels:   append(before.NpbTunnels[:0:0], before.NpbTunnels...),
+		NpbPolicies:  append(before.NpbPolicies[:0:0], before.NpbPolicies...),
+		PcapPolicies: append(before.PcapPolicies[:0:0], before.PcapPolicies...),
+	}
+	change(after)
+
+	var vtaps []mysql.VTap
+	mysql.Db.Order("id").Find(&vtaps)
+	vtapIDs := make([]int, 0, len(vtaps))
+	idToVTap := make(map[int]mysql.VTap, len(vtaps))
+	for _, vtap := range vtaps {
+		vtapIDs = append(vtapIDs, vtap.ID)
+		idToVTap[vtap.ID] = vtap
+	}
+	changedVTapIDs, analyzerPolicyChanged := m.DiffPolicies(vtapIDs, before, after)
+
+	response := model.PolicyDryRun{
+		VTaps:                 []model.PolicyDryRunVTap{},
+		AnalyzerPolicyChanged: analyzerPolicyChanged,
+	}
+	for _, vtapID := range changedVTapIDs {
+		vtap := idToVTap[vtapID]
+		response.VTaps = append(response.VTaps, model.PolicyDryRunVTap{ID: vtap.ID, Name: vtap.Name, Lcuuid: vtap.Lcuuid})
+	}
+	return response, nil
+}
+
+func refreshPolicy() {
+	refresh.RefreshCache([]string{common.FLOW_ACL_CHANGED})
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+)
+
+func TestCheckPorts(t *testing.T) {
+	cases := []struct {
+		ports string
+		valid bool
+	}{
+		{"", true},
+		{"80", true},
+		{"8000-8080", true},
+		{"80, 443,8000-8080", true},
+		{"0-65535", true},
+		{"8080-80", false},
+		{"1-2-3", false},
+		{"65536", false},
+		{"-1", false},
+		{"http", false},
+		{"80,", false},
+	}
+	for _, c := range cases {
+		if err := checkPorts(c.ports); (err == nil) != c.valid {
+			t.Errorf("checkPorts(%q) = %v, expected valid: %v", c.ports, err, c.valid)
+		}
+	}
+}
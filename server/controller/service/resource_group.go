@@ -0,0 +1,450 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/metadata"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
+)
+
+func getResourceGroupTypes() []int {
+	types := make([]int, 0, len(common.ResourceGroupTypeName))
+	for groupType := range common.ResourceGroupTypeName {
+		types = append(types, groupType)
+	}
+	return types
+}
+
+func getResourceGroupTypeNames() []string {
+	names := make([]string, 0, len(common.ResourceGroupTypeName))
+	for _, typeName := range common.ResourceGroupTypeName {
+		names = append(names, typeName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getResourceGroupType(name string) (int, bool) {
+	for groupType, typeName := range common.ResourceGroupTypeName {
+		if typeName == name {
+			return groupType, true
+		}
+	}
+	return 0, false
+}
+
+func splitNonEmpty(str string) []string {
+	items := []string{}
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func resourceGroupToModel(group *mysql.ResourceGroup, m *metadata.MetaData) model.ResourceGroup {
+	resourceGroup := model.ResourceGroup{
+		ID:            group.ID,
+		Name:          group.Name,
+		Type:          common.ResourceGroupTypeName[group.Type],
+		VPCID:         group.VPCID,
+		PodClusterID:  group.PodClusterID,
+		IPs:           splitNonEmpty(group.IPs),
+		SubnetIDs:     stringToInts(group.NetworkIDs),
+		PodGroupIDs:   stringToInts(group.PodGroupIDs),
+		PodServiceIDs: stringToInts(group.PodServiceIDs),
+		PodLabels:     splitNonEmpty(group.PodLabels),
+		CreatedAt:     group.CreatedAt.Format(common.GO_BIRTHDAY),
+		UpdatedAt:     group.UpdatedAt.Format(common.GO_BIRTHDAY),
+		Lcuuid:        group.Lcuuid,
+	}
+	if m != nil {
+		resourceGroup.ResolvedIPs, resourceGroup.ResolvedIPRanges = m.GetGroupDataOP().GetGroupIPs(group.ID)
+	}
+	return resourceGroup
+}
+
+// GetResourceGroups 仅返回可通过API管理的具名资源组, 解析结果为trisolaris最近一次刷新的数据
+func GetResourceGroups(filter map[string]interface{}, m *metadata.MetaData) (resp []model.ResourceGroup, err error) {
+	var response []model.ResourceGroup
+	var groups []mysql.ResourceGroup
+
+	Db := mysql.Db.Where("type IN (?)", getResourceGroupTypes())
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	if _, ok := filter["type"]; ok {
+		groupType, _ := getResourceGroupType(filter["type"].(string))
+		Db = Db.Where("type = ?", groupType)
+	}
+	Db.Order("id").Find(&groups)
+
+	for i := range groups {
+		response = append(response, resourceGroupToModel(&groups[i], m))
+	}
+	return response, nil
+}
+
+// checkResourceGroupIPs 校验IP、CIDR及IP范围, 返回资源组的ip_type
+func checkResourceGroupIPs(ips []string) (int, error) {
+	if len(ips) == 0 {
+		return 0, fmt.Errorf("ips must be specified for ip resource group")
+	}
+	ipTypes := make(map[int]bool)
+	for _, ip := range ips {
+		switch {
+		case strings.Contains(ip, "-"):
+			bounds := strings.Split(ip, "-")
+			if len(bounds) != 2 {
+				return 0, fmt.Errorf("ip range (%s) is invalid", ip)
+			}
+			start, end := net.ParseIP(strings.TrimSpace(bounds[0])), net.ParseIP(strings.TrimSpace(bounds[1]))
+			if start == nil || end == nil || (start.To4() == nil) != (end.To4() == nil) {
+				return 0, fmt.Errorf("ip range (%s) is invalid", ip)
+			}
+			if bytes.Compare(start.To16(), end.To16()) > 0 {
+				return 0, fmt.Errorf("ip range (%s) start is greater than end", ip)
+			}
+			ipTypes[common.RESOURCE_GROUP_IP_TYPE_RANGE] = true
+		case strings.Contains(ip, "/"):
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return 0, fmt.Errorf("cidr (%s) is invalid", ip)
+			}
+			ipTypes[common.RESOURCE_GROUP_IP_TYPE_CIDR] = true
+		default:
+			if net.ParseIP(ip) == nil {
+				return 0, fmt.Errorf("ip (%s) is invalid", ip)
+			}
+			ipTypes[common.RESOURCE_GROUP_IP_TYPE_SINGLE] = true
+		}
+	}
+	if len(ipTypes) > 1 {
+		return common.RESOURCE_GROUP_IP_TYPE_MIX, nil
+	}
+	for ipType := range ipTypes {
+		return ipType, nil
+	}
+	return 0, nil
+}
+
+func checkPodLabels(labels []string) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("pod_labels must be specified for pod_label resource group")
+	}
+	for _, label := range labels {
+		kv := strings.SplitN(label, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.Contains(label, ",") {
+			return fmt.Errorf("pod label (%s) is invalid, format should be key:value", label)
+		}
+	}
+	return nil
+}
+
+func getPodClusterVPCID(podClusterID int) (int, error) {
+	var podCluster mysql.PodCluster
+	if ret := mysql.Db.Where("id = ?", podClusterID).First(&podCluster); ret.Error != nil {
+		return 0, fmt.Errorf("pod_cluster (%d) not found", podClusterID)
+	}
+	return podCluster.VPCID, nil
+}
+
+// buildResourceGroup 校验参数并生成资源组及其关联的extra info, 不写入数据库
+// 未指定VPC时, 容器相关资源组使用所属容器集群或服务的VPC
+func buildResourceGroup(groupCreate model.ResourceGroupCreate) (*mysql.ResourceGroup, []*mysql.ResourceGroupExtraInfo, error) {
+	groupType, ok := getResourceGroupType(groupCreate.Type)
+	if !ok {
+		return nil, nil, fmt.Errorf(
+			"type (%s) not supported, supported types: %s",
+			groupCreate.Type, strings.Join(getResourceGroupTypeNames(), ", "),
+		)
+	}
+	if groupCreate.VPCID != 0 {
+		var vpc mysql.VPC
+		if ret := mysql.Db.Where("id = ?", groupCreate.VPCID).First(&vpc); ret.Error != nil {
+			return nil, nil, fmt.Errorf("vpc (%d) not found", groupCreate.VPCID)
+		}
+	}
+
+	group := &mysql.ResourceGroup{
+		BusinessID: common.RESOURCE_GROUP_DEFAULT_BUSINESS_ID,
+		Name:       groupCreate.Name,
+		Type:       groupType,
+		VPCID:      groupCreate.VPCID,
+	}
+	var extraInfos []*mysql.ResourceGroupExtraInfo
+	switch groupType {
+	case common.RESOURCE_GROUP_TYPE_IP:
+		ipType, err := checkResourceGroupIPs(groupCreate.IPs)
+		if err != nil {
+			return nil, nil, err
+		}
+		group.IPType = ipType
+		group.IPs = strings.Join(groupCreate.IPs, ",")
+
+	case common.RESOURCE_GROUP_TYPE_VL2:
+		if groupCreate.VPCID == 0 {
+			return nil, nil, fmt.Errorf("vpc_id must be specified for subnet resource group")
+		}
+		if len(groupCreate.SubnetIDs) == 0 {
+			return nil, nil, fmt.Errorf("subnet_ids must be specified for subnet resource group")
+		}
+		var networks []mysql.Network
+		mysql.Db.Where("id IN (?)", groupCreate.SubnetIDs).Find(&networks)
+		if len(networks) != len(groupCreate.SubnetIDs) {
+			return nil, nil, fmt.Errorf("some of subnets %v not found", groupCreate.SubnetIDs)
+		}
+		for _, network := range networks {
+			if network.VPCID != groupCreate.VPCID {
+				return nil, nil, fmt.Errorf("subnet (%d) not in vpc (%d)", network.ID, groupCreate.VPCID)
+			}
+		}
+		group.NetworkIDs = intsToString(groupCreate.SubnetIDs)
+
+	case common.RESOURCE_GROUP_TYPE_POD_GROUP:
+		if len(groupCreate.PodGroupIDs) == 0 {
+			return nil, nil, fmt.Errorf("pod_group_ids must be specified for pod_group resource group")
+		}
+		var podGroups []mysql.PodGroup
+		mysql.Db.Where("id IN (?)", groupCreate.PodGroupIDs).Order("id").Find(&podGroups)
+		if len(podGroups) != len(groupCreate.PodGroupIDs) {
+			return nil, nil, fmt.Errorf("some of pod_groups %v not found", groupCreate.PodGroupIDs)
+		}
+		for _, podGroup := range podGroups {
+			extraInfos = append(extraInfos, &mysql.ResourceGroupExtraInfo{
+				ResourceType: common.RESOURCE_GROUP_EXTRA_INFO_TYPE_POD_GROUP,
+				ResourceID:   podGroup.ID,
+				ResourceName: podGroup.Name,
+			})
+		}
+		if group.VPCID == 0 {
+			vpcID, err := getPodClusterVPCID(podGroups[0].PodClusterID)
+			if err != nil {
+				return nil, nil, err
+			}
+			group.VPCID = vpcID
+		}
+		group.PodGroupIDs = intsToString(groupCreate.PodGroupIDs)
+
+	case common.RESOURCE_GROUP_TYPE_POD_SERVICE:
+		if len(groupCreate.PodServiceIDs) == 0 {
+			return nil, nil, fmt.Errorf("pod_service_ids must be specified for pod_service resource group")
+		}
+		var podServices []mysql.PodService
+		mysql.Db.Where("id IN (?)", groupCreate.PodServiceIDs).Order("id").Find(&podServices)
+		if len(podServices) != len(groupCreate.PodServiceIDs) {
+			return nil, nil, fmt.Errorf("some of pod_services %v not found", groupCreate.PodServiceIDs)
+		}
+		for _, podService := range podServices {
+			extraInfos = append(extraInfos, &mysql.ResourceGroupExtraInfo{
+				ResourceType: common.RESOURCE_GROUP_EXTRA_INFO_TYPE_POD_SERVICE,
+				ResourceID:   podService.ID,
+				ResourceName: podService.Name,
+			})
+		}
+		if group.VPCID == 0 {
+			group.VPCID = podServices[0].VPCID
+		}
+		group.PodServiceIDs = intsToString(groupCreate.PodServiceIDs)
+
+	case common.RESOURCE_GROUP_TYPE_POD_LABEL:
+		if err := checkPodLabels(groupCreate.PodLabels); err != nil {
+			return nil, nil, err
+		}
+		if groupCreate.PodClusterID != 0 {
+			vpcID, err := getPodClusterVPCID(groupCreate.PodClusterID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if group.VPCID == 0 {
+				group.VPCID = vpcID
+			}
+		}
+		group.PodClusterID = groupCreate.PodClusterID
+		group.PodLabels = strings.Join(groupCreate.PodLabels, ",")
+	}
+	return group, extraInfos, nil
+}
+
+func checkResourceGroupName(name string, excludeLcuuid string) error {
+	var groupCount int64
+	mysql.Db.Model(&mysql.ResourceGroup{}).Where("name = ? AND lcuuid != ?", name, excludeLcuuid).Count(&groupCount)
+	if groupCount > 0 {
+		return NewError(common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("resource_group (%s) already exist", name))
+	}
+	return nil
+}
+
+// saveResourceGroup 先写入extra info, 再将其ID记录到资源组
+func saveResourceGroup(tx *gorm.DB, group *mysql.ResourceGroup, extraInfos []*mysql.ResourceGroupExtraInfo) error {
+	extraInfoIDs := make([]int, 0, len(extraInfos))
+	for _, extraInfo := range extraInfos {
+		if err := tx.Create(extraInfo).Error; err != nil {
+			return err
+		}
+		extraInfoIDs = append(extraInfoIDs, extraInfo.ID)
+	}
+	group.ExtraInfoIDs = intsToString(extraInfoIDs)
+	return tx.Save(group).Error
+}
+
+func refreshResourceGroup() {
+	refresh.RefreshCache([]string{common.GROUP_CHANGED})
+}
+
+func CreateResourceGroup(groupCreate model.ResourceGroupCreate, m *metadata.MetaData) (resp model.ResourceGroup, err error) {
+	if err := checkResourceGroupName(groupCreate.Name, ""); err != nil {
+		return model.ResourceGroup{}, err
+	}
+	group, extraInfos, err := buildResourceGroup(groupCreate)
+	if err != nil {
+		return model.ResourceGroup{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+
+	group.Lcuuid = uuid.New().String()
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		return saveResourceGroup(tx, group, extraInfos)
+	})
+	if err != nil {
+		return model.ResourceGroup{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("create resource_group (%s)", group.Name)
+	refreshResourceGroup()
+
+	response, _ := GetResourceGroups(map[string]interface{}{"lcuuid": group.Lcuuid}, m)
+	return response[0], nil
+}
+
+func UpdateResourceGroup(lcuuid string, groupUpdate model.ResourceGroupUpdate, m *metadata.MetaData) (resp model.ResourceGroup, err error) {
+	var oldGroup mysql.ResourceGroup
+	ret := mysql.Db.Where("lcuuid = ? AND type IN (?)", lcuuid, getResourceGroupTypes()).First(&oldGroup)
+	if ret.Error != nil {
+		return model.ResourceGroup{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("resource_group (%s) not found", lcuuid))
+	}
+
+	// 资源组类型不可修改
+	oldModel := resourceGroupToModel(&oldGroup, nil)
+	groupCreate := model.ResourceGroupCreate{
+		Name:          oldModel.Name,
+		Type:          oldModel.Type,
+		VPCID:         oldModel.VPCID,
+		PodClusterID:  oldModel.PodClusterID,
+		IPs:           oldModel.IPs,
+		SubnetIDs:     oldModel.SubnetIDs,
+		PodGroupIDs:   oldModel.PodGroupIDs,
+		PodServiceIDs: oldModel.PodServiceIDs,
+		PodLabels:     oldModel.PodLabels,
+	}
+	if groupUpdate.Name != nil {
+		if err := checkResourceGroupName(*groupUpdate.Name, lcuuid); err != nil {
+			return model.ResourceGroup{}, err
+		}
+		groupCreate.Name = *groupUpdate.Name
+	}
+	if groupUpdate.VPCID != nil {
+		groupCreate.VPCID = *groupUpdate.VPCID
+	}
+	if groupUpdate.PodClusterID != nil {
+		groupCreate.PodClusterID = *groupUpdate.PodClusterID
+	}
+	if groupUpdate.IPs != nil {
+		groupCreate.IPs = *groupUpdate.IPs
+	}
+	if groupUpdate.SubnetIDs != nil {
+		groupCreate.SubnetIDs = *groupUpdate.SubnetIDs
+	}
+	if groupUpdate.PodGroupIDs != nil {
+		groupCreate.PodGroupIDs = *groupUpdate.PodGroupIDs
+	}
+	if groupUpdate.PodServiceIDs != nil {
+		groupCreate.PodServiceIDs = *groupUpdate.PodServiceIDs
+	}
+	if groupUpdate.PodLabels != nil {
+		groupCreate.PodLabels = *groupUpdate.PodLabels
+	}
+	group, extraInfos, err := buildResourceGroup(groupCreate)
+	if err != nil {
+		return model.ResourceGroup{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	group.ID = oldGroup.ID
+	group.BusinessID = oldGroup.BusinessID
+	group.IconID = oldGroup.IconID
+	group.Lcuuid = oldGroup.Lcuuid
+	group.CreatedAt = oldGroup.CreatedAt
+
+	err = mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if oldExtraInfoIDs := stringToInts(oldGroup.ExtraInfoIDs); len(oldExtraInfoIDs) > 0 {
+			if err := tx.Where("id IN (?)", oldExtraInfoIDs).Delete(&mysql.ResourceGroupExtraInfo{}).Error; err != nil {
+				return err
+			}
+		}
+		return saveResourceGroup(tx, group, extraInfos)
+	})
+	if err != nil {
+		return model.ResourceGroup{}, NewError(common.SERVER_ERROR, err.Error())
+	}
+	log.Infof("update resource_group (%s) %+v", group.Name, groupCreate)
+	refreshResourceGroup()
+
+	response, _ := GetResourceGroups(map[string]interface{}{"lcuuid": lcuuid}, m)
+	return response[0], nil
+}
+
+func DeleteResourceGroup(lcuuid string) (resp map[string]string, err error) {
+	var group mysql.ResourceGroup
+	ret := mysql.Db.Where("lcuuid = ? AND type IN (?)", lcuuid, getResourceGroupTypes()).First(&group)
+	if ret.Error != nil {
+		return map[string]string{}, NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("resource_group (%s) not found", lcuuid))
+	}
+
+	var aclCount int64
+	groupID := strconv.Itoa(group.ID)
+	mysql.Db.Model(&mysql.ACL{}).Where(
+		"FIND_IN_SET(?, src_group_ids) OR FIND_IN_SET(?, dst_group_ids)", groupID, groupID,
+	).Count(&aclCount)
+	if aclCount > 0 {
+		return map[string]string{}, NewError(
+			common.INVALID_PARAMETERS, fmt.Sprintf("resource_group (%s) is used by %d acl(s)", group.Name, aclCount),
+		)
+	}
+
+	log.Infof("delete resource_group (%s)", group.Name)
+	mysql.Db.Transaction(func(tx *gorm.DB) error {
+		if extraInfoIDs := stringToInts(group.ExtraInfoIDs); len(extraInfoIDs) > 0 {
+			tx.Where("id IN (?)", extraInfoIDs).Delete(&mysql.ResourceGroupExtraInfo{})
+		}
+		return tx.Delete(&group).Error
+	})
+	refreshResourceGroup()
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+)
+
+func TestCheckResourceGroupIPs(t *testing.T) {
+	cases := []struct {
+		ips    []string
+		ipType int
+		valid  bool
+	}{
+		{[]string{"10.1.1.1", "2001:db8::1"}, common.RESOURCE_GROUP_IP_TYPE_SINGLE, true},
+		{[]string{"10.1.0.0/16"}, common.RESOURCE_GROUP_IP_TYPE_CIDR, true},
+		{[]string{"10.1.1.1-10.1.1.100", "10.1.1.1 - 10.1.1.1"}, common.RESOURCE_GROUP_IP_TYPE_RANGE, true},
+		{[]string{"10.1.1.1", "10.2.0.0/16", "10.3.1.1-10.3.1.9"}, common.RESOURCE_GROUP_IP_TYPE_MIX, true},
+		{[]string{}, 0, false},
+		{[]string{"10.1.1.256"}, 0, false},
+		{[]string{"10.1.0.0/33"}, 0, false},
+		{[]string{"10.1.1.100-10.1.1.1"}, 0, false},
+		{[]string{"10.1.1.1-10.1.1.2-10.1.1.3"}, 0, false},
+		{[]string{"10.1.1.1-2001:db8::1"}, 0, false},
+	}
+	for _, c := range cases {
+		ipType, err := checkResourceGroupIPs(c.ips)
+		if (err == nil) != c.valid || ipType != c.ipType {
+			t.Errorf("checkResourceGroupIPs(%v) = (%d, %v), expected (%d, valid: %v)", c.ips, ipType, err, c.ipType, c.valid)
+		}
+	}
+}
+
+func (t *SuiteTest) TestBuildResourceGroup() {
+	vpc := mysql.VPC{Base: mysql.Base{ID: 1001, Lcuuid: uuid.NewString()}}
+	t.db.Create(&vpc)
+	t.db.Create(&mysql.Network{Base: mysql.Base{ID: 1001, Lcuuid: uuid.NewString()}, VPCID: vpc.ID})
+	t.db.Create(&mysql.Network{Base: mysql.Base{ID: 1002, Lcuuid: uuid.NewString()}, VPCID: vpc.ID + 1})
+	t.db.Create(&mysql.PodCluster{Base: mysql.Base{ID: 1001, Lcuuid: uuid.NewString()}, VPCID: vpc.ID})
+	t.db.Create(&mysql.PodGroup{Base: mysql.Base{ID: 1001, Lcuuid: uuid.NewString()}, Name: "web", PodClusterID: 1001})
+
+	group, _, err := buildResourceGroup(model.ResourceGroupCreate{Name: "ip", Type: "ip", IPs: []string{"10.1.1.1", "10.2.0.0/16"}})
+	if assert.Nil(t.T(), err) {
+		assert.Equal(t.T(), common.RESOURCE_GROUP_IP_TYPE_MIX, group.IPType)
+		assert.Equal(t.T(), "10.1.1.1,10.2.0.0/16", group.IPs)
+	}
+	_, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "ip", Type: "ip", IPs: []string{"10.1.1.9-10.1.1.1"}})
+	assert.NotNil(t.T(), err)
+	_, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "vm", Type: "vm"})
+	assert.NotNil(t.T(), err)
+
+	group, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "subnet", Type: "subnet", VPCID: vpc.ID, SubnetIDs: []int{1001}})
+	if assert.Nil(t.T(), err) {
+		assert.Equal(t.T(), "1001", group.NetworkIDs)
+	}
+	// 子网不属于指定的VPC
+	_, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "subnet", Type: "subnet", VPCID: vpc.ID, SubnetIDs: []int{1001, 1002}})
+	assert.NotNil(t.T(), err)
+	_, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "subnet", Type: "subnet", SubnetIDs: []int{1001}})
+	assert.NotNil(t.T(), err)
+
+	// 未指定VPC时使用容器集群的VPC
+	group, extraInfos, err := buildResourceGroup(model.ResourceGroupCreate{Name: "pod_group", Type: "pod_group", PodGroupIDs: []int{1001}})
+	if assert.Nil(t.T(), err) && assert.Equal(t.T(), 1, len(extraInfos)) {
+		assert.Equal(t.T(), vpc.ID, group.VPCID)
+		assert.Equal(t.T(), common.RESOURCE_GROUP_EXTRA_INFO_TYPE_POD_GROUP, extraInfos[0].ResourceType)
+		assert.Equal(t.T(), "web", extraInfos[0].ResourceName)
+	}
+
+	group, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "pod_label", Type: "pod_label", PodClusterID: 1001, PodLabels: []string{"app:nginx", "env:"}})
+	if assert.Nil(t.T(), err) {
+		assert.Equal(t.T(), vpc.ID, group.VPCID)
+		assert.Equal(t.T(), "app:nginx,env:", group.PodLabels)
+	}
+	for _, labels := range [][]string{{}, {"app"}, {":nginx"}, {"app:a,b"}} {
+		_, _, err = buildResourceGroup(model.ResourceGroupCreate{Name: "pod_label", Type: "pod_label", PodLabels: labels})
+		assert.NotNil(t.T(), err, labels)
+	}
+}
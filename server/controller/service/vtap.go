@@ -27,8 +27,11 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/config"
 	"github.com/deepflowys/deepflow/server/controller/db/mysql"
 	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/monitor"
+	mconfig "github.com/deepflowys/deepflow/server/controller/monitor/config"
 	"github.com/deepflowys/deepflow/server/controller/monitor/license"
 	"github.com/deepflowys/deepflow/server/controller/trisolaris/utils"
 	vtapop "github.com/deepflowys/deepflow/server/controller/trisolaris/vtap"
@@ -546,7 +549,125 @@ func execAZRebalance(
 	return response
 }
 
-func vtapControllerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceResult, error) {
+// execAZRebalanceByLoad 按采集器最近发送的数据量进行均衡，仅考虑状态正常的控制器/数据节点。
+// 每次将负载最高节点上的一个采集器迁移至有剩余采集器个数且负载最低的节点，
+// 直到所有节点的负载不超过平均负载的(1+maxSkew)倍或无法继续降低最高负载
+func execAZRebalanceByLoad(
+	azLcuuid string, hostType string, hostIPToVTaps map[string][]*mysql.VTap,
+	hostIPToAvailableVTapNum map[string]int, hostIPToState map[string]int,
+	vtapNameToLoad map[string]float64, maxSkew float64, ifCheck bool,
+) model.AZVTapRebalanceResult {
+	response := model.AZVTapRebalanceResult{}
+	hostIPToRebalanceResult := make(map[string]*model.HostVTapRebalanceResult)
+	hostIPToLoad := make(map[string]float64)
+	normalHostIPs := []string{}
+	totalLoad := float64(0)
+	vtapNum := 0
+	for hostIP := range hostIPToAvailableVTapNum {
+		state, ok := hostIPToState[hostIP]
+		if !ok {
+			continue
+		}
+		load := float64(0)
+		for _, vtap := range hostIPToVTaps[hostIP] {
+			load += vtapNameToLoad[vtap.Name]
+		}
+		hostIPToLoad[hostIP] = load
+		if state == common.HOST_STATE_COMPLETE {
+			normalHostIPs = append(normalHostIPs, hostIP)
+			totalLoad += load
+			vtapNum += len(hostIPToVTaps[hostIP])
+		}
+		hostIPToRebalanceResult[hostIP] = &model.HostVTapRebalanceResult{
+			IP:             hostIP,
+			State:          state,
+			AZ:             azLcuuid,
+			BeforeVTapNum:  len(hostIPToVTaps[hostIP]),
+			AfterVTapNum:   len(hostIPToVTaps[hostIP]),
+			BeforeVTapLoad: load,
+			AfterVTapLoad:  load,
+		}
+	}
+
+	if len(normalHostIPs) > 1 && totalLoad > 0 {
+		maxLoad := totalLoad / float64(len(normalHostIPs)) * (1 + maxSkew)
+		migrated := make(map[*mysql.VTap]bool)
+		for i := 0; i < vtapNum; i++ {
+			sort.Slice(normalHostIPs, func(m, n int) bool {
+				return hostIPToLoad[normalHostIPs[m]] > hostIPToLoad[normalHostIPs[n]]
+			})
+			fromHostIP := normalHostIPs[0]
+			if hostIPToLoad[fromHostIP] <= maxLoad {
+				break
+			}
+			toHostIP := ""
+			for j := len(normalHostIPs) - 1; j > 0; j-- {
+				if hostIPToAvailableVTapNum[normalHostIPs[j]] > 0 {
+					toHostIP = normalHostIPs[j]
+					break
+				}
+			}
+			if toHostIP == "" {
+				break
+			}
+
+			// 选择迁移后两个节点中较高负载最低的采集器，迁移后目标节点负载需低于原节点迁移前的负载
+			vtapIndex := -1
+			minPeakLoad := hostIPToLoad[fromHostIP]
+			for j, vtap := range hostIPToVTaps[fromHostIP] {
+				load := vtapNameToLoad[vtap.Name]
+				if migrated[vtap] || load <= 0 {
+					continue
+				}
+				peakLoad := math.Max(hostIPToLoad[fromHostIP]-load, hostIPToLoad[toHostIP]+load)
+				if peakLoad < minPeakLoad {
+					vtapIndex = j
+					minPeakLoad = peakLoad
+				}
+			}
+			if vtapIndex < 0 {
+				break
+			}
+			vtap := hostIPToVTaps[fromHostIP][vtapIndex]
+			load := vtapNameToLoad[vtap.Name]
+			migrated[vtap] = true
+			hostIPToVTaps[fromHostIP] = append(hostIPToVTaps[fromHostIP][:vtapIndex], hostIPToVTaps[fromHostIP][vtapIndex+1:]...)
+			hostIPToVTaps[toHostIP] = append(hostIPToVTaps[toHostIP], vtap)
+			hostIPToLoad[fromHostIP] -= load
+			hostIPToLoad[toHostIP] += load
+			hostIPToAvailableVTapNum[fromHostIP] += 1
+			hostIPToAvailableVTapNum[toHostIP] -= 1
+
+			if !ifCheck {
+				if hostType == "controller" {
+					mysql.Db.Model(vtap).Update("controller_ip", toHostIP)
+				} else {
+					mysql.Db.Model(vtap).Update("analyzer_ip", toHostIP)
+				}
+			}
+			fromResult, toResult := hostIPToRebalanceResult[fromHostIP], hostIPToRebalanceResult[toHostIP]
+			fromResult.AfterVTapNum -= 1
+			fromResult.SwitchVTapNum += 1
+			fromResult.AfterVTapLoad = hostIPToLoad[fromHostIP]
+			toResult.AfterVTapNum += 1
+			toResult.SwitchVTapNum += 1
+			toResult.AfterVTapLoad = hostIPToLoad[toHostIP]
+			response.TotalSwitchVTapNum += 1
+			response.Migrations = append(response.Migrations, model.VTapMigration{
+				Name: vtap.Name, AZ: azLcuuid, From: fromHostIP, To: toHostIP, Load: load,
+			})
+		}
+	}
+
+	for _, hostRebalanceResult := range hostIPToRebalanceResult {
+		response.Details = append(response.Details, *hostRebalanceResult)
+	}
+	return response
+}
+
+func vtapControllerRebalance(
+	azs []mysql.AZ, ifCheck bool, vtapNameToLoad map[string]float64, maxSkew float64,
+) (*model.VTapRebalanceResult, error) {
 	var controllers []mysql.Controller
 	var azControllerConns []mysql.AZControllerConnection
 	var vtaps []mysql.VTap
@@ -632,18 +753,29 @@ func vtapControllerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalance
 		}
 
 		// 执行均衡操作
-		azVTapRebalanceResult := execAZRebalance(
-			az.Lcuuid, len(azVTaps), "controller", controllerIPToVTaps,
-			controllerIPToAvailableVTapNum, controllerIPToUsedVTapNum,
-			controllerIPToState, ifCheck,
-		)
+		var azVTapRebalanceResult model.AZVTapRebalanceResult
+		if vtapNameToLoad != nil {
+			azVTapRebalanceResult = execAZRebalanceByLoad(
+				az.Lcuuid, "controller", controllerIPToVTaps, controllerIPToAvailableVTapNum,
+				controllerIPToState, vtapNameToLoad, maxSkew, ifCheck,
+			)
+		} else {
+			azVTapRebalanceResult = execAZRebalance(
+				az.Lcuuid, len(azVTaps), "controller", controllerIPToVTaps,
+				controllerIPToAvailableVTapNum, controllerIPToUsedVTapNum,
+				controllerIPToState, ifCheck,
+			)
+		}
 		response.TotalSwitchVTapNum += azVTapRebalanceResult.TotalSwitchVTapNum
 		response.Details = append(response.Details, azVTapRebalanceResult.Details...)
+		response.Migrations = append(response.Migrations, azVTapRebalanceResult.Migrations...)
 	}
 	return &response, nil
 }
 
-func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceResult, error) {
+func vtapAnalyzerRebalance(
+	azs []mysql.AZ, ifCheck bool, vtapNameToLoad map[string]float64, maxSkew float64,
+) (*model.VTapRebalanceResult, error) {
 	var analyzers []mysql.Analyzer
 	var azAnalyzerConns []mysql.AZAnalyzerConnection
 	var vtaps []mysql.VTap
@@ -727,19 +859,30 @@ func vtapAnalyzerRebalance(azs []mysql.AZ, ifCheck bool) (*model.VTapRebalanceRe
 		}
 
 		// 执行均衡操作
-		azVTapRebalanceResult := execAZRebalance(
-			az.Lcuuid, len(azVTaps), "analyzer", analyzerIPToVTaps,
-			analyzerIPToAvailableVTapNum, analyzerIPToUsedVTapNum,
-			analyzerIPToState, ifCheck,
-		)
+		var azVTapRebalanceResult model.AZVTapRebalanceResult
+		if vtapNameToLoad != nil {
+			azVTapRebalanceResult = execAZRebalanceByLoad(
+				az.Lcuuid, "analyzer", analyzerIPToVTaps, analyzerIPToAvailableVTapNum,
+				analyzerIPToState, vtapNameToLoad, maxSkew, ifCheck,
+			)
+		} else {
+			azVTapRebalanceResult = execAZRebalance(
+				az.Lcuuid, len(azVTaps), "analyzer", analyzerIPToVTaps,
+				analyzerIPToAvailableVTapNum, analyzerIPToUsedVTapNum,
+				analyzerIPToState, ifCheck,
+			)
+		}
 		response.TotalSwitchVTapNum += azVTapRebalanceResult.TotalSwitchVTapNum
 		response.Details = append(response.Details, azVTapRebalanceResult.Details...)
+		response.Migrations = append(response.Migrations, azVTapRebalanceResult.Migrations...)
 	}
 	return &response, nil
 }
 
-func VTapRebalance(args map[string]interface{}) (*model.VTapRebalanceResult, error) {
+func VTapRebalance(args map[string]interface{}, cfg *config.ControllerConfig) (*model.VTapRebalanceResult, error) {
 	var azs []mysql.AZ
+	var vtapNameToLoad map[string]float64
+	var err error
 
 	hostType := "controller"
 	if argsType, ok := args["type"]; ok {
@@ -751,12 +894,33 @@ func VTapRebalance(args map[string]interface{}) (*model.VTapRebalanceResult, err
 		ifCheck = argsCheck.(bool)
 	}
 
+	// 未指定均衡算法时使用配置中的算法
+	loadBalancingCfg := cfg.MonitorCfg.VTapLoadBalancing
+	algorithm := loadBalancingCfg.Algorithm
+	if argsAlgorithm, ok := args["algorithm"]; ok {
+		algorithm = argsAlgorithm.(string)
+	}
+
+	// 按数据量均衡时，获取各采集器最近发送的数据量
+	if algorithm == mconfig.VTAP_LOAD_BALANCING_BY_INGESTED_DATA {
+		vtapNameToLoad, err = monitor.GetVTapIngestedLoads(cfg.ClickHouseCfg, loadBalancingCfg.DataDuration)
+		if err != nil {
+			errMsg := fmt.Sprintf("get vtap ingested data failed, (%v)", err)
+			return nil, NewError(common.SERVER_ERROR, errMsg)
+		}
+	}
+
+	var response *model.VTapRebalanceResult
 	mysql.Db.Find(&azs)
 	if hostType == "controller" {
-		return vtapControllerRebalance(azs, ifCheck)
+		response, err = vtapControllerRebalance(azs, ifCheck, vtapNameToLoad, loadBalancingCfg.MaxSkew)
 	} else {
-		return vtapAnalyzerRebalance(azs, ifCheck)
+		response, err = vtapAnalyzerRebalance(azs, ifCheck, vtapNameToLoad, loadBalancingCfg.MaxSkew)
+	}
+	if response != nil && vtapNameToLoad != nil {
+		response.Algorithm = algorithm
 	}
+	return response, err
 }
 
 func formatLKResult(vtapLKResult *vtapop.VTapLKResult, updateMap map[string]interface{}) {
@@ -847,8 +1011,8 @@ func BatchUpdateVtapTapMode(vtapUpdate *model.VtapUpdateTapMode) (interface{}, e
 	responseChannel := make(chan string, len(vtapUpdate.VTapLcuuids))
 	wg := &sync.WaitGroup{}
 	wgResponse := &sync.WaitGroup{}
+	wgResponse.Add(1)
 	go func() {
-		wgResponse.Add(1)
 		for response := range responseChannel {
 			errorMessage = append(errorMessage, response)
 		}
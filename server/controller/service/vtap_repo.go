@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/config"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+)
+
+// 查询时不加载升级包内容
+var vtapRepoColumns = []string{
+	"id", "name", "revision", "arch", "os", "vtap_type", "size", "sha256", "signature", "created_at", "updated_at", "lcuuid",
+}
+
+func GetVTapRepos(filter map[string]interface{}) (resp []model.VTapRepo, err error) {
+	var response []model.VTapRepo
+	var vtapRepos []mysql.VTapRepo
+
+	Db := mysql.Db.Select(vtapRepoColumns)
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("created_at DESC").Find(&vtapRepos)
+
+	for _, vtapRepo := range vtapRepos {
+		response = append(response, model.VTapRepo{
+			ID:        vtapRepo.ID,
+			Name:      vtapRepo.Name,
+			Revision:  vtapRepo.Revision,
+			Arch:      vtapRepo.Arch,
+			OS:        vtapRepo.OS,
+			VTapType:  vtapRepo.VTapType,
+			Size:      vtapRepo.Size,
+			SHA256:    vtapRepo.SHA256,
+			Signature: vtapRepo.Signature,
+			CreatedAt: vtapRepo.CreatedAt.Format(common.GO_BIRTHDAY),
+			UpdatedAt: vtapRepo.UpdatedAt.Format(common.GO_BIRTHDAY),
+			Lcuuid:    vtapRepo.Lcuuid,
+		})
+	}
+	return response, nil
+}
+
+func CreateVTapRepo(vtapRepoCreate model.VTapRepoCreate, image []byte, cfg *config.ControllerConfig) (resp model.VTapRepo, err error) {
+	var vtapRepoCount int64
+	mysql.Db.Model(&mysql.VTapRepo{}).Where("name = ?", vtapRepoCreate.Name).Count(&vtapRepoCount)
+	if vtapRepoCount > 0 {
+		return model.VTapRepo{}, NewError(
+			common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("vtap_repo (%s) already exist", vtapRepoCreate.Name),
+		)
+	}
+
+	if vtapRepoCreate.VTapType != "" {
+		vtapTypeValid := false
+		for _, name := range common.VTapTypeName {
+			if name == vtapRepoCreate.VTapType {
+				vtapTypeValid = true
+				break
+			}
+		}
+		if !vtapTypeValid {
+			return model.VTapRepo{}, NewError(
+				common.INVALID_PARAMETERS, fmt.Sprintf("unsupported vtap type (%s)", vtapRepoCreate.VTapType),
+			)
+		}
+	}
+	if len(image) == 0 {
+		return model.VTapRepo{}, NewError(common.INVALID_PARAMETERS, "vtap_repo image is empty")
+	}
+
+	// 上传时即校验签名，下发前trisolaris会再次校验
+	sha256Sum := common.GetAgentPackageSHA256(image)
+	if vtapRepoCreate.SHA256 != "" && strings.ToLower(vtapRepoCreate.SHA256) != sha256Sum {
+		return model.VTapRepo{}, NewError(
+			common.INVALID_PARAMETERS,
+			fmt.Sprintf("vtap_repo (%s) sha256 mismatch, expected: %s, actual: %s", vtapRepoCreate.Name, vtapRepoCreate.SHA256, sha256Sum),
+		)
+	}
+	err = common.VerifyAgentPackage(image, sha256Sum, vtapRepoCreate.Signature, cfg.TrisolarisCfg.AgentPackagePublicKey)
+	if err != nil {
+		return model.VTapRepo{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+
+	vtapRepo := mysql.VTapRepo{
+		Name:      vtapRepoCreate.Name,
+		Revision:  vtapRepoCreate.Revision,
+		Arch:      vtapRepoCreate.Arch,
+		OS:        vtapRepoCreate.OS,
+		VTapType:  vtapRepoCreate.VTapType,
+		Image:     image,
+		Size:      len(image),
+		SHA256:    sha256Sum,
+		Signature: vtapRepoCreate.Signature,
+		Lcuuid:    uuid.New().String(),
+	}
+	if ret := mysql.Db.Create(&vtapRepo); ret.Error != nil {
+		return model.VTapRepo{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof("create vtap_repo (%s) revision (%s), size: %d, sha256: %s", vtapRepo.Name, vtapRepo.Revision, vtapRepo.Size, sha256Sum)
+
+	response, _ := GetVTapRepos(map[string]interface{}{"lcuuid": vtapRepo.Lcuuid})
+	return response[0], nil
+}
+
+func DeleteVTapRepo(lcuuid string) (resp map[string]string, err error) {
+	var vtapRepo mysql.VTapRepo
+	if ret := mysql.Db.Select(vtapRepoColumns).Where("lcuuid = ?", lcuuid).First(&vtapRepo); ret.Error != nil {
+		return map[string]string{}, NewError(
+			common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap_repo (%s) not found", lcuuid),
+		)
+	}
+
+	var campaigns []mysql.VTapUpgradeCampaign
+	mysql.Db.Where(
+		"state IN (?) AND (upgrade_package = ? OR rollback_package = ?)",
+		[]int{
+			common.UPGRADE_CAMPAIGN_STATE_RUNNING, common.UPGRADE_CAMPAIGN_STATE_PAUSED,
+			common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK,
+		},
+		vtapRepo.Name, vtapRepo.Name,
+	).Find(&campaigns)
+	if len(campaigns) > 0 {
+		return map[string]string{}, NewError(
+			common.INVALID_PARAMETERS,
+			fmt.Sprintf("vtap_repo (%s) is used by vtap_upgrade_campaign (%s)", vtapRepo.Name, campaigns[0].Name),
+		)
+	}
+
+	log.Infof("delete vtap_repo (%s)", vtapRepo.Name)
+	mysql.Db.Delete(&vtapRepo)
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
+
+// checkVTapRepoRevision 升级包为仓库中的升级包时，校验期望版本与升级包版本一致
+func checkVTapRepoRevision(upgradePackage, revision string) error {
+	var vtapRepo mysql.VTapRepo
+	if ret := mysql.Db.Select(vtapRepoColumns).Where("name = ?", upgradePackage).First(&vtapRepo); ret.Error != nil {
+		return nil
+	}
+	if vtapRepo.Revision != revision {
+		return fmt.Errorf(
+			"revision (%s) does not match vtap_repo (%s) revision (%s)", revision, vtapRepo.Name, vtapRepo.Revision,
+		)
+	}
+	return nil
+}
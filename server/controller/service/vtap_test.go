@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+)
+
+func TestExecAZRebalanceByLoad(t *testing.T) {
+	vtaps := []*mysql.VTap{
+		{Name: "a", AnalyzerIP: "10.0.0.1"},
+		{Name: "b", AnalyzerIP: "10.0.0.1"},
+		{Name: "c", AnalyzerIP: "10.0.0.1"},
+		{Name: "d", AnalyzerIP: "10.0.0.2"},
+		{Name: "e", AnalyzerIP: "10.0.0.3"},
+	}
+	hostIPToVTaps := map[string][]*mysql.VTap{
+		"10.0.0.1": vtaps[:3],
+		"10.0.0.2": vtaps[3:4],
+		"10.0.0.3": vtaps[4:],
+	}
+	hostIPToAvailableVTapNum := map[string]int{"10.0.0.1": 7, "10.0.0.2": 9, "10.0.0.3": 9}
+	hostIPToState := map[string]int{
+		"10.0.0.1": common.HOST_STATE_COMPLETE,
+		"10.0.0.2": common.HOST_STATE_COMPLETE,
+		"10.0.0.3": common.HOST_STATE_EXCEPTION,
+	}
+	vtapNameToLoad := map[string]float64{"a": 100, "b": 30, "c": 10, "d": 20, "e": 1000}
+
+	result := execAZRebalanceByLoad(
+		"az-1", "analyzer", hostIPToVTaps, hostIPToAvailableVTapNum,
+		hostIPToState, vtapNameToLoad, 0.2, true,
+	)
+	// 平均负载80, 上限96, 异常节点不参与均衡: 依次迁移b、c使负载由140/20变为110/50、100/60,
+	// 此时只有a可以迁移, 但迁移后目标节点负载会更高, 均衡结束
+	if result.TotalSwitchVTapNum != 2 || len(result.Migrations) != 2 {
+		t.Fatalf("execAZRebalanceByLoad() = %+v", result)
+	}
+	if m := result.Migrations[0]; m.Name != "b" || m.From != "10.0.0.1" || m.To != "10.0.0.2" || m.Load != 30 {
+		t.Errorf("migration = %+v", m)
+	}
+	if m := result.Migrations[1]; m.Name != "c" || m.To != "10.0.0.2" {
+		t.Errorf("migration = %+v", m)
+	}
+	for _, detail := range result.Details {
+		switch detail.IP {
+		case "10.0.0.1":
+			if detail.BeforeVTapLoad != 140 || detail.AfterVTapLoad != 100 || detail.AfterVTapNum != 1 {
+				t.Errorf("detail = %+v", detail)
+			}
+		case "10.0.0.2":
+			if detail.BeforeVTapLoad != 20 || detail.AfterVTapLoad != 60 || detail.AfterVTapNum != 3 {
+				t.Errorf("detail = %+v", detail)
+			}
+		case "10.0.0.3":
+			if detail.SwitchVTapNum != 0 || detail.AfterVTapLoad != 1000 {
+				t.Errorf("detail = %+v", detail)
+			}
+		}
+	}
+
+	// 负载在允许的偏差内时不迁移
+	result = execAZRebalanceByLoad(
+		"az-1", "analyzer", map[string][]*mysql.VTap{"10.0.0.1": vtaps[1:2], "10.0.0.2": vtaps[3:4]},
+		map[string]int{"10.0.0.1": 9, "10.0.0.2": 9}, hostIPToState, vtapNameToLoad, 0.2, true,
+	)
+	if result.TotalSwitchVTapNum != 0 {
+		t.Errorf("execAZRebalanceByLoad() within skew = %+v", result)
+	}
+}
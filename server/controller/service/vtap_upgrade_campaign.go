@@ -0,0 +1,534 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	"github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/model"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/refresh"
+)
+
+const (
+	UPGRADE_CAMPAIGN_DEFAULT_BATCH_TIMEOUT = 600
+)
+
+var upgradeCampaignDefaultBatches = []int{1, 10, 100}
+
+// 升级任务可通过以下采集器属性筛选采集器
+var vtapLabelSelectorKeys = []string{
+	"name", "type", "ctrl_ip", "launch_server", "controller_ip", "analyzer_ip",
+	"region", "az", "arch", "os", "kernel_version", "revision",
+}
+
+type vtapLabelRequirement struct {
+	key   string
+	value string
+	equal bool
+}
+
+// parseVTapLabelSelector 解析形如`arch=x86_64,os!=windows`的采集器筛选条件
+func parseVTapLabelSelector(selector string) ([]vtapLabelRequirement, error) {
+	var requirements []vtapLabelRequirement
+	for _, item := range strings.Split(selector, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		requirement := vtapLabelRequirement{equal: true}
+		var kv []string
+		if strings.Contains(item, "!=") {
+			kv = strings.SplitN(item, "!=", 2)
+			requirement.equal = false
+		} else if strings.Contains(item, "==") {
+			kv = strings.SplitN(item, "==", 2)
+		} else {
+			kv = strings.SplitN(item, "=", 2)
+		}
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid label selector (%s)", item)
+		}
+		requirement.key = strings.TrimSpace(kv[0])
+		requirement.value = strings.TrimSpace(kv[1])
+		if !common.Contains(vtapLabelSelectorKeys, requirement.key) {
+			return nil, fmt.Errorf("unsupported label (%s) in selector, supported labels: %s", requirement.key, strings.Join(vtapLabelSelectorKeys, ","))
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+func matchVTapLabelSelector(labels map[string]string, requirements []vtapLabelRequirement) bool {
+	for _, requirement := range requirements {
+		if (labels[requirement.key] == requirement.value) != requirement.equal {
+			return false
+		}
+	}
+	return true
+}
+
+func getVTapLabels(vtap *mysql.VTap, regionLcuuidToName, azLcuuidToName map[string]string) map[string]string {
+	return map[string]string{
+		"name":           vtap.Name,
+		"type":           common.VTapTypeName[vtap.Type],
+		"ctrl_ip":        vtap.CtrlIP,
+		"launch_server":  vtap.LaunchServer,
+		"controller_ip":  vtap.ControllerIP,
+		"analyzer_ip":    vtap.AnalyzerIP,
+		"region":         regionLcuuidToName[vtap.Region],
+		"az":             azLcuuidToName[vtap.AZ],
+		"arch":           vtap.Arch,
+		"os":             vtap.Os,
+		"kernel_version": vtap.KernelVersion,
+		"revision":       GetVTapRealRevision(vtap.Revision),
+	}
+}
+
+// GetVTapRealRevision 采集器上报的revision格式为`<branch> <rev_count>-<commit_id>`, 升级时仅比较后半部分
+func GetVTapRealRevision(revision string) string {
+	splitStr := strings.Split(revision, " ")
+	if len(splitStr) == 2 {
+		return splitStr[1]
+	}
+	return revision
+}
+
+// parseUpgradeCampaignBatches 校验各批次的累计升级比例, 最后一个批次不足100%时补充100%
+func parseUpgradeCampaignBatches(batches []int) ([]int, error) {
+	if len(batches) == 0 {
+		return upgradeCampaignDefaultBatches, nil
+	}
+	result := make([]int, 0, len(batches)+1)
+	prev := 0
+	for _, percent := range batches {
+		if percent <= prev || percent > 100 {
+			return nil, fmt.Errorf("batches (%v) must be ascending percentages in (0, 100]", batches)
+		}
+		result = append(result, percent)
+		prev = percent
+	}
+	if prev != 100 {
+		result = append(result, 100)
+	}
+	return result, nil
+}
+
+// getUpgradeCampaignBatchEnds 按累计比例计算各批次最后一个采集器的位置(不含),
+// 每个批次至少包含一个采集器, 采集器数量少于批次数量时忽略多余批次
+func getUpgradeCampaignBatchEnds(vtapCount int, batches []int) []int {
+	var ends []int
+	prev := 0
+	for _, percent := range batches {
+		end := int(math.Ceil(float64(vtapCount*percent) / 100))
+		if end <= prev {
+			end = prev + 1
+		}
+		if end > vtapCount {
+			end = vtapCount
+		}
+		if end == prev {
+			break
+		}
+		ends = append(ends, end)
+		prev = end
+	}
+	return ends
+}
+
+func batchesToString(batches []int) string {
+	items := make([]string, len(batches))
+	for i, batch := range batches {
+		items[i] = fmt.Sprintf("%d", batch)
+	}
+	return strings.Join(items, ",")
+}
+
+func stringToBatches(str string) []int {
+	batches := []int{}
+	for _, item := range strings.Split(str, ",") {
+		var batch int
+		if _, err := fmt.Sscanf(item, "%d", &batch); err == nil {
+			batches = append(batches, batch)
+		}
+	}
+	return batches
+}
+
+func GetVTapUpgradeCampaigns(filter map[string]interface{}) (resp []model.VTapUpgradeCampaign, err error) {
+	var response []model.VTapUpgradeCampaign
+	var campaigns []mysql.VTapUpgradeCampaign
+	var campaignVTaps []mysql.VTapUpgradeCampaignVTap
+
+	Db := mysql.Db
+	if _, ok := filter["lcuuid"]; ok {
+		Db = Db.Where("lcuuid = ?", filter["lcuuid"])
+	}
+	if _, ok := filter["name"]; ok {
+		Db = Db.Where("name = ?", filter["name"])
+	}
+	Db.Order("created_at DESC").Find(&campaigns)
+
+	campaignLcuuids := make([]string, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		campaignLcuuids = append(campaignLcuuids, campaign.Lcuuid)
+	}
+	mysql.Db.Where("campaign_lcuuid IN (?)", campaignLcuuids).Order("batch, id").Find(&campaignVTaps)
+	campaignLcuuidToVTaps := make(map[string][]model.VTapUpgradeCampaignVTap)
+	for _, campaignVTap := range campaignVTaps {
+		campaignLcuuidToVTaps[campaignVTap.CampaignLcuuid] = append(
+			campaignLcuuidToVTaps[campaignVTap.CampaignLcuuid],
+			model.VTapUpgradeCampaignVTap{
+				VTapLcuuid:       campaignVTap.VTapLcuuid,
+				VTapName:         campaignVTap.VTapName,
+				Batch:            campaignVTap.Batch,
+				State:            campaignVTap.State,
+				StateName:        common.UpgradeCampaignVTapStateName[campaignVTap.State],
+				OriginRevision:   campaignVTap.OriginRevision,
+				ExpectedRevision: campaignVTap.ExpectedRevision,
+				Message:          campaignVTap.Message,
+				StartedAt:        campaignVTap.StartedAt.Format(common.GO_BIRTHDAY),
+			},
+		)
+	}
+
+	for _, campaign := range campaigns {
+		campaignResp := model.VTapUpgradeCampaign{
+			ID:               campaign.ID,
+			Name:             campaign.Name,
+			Lcuuid:           campaign.Lcuuid,
+			VTapGroupLcuuid:  campaign.VTapGroupLcuuid,
+			LabelSelector:    campaign.LabelSelector,
+			ExpectedRevision: campaign.ExpectedRevision,
+			UpgradePackage:   campaign.UpgradePackage,
+			RollbackRevision: campaign.RollbackRevision,
+			RollbackPackage:  campaign.RollbackPackage,
+			Batches:          stringToBatches(campaign.Batches),
+			CurrentBatch:     campaign.CurrentBatch,
+			FailureThreshold: campaign.FailureThreshold,
+			FailureAction:    campaign.FailureAction,
+			BatchTimeout:     campaign.BatchTimeout,
+			State:            campaign.State,
+			StateName:        common.UpgradeCampaignStateName[campaign.State],
+			Message:          campaign.Message,
+			StateCounts:      map[string]int{},
+			VTaps:            []model.VTapUpgradeCampaignVTap{},
+			CreatedAt:        campaign.CreatedAt.Format(common.GO_BIRTHDAY),
+			UpdatedAt:        campaign.UpdatedAt.Format(common.GO_BIRTHDAY),
+		}
+		if vtaps, ok := campaignLcuuidToVTaps[campaign.Lcuuid]; ok {
+			campaignResp.VTaps = vtaps
+		}
+		campaignResp.VTapCount = len(campaignResp.VTaps)
+		for _, vtap := range campaignResp.VTaps {
+			campaignResp.StateCounts[vtap.StateName] += 1
+		}
+		response = append(response, campaignResp)
+	}
+	return response, nil
+}
+
+func CreateVTapUpgradeCampaign(campaignCreate model.VTapUpgradeCampaignCreate) (resp model.VTapUpgradeCampaign, err error) {
+	var campaignCount int64
+	mysql.Db.Model(&mysql.VTapUpgradeCampaign{}).Where("name = ?", campaignCreate.Name).Count(&campaignCount)
+	if campaignCount > 0 {
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.RESOURCE_ALREADY_EXIST, fmt.Sprintf("vtap_upgrade_campaign (%s) already exist", campaignCreate.Name),
+		)
+	}
+
+	if campaignCreate.VTapGroupLcuuid == "" && campaignCreate.LabelSelector == "" {
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.INVALID_PARAMETERS, "one of VTAP_GROUP_LCUUID and LABEL_SELECTOR must be specified",
+		)
+	}
+	if campaignCreate.VTapGroupLcuuid != "" {
+		var vtapGroup mysql.VTapGroup
+		if ret := mysql.Db.Where("lcuuid = ?", campaignCreate.VTapGroupLcuuid).First(&vtapGroup); ret.Error != nil {
+			return model.VTapUpgradeCampaign{}, NewError(
+				common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap_group (%s) not found", campaignCreate.VTapGroupLcuuid),
+			)
+		}
+	}
+	requirements, err := parseVTapLabelSelector(campaignCreate.LabelSelector)
+	if err != nil {
+		return model.VTapUpgradeCampaign{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	batches, err := parseUpgradeCampaignBatches(campaignCreate.Batches)
+	if err != nil {
+		return model.VTapUpgradeCampaign{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	if campaignCreate.FailureThreshold < 0 || campaignCreate.FailureThreshold > 100 {
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.INVALID_PARAMETERS, fmt.Sprintf("failure threshold (%d) must be in [0, 100]", campaignCreate.FailureThreshold),
+		)
+	}
+	switch campaignCreate.FailureAction {
+	case "":
+		campaignCreate.FailureAction = common.UPGRADE_CAMPAIGN_FAILURE_ACTION_PAUSE
+	case common.UPGRADE_CAMPAIGN_FAILURE_ACTION_PAUSE:
+	case common.UPGRADE_CAMPAIGN_FAILURE_ACTION_ROLLBACK:
+		if campaignCreate.RollbackRevision == "" || campaignCreate.RollbackPackage == "" {
+			return model.VTapUpgradeCampaign{}, NewError(
+				common.INVALID_PARAMETERS, "ROLLBACK_REVISION and ROLLBACK_PACKAGE are required when failure action is rollback",
+			)
+		}
+	default:
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.INVALID_PARAMETERS, fmt.Sprintf("unsupported failure action (%s)", campaignCreate.FailureAction),
+		)
+	}
+	if campaignCreate.BatchTimeout <= 0 {
+		campaignCreate.BatchTimeout = UPGRADE_CAMPAIGN_DEFAULT_BATCH_TIMEOUT
+	}
+	if err = checkVTapRepoRevision(campaignCreate.UpgradePackage, campaignCreate.ExpectedRevision); err != nil {
+		return model.VTapUpgradeCampaign{}, NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	if campaignCreate.RollbackPackage != "" {
+		if err = checkVTapRepoRevision(campaignCreate.RollbackPackage, campaignCreate.RollbackRevision); err != nil {
+			return model.VTapUpgradeCampaign{}, NewError(common.INVALID_PARAMETERS, err.Error())
+		}
+	}
+
+	// 筛选需要升级的采集器, 排除未注册、已禁用及已是目标版本的采集器
+	var vtaps []mysql.VTap
+	Db := mysql.Db.Where("state != ? AND enable = ?", common.VTAP_STATE_PENDING, common.VTAP_ENABLE_TRUE)
+	if campaignCreate.VTapGroupLcuuid != "" {
+		Db = Db.Where("vtap_group_lcuuid = ?", campaignCreate.VTapGroupLcuuid)
+	}
+	Db.Order("id").Find(&vtaps)
+	var regions []mysql.Region
+	var azs []mysql.AZ
+	mysql.Db.Find(&regions)
+	mysql.Db.Find(&azs)
+	regionLcuuidToName := make(map[string]string)
+	for _, region := range regions {
+		regionLcuuidToName[region.Lcuuid] = region.Name
+	}
+	azLcuuidToName := make(map[string]string)
+	for _, az := range azs {
+		azLcuuidToName[az.Lcuuid] = az.Name
+	}
+
+	// 同一采集器不能同时处于多个进行中的升级任务
+	busyVTapLcuuids := make(map[string]string)
+	var activeCampaigns []mysql.VTapUpgradeCampaign
+	mysql.Db.Where("state IN (?)", []int{
+		common.UPGRADE_CAMPAIGN_STATE_RUNNING, common.UPGRADE_CAMPAIGN_STATE_PAUSED, common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK,
+	}).Find(&activeCampaigns)
+	if len(activeCampaigns) > 0 {
+		activeCampaignLcuuidToName := make(map[string]string)
+		activeCampaignLcuuids := []string{}
+		for _, campaign := range activeCampaigns {
+			activeCampaignLcuuidToName[campaign.Lcuuid] = campaign.Name
+			activeCampaignLcuuids = append(activeCampaignLcuuids, campaign.Lcuuid)
+		}
+		var activeVTaps []mysql.VTapUpgradeCampaignVTap
+		mysql.Db.Where("campaign_lcuuid IN (?)", activeCampaignLcuuids).Find(&activeVTaps)
+		for _, activeVTap := range activeVTaps {
+			busyVTapLcuuids[activeVTap.VTapLcuuid] = activeCampaignLcuuidToName[activeVTap.CampaignLcuuid]
+		}
+	}
+
+	var selectedVTaps []mysql.VTap
+	for i, vtap := range vtaps {
+		if GetVTapRealRevision(vtap.Revision) == campaignCreate.ExpectedRevision {
+			continue
+		}
+		if !matchVTapLabelSelector(getVTapLabels(&vtaps[i], regionLcuuidToName, azLcuuidToName), requirements) {
+			continue
+		}
+		if campaignName, ok := busyVTapLcuuids[vtap.Lcuuid]; ok {
+			return model.VTapUpgradeCampaign{}, NewError(
+				common.INVALID_PARAMETERS,
+				fmt.Sprintf("vtap (%s) is in vtap_upgrade_campaign (%s)", vtap.Name, campaignName),
+			)
+		}
+		selectedVTaps = append(selectedVTaps, vtap)
+	}
+	if len(selectedVTaps) == 0 {
+		return model.VTapUpgradeCampaign{}, NewError(common.INVALID_PARAMETERS, "no vtap needs to be upgraded")
+	}
+
+	lcuuid := uuid.New().String()
+	campaign := mysql.VTapUpgradeCampaign{
+		Name:             campaignCreate.Name,
+		VTapGroupLcuuid:  campaignCreate.VTapGroupLcuuid,
+		LabelSelector:    campaignCreate.LabelSelector,
+		ExpectedRevision: campaignCreate.ExpectedRevision,
+		UpgradePackage:   campaignCreate.UpgradePackage,
+		RollbackRevision: campaignCreate.RollbackRevision,
+		RollbackPackage:  campaignCreate.RollbackPackage,
+		Batches:          batchesToString(batches),
+		FailureThreshold: campaignCreate.FailureThreshold,
+		FailureAction:    campaignCreate.FailureAction,
+		BatchTimeout:     campaignCreate.BatchTimeout,
+		State:            common.UPGRADE_CAMPAIGN_STATE_RUNNING,
+		Lcuuid:           lcuuid,
+	}
+	campaignVTaps := make([]mysql.VTapUpgradeCampaignVTap, 0, len(selectedVTaps))
+	batch, ends := 0, getUpgradeCampaignBatchEnds(len(selectedVTaps), batches)
+	for i, vtap := range selectedVTaps {
+		if i >= ends[batch] {
+			batch += 1
+		}
+		campaignVTaps = append(campaignVTaps, mysql.VTapUpgradeCampaignVTap{
+			CampaignLcuuid:   lcuuid,
+			VTapLcuuid:       vtap.Lcuuid,
+			VTapName:         vtap.Name,
+			Batch:            batch,
+			State:            common.UPGRADE_CAMPAIGN_VTAP_STATE_PENDING,
+			OriginRevision:   vtap.Revision,
+			ExpectedRevision: campaignCreate.ExpectedRevision,
+			UpgradePackage:   campaignCreate.UpgradePackage,
+		})
+	}
+	if ret := mysql.Db.Create(&campaign); ret.Error != nil {
+		return model.VTapUpgradeCampaign{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	if ret := mysql.Db.Create(&campaignVTaps); ret.Error != nil {
+		mysql.Db.Delete(&campaign)
+		return model.VTapUpgradeCampaign{}, NewError(common.SERVER_ERROR, ret.Error.Error())
+	}
+	log.Infof(
+		"create vtap_upgrade_campaign (%s) to upgrade %d vtaps to revision (%s) in batches (%s)",
+		campaign.Name, len(campaignVTaps), campaign.ExpectedRevision, campaign.Batches,
+	)
+
+	response, _ := GetVTapUpgradeCampaigns(map[string]interface{}{"lcuuid": lcuuid})
+	return response[0], nil
+}
+
+func UpdateVTapUpgradeCampaign(lcuuid string, campaignUpdate model.VTapUpgradeCampaignUpdate) (resp model.VTapUpgradeCampaign, err error) {
+	var campaign mysql.VTapUpgradeCampaign
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&campaign); ret.Error != nil {
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap_upgrade_campaign (%s) not found", lcuuid),
+		)
+	}
+
+	log.Infof("%s vtap_upgrade_campaign (%s)", campaignUpdate.Action, campaign.Name)
+	invalidState := NewError(
+		common.INVALID_PARAMETERS,
+		fmt.Sprintf(
+			"can not %s vtap_upgrade_campaign (%s) in state %s",
+			campaignUpdate.Action, campaign.Name, common.UpgradeCampaignStateName[campaign.State],
+		),
+	)
+	switch campaignUpdate.Action {
+	case "pause":
+		if campaign.State != common.UPGRADE_CAMPAIGN_STATE_RUNNING {
+			return model.VTapUpgradeCampaign{}, invalidState
+		}
+		mysql.Db.Model(&campaign).Updates(map[string]interface{}{
+			"state": common.UPGRADE_CAMPAIGN_STATE_PAUSED, "message": "paused by user",
+		})
+	case "resume":
+		if campaign.State != common.UPGRADE_CAMPAIGN_STATE_PAUSED {
+			return model.VTapUpgradeCampaign{}, invalidState
+		}
+		mysql.Db.Model(&campaign).Updates(map[string]interface{}{
+			"state": common.UPGRADE_CAMPAIGN_STATE_RUNNING, "message": "",
+		})
+	case "cancel":
+		if campaign.State != common.UPGRADE_CAMPAIGN_STATE_RUNNING && campaign.State != common.UPGRADE_CAMPAIGN_STATE_PAUSED {
+			return model.VTapUpgradeCampaign{}, invalidState
+		}
+		// 取消尚未完成升级的采集器, 由trisolaris清除其预期版本
+		mysql.Db.Model(&mysql.VTapUpgradeCampaignVTap{}).Where(
+			"campaign_lcuuid = ? AND state IN (?)", lcuuid,
+			[]int{common.UPGRADE_CAMPAIGN_VTAP_STATE_PENDING, common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING},
+		).Update("state", common.UPGRADE_CAMPAIGN_VTAP_STATE_CANCELLED)
+		mysql.Db.Model(&campaign).Updates(map[string]interface{}{
+			"state": common.UPGRADE_CAMPAIGN_STATE_CANCELLED, "message": "cancelled by user",
+		})
+		refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	case "rollback":
+		if campaign.State != common.UPGRADE_CAMPAIGN_STATE_RUNNING && campaign.State != common.UPGRADE_CAMPAIGN_STATE_PAUSED &&
+			campaign.State != common.UPGRADE_CAMPAIGN_STATE_COMPLETED {
+			return model.VTapUpgradeCampaign{}, invalidState
+		}
+		if campaign.RollbackRevision == "" || campaign.RollbackPackage == "" {
+			return model.VTapUpgradeCampaign{}, NewError(
+				common.INVALID_PARAMETERS, fmt.Sprintf("vtap_upgrade_campaign (%s) has no rollback package", campaign.Name),
+			)
+		}
+		RollbackVTapUpgradeCampaign(&campaign, "rolled back by user")
+	default:
+		return model.VTapUpgradeCampaign{}, NewError(
+			common.INVALID_PARAMETERS, fmt.Sprintf("unsupported action (%s)", campaignUpdate.Action),
+		)
+	}
+
+	response, _ := GetVTapUpgradeCampaigns(map[string]interface{}{"lcuuid": lcuuid})
+	return response[0], nil
+}
+
+// RollbackVTapUpgradeCampaign 将已开始升级的采集器回滚至回滚版本, 尚未开始的采集器不再升级
+func RollbackVTapUpgradeCampaign(campaign *mysql.VTapUpgradeCampaign, message string) {
+	mysql.Db.Model(&mysql.VTapUpgradeCampaignVTap{}).Where(
+		"campaign_lcuuid = ? AND state = ?", campaign.Lcuuid, common.UPGRADE_CAMPAIGN_VTAP_STATE_PENDING,
+	).Update("state", common.UPGRADE_CAMPAIGN_VTAP_STATE_CANCELLED)
+	mysql.Db.Model(&mysql.VTapUpgradeCampaignVTap{}).Where(
+		"campaign_lcuuid = ? AND state IN (?)", campaign.Lcuuid,
+		[]int{
+			common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, common.UPGRADE_CAMPAIGN_VTAP_STATE_SUCCEEDED,
+			common.UPGRADE_CAMPAIGN_VTAP_STATE_FAILED,
+		},
+	).Updates(map[string]interface{}{
+		"state":             common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK,
+		"expected_revision": campaign.RollbackRevision,
+		"upgrade_package":   campaign.RollbackPackage,
+		"started_at":        time.Now(),
+		"message":           "",
+	})
+	mysql.Db.Model(campaign).Updates(map[string]interface{}{
+		"state": common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK, "message": message,
+	})
+	log.Infof("rollback vtap_upgrade_campaign (%s) to revision (%s): %s", campaign.Name, campaign.RollbackRevision, message)
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
+}
+
+func DeleteVTapUpgradeCampaign(lcuuid string) (resp map[string]string, err error) {
+	var campaign mysql.VTapUpgradeCampaign
+	if ret := mysql.Db.Where("lcuuid = ?", lcuuid).First(&campaign); ret.Error != nil {
+		return map[string]string{}, NewError(
+			common.RESOURCE_NOT_FOUND, fmt.Sprintf("vtap_upgrade_campaign (%s) not found", lcuuid),
+		)
+	}
+	if campaign.State == common.UPGRADE_CAMPAIGN_STATE_RUNNING || campaign.State == common.UPGRADE_CAMPAIGN_STATE_ROLLING_BACK {
+		return map[string]string{}, NewError(
+			common.INVALID_PARAMETERS,
+			fmt.Sprintf(
+				"can not delete vtap_upgrade_campaign (%s) in state %s, please cancel it first",
+				campaign.Name, common.UpgradeCampaignStateName[campaign.State],
+			),
+		)
+	}
+
+	log.Infof("delete vtap_upgrade_campaign (%s)", campaign.Name)
+	mysql.Db.Where("campaign_lcuuid = ?", lcuuid).Delete(&mysql.VTapUpgradeCampaignVTap{})
+	mysql.Db.Delete(&campaign)
+	refresh.RefreshCache([]string{common.VTAP_CHANGED})
+	return map[string]string{"LCUUID": lcuuid}, nil
+}
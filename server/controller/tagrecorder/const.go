@@ -154,6 +154,17 @@ const (
 		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select updated_at from %s order by updated_at desc limit 1'))\n" +
 		"LIFETIME(MIN 0 MAX 60)\n" +
 		"LAYOUT(FLAT())"
+	CREATE_POD_GROUP_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
+		"(\n" +
+		"    `id` UInt64,\n" +
+		"    `name` String,\n" +
+		"    `pod_group_type` UInt64,\n" +
+		"    `icon_id` Int64\n" +
+		")\n" +
+		"PRIMARY KEY id\n" +
+		"SOURCE(MYSQL(PORT %s USER '%s' PASSWORD '%s' %s DB %s TABLE %s INVALIDATE_QUERY 'select updated_at from %s order by updated_at desc limit 1'))\n" +
+		"LIFETIME(MIN 0 MAX 60)\n" +
+		"LAYOUT(FLAT())"
 	CREATE_VPC_DICTIONARY_SQL = "CREATE DICTIONARY %s.%s\n" +
 		"(\n" +
 		"    `id` UInt64,\n" +
@@ -452,7 +463,7 @@ var CREATE_SQL_MAP = map[string]string{
 	CH_DICTIONARY_POD_CLUSTER:    CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_NAMESPACE:  CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_POD_NODE:       CREATE_DICTIONARY_SQL,
-	CH_DICTIONARY_POD_GROUP:      CREATE_DICTIONARY_SQL,
+	CH_DICTIONARY_POD_GROUP:      CREATE_POD_GROUP_DICTIONARY_SQL,
 	CH_DICTIONARY_POD:            CREATE_DICTIONARY_SQL,
 	CH_DICTIONARY_DEVICE:         CREATE_DEVICE_DICTIONARY_SQL,
 	CH_DICTIONARY_VTAP_PORT:      CREATE_VTAP_PORT_DICTIONARY_SQL,
@@ -52,6 +52,9 @@ type Config struct {
 	VTapAutoRegister         bool   `default:"true" yaml:"vtap-auto-register"`
 	DefaultTapMode           int    `yaml:"default-tap-mode"`
 	BillingMethod            string `default:"license" yaml:"billing-method"`
+	AgentPackagePublicKey    string `yaml:"agent-package-public-key"`
+	AllowLocalUpgradePackage bool   `default:"false" yaml:"allow-local-upgrade-package"`
+	AgentPackageMaxSize      int64  `default:"104857600" yaml:"agent-package-max-size"`
 	GrpcPort                 int
 	IngesterPort             int
 }
@@ -101,6 +101,13 @@ func (obj *_DBMgr[M]) GetBatchFromState(state int) (result []*M, err error) {
 	return
 }
 
+// GetBatchFromStates 批量查找state类型数据
+func (obj *_DBMgr[M]) GetBatchFromStates(states []int) (result []*M, err error) {
+	err = obj.DB.WithContext(obj.ctx).Model(obj.m).Where("`state` IN (?)", states).Find(&result).Error
+
+	return
+}
+
 // GetBatchFromName 查找name相同数据
 func (obj *_DBMgr[M]) GetBatchFromName(name string) (result []*M, err error) {
 	err = obj.DB.WithContext(obj.ctx).Model(obj.m).Where("`name` = ?", name).Find(&result).Error
@@ -151,6 +158,12 @@ func (obj *_DBMgr[M]) GetFromName(name string) (result *M, err error) {
 	return
 }
 
+// GetFromNameOmit 通过name获取内容, 不查询指定字段
+func (obj *_DBMgr[M]) GetFromNameOmit(name string, columns ...string) (result *M, err error) {
+	err = obj.DB.WithContext(obj.ctx).Model(obj.m).Omit(columns...).Where("`name` = ?", name).First(&result).Error
+	return
+}
+
 // GetFromRegion 通过region获取内容
 func (obj *_DBMgr[M]) GetFromRegion(region string) (result *M, err error) {
 	err = obj.DB.WithContext(obj.ctx).Model(obj.m).Where("`region` = ?", region).First(&result).Error
@@ -148,6 +148,15 @@ func (g *GroupDataOP) GetGroupIDToPodServiceIDs() map[int][]int {
 	return g.groupRawData.groupIDToPodServiceIDs
 }
 
+// GetGroupIPs 返回资源组最近一次解析得到的CIDR及IP范围
+func (g *GroupDataOP) GetGroupIPs(groupID int) ([]string, []string) {
+	groupIP, ok := g.groupRawData.groupIDToIPs[groupID]
+	if !ok {
+		return nil, nil
+	}
+	return groupIP.cidrs, groupIP.ipRanges
+}
+
 func (g *GroupDataOP) getTridentGroups() []byte {
 	return g.tridentGroupProto.getGroups()
 }
@@ -212,16 +221,23 @@ func (g *GroupDataOP) generateGroupRawData() {
 	groupIDToPodGroupIDs := make(map[int][]int)
 	groupIDToPodIDs := make(map[int][]int)
 	groupIDToPodServiceIDs := make(map[int][]int)
+	podLabelGroups := []*models.ResourceGroup{}
+	groupIDToPodLabels := make(map[int]map[string]string)
 	resourceGroups := dbDataCache.GetResourceGroups()
 	tridentGroups := make([]*models.ResourceGroup, 0, len(resourceGroups))
 	dropletGroups := make([]*models.ResourceGroup, 0, len(resourceGroups))
 	idToGroup := make(map[int]*models.ResourceGroup)
 	for _, resourceGroup := range resourceGroups {
 		idToGroup[resourceGroup.ID] = resourceGroup
-		if Find[int](tridentGroup, resourceGroup.BusinessID) && groupIDsUsedByNpbPcap.Contains(resourceGroup.ID) {
+		// 资源组被NPB/PCAP策略引用时即下发给采集器, 与资源组自身的业务ID无关
+		if groupIDsUsedByNpbPcap.Contains(resourceGroup.ID) {
 			tridentGroups = append(tridentGroups, resourceGroup)
 		}
 		dropletGroups = append(dropletGroups, resourceGroup)
+		if resourceGroup.Type == RESOURCE_GROUP_TYPE_POD_LABEL {
+			podLabelGroups = append(podLabelGroups, resourceGroup)
+			groupIDToPodLabels[resourceGroup.ID] = parsePodLabels(resourceGroup.PodLabels)
+		}
 		if resourceGroup.ExtraInfoIDs != "" {
 			ids := strings.Split(resourceGroup.ExtraInfoIDs, ",")
 			for _, id := range ids {
@@ -236,7 +252,7 @@ func (g *GroupDataOP) generateGroupRawData() {
 					continue
 				}
 				switch resourceGroup.Type {
-				case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP:
+				case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP, RESOURCE_GROUP_TYPE_POD_GROUP:
 					podGroupIDs.Add(extraInfo.ResourceID)
 					if _, ok := groupIDToPodGroupIDs[resourceGroup.ID]; ok {
 						groupIDToPodGroupIDs[resourceGroup.ID] = append(
@@ -244,7 +260,8 @@ func (g *GroupDataOP) generateGroupRawData() {
 					} else {
 						groupIDToPodGroupIDs[resourceGroup.ID] = []int{extraInfo.ResourceID}
 					}
-				case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_SERVICE, RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP_AS_POD_SERVICE:
+				case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_SERVICE, RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP_AS_POD_SERVICE,
+					RESOURCE_GROUP_TYPE_POD_SERVICE:
 					if ids, ok := podServiceIDToPodGroupIDs[extraInfo.ResourceID]; ok {
 						newPodGroupIDs := mapset.NewSet()
 						for _, id := range ids {
@@ -274,6 +291,18 @@ func (g *GroupDataOP) generateGroupRawData() {
 	pgidToPids := make(map[int][]int)
 	groupPodIDs := mapset.NewSet()
 	for _, pod := range dbDataCache.GetPods() {
+		if len(podLabelGroups) > 0 {
+			podLabels := parsePodLabels(pod.Label)
+			for _, resourceGroup := range podLabelGroups {
+				if resourceGroup.PodClusterID != 0 && resourceGroup.PodClusterID != pod.PodClusterID {
+					continue
+				}
+				if matchPodLabels(podLabels, groupIDToPodLabels[resourceGroup.ID]) {
+					podIDs.Add(pod.ID)
+					groupIDToPodIDs[resourceGroup.ID] = append(groupIDToPodIDs[resourceGroup.ID], pod.ID)
+				}
+			}
+		}
 		if podGroupIDs.Contains(pod.PodGroupID) {
 			groupPodIDs.Add(pod.ID)
 			if _, ok := pgidToPids[pod.PodGroupID]; ok {
@@ -390,7 +419,7 @@ func (g *GroupDataOP) generateGroupRawData() {
 				ips = append(ips, "0.0.0.0/0")
 				ips = append(ips, "::/0")
 			}
-		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD:
+		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD, RESOURCE_GROUP_TYPE_POD_LABEL:
 			for _, podID := range groupIDToPodIDs[resourceGroup.ID] {
 				if vifIDs, ok := pidToVifIDs[podID]; ok {
 					for _, vifID := range vifIDs {
@@ -400,13 +429,13 @@ func (g *GroupDataOP) generateGroupRawData() {
 					}
 				}
 			}
-		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP:
+		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_GROUP, RESOURCE_GROUP_TYPE_POD_GROUP:
 			for _, podGroupID := range groupIDToPodGroupIDs[resourceGroup.ID] {
 				if tips, ok := pgidToIPs[podGroupID]; ok {
 					ips = append(ips, tips...)
 				}
 			}
-		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_SERVICE:
+		case RESOURCE_GROUP_TYPE_ANONYMOUS_POD_SERVICE, RESOURCE_GROUP_TYPE_POD_SERVICE:
 			for _, podServiceID := range groupIDToPodServiceIDs[resourceGroup.ID] {
 				if podService, ok := rawData.idToPodService[podServiceID]; ok {
 					if podService.ServiceClusterIP == "" {
@@ -436,7 +465,7 @@ func (g *GroupDataOP) generateGroupRawData() {
 					}
 				}
 			}
-		case RESOURCE_GROUP_TYPE_ANONYMOUS_VL2:
+		case RESOURCE_GROUP_TYPE_ANONYMOUS_VL2, RESOURCE_GROUP_TYPE_VL2:
 			nets := []*models.Subnet{}
 			if resourceGroup.NetworkIDs != "" {
 				strNetworkIDs := strings.Split(resourceGroup.NetworkIDs, ",")
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/deepflowys/deepflow/server/controller/common"
+	models "github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/config"
+)
+
+func TestGetGroupIPs(t *testing.T) {
+	m := NewMetaData(nil, &config.Config{})
+	dbDataCache := newDBDataCache()
+	dbDataCache.resourceGroups = []*models.ResourceGroup{
+		{ID: 1, Type: RESOURCE_GROUP_TYPE_IP, IPs: "10.1.1.1,10.2.0.0/16,10.3.1.1-10.3.1.9,2001:db8::1"},
+		{ID: 2, Type: RESOURCE_GROUP_TYPE_VL2, NetworkIDs: "1"},
+		{ID: 3, Type: RESOURCE_GROUP_TYPE_POD_LABEL, PodClusterID: 1, PodLabels: "app:nginx"},
+	}
+	// pod 2 属于其他容器集群, pod 3 标签不匹配
+	dbDataCache.pods = []*models.Pod{
+		{Base: models.Base{ID: 1}, PodClusterID: 1, Label: "app:nginx,tier:web"},
+		{Base: models.Base{ID: 2}, PodClusterID: 2, Label: "app:nginx"},
+		{Base: models.Base{ID: 3}, PodClusterID: 1, Label: "app:redis"},
+	}
+	for id := 1; id <= 3; id++ {
+		dbDataCache.vInterfaces = append(dbDataCache.vInterfaces, &models.VInterface{
+			Base: models.Base{ID: id}, Type: VIF_TYPE_LAN, NetworkID: 1, DeviceType: VIF_DEVICE_TYPE_POD, DeviceID: id,
+		})
+	}
+	dbDataCache.lanIPs = []*models.LANIP{
+		{VInterfaceID: 1, IP: "10.0.0.1"},
+		{VInterfaceID: 2, IP: "10.0.0.2"},
+		{VInterfaceID: 3, IP: "10.0.0.3"},
+	}
+	m.updateDBDataCache(dbDataCache)
+	rawData := NewPlatformRawData()
+	// 0.0.0.0/32 为无效子网
+	rawData.networkIDToSubnets[1] = []*models.Subnet{
+		{Prefix: "192.168.1.0", Netmask: "255.255.255.0", NetworkID: 1},
+		{Prefix: "0.0.0.0", Netmask: "255.255.255.255", NetworkID: 1},
+	}
+	m.platformDataOP.updateRawData(rawData)
+	m.groupDataOP.generateGroupRawData()
+
+	cases := []struct {
+		groupID  int
+		cidrs    []string
+		ipRanges []string
+	}{
+		{1, []string{"10.1.1.1/32", "10.2.0.0/16", "2001:db8::1/128"}, []string{"10.3.1.1-10.3.1.9"}},
+		{2, []string{"192.168.1.0/24"}, nil},
+		{3, []string{"10.0.0.1/32"}, nil},
+		{4, nil, nil},
+	}
+	for _, c := range cases {
+		cidrs, ipRanges := m.groupDataOP.GetGroupIPs(c.groupID)
+		if !reflect.DeepEqual(cidrs, c.cidrs) || !reflect.DeepEqual(ipRanges, c.ipRanges) {
+			t.Errorf("GetGroupIPs(%d) = (%v, %v), expected (%v, %v)", c.groupID, cidrs, ipRanges, c.cidrs, c.ipRanges)
+		}
+	}
+}
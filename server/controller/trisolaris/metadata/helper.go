@@ -57,3 +57,29 @@ func ConvertToCIDR(ips []string) {
 		}
 	}
 }
+
+// parsePodLabels 解析容器标签, 格式如: app:nginx, tier:web
+func parsePodLabels(label string) map[string]string {
+	labels := make(map[string]string)
+	for _, item := range strings.Split(label, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels
+}
+
+// matchPodLabels 容器需匹配资源组的所有标签
+func matchPodLabels(podLabels map[string]string, selector map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if value, ok := podLabels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
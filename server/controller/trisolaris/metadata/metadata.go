@@ -146,6 +146,11 @@ func (m *MetaData) GetVTapPolicyVersion(vtapID int, functions mapset.Set) uint64
 	return m.policyDataOP.getVTapPolicyVersion(vtapID, functions)
 }
 
+// DiffPolicies 预览策略变更, 返回策略版本将变化的采集器ID及数据节点策略是否变化
+func (m *MetaData) DiffPolicies(vtapIDs []int, before, after *PolicySourceData) ([]int, bool) {
+	return m.policyDataOP.diffPolicies(vtapIDs, before, after)
+}
+
 func (m *MetaData) GetVTapPolicyString(vtapID int, functions mapset.Set) []byte {
 	return m.policyDataOP.getVTapPolicyString(vtapID, functions)
 }
@@ -360,24 +360,46 @@ func (op *PolicyDataOP) generatePolicyData() {
 	op.generatePolicies()
 }
 
-func (op *PolicyDataOP) generateRawData() {
-	dbDataCache := op.metaData.GetDBDataCache()
-
-	npbTunnels := dbDataCache.GetNpbTunnels()
-	acls := dbDataCache.GetACLs()
-	npbPolicies := dbDataCache.GetNpbPolicies()
-	pcapPolicies := dbDataCache.GetPcapPolicies()
+// PolicySourceData 生成策略所需的数据库数据, 预览策略变更时由调用方提供
+type PolicySourceData struct {
+	ACLs         []*models.ACL
+	NpbTunnels   []*models.NpbTunnel
+	NpbPolicies  []*models.NpbPolicy
+	PcapPolicies []*models.PcapPolicy
+}
+
+// enabled 仅保留已启用的ACL及策略, 与数据库缓存加载逻辑一致
+func (d *PolicySourceData) enabled() *PolicySourceData {
+	result := &PolicySourceData{NpbTunnels: d.NpbTunnels}
+	for _, acl := range d.ACLs {
+		if acl.State == ACL_STATE_ENABLE {
+			result.ACLs = append(result.ACLs, acl)
+		}
+	}
+	for _, npbPolicy := range d.NpbPolicies {
+		if npbPolicy.State == ACL_STATE_ENABLE {
+			result.NpbPolicies = append(result.NpbPolicies, npbPolicy)
+		}
+	}
+	for _, pcapPolicy := range d.PcapPolicies {
+		if pcapPolicy.State == ACL_STATE_ENABLE {
+			result.PcapPolicies = append(result.PcapPolicies, pcapPolicy)
+		}
+	}
+	return result
+}
 
+func newPolicyRawDataFromSource(data *PolicySourceData) *PolicyRawData {
 	rawData := newPolicyRawData()
-	for _, npbTunnel := range npbTunnels {
+	for _, npbTunnel := range data.NpbTunnels {
 		rawData.idToNpbTunnel[npbTunnel.ID] = npbTunnel
 	}
 
-	for _, acl := range acls {
+	for _, acl := range data.ACLs {
 		rawData.idToACL[acl.ID] = acl
 	}
 
-	for _, npbPolicy := range npbPolicies {
+	for _, npbPolicy := range data.NpbPolicies {
 		rawData.idToNpbPolicy[npbPolicy.ID] = npbPolicy
 		if _, ok := rawData.aclIDToNpbPolices[npbPolicy.ACLID]; ok {
 			rawData.aclIDToNpbPolices[npbPolicy.ACLID] = append(
@@ -387,7 +409,7 @@ func (op *PolicyDataOP) generateRawData() {
 		}
 	}
 
-	for _, pcapPolicy := range pcapPolicies {
+	for _, pcapPolicy := range data.PcapPolicies {
 		rawData.idToPcapPolicy[pcapPolicy.ID] = pcapPolicy
 		if _, ok := rawData.aclIDToPcapPolices[pcapPolicy.ACLID]; ok {
 			rawData.aclIDToPcapPolices[pcapPolicy.ACLID] = append(
@@ -396,7 +418,17 @@ func (op *PolicyDataOP) generateRawData() {
 			rawData.aclIDToPcapPolices[pcapPolicy.ACLID] = []*models.PcapPolicy{pcapPolicy}
 		}
 	}
+	return rawData
+}
 
+func (op *PolicyDataOP) generateRawData() {
+	dbDataCache := op.metaData.GetDBDataCache()
+	rawData := newPolicyRawDataFromSource(&PolicySourceData{
+		ACLs:         dbDataCache.GetACLs(),
+		NpbTunnels:   dbDataCache.GetNpbTunnels(),
+		NpbPolicies:  dbDataCache.GetNpbPolicies(),
+		PcapPolicies: dbDataCache.GetPcapPolicies(),
+	})
 	op.updateRawData(rawData)
 }
 
@@ -573,10 +605,9 @@ var (
 	tunnelTypePCAP = trident.TunnelType_PCAP
 )
 
-func (op *PolicyDataOP) generateProtoActions(acl *models.ACL) (map[int][]*trident.NpbAction, []*trident.NpbAction) {
+func (op *PolicyDataOP) generateProtoActions(acl *models.ACL, rawData *PolicyRawData) (map[int][]*trident.NpbAction, []*trident.NpbAction) {
 	vtapIDToNpbActions := make(map[int][]*trident.NpbAction)
 	allVTapNpbActions := []*trident.NpbAction{}
-	rawData := op.GetRawData()
 	appInt, err := strconv.Atoi(acl.Applications)
 	if err != nil {
 		log.Errorf("err: %s, applications: %s", err, acl.Applications)
@@ -656,13 +687,17 @@ func (op *PolicyDataOP) generateProtoActions(acl *models.ACL) (map[int][]*triden
 }
 
 func (op *PolicyDataOP) generatePolicies() {
+	dbDataCache := op.metaData.GetDBDataCache()
+	vtapIDToPolicy, allVTapSharePolicy, dropletPolicy := op.buildPolicies(dbDataCache.GetACLs(), op.GetRawData())
+	op.checkNewPolicies(vtapIDToPolicy, allVTapSharePolicy, dropletPolicy)
+}
+
+func (op *PolicyDataOP) buildPolicies(acls []*models.ACL, rawData *PolicyRawData) (map[int]*Policy, *Policy, *Policy) {
 	vtapIDToPolicy := make(map[int]*Policy)
 	allVTapSharePolicy := newPolicy(0, op.billingMethod)
 	dropletPolicy := newPolicy(-1, op.billingMethod)
-	rawData := op.GetRawData()
 
-	dbDataCache := op.metaData.GetDBDataCache()
-	for _, acl := range dbDataCache.GetACLs() {
+	for _, acl := range acls {
 		appInt, err := strconv.Atoi(acl.Applications)
 		if err != nil {
 			log.Error(err, acl.Applications)
@@ -713,7 +748,7 @@ func (op *PolicyDataOP) generatePolicies() {
 			DstGroupIds: groupIDs.dstGroupIDs,
 		}
 		protocol := op.generateProtoPorts(acl, flowACL, groupIDs)
-		vtapIDToNpbActions, allVTapNpbActions := op.generateProtoActions(acl)
+		vtapIDToNpbActions, allVTapNpbActions := op.generateProtoActions(acl, rawData)
 		if protocol == PROTOCOL_ALL && (acl.SrcPorts != "" || acl.DstPorts != "") {
 			// If the protocol is all and the port is filled in, it means that the protocol is tcp+udp,
 			// and if the protocol is empty and the port is empty, it means any
@@ -766,7 +801,47 @@ func (op *PolicyDataOP) generatePolicies() {
 		}
 	}
 
-	op.checkNewPolicies(vtapIDToPolicy, allVTapSharePolicy, dropletPolicy)
+	return vtapIDToPolicy, allVTapSharePolicy, dropletPolicy
+}
+
+func (p *Policy) dataEqual(other *Policy) bool {
+	return p.allDataHash == other.allDataHash &&
+		p.npbDataHash == other.npbDataHash &&
+		p.pcapDataHash == other.pcapDataHash
+}
+
+// buildSerializedPolicies 生成并序列化策略, 但不更新当前生效的策略
+func (op *PolicyDataOP) buildSerializedPolicies(data *PolicySourceData) (map[int]*Policy, *Policy, *Policy) {
+	data = data.enabled()
+	vtapIDToPolicy, allVTapSharePolicy, dropletPolicy := op.buildPolicies(data.ACLs, newPolicyRawDataFromSource(data))
+	allVTapSharePolicy.toSerializeString()
+	dropletPolicy.toSerializeString()
+	for _, vtapPolicy := range vtapIDToPolicy {
+		vtapPolicy.merger(allVTapSharePolicy)
+		vtapPolicy.toSerializeString()
+	}
+	return vtapIDToPolicy, allVTapSharePolicy, dropletPolicy
+}
+
+// diffPolicies 对比变更前后的策略, 返回策略版本将变化的采集器及数据节点策略是否变化
+func (op *PolicyDataOP) diffPolicies(vtapIDs []int, before, after *PolicySourceData) ([]int, bool) {
+	oldVTapIDToPolicy, oldAllVTapSharePolicy, oldDropletPolicy := op.buildSerializedPolicies(before)
+	newVTapIDToPolicy, newAllVTapSharePolicy, newDropletPolicy := op.buildSerializedPolicies(after)
+	changedVTapIDs := []int{}
+	for _, vtapID := range vtapIDs {
+		oldVTapPolicy, ok := oldVTapIDToPolicy[vtapID]
+		if !ok {
+			oldVTapPolicy = oldAllVTapSharePolicy
+		}
+		newVTapPolicy, ok := newVTapIDToPolicy[vtapID]
+		if !ok {
+			newVTapPolicy = newAllVTapSharePolicy
+		}
+		if !oldVTapPolicy.dataEqual(newVTapPolicy) {
+			changedVTapIDs = append(changedVTapIDs, vtapID)
+		}
+	}
+	return changedVTapIDs, !oldDropletPolicy.dataEqual(newDropletPolicy)
 }
 
 func getSortKey(vtapIDToPolicy map[int]*Policy) []int {
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	. "github.com/deepflowys/deepflow/server/controller/common"
+	models "github.com/deepflowys/deepflow/server/controller/db/mysql"
+	. "github.com/deepflowys/deepflow/server/controller/trisolaris/common"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/config"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	op := NewMetaData(nil, &config.Config{BillingMethod: BILLING_METHOD_LICENSE}).policyDataOP
+	vtapIDs := []int{1, 2, 3}
+	newSourceData := func() *PolicySourceData {
+		return &PolicySourceData{
+			ACLs: []*models.ACL{
+				{ID: 1, State: ACL_STATE_ENABLE, TapType: 3, Applications: strconv.Itoa(APPLICATION_NPB), DstPorts: "80"},
+				{ID: 2, State: ACL_STATE_ENABLE, TapType: 3, Applications: strconv.Itoa(APPLICATION_PCAP), Protocol: 6},
+			},
+			NpbTunnels: []*models.NpbTunnel{{ID: 1, IP: "10.0.0.1"}},
+			NpbPolicies: []*models.NpbPolicy{
+				{ID: 1, State: ACL_STATE_ENABLE, ACLID: 1, NpbTunnelID: 1, Vni: 100, VtapIDs: "1"},
+			},
+			PcapPolicies: []*models.PcapPolicy{
+				{ID: 1, State: ACL_STATE_ENABLE, ACLID: 2, PolicyACLGroupID: 1, VtapIDs: "2"},
+			},
+		}
+	}
+
+	cases := []struct {
+		name                  string
+		change                func(data *PolicySourceData)
+		changedVTapIDs        []int
+		analyzerPolicyChanged bool
+	}{{
+		name:           "unchanged",
+		change:         func(data *PolicySourceData) {},
+		changedVTapIDs: []int{},
+	}, {
+		name: "add npb acl for vtap 3",
+		change: func(data *PolicySourceData) {
+			data.ACLs = append(data.ACLs, &models.ACL{ID: 3, State: ACL_STATE_ENABLE, TapType: 3, Applications: strconv.Itoa(APPLICATION_NPB)})
+			data.NpbPolicies = append(data.NpbPolicies, &models.NpbPolicy{ID: 2, State: ACL_STATE_ENABLE, ACLID: 3, NpbTunnelID: 1, Vni: 200, VtapIDs: "3"})
+		},
+		changedVTapIDs: []int{3},
+	}, {
+		name: "add pcap acl for all vtaps",
+		change: func(data *PolicySourceData) {
+			data.ACLs = append(data.ACLs, &models.ACL{ID: 3, State: ACL_STATE_ENABLE, TapType: 3, Applications: strconv.Itoa(APPLICATION_PCAP)})
+			data.PcapPolicies = append(data.PcapPolicies, &models.PcapPolicy{ID: 2, State: ACL_STATE_ENABLE, ACLID: 3, PolicyACLGroupID: 2})
+		},
+		changedVTapIDs:        []int{1, 2, 3},
+		analyzerPolicyChanged: true,
+	}, {
+		name: "disable npb acl",
+		change: func(data *PolicySourceData) {
+			acl := *data.ACLs[0]
+			acl.State = ACL_STATE_DISABLE
+			data.ACLs[0] = &acl
+		},
+		changedVTapIDs: []int{1},
+	}, {
+		name: "delete pcap acl",
+		change: func(data *PolicySourceData) {
+			data.ACLs = data.ACLs[:1]
+		},
+		changedVTapIDs:        []int{2},
+		analyzerPolicyChanged: true,
+	}}
+	for _, c := range cases {
+		after := newSourceData()
+		c.change(after)
+		changedVTapIDs, analyzerPolicyChanged := op.diffPolicies(vtapIDs, newSourceData(), after)
+		if !reflect.DeepEqual(changedVTapIDs, c.changedVTapIDs) || analyzerPolicyChanged != c.analyzerPolicyChanged {
+			t.Errorf("%s: diffPolicies() = (%v, %v), expected (%v, %v)",
+				c.name, changedVTapIDs, analyzerPolicyChanged, c.changedVTapIDs, c.analyzerPolicyChanged)
+		}
+	}
+}
@@ -18,17 +18,34 @@ package synchronize
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"gorm.io/gorm"
 
 	api "github.com/deepflowys/deepflow/message/trident"
+	"github.com/deepflowys/deepflow/server/controller/common"
+	models "github.com/deepflowys/deepflow/server/controller/db/mysql"
 	"github.com/deepflowys/deepflow/server/controller/trisolaris"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/dbmgr"
 )
 
-type UpgradeEvent struct{}
+type UpgradeEvent struct {
+	// 升级包缓存, 分批升级时大量采集器同时下载同一升级包, 升级包仅加载及校验一次, 由所有升级流共享
+	sync.Mutex
+	packageToData map[string]*UpgradeData
+	// 上次检查缓存的时间, 升级包在仓库中删除或变化后, 由后续的升级请求淘汰其缓存
+	lastEvictTime time.Time
+}
+
+const UPGRADE_CACHE_EVICT_INTERVAL = time.Minute
 
 type UpgradeData struct {
 	content  []byte
@@ -36,10 +53,23 @@ type UpgradeData struct {
 	pktCount uint32
 	md5Sum   string
 	step     uint64
+
+	// 仓库升级包为sha256, 本地升级包为文件大小及修改时间, 变化时重新加载
+	version  string
+	arch     string
+	os       string
+	vtapType string
+	// 加载完成后关闭, 加载期间其他升级流等待加载结果
+	ready chan struct{}
+	err   error
 }
 
+type upgradeLoader func(data *UpgradeData) error
+
 func NewUpgradeEvent() *UpgradeEvent {
-	return &UpgradeEvent{}
+	return &UpgradeEvent{
+		packageToData: make(map[string]*UpgradeData),
+	}
 }
 
 func sendFailed(in api.Synchronizer_UpgradeServer) error {
@@ -53,26 +83,148 @@ func sendFailed(in api.Synchronizer_UpgradeServer) error {
 	return err
 }
 
-func (e *UpgradeEvent) GetUpgradeFile(upgradePackage string) (*UpgradeData, error) {
+// getPackageSource 优先使用升级包仓库中的升级包, 仅在配置允许时使用控制器本地路径
+func (e *UpgradeEvent) getPackageSource(upgradePackage string) (string, upgradeLoader, error) {
 	if upgradePackage == "" {
-		return nil, fmt.Errorf("upgradePackage(%s) file does not exist", upgradePackage)
+		return "", nil, fmt.Errorf("upgradePackage(%s) file does not exist", upgradePackage)
+	}
+	db := trisolaris.GetDB()
+	vtapRepo, err := dbmgr.DBMgr[models.VTapRepo](db).GetFromNameOmit(upgradePackage, "image")
+	if err == nil {
+		return "repo:" + vtapRepo.SHA256, func(data *UpgradeData) error {
+			return e.loadRepoPackage(vtapRepo.Lcuuid, data)
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil, fmt.Errorf("get vtap_repo(%s) failed, err: %s", upgradePackage, err)
+	}
+
+	if !trisolaris.GetConfig().AllowLocalUpgradePackage {
+		return "", nil, fmt.Errorf("upgradePackage(%s) not found in vtap_repo and local upgrade package is not allowed", upgradePackage)
+	}
+	fileInfo, err := os.Stat(upgradePackage)
+	if err != nil {
+		return "", nil, fmt.Errorf("trident(%s) file does not exist, err: %s", upgradePackage, err)
+	}
+	version := fmt.Sprintf("local:%d-%d", fileInfo.Size(), fileInfo.ModTime().UnixNano())
+	return version, func(data *UpgradeData) error {
+		return e.readUpgradeFile(upgradePackage, data)
+	}, nil
+}
+
+// GetUpgradeFile 获取升级包, 升级包未变化时使用缓存, 同一升级包的并发请求仅加载一次
+func (e *UpgradeEvent) GetUpgradeFile(upgradePackage string) (*UpgradeData, error) {
+	e.evictStalePackages(time.Now())
+	version, loader, err := e.getPackageSource(upgradePackage)
+	if err != nil {
+		e.Lock()
+		delete(e.packageToData, upgradePackage)
+		e.Unlock()
+		return nil, err
+	}
+
+	e.Lock()
+	data, ok := e.packageToData[upgradePackage]
+	if ok && data.version == version {
+		e.Unlock()
+		<-data.ready
+		return data, data.err
+	}
+	data = &UpgradeData{version: version, ready: make(chan struct{})}
+	e.packageToData[upgradePackage] = data
+	e.Unlock()
+
+	data.err = loader(data)
+	close(data.ready)
+	if data.err != nil {
+		e.Lock()
+		if e.packageToData[upgradePackage] == data {
+			delete(e.packageToData, upgradePackage)
+		}
+		e.Unlock()
+		return nil, data.err
+	}
+	log.Infof("load upgrade package(%s), version: %s, size: %d, md5: %s", upgradePackage, version, data.totalLen, data.md5Sum)
+	return data, nil
+}
+
+// evictStalePackages 淘汰已删除或已变化的升级包缓存, 避免删除的升级包一直占用内存,
+// 升级包可能在其他控制器上删除, 因此在查找时按间隔检查而非在删除时清理
+func (e *UpgradeEvent) evictStalePackages(now time.Time) {
+	e.Lock()
+	if now.Sub(e.lastEvictTime) < UPGRADE_CACHE_EVICT_INTERVAL {
+		e.Unlock()
+		return
 	}
+	e.lastEvictTime = now
+	cached := make(map[string]*UpgradeData, len(e.packageToData))
+	for upgradePackage, data := range e.packageToData {
+		cached[upgradePackage] = data
+	}
+	e.Unlock()
+
+	for upgradePackage, data := range cached {
+		version, _, err := e.getPackageSource(upgradePackage)
+		if err == nil && version == data.version {
+			continue
+		}
+		e.Lock()
+		if e.packageToData[upgradePackage] == data {
+			delete(e.packageToData, upgradePackage)
+			log.Infof("evict upgrade package(%s) cache, version: %s", upgradePackage, data.version)
+		}
+		e.Unlock()
+	}
+}
+
+// loadRepoPackage 从升级包仓库加载升级包, 校验SHA-256及签名后才可下发
+func (e *UpgradeEvent) loadRepoPackage(lcuuid string, data *UpgradeData) error {
+	vtapRepo, err := dbmgr.DBMgr[models.VTapRepo](trisolaris.GetDB()).GetFromLcuuid(lcuuid)
+	if err != nil {
+		return fmt.Errorf("get vtap_repo(%s) failed, err: %s", lcuuid, err)
+	}
+	err = common.VerifyAgentPackage(
+		vtapRepo.Image, vtapRepo.SHA256, vtapRepo.Signature, trisolaris.GetConfig().AgentPackagePublicKey,
+	)
+	if err != nil {
+		return fmt.Errorf("verify vtap_repo(%s) failed, err: %s", vtapRepo.Name, err)
+	}
+	data.arch = vtapRepo.Arch
+	data.os = vtapRepo.OS
+	data.vtapType = vtapRepo.VTapType
+	data.fillContent(vtapRepo.Image)
+	return nil
+}
+
+func (e *UpgradeEvent) readUpgradeFile(upgradePackage string, data *UpgradeData) error {
 	content, err := ioutil.ReadFile(upgradePackage)
 	if err != nil {
-		return nil, fmt.Errorf("trident(%s) file does not exist, err: %s", upgradePackage, err)
-	}
-	totalLen := uint64(len(content))
-	step := uint64(1024 * 1024)
-	pktCount := uint32(math.Ceil(float64(totalLen) / float64(step)))
-	cipherStr := md5.Sum(content)
-	md5Sum := fmt.Sprintf("%x", cipherStr)
-	return &UpgradeData{
-		content:  content,
-		totalLen: totalLen,
-		pktCount: pktCount,
-		md5Sum:   md5Sum,
-		step:     step,
-	}, err
+		return fmt.Errorf("trident(%s) file does not exist, err: %s", upgradePackage, err)
+	}
+	data.fillContent(content)
+	return nil
+}
+
+func (d *UpgradeData) fillContent(content []byte) {
+	d.content = content
+	d.totalLen = uint64(len(content))
+	d.step = uint64(1024 * 1024)
+	d.pktCount = uint32(math.Ceil(float64(d.totalLen) / float64(d.step)))
+	d.md5Sum = fmt.Sprintf("%x", md5.Sum(content))
+}
+
+// checkVTap 校验升级包是否适用于采集器, 采集器未上报的属性不校验
+func (d *UpgradeData) checkVTap(vtapArch, vtapOS string, vtapType int) error {
+	if d.arch != "" && vtapArch != "" && !strings.EqualFold(d.arch, vtapArch) {
+		return fmt.Errorf("upgrade package arch(%s) does not match vtap arch(%s)", d.arch, vtapArch)
+	}
+	if d.os != "" && vtapOS != "" && !strings.EqualFold(d.os, vtapOS) {
+		return fmt.Errorf("upgrade package os(%s) does not match vtap os(%s)", d.os, vtapOS)
+	}
+	if d.vtapType != "" && d.vtapType != common.VTapTypeName[vtapType] {
+		return fmt.Errorf("upgrade package vtap type(%s) does not match vtap type(%s)", d.vtapType, common.VTapTypeName[vtapType])
+	}
+	return nil
 }
 
 func (e *UpgradeEvent) Upgrade(r *api.UpgradeRequest, in api.Synchronizer_UpgradeServer) error {
@@ -89,6 +241,11 @@ func (e *UpgradeEvent) Upgrade(r *api.UpgradeRequest, in api.Synchronizer_Upgrad
 		log.Error(err)
 		return sendFailed(in)
 	}
+	err = upgradeData.checkVTap(vtapCache.GetArch(), vtapCache.GetOs(), vtapCache.GetVTapType())
+	if err != nil {
+		log.Errorf("vtap(%s), err:%s", vtapCacheKey, err)
+		return sendFailed(in)
+	}
 	for start := uint64(0); start < upgradeData.totalLen; start += upgradeData.step {
 		end := start + upgradeData.step
 		if end > upgradeData.totalLen {
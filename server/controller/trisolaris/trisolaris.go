@@ -54,6 +54,10 @@ func GetGKubernetesInfo() *kubernetes.KubernetesInfo {
 	return trisolaris.kubernetesInfo
 }
 
+func GetMetaData() *metadata.MetaData {
+	return trisolaris.metaData
+}
+
 func GetConfig() *config.Config {
 	return trisolaris.config
 }
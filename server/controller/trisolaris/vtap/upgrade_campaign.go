@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vtap
+
+import (
+	"strings"
+
+	"github.com/deepflowys/deepflow/server/controller/common"
+	models "github.com/deepflowys/deepflow/server/controller/db/mysql"
+	"github.com/deepflowys/deepflow/server/controller/trisolaris/dbmgr"
+)
+
+// GetRealRevision 采集器上报的revision格式为`<branch> <rev_count>-<commit_id>`, 预期版本仅包含后半部分
+func GetRealRevision(revision string) string {
+	splitStr := strings.Split(revision, " ")
+	if len(splitStr) == 2 {
+		return splitStr[1]
+	}
+	return revision
+}
+
+func (v *VTapInfo) loadUpgradeCampaignVTaps() {
+	campaignVTaps, err := dbmgr.DBMgr[models.VTapUpgradeCampaignVTap](v.db).GetBatchFromStates(
+		[]int{common.UPGRADE_CAMPAIGN_VTAP_STATE_UPGRADING, common.UPGRADE_CAMPAIGN_VTAP_STATE_ROLLING_BACK},
+	)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	v.upgradeCampaignVTaps = campaignVTaps
+}
+
+// applyUpgradeCampaigns 按升级任务设置采集器的预期版本及升级包,
+// 升级任务不再需要升级的采集器(升级超时、任务取消等)清除由升级任务设置的预期版本
+func (v *VTapInfo) applyUpgradeCampaigns() {
+	lcuuidToKey := make(map[string]string, len(v.vtaps))
+	for _, vtap := range v.vtaps {
+		lcuuidToKey[vtap.Lcuuid] = GetKey(vtap)
+	}
+
+	upgradeCampaignRevisions := make(map[string]string)
+	for _, campaignVTap := range v.upgradeCampaignVTaps {
+		key, ok := lcuuidToKey[campaignVTap.VTapLcuuid]
+		if !ok {
+			continue
+		}
+		vTapCache := v.GetVTapCache(key)
+		if vTapCache == nil {
+			continue
+		}
+		upgradeCampaignRevisions[key] = campaignVTap.ExpectedRevision
+		if GetRealRevision(vTapCache.GetRevision()) == campaignVTap.ExpectedRevision {
+			continue
+		}
+		if vTapCache.GetExpectedRevision() == campaignVTap.ExpectedRevision &&
+			vTapCache.GetUpgradePackage() == campaignVTap.UpgradePackage {
+			continue
+		}
+		vTapCache.UpdateUpgradeInfo(campaignVTap.ExpectedRevision, campaignVTap.UpgradePackage)
+		log.Infof(
+			"vtap(%s, %s) upgrade to revision(%s) by campaign(%s)",
+			vTapCache.GetVTapHost(), key, campaignVTap.ExpectedRevision, campaignVTap.CampaignLcuuid,
+		)
+	}
+
+	for key, revision := range v.upgradeCampaignRevisions {
+		if _, ok := upgradeCampaignRevisions[key]; ok {
+			continue
+		}
+		vTapCache := v.GetVTapCache(key)
+		if vTapCache != nil && vTapCache.GetExpectedRevision() == revision {
+			vTapCache.UpdateUpgradeInfo("", "")
+			log.Infof("vtap(%s, %s) stop upgrading to revision(%s)", vTapCache.GetVTapHost(), key, revision)
+		}
+	}
+	v.upgradeCampaignRevisions = upgradeCampaignRevisions
+}
@@ -104,6 +104,10 @@ type VTapInfo struct {
 	vTapIPs *atomic.Value // []*trident.VtapIp
 
 	localClusterID *string
+
+	// 升级任务中需要升级或回滚的采集器, 及由升级任务设置的预期版本(key: 采集器缓存key)
+	upgradeCampaignVTaps     []*models.VTapUpgradeCampaignVTap
+	upgradeCampaignRevisions map[string]string
 }
 
 func NewVTapInfo(db *gorm.DB, metaData *metadata.MetaData, cfg *config.Config) *VTapInfo {
@@ -140,6 +144,7 @@ func NewVTapInfo(db *gorm.DB, metaData *metadata.MetaData, cfg *config.Config) *
 		db:                             db,
 		config:                         cfg,
 		vTapIPs:                        &atomic.Value{},
+		upgradeCampaignRevisions:       make(map[string]string),
 	}
 }
 
@@ -390,6 +395,7 @@ func (v *VTapInfo) loadBaseData() {
 	v.loadRegion()
 	v.loadDefaultVTapGroup()
 	v.loadVTapGroup()
+	v.loadUpgradeCampaignVTaps()
 }
 
 func isBlank(value reflect.Value) bool {
@@ -670,6 +676,7 @@ func (v *VTapInfo) generateVTapIP() {
 func (v *VTapInfo) GenerateVTapCache() {
 	v.loadBaseData()
 	v.updateVTapInfo()
+	v.applyUpgradeCampaigns()
 	v.updateCacheToDB()
 	v.generateVTapIP()
 	v.generateLocalClusterID()
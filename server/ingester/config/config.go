@@ -53,6 +53,8 @@ const (
 	DefaultListenPort              = 20033
 	DefaultGrpcBufferSize          = 41943040
 	DefaultCKDBEndpointTCPPortName = "tcp-port"
+	DefaultCKWriterSpillDirectory  = "/var/lib/deepflow/ingester/ckwriter-spill"
+	DefaultCKWriterSpillMaxSize    = 1024 // MB
 )
 
 type CKDiskMonitor struct {
@@ -96,15 +98,24 @@ type CKWriterConfig struct {
 	FlushTimeout int `yaml:"flush-timeout"`
 }
 
+// CKWriterSpill 写clickhouse失败时, 将数据落盘并在clickhouse恢复后重放
+type CKWriterSpill struct {
+	Enabled   bool   `yaml:"enabled"`
+	Directory string `yaml:"directory"`
+	MaxSize   int    `yaml:"max-size"` // 每个表落盘占用的最大空间, 单位MB
+}
+
 type CKDB struct {
 	External            bool   `yaml:"external"`
 	Host                string `yaml:"host"`
 	ActualAddr          string
 	Watcher             *Watcher
-	Port                int    `yaml:"port"`
-	EndpointTCPPortName string `yaml:"endpoint-tcp-port-name"`
-	ClusterName         string `yaml:"cluster-name"`
-	StoragePolicy       string `yaml:"storage-policy"`
+	Port                int      `yaml:"port"`
+	EndpointTCPPortName string   `yaml:"endpoint-tcp-port-name"`
+	Discovery           string   `yaml:"discovery"`
+	Endpoints           []string `yaml:"endpoints"`
+	ClusterName         string   `yaml:"cluster-name"`
+	StoragePolicy       string   `yaml:"storage-policy"`
 }
 
 type Config struct {
@@ -121,10 +132,11 @@ type Config struct {
 	CKDiskMonitor         CKDiskMonitor   `yaml:"ck-disk-monitor"`
 	ColdStorage           CKDBColdStorage `yaml:"ckdb-cold-storage"`
 	ckdbColdStorages      map[string]*ckdb.ColdStorage
-	InfluxdbWriterEnabled bool     `yaml:"influxdb-writer-enabled"`
-	Influxdb              HostPort `yaml:"influxdb"`
-	NodeIP                string   `yaml:"node-ip"`
-	GrpcBufferSize        int      `yaml:"grpc-buffer-size"`
+	InfluxdbWriterEnabled bool          `yaml:"influxdb-writer-enabled"`
+	Influxdb              HostPort      `yaml:"influxdb"`
+	NodeIP                string        `yaml:"node-ip"`
+	GrpcBufferSize        int           `yaml:"grpc-buffer-size"`
+	CKWriterSpill         CKWriterSpill `yaml:"ckwriter-spill"`
 	LogFile               string
 	LogLevel              string
 }
@@ -161,18 +173,34 @@ func (c *Config) Validate() error {
 		c.NodeIP = nodeIP
 	}
 
+	if c.CKDB.Discovery == "" {
+		c.CKDB.Discovery = ckdb.DISCOVERY_KUBERNETES
+	}
+	if !ckdb.IsValidDiscovery(c.CKDB.Discovery) {
+		return fmt.Errorf("ckdb discovery(%s) is not supported", c.CKDB.Discovery)
+	}
+	isKubernetes := c.CKDB.Discovery == ckdb.DISCOVERY_KUBERNETES
+
+	// when not running in kubernetes, use hostname as node name and pod name, the same as controller
+	hostname, _ := os.Hostname()
 	myNodeName, exist := os.LookupEnv(EnvK8sNodeName)
 	if !exist {
-		log.Errorf("Can't get node name env %s", EnvK8sNodeName)
-		sleepAndExit()
+		if isKubernetes {
+			log.Errorf("Can't get node name env %s", EnvK8sNodeName)
+			sleepAndExit()
+		}
+		myNodeName = hostname
 	}
 	myPodName, exist := os.LookupEnv(EnvK8sPodName)
 	if !exist {
-		log.Errorf("Can't get pod name env %s", EnvK8sPodName)
-		sleepAndExit()
+		if isKubernetes {
+			log.Errorf("Can't get pod name env %s", EnvK8sPodName)
+			sleepAndExit()
+		}
+		myPodName = myNodeName
 	}
 	myNamespace, exist := os.LookupEnv(EnvK8sNamespace)
-	if !exist {
+	if !exist && isKubernetes {
 		log.Errorf("Can't get pod namespace env %s", EnvK8sNamespace)
 		sleepAndExit()
 	}
@@ -210,9 +238,9 @@ func (c *Config) Validate() error {
 			time.Sleep(time.Second * 30)
 		}
 		if watcher == nil {
-			watcher, err = NewWatcher(myNodeName, myPodName, myNamespace, c.CKDB.Host, c.CKDB.EndpointTCPPortName, c.CKDB.External, c.ControllerIPs, int(c.ControllerPort), c.GrpcBufferSize)
+			watcher, err = NewWatcher(myNodeName, myPodName, myNamespace, c.CKDB.Host, c.CKDB.EndpointTCPPortName, c.CKDB.Discovery, c.CKDB.Endpoints, c.CKDB.Port, c.CKDB.External, c.ControllerIPs, int(c.ControllerPort), c.GrpcBufferSize)
 			if err != nil {
-				log.Warningf("get kubernetes watcher failed %s", err)
+				log.Warningf("get clickhouse watcher failed %s", err)
 				continue
 			}
 		}
@@ -256,6 +284,13 @@ func (c *Config) Validate() error {
 		c.GrpcBufferSize = DefaultGrpcBufferSize
 	}
 
+	if c.CKWriterSpill.Directory == "" {
+		c.CKWriterSpill.Directory = DefaultCKWriterSpillDirectory
+	}
+	if c.CKWriterSpill.MaxSize <= 0 {
+		c.CKWriterSpill.MaxSize = DefaultCKWriterSpillMaxSize
+	}
+
 	return c.ValidateAndSetckdbColdStorages()
 }
 
@@ -326,6 +361,7 @@ func Load(path string) *Config {
 			Influxdb:          HostPort{DefaultInfluxdbHost, DefaultInfluxdbPort},
 			ListenPort:        DefaultListenPort,
 			GrpcBufferSize:    DefaultGrpcBufferSize,
+			CKWriterSpill:     CKWriterSpill{Directory: DefaultCKWriterSpillDirectory, MaxSize: DefaultCKWriterSpillMaxSize},
 		},
 	}
 	if err != nil {
@@ -26,6 +26,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
 	libs "github.com/deepflowys/deepflow/server/libs/kubernetes"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -44,6 +45,9 @@ type Watcher struct {
 	EndpointWatch                 libs.Watcher
 	clickhouseEndpointKey         string
 	clickhouseEndpointTCPPortName string
+	clickhouseDiscovery           string
+	clickhouseEndpoints           []string
+	clickhousePort                int
 	myNodeName                    string
 	myPodName                     string
 	clickhouseIsExternal          bool
@@ -52,25 +56,29 @@ type Watcher struct {
 	lastServerEndpointMap         map[string]Endpoint
 }
 
-func NewWatcher(myNodeName, myPodName, myPodNamespace, clickhouseEndpointKey, clickhouseEndpointTCPPortName string, clickhouseIsExternal bool, controllerIPs []string, controllerPort, grpcBufferSize int) (*Watcher, error) {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		errMsg := fmt.Errorf("get cluster config failed: %v", err)
-		log.Warning(errMsg)
-		return nil, errMsg
-	}
-	kubernetesClient, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		errMsg := fmt.Errorf("create kubernetes client failed: %v", err)
-		log.Warning(errMsg)
-		return nil, errMsg
-	}
+func NewWatcher(myNodeName, myPodName, myPodNamespace, clickhouseEndpointKey, clickhouseEndpointTCPPortName, clickhouseDiscovery string, clickhouseEndpoints []string, clickhousePort int, clickhouseIsExternal bool, controllerIPs []string, controllerPort, grpcBufferSize int) (*Watcher, error) {
+	// 非Kubernetes环境下通过静态配置或DNS获取ClickHouse地址，无需监听Endpoints
+	var endpointsWatcher libs.Watcher
+	if clickhouseDiscovery == ckdb.DISCOVERY_KUBERNETES {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			errMsg := fmt.Errorf("get cluster config failed: %v", err)
+			log.Warning(errMsg)
+			return nil, errMsg
+		}
+		kubernetesClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			errMsg := fmt.Errorf("create kubernetes client failed: %v", err)
+			log.Warning(errMsg)
+			return nil, errMsg
+		}
 
-	endpointsWatcher, err := libs.StartCoreV1EndpointsWatcher(context.Background(), libs.NewKubernetesWatchClient(kubernetesClient), myPodNamespace)
-	if err != nil {
-		errMsg := fmt.Errorf("create endpoints watcher failed: %v", err)
-		log.Warning(errMsg)
-		return nil, errMsg
+		endpointsWatcher, err = libs.StartCoreV1EndpointsWatcher(context.Background(), libs.NewKubernetesWatchClient(kubernetesClient), myPodNamespace)
+		if err != nil {
+			errMsg := fmt.Errorf("create endpoints watcher failed: %v", err)
+			log.Warning(errMsg)
+			return nil, errMsg
+		}
 	}
 
 	controllers := make([]net.IP, len(controllerIPs))
@@ -87,6 +95,9 @@ func NewWatcher(myNodeName, myPodName, myPodNamespace, clickhouseEndpointKey, cl
 		EndpointWatch:                 endpointsWatcher,
 		clickhouseEndpointKey:         clickhouseEndpointKey,
 		clickhouseEndpointTCPPortName: clickhouseEndpointTCPPortName,
+		clickhouseDiscovery:           clickhouseDiscovery,
+		clickhouseEndpoints:           clickhouseEndpoints,
+		clickhousePort:                clickhousePort,
 		myNodeName:                    myNodeName,
 		myPodName:                     myPodName,
 		clickhouseIsExternal:          clickhouseIsExternal,
@@ -134,6 +145,7 @@ func indexOf(ss []string, s string) int {
 // 1, Get a list of all 'deepflow-server' pods, and sort by name to find the 'index' of myself pod in it
 // 2. Get the list and total 'len' of all clickhouse endpoints, and sort by IP
 // 3, my corresponding 'clickhouse endpoint' is on position 'index%len'  in the 'clickhouse endpoints list'
+// When clickhouse is not discovered through kubernetes, the node of each endpoint is unknown, the same way is used.
 func (w *Watcher) getMyClickhouseEndpointExternal() (*Endpoint, error) {
 	podNames, err := w.getPodNames()
 	if err != nil {
@@ -174,7 +186,7 @@ func (w *Watcher) getMyClickhouseEndpointInternal() (*Endpoint, error) {
 }
 
 func (w *Watcher) GetMyClickhouseEndpoint() (*Endpoint, error) {
-	if w.clickhouseIsExternal {
+	if w.clickhouseIsExternal || w.clickhouseDiscovery != ckdb.DISCOVERY_KUBERNETES {
 		return w.getMyClickhouseEndpointExternal()
 	} else {
 		return w.getMyClickhouseEndpointInternal()
@@ -253,6 +265,9 @@ func (w *Watcher) getPodNames() ([]string, error) {
 }
 
 func (w *Watcher) getNodeEndpoints() (map[string][]Endpoint, error) {
+	if w.clickhouseDiscovery != ckdb.DISCOVERY_KUBERNETES {
+		return w.resolveNodeEndpoints()
+	}
 	for i := 0; i < TIMEOUT; i++ {
 		entries := w.EndpointWatch.Entries()
 		nodeEndpoints := make(map[string][]Endpoint)
@@ -297,6 +312,23 @@ func (w *Watcher) getNodeEndpoints() (map[string][]Endpoint, error) {
 	return nil, fmt.Errorf("get endpoint(%s) empty, timeout is %d", w.clickhouseEndpointKey, TIMEOUT)
 }
 
+// resolveNodeEndpoints get clickhouse endpoints by static config or dns, all endpoints are put under an empty node name
+func (w *Watcher) resolveNodeEndpoints() (map[string][]Endpoint, error) {
+	resolved, err := ckdb.ResolveEndpoints(w.clickhouseDiscovery, w.clickhouseEndpoints, w.clickhouseEndpointKey, w.clickhousePort)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("get endpoint(%s) empty, discovery is %s", w.clickhouseEndpointKey, w.clickhouseDiscovery)
+	}
+	endpoints := make([]Endpoint, 0, len(resolved))
+	for _, e := range resolved {
+		endpoints = append(endpoints, Endpoint{e.Host, e.Port})
+	}
+	log.Debugf("get endpoints %+v", endpoints)
+	return map[string][]Endpoint{"": endpoints}, nil
+}
+
 func (w *Watcher) getEndpoints() ([]Endpoint, error) {
 	nodeEndpoints, err := w.getNodeEndpoints()
 	if err != nil {
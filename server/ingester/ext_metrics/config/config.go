@@ -29,9 +29,12 @@ import (
 var log = logging.MustGetLogger("ext_metrics.config")
 
 const (
-	DefaultDecoderQueueCount = 2
-	DefaultDecoderQueueSize  = 100000
-	DefaultExtMetricsTTL     = 7
+	DefaultDecoderQueueCount      = 2
+	DefaultDecoderQueueSize       = 100000
+	DefaultExtMetricsTTL          = 7
+	DefaultRemoteWritePort        = 20107
+	DefaultRemoteWriteMaxBodySize = 16 << 20
+	DefaultAppLogTTL              = 3
 )
 
 type Config struct {
@@ -40,6 +43,15 @@ type Config struct {
 	DecoderQueueCount int                   `yaml:"decoder-queue-count"`
 	DecoderQueueSize  int                   `yaml:"decoder-queue-size"`
 	TTL               int                   `yaml:"ext-metrics-ttl"`
+	RemoteWrite       RemoteWriteConfig     `yaml:"prometheus-remote-write"`
+	AppLogTTL         int                   `yaml:"application-log-ttl"`
+}
+
+// RemoteWriteConfig 直接接收prometheus remote_write请求的http服务配置
+type RemoteWriteConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	ListenPort  int  `yaml:"listen-port"`
+	MaxBodySize int  `yaml:"max-body-size"`
 }
 
 type ExtMetricsConfig struct {
@@ -53,6 +65,15 @@ func (c *Config) Validate() error {
 	if c.TTL <= 0 {
 		c.TTL = DefaultExtMetricsTTL
 	}
+	if c.AppLogTTL <= 0 {
+		c.AppLogTTL = DefaultAppLogTTL
+	}
+	if c.RemoteWrite.ListenPort == 0 {
+		c.RemoteWrite.ListenPort = DefaultRemoteWritePort
+	}
+	if c.RemoteWrite.MaxBodySize <= 0 {
+		c.RemoteWrite.MaxBodySize = DefaultRemoteWriteMaxBodySize
+	}
 
 	return nil
 }
@@ -65,6 +86,8 @@ func Load(base *config.Config, path string) *Config {
 			DecoderQueueSize:  DefaultDecoderQueueSize,
 			CKWriterConfig:    config.CKWriterConfig{QueueCount: 1, QueueSize: 100000, BatchSize: 51200, FlushTimeout: 10},
 			TTL:               DefaultExtMetricsTTL,
+			RemoteWrite:       RemoteWriteConfig{Enabled: false, ListenPort: DefaultRemoteWritePort, MaxBodySize: DefaultRemoteWriteMaxBodySize},
+			AppLogTTL:         DefaultAppLogTTL,
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
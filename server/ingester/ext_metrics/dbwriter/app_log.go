@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbwriter
+
+import (
+	"sync/atomic"
+
+	"github.com/deepflowys/deepflow/server/ingester/common"
+	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/config"
+	"github.com/deepflowys/deepflow/server/ingester/pkg/ckwriter"
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
+	"github.com/deepflowys/deepflow/server/libs/pool"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+	"github.com/deepflowys/deepflow/server/libs/zerodoc"
+)
+
+const (
+	APPLICATION_LOG_DB    = "application_log"
+	APPLICATION_LOG_TABLE = "log"
+)
+
+// AppLog 应用日志(如OTLP logs), 通用标签与ext_metrics一致
+type AppLog struct {
+	Timestamp uint32 // s
+	Time      int64  // us
+
+	Tag zerodoc.Tag
+
+	TraceID        string
+	SpanID         string
+	SeverityNumber uint8
+	SeverityText   string
+	ServiceName    string
+	Body           string
+
+	AttributeNames  []string
+	AttributeValues []string
+}
+
+func AppLogColumns() []*ckdb.Column {
+	columns := zerodoc.GenTagColumns(EXT_METRICS_TAG_CODE)
+	columns = append(columns,
+		ckdb.NewColumn("log_time", ckdb.DateTime64us).SetComment("精度: 微秒"),
+		ckdb.NewColumn("trace_id", ckdb.String).SetIndex(ckdb.IndexBloomfilter),
+		ckdb.NewColumn("span_id", ckdb.String).SetIndex(ckdb.IndexNone),
+		ckdb.NewColumn("severity_number", ckdb.UInt8).SetIndex(ckdb.IndexSet),
+		ckdb.NewColumn("severity_text", ckdb.LowCardinalityString),
+		ckdb.NewColumn("service_name", ckdb.LowCardinalityString),
+		ckdb.NewColumn("body", ckdb.String).SetIndex(ckdb.IndexNone),
+		ckdb.NewColumn("attribute_names", ckdb.ArrayString).SetComment("额外的属性"),
+		ckdb.NewColumn("attribute_values", ckdb.ArrayString).SetComment("额外的属性对应的值"),
+	)
+	return columns
+}
+
+func (l *AppLog) WriteBlock(block *ckdb.Block) error {
+	if err := l.Tag.WriteBlock(block, l.Timestamp); err != nil {
+		return err
+	}
+	if err := block.WriteInt64(l.Time); err != nil {
+		return err
+	}
+	if err := block.WriteString(l.TraceID); err != nil {
+		return err
+	}
+	if err := block.WriteString(l.SpanID); err != nil {
+		return err
+	}
+	if err := block.WriteUInt8(l.SeverityNumber); err != nil {
+		return err
+	}
+	if err := block.WriteString(l.SeverityText); err != nil {
+		return err
+	}
+	if err := block.WriteString(l.ServiceName); err != nil {
+		return err
+	}
+	if err := block.WriteString(l.Body); err != nil {
+		return err
+	}
+	if err := block.WriteArrayString(l.AttributeNames); err != nil {
+		return err
+	}
+	if err := block.WriteArrayString(l.AttributeValues); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *AppLog) Release() {
+	ReleaseAppLog(l)
+}
+
+var appLogPool = pool.NewLockFreePool(func() interface{} {
+	return &AppLog{
+		Tag: zerodoc.Tag{
+			Field: &zerodoc.Field{},
+		},
+	}
+})
+
+func AcquireAppLog() *AppLog {
+	return appLogPool.Get().(*AppLog)
+}
+
+func ReleaseAppLog(l *AppLog) {
+	field := l.Tag.Field
+	*field = zerodoc.Field{}
+	names, values := l.AttributeNames[:0], l.AttributeValues[:0]
+	*l = AppLog{}
+	l.Tag.Field = field
+	l.AttributeNames, l.AttributeValues = names, values
+	appLogPool.Put(l)
+}
+
+type AppLogCounter struct {
+	LogCount int64 `statsd:"log-count"`
+}
+
+type AppLogWriter struct {
+	ckwriter *ckwriter.CKWriter
+
+	counter *AppLogCounter
+	utils.Closable
+}
+
+func NewAppLogWriter(config *config.Config) (*AppLogWriter, error) {
+	table := &ckdb.Table{
+		Database:        APPLICATION_LOG_DB,
+		LocalName:       APPLICATION_LOG_TABLE + ckdb.LOCAL_SUBFFIX,
+		GlobalName:      APPLICATION_LOG_TABLE,
+		Columns:         AppLogColumns(),
+		TimeKey:         "time",
+		TTL:             config.AppLogTTL,
+		PartitionFunc:   ckdb.TimeFuncHour,
+		Engine:          ckdb.MergeTree,
+		Cluster:         config.Base.CKDB.ClusterName,
+		StoragePolicy:   config.Base.CKDB.StoragePolicy,
+		ColdStorage:     *ckdb.GetColdStorage(config.Base.GetCKDBColdStorages(), APPLICATION_LOG_DB, APPLICATION_LOG_TABLE),
+		OrderKeys:       []string{"service_name", "l3_epc_id", "ip4", "ip6", "time"},
+		PrimaryKeyCount: 5,
+	}
+	ckwriter, err := ckwriter.NewCKWriter(config.Base.CKDB.ActualAddr, "", config.Base.CKDBAuth.Username, config.Base.CKDBAuth.Password,
+		APPLICATION_LOG_DB, table, false, config.CKWriterConfig.QueueCount, config.CKWriterConfig.QueueSize, config.CKWriterConfig.BatchSize, config.CKWriterConfig.FlushTimeout)
+	if err != nil {
+		return nil, err
+	}
+	ckwriter.Run()
+	w := &AppLogWriter{
+		ckwriter: ckwriter,
+		counter:  &AppLogCounter{},
+	}
+	common.RegisterCountableForIngester("application_log_writer", w)
+	return w, nil
+}
+
+func (w *AppLogWriter) GetCounter() interface{} {
+	counter := &AppLogCounter{}
+	counter.LogCount = atomic.SwapInt64(&w.counter.LogCount, 0)
+	return counter
+}
+
+func (w *AppLogWriter) Write(l *AppLog) {
+	atomic.AddInt64(&w.counter.LogCount, 1)
+	w.ckwriter.Put(l)
+}
+
+func (w *AppLogWriter) Close() {
+	w.Closable.Close()
+	w.ckwriter.Close()
+}
@@ -25,6 +25,9 @@ import (
 
 const (
 	DefaultPartition = ckdb.TimeFuncTwelveHour
+
+	// ext_metrics及应用日志中的通用标签
+	EXT_METRICS_TAG_CODE = zerodoc.AZID | zerodoc.HostID | zerodoc.IP | zerodoc.L3Device | zerodoc.L3EpcID | zerodoc.PodClusterID | zerodoc.PodGroupID | zerodoc.PodID | zerodoc.PodNodeID | zerodoc.PodNSID | zerodoc.RegionID | zerodoc.SubnetID | zerodoc.VTAPID | zerodoc.ServiceID | zerodoc.Resource
 )
 
 type ExtMetrics struct {
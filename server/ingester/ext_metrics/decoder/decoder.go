@@ -54,6 +54,8 @@ const (
 	PROMETHEUS_INSTANCE     = "instance"
 	TABLE_PREFIX_TELEGRAF   = "influxdb."
 	TABLE_PREFIX_PROMETHEUS = "prometheus."
+	// exemplar单独写入prometheus_exemplar.<metric_name>，避免影响原指标的聚合计算
+	TABLE_PREFIX_PROMETHEUS_EXEMPLAR = "prometheus_exemplar."
 )
 
 type Counter struct {
@@ -62,6 +64,8 @@ type Counter struct {
 	ErrorCount             int64 `statsd:"err-count"`
 	ErrMetrics             int64 `statsd:"err-metrics"`
 	DropUnsupportedMetrics int64 `statsd:"drop-unsupported-metrics"`
+	ExemplarCount          int64 `statsd:"exemplar-count"`
+	MetadataCount          int64 `statsd:"metadata-count"`
 }
 
 type Decoder struct {
@@ -70,6 +74,7 @@ type Decoder struct {
 	platformData     *grpc.PlatformInfoTable
 	inQueue          queue.QueueReader
 	extMetricsWriter *dbwriter.ExtMetricsWriter
+	appLogWriter     *dbwriter.AppLogWriter
 	debugEnabled     bool
 	config           *config.Config
 
@@ -82,6 +87,7 @@ func NewDecoder(
 	platformData *grpc.PlatformInfoTable,
 	inQueue queue.QueueReader,
 	extMetricsWriter *dbwriter.ExtMetricsWriter,
+	appLogWriter *dbwriter.AppLogWriter,
 	config *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -91,6 +97,7 @@ func NewDecoder(
 		inQueue:          inQueue,
 		debugEnabled:     log.IsEnabledFor(logging.DEBUG),
 		extMetricsWriter: extMetricsWriter,
+		appLogWriter:     appLogWriter,
 		config:           config,
 		counter:          &Counter{},
 	}
@@ -128,6 +135,10 @@ func (d *Decoder) Run() {
 				d.handlePrometheus(recvBytes.VtapID, decoder)
 			} else if d.msgType == datatype.MESSAGE_TYPE_DFSTATS {
 				d.handleDeepflowStats(recvBytes.VtapID, decoder)
+			} else if d.msgType == datatype.MESSAGE_TYPE_OTEL_METRICS {
+				d.handleOTelMetrics(recvBytes.VtapID, recvBytes.IP, decoder)
+			} else if d.msgType == datatype.MESSAGE_TYPE_OTEL_LOGS {
+				d.handleOTelLogs(recvBytes.VtapID, recvBytes.IP, decoder)
 			}
 			receiver.ReleaseRecvBuffer(recvBytes)
 		}
@@ -150,10 +161,18 @@ func (d *Decoder) handlePrometheus(vtapID uint16, decoder *codec.SimpleDecoder)
 				log.Warningf("prometheus parse failed, err msg:%s", err)
 			}
 			d.counter.ErrorCount++
+			continue
 		}
 
-		for _, ts := range req.Timeseries {
-			d.sendPrometheus(vtapID, &ts)
+		for i := range req.Timeseries {
+			d.sendPrometheus(vtapID, &req.Timeseries[i])
+		}
+		// ext_metrics中没有存储metadata(type/help/unit)的位置，仅做统计
+		if len(req.Metadata) > 0 {
+			d.counter.MetadataCount += int64(len(req.Metadata))
+			if d.debugEnabled {
+				log.Debugf("decoder %d vtap %d recv prometheus metadata: %v", d.index, vtapID, req.Metadata)
+			}
 		}
 	}
 }
@@ -258,7 +277,7 @@ func StatsToExtMetrics(vtapID uint16, s *pb.Stats) *dbwriter.ExtMetrics {
 }
 
 func (d *Decoder) TimeSeriesToExtMetrics(vtapID uint16, ts *prompb.TimeSeries) ([]*dbwriter.ExtMetrics, error) {
-	ms := make([]*dbwriter.ExtMetrics, 0, len(ts.Samples))
+	ms := make([]*dbwriter.ExtMetrics, 0, len(ts.Samples)+len(ts.Exemplars))
 
 	metricNameLabel, podName, instance := "", "", ""
 	tagNames := make([]string, 0, len(ts.Labels))
@@ -303,12 +322,46 @@ func (d *Decoder) TimeSeriesToExtMetrics(vtapID uint16, ts *prompb.TimeSeries) (
 		d.fillExtMetricsBase(m, vtapID, podName, instance, false)
 		ms = append(ms, m)
 	}
+
+	// exemplar的标签(如trace_id)追加在时间序列的标签之后
+	for _, e := range ts.Exemplars {
+		v := float64(e.Value)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		m := dbwriter.AcquireExtMetrics()
+
+		m.Timestamp = uint32(model.Time(e.Timestamp).Unix())
+		m.Database = dbwriter.EXT_METRICS_DB
+		m.TableName = dbwriter.EXT_METRICS_TABLE
+		m.VirtualTableName = TABLE_PREFIX_PROMETHEUS_EXEMPLAR + metricNameLabel
+
+		m.TagNames = make([]string, 0, len(tagNames)+len(e.Labels))
+		m.TagValues = make([]string, 0, len(tagValues)+len(e.Labels))
+		m.TagNames = append(m.TagNames, tagNames...)
+		m.TagValues = append(m.TagValues, tagValues...)
+		for _, l := range e.Labels {
+			m.TagNames = append(m.TagNames, l.Name)
+			m.TagValues = append(m.TagValues, l.Value)
+		}
+
+		m.MetricsFloatNames = append(m.MetricsFloatNames, metricNameLabel)
+		m.MetricsFloatValues = append(m.MetricsFloatValues, v)
+
+		d.fillExtMetricsBase(m, vtapID, podName, instance, false)
+		ms = append(ms, m)
+		d.counter.ExemplarCount++
+	}
 	return ms, nil
 }
 
 func (d *Decoder) fillExtMetricsBase(m *dbwriter.ExtMetrics, vtapID uint16, podName, instance string, fillWithVtapId bool) {
-	t := &m.Tag
-	t.Code = zerodoc.AZID | zerodoc.HostID | zerodoc.IP | zerodoc.L3Device | zerodoc.L3EpcID | zerodoc.PodClusterID | zerodoc.PodGroupID | zerodoc.PodID | zerodoc.PodNodeID | zerodoc.PodNSID | zerodoc.RegionID | zerodoc.SubnetID | zerodoc.VTAPID | zerodoc.ServiceID | zerodoc.Resource
+	d.fillUniversalTag(&m.Tag, vtapID, podName, instance, fillWithVtapId)
+}
+
+// fillUniversalTag 根据pod名或instance(ip)查询平台信息, 填充通用标签
+func (d *Decoder) fillUniversalTag(t *zerodoc.Tag, vtapID uint16, podName, instance string, fillWithVtapId bool) {
+	t.Code = dbwriter.EXT_METRICS_TAG_CODE
 	t.VTAPID = vtapID
 	t.GlobalThreadID = uint8(vtapID)
 	t.L3EpcID = datatype.EPC_FROM_INTERNET
@@ -322,8 +375,17 @@ func (d *Decoder) fillExtMetricsBase(m *dbwriter.ExtMetrics, vtapID uint16, podN
 			ip = net.ParseIP(podInfo.Ip)
 		}
 	} else if instance != "" {
-		t.L3EpcID = int16(d.platformData.QueryVtapEpc0(uint32(vtapID)))
 		ip = parseIPFromInstance(instance)
+		if vtapID == 0 && ip != nil {
+			// 非采集器发送的数据(如直接发送至ingester的OTLP数据), 使用ip查询epc
+			if ip4 := ip.To4(); ip4 != nil {
+				t.L3EpcID = int16(d.platformData.QueryIPEpc(true, utils.IpToUint32(ip4), nil))
+			} else {
+				t.L3EpcID = int16(d.platformData.QueryIPEpc(false, 0, ip))
+			}
+		} else {
+			t.L3EpcID = int16(d.platformData.QueryVtapEpc0(uint32(vtapID)))
+		}
 	} else if fillWithVtapId {
 		t.L3EpcID = int16(d.platformData.QueryVtapEpc0(uint32(vtapID)))
 		vtapInfo := d.platformData.QueryVtapInfo(uint32(vtapID))
@@ -380,8 +442,11 @@ func (d *Decoder) fillExtMetricsBase(m *dbwriter.ExtMetrics, vtapID uint16, podN
 	}
 }
 
-// parse ip from "192.168.0.1:22" or "[2001:db8::68]:22"
+// parse ip from "192.168.0.1:22" or "[2001:db8::68]:22", or a bare ip
 func parseIPFromInstance(instance string) net.IP {
+	if ip := net.ParseIP(instance); ip != nil {
+		return ip
+	}
 	var ipPart string
 	index := strings.LastIndex(instance, ":")
 	if index < 0 {
@@ -389,7 +454,7 @@ func parseIPFromInstance(instance string) net.IP {
 	} else {
 		ipPart = instance[:index]
 	}
-	if ipPart[0] == '[' && ipPart[len(ipPart)-1] == ']' {
+	if len(ipPart) > 1 && ipPart[0] == '[' && ipPart[len(ipPart)-1] == ']' {
 		ipPart = ipPart[1 : len(ipPart)-1]
 	}
 
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/deepflowys/deepflow/server/libs/codec"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+	"github.com/deepflowys/deepflow/server/libs/grpc"
+)
+
+func TestHandlePrometheusMetadata(t *testing.T) {
+	req := &prompb.WriteRequest{Metadata: []prompb.MetricMetadata{
+		{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "http_requests_total", Help: "requests"},
+		{Type: prompb.MetricMetadata_GAUGE, MetricFamilyName: "up"},
+	}}
+	data, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := &codec.SimpleEncoder{}
+	encoder.WriteBytes(snappy.Encode(nil, data))
+	decoder := &codec.SimpleDecoder{}
+	decoder.Init(encoder.Bytes())
+
+	d := NewDecoder(0, datatype.MESSAGE_TYPE_PROMETHEUS, grpc.NewPlatformInfoTable(nil, 0, 0, "", "", "", nil), nil, nil, nil, nil)
+	d.handlePrometheus(0, decoder)
+	// ext_metrics不存储metadata, 仅统计
+	if d.counter.MetadataCount != 2 || d.counter.ErrorCount != 0 {
+		t.Errorf("counter = %+v", d.counter)
+	}
+}
@@ -0,0 +1,410 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"encoding/hex"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/dbwriter"
+	"github.com/deepflowys/deepflow/server/libs/codec"
+	"github.com/deepflowys/deepflow/server/libs/zerodoc"
+)
+
+const (
+	TABLE_PREFIX_OTEL = "otel."
+
+	OTEL_SERVICE_NAME = "service.name"
+	OTEL_POD_NAME     = "k8s.pod.name"
+
+	OTEL_SUFFIX_COUNT   = "_count"
+	OTEL_SUFFIX_SUM     = "_sum"
+	OTEL_SUFFIX_BUCKET  = "_bucket"
+	OTEL_LABEL_LE       = "le"
+	OTEL_LABEL_QUANTILE = "quantile"
+)
+
+// 资源属性中可用于查询通用标签的ip, 按优先级排列
+var otelHostIPAttributes = []string{"k8s.pod.ip", "host.ip", "net.host.ip", "app.host.ip"}
+
+func (d *Decoder) handleOTelMetrics(vtapID uint16, srcIP net.IP, decoder *codec.SimpleDecoder) {
+	metricsData := &metricsv1.MetricsData{}
+	for !decoder.IsEnd() {
+		bytes := decoder.ReadBytes()
+		if decoder.Failed() {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("OpenTelemetry metrics decode failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		metricsData.Reset()
+		if err := proto.Unmarshal(bytes, metricsData); err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Warningf("OpenTelemetry metrics parse failed, err msg: %s", err)
+			}
+			d.counter.ErrorCount++
+			continue
+		}
+		if d.debugEnabled {
+			log.Debugf("decoder %d vtap %d recv otel metrics: %s", d.index, vtapID, metricsData)
+		}
+		for _, m := range d.OTelMetricsToExtMetrics(vtapID, srcIP, metricsData) {
+			d.extMetricsWriter.Write(m)
+			d.counter.OutCount++
+		}
+	}
+}
+
+func (d *Decoder) handleOTelLogs(vtapID uint16, srcIP net.IP, decoder *codec.SimpleDecoder) {
+	logsData := &logsv1.LogsData{}
+	for !decoder.IsEnd() {
+		bytes := decoder.ReadBytes()
+		if decoder.Failed() {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("OpenTelemetry logs decode failed, offset=%d len=%d", decoder.Offset(), len(decoder.Bytes()))
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		logsData.Reset()
+		if err := proto.Unmarshal(bytes, logsData); err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Warningf("OpenTelemetry logs parse failed, err msg: %s", err)
+			}
+			d.counter.ErrorCount++
+			continue
+		}
+		if d.debugEnabled {
+			log.Debugf("decoder %d vtap %d recv otel logs: %s", d.index, vtapID, logsData)
+		}
+		for _, l := range d.OTelLogsToAppLogs(vtapID, srcIP, logsData) {
+			d.appLogWriter.Write(l)
+			d.counter.OutCount++
+		}
+	}
+}
+
+// otelResource 为同一资源下所有数据共用的标签
+type otelResource struct {
+	tag         zerodoc.Tag
+	serviceName string
+	tagNames    []string
+	tagValues   []string
+}
+
+func (d *Decoder) newOTelResource(vtapID uint16, srcIP net.IP, attributes []*v11.KeyValue, normalizeName bool) *otelResource {
+	r := &otelResource{tag: zerodoc.Tag{Field: &zerodoc.Field{}}}
+	podName, instance := "", ""
+	hostIPs := make(map[string]string)
+	for _, attr := range attributes {
+		value := anyValueToString(attr.GetValue())
+		switch attr.Key {
+		case OTEL_SERVICE_NAME:
+			r.serviceName = value
+		case OTEL_POD_NAME:
+			podName = value
+		}
+		hostIPs[attr.Key] = value
+		name := attr.Key
+		if normalizeName {
+			name = otelNameToPrometheus(name)
+		}
+		r.tagNames = append(r.tagNames, name)
+		r.tagValues = append(r.tagValues, value)
+	}
+	for _, key := range otelHostIPAttributes {
+		if ip, ok := hostIPs[key]; ok && ip != "" {
+			instance = ip
+			break
+		}
+	}
+	// 没有采集器及资源属性中的ip时, 使用发送方的ip
+	if instance == "" && vtapID == 0 && srcIP != nil {
+		instance = srcIP.String()
+	}
+	d.fillUniversalTag(&r.tag, vtapID, podName, instance, vtapID != 0)
+	return r
+}
+
+func (r *otelResource) fillTag(t *zerodoc.Tag) {
+	*t.Field = *r.tag.Field
+	t.Code = r.tag.Code
+}
+
+// OTelMetricsToExtMetrics 参考prometheus的命名方式, 将OTLP指标转换为ext_metrics:
+//   - gauge, sum: otel.<name>
+//   - histogram, exponential histogram: otel.<name>_bucket(le标签为累计计数的上界), otel.<name>_sum, otel.<name>_count
+//   - summary: otel.<name>(quantile标签), otel.<name>_sum, otel.<name>_count
+//
+// 指标名及标签名中prometheus不支持的字符会转换为'_'. delta类型的sum/histogram不做累加, 按原值写入
+func (d *Decoder) OTelMetricsToExtMetrics(vtapID uint16, srcIP net.IP, metricsData *metricsv1.MetricsData) []*dbwriter.ExtMetrics {
+	ms := []*dbwriter.ExtMetrics{}
+	for _, resourceMetrics := range metricsData.GetResourceMetrics() {
+		resource := d.newOTelResource(vtapID, srcIP, resourceMetrics.GetResource().GetAttributes(), true)
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, metric := range scopeMetrics.GetMetrics() {
+				ms = d.appendOTelMetric(ms, resource, metric)
+			}
+		}
+		// 兼容旧版本的otel sdk
+		for _, libraryMetrics := range resourceMetrics.GetInstrumentationLibraryMetrics() {
+			for _, metric := range libraryMetrics.GetMetrics() {
+				ms = d.appendOTelMetric(ms, resource, metric)
+			}
+		}
+	}
+	return ms
+}
+
+func (d *Decoder) appendOTelMetric(ms []*dbwriter.ExtMetrics, resource *otelResource, metric *metricsv1.Metric) []*dbwriter.ExtMetrics {
+	name := otelNameToPrometheus(metric.GetName())
+	if name == "" {
+		d.counter.ErrMetrics++
+		return ms
+	}
+	switch data := metric.Data.(type) {
+	case *metricsv1.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			ms = appendOTelSample(ms, resource, name, dp.TimeUnixNano, dp.Attributes, numberDataPointValue(dp))
+		}
+	case *metricsv1.Metric_Sum:
+		for _, dp := range data.Sum.GetDataPoints() {
+			ms = appendOTelSample(ms, resource, name, dp.TimeUnixNano, dp.Attributes, numberDataPointValue(dp))
+		}
+	case *metricsv1.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_COUNT, dp.TimeUnixNano, dp.Attributes, float64(dp.Count))
+			if dp.Sum != nil {
+				ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_SUM, dp.TimeUnixNano, dp.Attributes, *dp.Sum)
+			}
+			cumulative := uint64(0)
+			for i, count := range dp.BucketCounts {
+				cumulative += count
+				le := math.Inf(1)
+				if i < len(dp.ExplicitBounds) {
+					le = dp.ExplicitBounds[i]
+				}
+				ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_BUCKET, dp.TimeUnixNano, dp.Attributes, float64(cumulative), OTEL_LABEL_LE, formatFloat(le))
+			}
+		}
+	case *metricsv1.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_COUNT, dp.TimeUnixNano, dp.Attributes, float64(dp.Count))
+			if dp.Sum != nil {
+				ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_SUM, dp.TimeUnixNano, dp.Attributes, *dp.Sum)
+			}
+			for _, bucket := range exponentialHistogramBuckets(dp) {
+				ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_BUCKET, dp.TimeUnixNano, dp.Attributes, float64(bucket.count), OTEL_LABEL_LE, formatFloat(bucket.le))
+			}
+		}
+	case *metricsv1.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_COUNT, dp.TimeUnixNano, dp.Attributes, float64(dp.Count))
+			ms = appendOTelSample(ms, resource, name+OTEL_SUFFIX_SUM, dp.TimeUnixNano, dp.Attributes, dp.Sum)
+			for _, q := range dp.QuantileValues {
+				ms = appendOTelSample(ms, resource, name, dp.TimeUnixNano, dp.Attributes, q.Value, OTEL_LABEL_QUANTILE, formatFloat(q.Quantile))
+			}
+		}
+	default:
+		if d.counter.DropUnsupportedMetrics&0xff == 0 {
+			log.Warningf("drop unsupported otel metrics name: %s type: %T. total drop %d", metric.GetName(), metric.Data, d.counter.DropUnsupportedMetrics)
+		}
+		d.counter.DropUnsupportedMetrics++
+	}
+	return ms
+}
+
+func appendOTelSample(ms []*dbwriter.ExtMetrics, resource *otelResource, name string, timeUnixNano uint64, attributes []*v11.KeyValue, value float64, extraLabels ...string) []*dbwriter.ExtMetrics {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return ms
+	}
+	m := dbwriter.AcquireExtMetrics()
+	m.Timestamp = uint32(timeUnixNano / uint64(time.Second))
+	m.Database = dbwriter.EXT_METRICS_DB
+	m.TableName = dbwriter.EXT_METRICS_TABLE
+	m.VirtualTableName = TABLE_PREFIX_OTEL + name
+	resource.fillTag(&m.Tag)
+
+	m.TagNames = append(m.TagNames, resource.tagNames...)
+	m.TagValues = append(m.TagValues, resource.tagValues...)
+	for _, attr := range attributes {
+		m.TagNames = append(m.TagNames, otelNameToPrometheus(attr.Key))
+		m.TagValues = append(m.TagValues, anyValueToString(attr.GetValue()))
+	}
+	for i := 0; i+1 < len(extraLabels); i += 2 {
+		m.TagNames = append(m.TagNames, extraLabels[i])
+		m.TagValues = append(m.TagValues, extraLabels[i+1])
+	}
+
+	m.MetricsFloatNames = append(m.MetricsFloatNames, name)
+	m.MetricsFloatValues = append(m.MetricsFloatValues, value)
+	return append(ms, m)
+}
+
+func numberDataPointValue(dp *metricsv1.NumberDataPoint) float64 {
+	switch v := dp.Value.(type) {
+	case *metricsv1.NumberDataPoint_AsDouble:
+		return v.AsDouble
+	case *metricsv1.NumberDataPoint_AsInt:
+		return float64(v.AsInt)
+	}
+	return math.NaN()
+}
+
+type cumulativeBucket struct {
+	le    float64
+	count uint64
+}
+
+// exponentialHistogramBuckets 将指数直方图转换为累计计数的桶, 桶的上界计算方式:
+// base = 2^(2^-scale), 正数桶index的范围为(base^index, base^(index+1)], 负数桶为[-base^(index+1), -base^index)
+func exponentialHistogramBuckets(dp *metricsv1.ExponentialHistogramDataPoint) []cumulativeBucket {
+	base := math.Pow(2, math.Pow(2, -float64(dp.Scale)))
+	buckets := []cumulativeBucket{}
+	cumulative := uint64(0)
+	if negative := dp.GetNegative(); negative != nil {
+		// 从绝对值最大的负数桶开始累计
+		for i := len(negative.BucketCounts) - 1; i >= 0; i-- {
+			cumulative += negative.BucketCounts[i]
+			index := float64(negative.Offset) + float64(i)
+			buckets = append(buckets, cumulativeBucket{-math.Pow(base, index), cumulative})
+		}
+	}
+	cumulative += dp.ZeroCount
+	buckets = append(buckets, cumulativeBucket{0, cumulative})
+	if positive := dp.GetPositive(); positive != nil {
+		for i, count := range positive.BucketCounts {
+			cumulative += count
+			index := float64(positive.Offset) + float64(i)
+			buckets = append(buckets, cumulativeBucket{math.Pow(base, index+1), cumulative})
+		}
+	}
+	return append(buckets, cumulativeBucket{math.Inf(1), dp.Count})
+}
+
+// OTelLogsToAppLogs 将OTLP日志转换为应用日志, 资源属性及日志属性均写入attribute_names/values
+func (d *Decoder) OTelLogsToAppLogs(vtapID uint16, srcIP net.IP, logsData *logsv1.LogsData) []*dbwriter.AppLog {
+	ls := []*dbwriter.AppLog{}
+	for _, resourceLogs := range logsData.GetResourceLogs() {
+		resource := d.newOTelResource(vtapID, srcIP, resourceLogs.GetResource().GetAttributes(), false)
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				ls = append(ls, otelLogRecordToAppLog(resource, record))
+			}
+		}
+		// 兼容旧版本的otel sdk
+		for _, libraryLogs := range resourceLogs.GetInstrumentationLibraryLogs() {
+			for _, record := range libraryLogs.GetLogRecords() {
+				ls = append(ls, otelLogRecordToAppLog(resource, record))
+			}
+		}
+	}
+	return ls
+}
+
+func otelLogRecordToAppLog(resource *otelResource, record *logsv1.LogRecord) *dbwriter.AppLog {
+	l := dbwriter.AcquireAppLog()
+	timeUnixNano := record.TimeUnixNano
+	if timeUnixNano == 0 {
+		timeUnixNano = record.ObservedTimeUnixNano
+	}
+	if timeUnixNano == 0 {
+		timeUnixNano = uint64(time.Now().UnixNano())
+	}
+	l.Timestamp = uint32(timeUnixNano / uint64(time.Second))
+	l.Time = int64(timeUnixNano / uint64(time.Microsecond))
+	resource.fillTag(&l.Tag)
+
+	if len(record.TraceId) > 0 {
+		l.TraceID = hex.EncodeToString(record.TraceId)
+	}
+	if len(record.SpanId) > 0 {
+		l.SpanID = hex.EncodeToString(record.SpanId)
+	}
+	l.SeverityNumber = uint8(record.SeverityNumber)
+	l.SeverityText = record.SeverityText
+	l.ServiceName = resource.serviceName
+	l.Body = anyValueToString(record.Body)
+
+	l.AttributeNames = append(l.AttributeNames, resource.tagNames...)
+	l.AttributeValues = append(l.AttributeValues, resource.tagValues...)
+	for _, attr := range record.Attributes {
+		l.AttributeNames = append(l.AttributeNames, attr.Key)
+		l.AttributeValues = append(l.AttributeValues, anyValueToString(attr.GetValue()))
+	}
+	return l
+}
+
+func anyValueToString(value *v11.AnyValue) string {
+	if value == nil {
+		return ""
+	}
+	switch v := value.Value.(type) {
+	case *v11.AnyValue_StringValue:
+		return v.StringValue
+	case *v11.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *v11.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *v11.AnyValue_DoubleValue:
+		return formatFloat(v.DoubleValue)
+	case *v11.AnyValue_BytesValue:
+		return hex.EncodeToString(v.BytesValue)
+	case *v11.AnyValue_ArrayValue:
+		values := make([]string, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, anyValueToString(item))
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case *v11.AnyValue_KvlistValue:
+		values := make([]string, 0, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values = append(values, kv.Key+"="+anyValueToString(kv.GetValue()))
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	}
+	return ""
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// otelNameToPrometheus 将otel的指标名及属性名转换为prometheus支持的格式: [a-zA-Z_:][a-zA-Z0-9_:]*
+func otelNameToPrometheus(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == ':' || (c >= '0' && c <= '9' && i > 0) {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
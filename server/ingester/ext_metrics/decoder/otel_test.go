@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"testing"
+
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/dbwriter"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+	"github.com/deepflowys/deepflow/server/libs/grpc"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+)
+
+const testTimeUnixNano = 1660000000 * 1000000000
+
+func newTestDecoder() *Decoder {
+	return NewDecoder(0, datatype.MESSAGE_TYPE_OTEL_METRICS, grpc.NewPlatformInfoTable(nil, 0, 0, "", "", "", nil), nil, nil, nil, nil)
+}
+
+func stringKeyValue(key, value string) *v11.KeyValue {
+	return &v11.KeyValue{Key: key, Value: &v11.AnyValue{Value: &v11.AnyValue_StringValue{StringValue: value}}}
+}
+
+func newTestResource() *resourcev1.Resource {
+	return &resourcev1.Resource{Attributes: []*v11.KeyValue{
+		stringKeyValue("service.name", "cart"),
+		stringKeyValue("host.ip", "10.1.1.1"),
+	}}
+}
+
+// sample 为便于比较的ExtMetrics
+type sample struct {
+	table  string
+	labels map[string]string
+	value  float64
+}
+
+func toSamples(ms []*dbwriter.ExtMetrics) []sample {
+	samples := make([]sample, 0, len(ms))
+	for _, m := range ms {
+		labels := make(map[string]string, len(m.TagNames))
+		for i, name := range m.TagNames {
+			labels[name] = m.TagValues[i]
+		}
+		samples = append(samples, sample{m.VirtualTableName, labels, m.MetricsFloatValues[0]})
+	}
+	return samples
+}
+
+func TestOTelMetricsToExtMetrics(t *testing.T) {
+	sum := 12.5
+	metricsData := &metricsv1.MetricsData{ResourceMetrics: []*metricsv1.ResourceMetrics{{
+		Resource: newTestResource(),
+		ScopeMetrics: []*metricsv1.ScopeMetrics{{Metrics: []*metricsv1.Metric{
+			{
+				Name: "http.server.active_requests",
+				Data: &metricsv1.Metric_Gauge{Gauge: &metricsv1.Gauge{DataPoints: []*metricsv1.NumberDataPoint{
+					{TimeUnixNano: testTimeUnixNano, Attributes: []*v11.KeyValue{stringKeyValue("http.method", "GET")}, Value: &metricsv1.NumberDataPoint_AsInt{AsInt: 3}},
+					// NaN不写入
+					{TimeUnixNano: testTimeUnixNano, Value: &metricsv1.NumberDataPoint_AsDouble{AsDouble: math.NaN()}},
+				}}},
+			},
+			{
+				Name: "requests",
+				Data: &metricsv1.Metric_Sum{Sum: &metricsv1.Sum{DataPoints: []*metricsv1.NumberDataPoint{
+					{TimeUnixNano: testTimeUnixNano, Value: &metricsv1.NumberDataPoint_AsDouble{AsDouble: 100}},
+				}}},
+			},
+			{
+				Name: "latency",
+				Data: &metricsv1.Metric_Histogram{Histogram: &metricsv1.Histogram{DataPoints: []*metricsv1.HistogramDataPoint{
+					{TimeUnixNano: testTimeUnixNano, Count: 6, Sum: &sum, BucketCounts: []uint64{1, 2, 3}, ExplicitBounds: []float64{0.5, 1}},
+				}}},
+			},
+			{
+				Name: "size",
+				Data: &metricsv1.Metric_Summary{Summary: &metricsv1.Summary{DataPoints: []*metricsv1.SummaryDataPoint{
+					{TimeUnixNano: testTimeUnixNano, Count: 4, Sum: 40, QuantileValues: []*metricsv1.SummaryDataPoint_ValueAtQuantile{{Quantile: 0.99, Value: 20}}},
+				}}},
+			},
+			// 不支持的类型及非法的指标名被丢弃
+			{Name: "unknown"},
+			{Name: ""},
+		}}},
+	}}}
+
+	d := newTestDecoder()
+	ms := d.OTelMetricsToExtMetrics(0, net.ParseIP("10.2.2.2"), metricsData)
+	resourceLabels := map[string]string{"service_name": "cart", "host_ip": "10.1.1.1"}
+	withLabels := func(labels ...string) map[string]string {
+		m := make(map[string]string)
+		for k, v := range resourceLabels {
+			m[k] = v
+		}
+		for i := 0; i+1 < len(labels); i += 2 {
+			m[labels[i]] = labels[i+1]
+		}
+		return m
+	}
+	expected := []sample{
+		{"otel.http_server_active_requests", withLabels("http_method", "GET"), 3},
+		{"otel.requests", withLabels(), 100},
+		{"otel.latency_count", withLabels(), 6},
+		{"otel.latency_sum", withLabels(), 12.5},
+		{"otel.latency_bucket", withLabels("le", "0.5"), 1},
+		{"otel.latency_bucket", withLabels("le", "1"), 3},
+		{"otel.latency_bucket", withLabels("le", "+Inf"), 6},
+		{"otel.size_count", withLabels(), 4},
+		{"otel.size_sum", withLabels(), 40},
+		{"otel.size", withLabels("quantile", "0.99"), 20},
+	}
+	if samples := toSamples(ms); !reflect.DeepEqual(samples, expected) {
+		t.Errorf("samples = %+v\nwant %+v", samples, expected)
+	}
+	for _, m := range ms {
+		if m.Timestamp != 1660000000 || m.Database != dbwriter.EXT_METRICS_DB || m.TableName != dbwriter.EXT_METRICS_TABLE {
+			t.Errorf("ext metrics = %+v", m)
+		}
+		// 资源属性中的ip优先于发送方的ip
+		if m.Tag.IP != utils.IpToUint32(net.ParseIP("10.1.1.1").To4()) {
+			t.Errorf("tag ip = %d", m.Tag.IP)
+		}
+	}
+	if d.counter.DropUnsupportedMetrics != 1 || d.counter.ErrMetrics != 1 {
+		t.Errorf("counter = %+v", d.counter)
+	}
+}
+
+func TestOTelMetricsSrcIP(t *testing.T) {
+	metricsData := &metricsv1.MetricsData{ResourceMetrics: []*metricsv1.ResourceMetrics{{
+		ScopeMetrics: []*metricsv1.ScopeMetrics{{Metrics: []*metricsv1.Metric{{
+			Name: "up",
+			Data: &metricsv1.Metric_Gauge{Gauge: &metricsv1.Gauge{DataPoints: []*metricsv1.NumberDataPoint{
+				{TimeUnixNano: testTimeUnixNano, Value: &metricsv1.NumberDataPoint_AsInt{AsInt: 1}},
+			}}},
+		}}}},
+	}}}
+	ms := newTestDecoder().OTelMetricsToExtMetrics(0, net.ParseIP("10.2.2.2"), metricsData)
+	if len(ms) != 1 || ms[0].Tag.IP != utils.IpToUint32(net.ParseIP("10.2.2.2").To4()) {
+		t.Errorf("ext metrics = %+v", ms)
+	}
+}
+
+func TestExponentialHistogramBuckets(t *testing.T) {
+	// scale为0时base为2
+	dp := &metricsv1.ExponentialHistogramDataPoint{
+		Count:     10,
+		Scale:     0,
+		ZeroCount: 1,
+		Positive:  &metricsv1.ExponentialHistogramDataPoint_Buckets{Offset: 0, BucketCounts: []uint64{2, 4}},
+		Negative:  &metricsv1.ExponentialHistogramDataPoint_Buckets{Offset: 1, BucketCounts: []uint64{3}},
+	}
+	expected := []cumulativeBucket{
+		{-2, 3},
+		{0, 4},
+		{2, 6},
+		{4, 10},
+		{math.Inf(1), 10},
+	}
+	if buckets := exponentialHistogramBuckets(dp); !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("buckets = %+v, want %+v", buckets, expected)
+	}
+
+	metricsData := &metricsv1.MetricsData{ResourceMetrics: []*metricsv1.ResourceMetrics{{
+		ScopeMetrics: []*metricsv1.ScopeMetrics{{Metrics: []*metricsv1.Metric{{
+			Name: "latency",
+			Data: &metricsv1.Metric_ExponentialHistogram{ExponentialHistogram: &metricsv1.ExponentialHistogram{
+				DataPoints: []*metricsv1.ExponentialHistogramDataPoint{dp},
+			}},
+		}}}},
+	}}}
+	ms := newTestDecoder().OTelMetricsToExtMetrics(1, nil, metricsData)
+	// _count及5个_bucket, 没有sum
+	if len(ms) != 6 || ms[0].VirtualTableName != "otel.latency_count" || ms[5].TagValues[len(ms[5].TagValues)-1] != "+Inf" {
+		t.Errorf("samples = %+v", toSamples(ms))
+	}
+}
+
+func TestOTelLogsToAppLogs(t *testing.T) {
+	logsData := &logsv1.LogsData{ResourceLogs: []*logsv1.ResourceLogs{{
+		Resource: newTestResource(),
+		ScopeLogs: []*logsv1.ScopeLogs{{LogRecords: []*logsv1.LogRecord{
+			{
+				TimeUnixNano:   testTimeUnixNano + 1500,
+				SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR,
+				SeverityText:   "ERROR",
+				Body:           &v11.AnyValue{Value: &v11.AnyValue_StringValue{StringValue: "payment failed"}},
+				Attributes:     []*v11.KeyValue{stringKeyValue("order.id", "42")},
+				TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+				SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+			},
+			// 没有日志时间时使用观测时间
+			{ObservedTimeUnixNano: testTimeUnixNano + 2000000000},
+		}}},
+	}}}
+	ls := newTestDecoder().OTelLogsToAppLogs(0, nil, logsData)
+	if len(ls) != 2 {
+		t.Fatalf("app logs = %+v", ls)
+	}
+	l := ls[0]
+	if l.Timestamp != 1660000000 || l.Time != 1660000000*1000000+1 || l.SeverityNumber != 17 || l.SeverityText != "ERROR" ||
+		l.ServiceName != "cart" || l.Body != "payment failed" ||
+		l.TraceID != "5b8efff798038103d269b633813fc60c" || l.SpanID != "eee19b7ec3c1b174" {
+		t.Errorf("app log = %+v", l)
+	}
+	// 日志的属性名不做转换
+	if !reflect.DeepEqual(l.AttributeNames, []string{"service.name", "host.ip", "order.id"}) ||
+		!reflect.DeepEqual(l.AttributeValues, []string{"cart", "10.1.1.1", "42"}) {
+		t.Errorf("attributes = %v %v", l.AttributeNames, l.AttributeValues)
+	}
+	if ls[1].Timestamp != 1660000002 || ls[1].TraceID != "" || ls[1].Body != "" {
+		t.Errorf("app log = %+v", ls[1])
+	}
+}
+
+func TestAnyValueToString(t *testing.T) {
+	for _, c := range []struct {
+		value    *v11.AnyValue
+		expected string
+	}{
+		{nil, ""},
+		{&v11.AnyValue{Value: &v11.AnyValue_BoolValue{BoolValue: true}}, "true"},
+		{&v11.AnyValue{Value: &v11.AnyValue_IntValue{IntValue: -7}}, "-7"},
+		{&v11.AnyValue{Value: &v11.AnyValue_DoubleValue{DoubleValue: 0.25}}, "0.25"},
+		{&v11.AnyValue{Value: &v11.AnyValue_BytesValue{BytesValue: []byte{0xab, 0x01}}}, "ab01"},
+		{&v11.AnyValue{Value: &v11.AnyValue_ArrayValue{ArrayValue: &v11.ArrayValue{Values: []*v11.AnyValue{
+			{Value: &v11.AnyValue_StringValue{StringValue: "a"}},
+			{Value: &v11.AnyValue_IntValue{IntValue: 1}},
+		}}}}, "[a,1]"},
+		{&v11.AnyValue{Value: &v11.AnyValue_KvlistValue{KvlistValue: &v11.KeyValueList{Values: []*v11.KeyValue{
+			stringKeyValue("k", "v"),
+		}}}}, "{k=v}"},
+	} {
+		if s := anyValueToString(c.value); s != c.expected {
+			t.Errorf("anyValueToString(%v) = %q, want %q", c.value, s, c.expected)
+		}
+	}
+}
+
+func TestOTelNameToPrometheus(t *testing.T) {
+	for name, expected := range map[string]string{
+		"http.server.duration": "http_server_duration",
+		"k8s.pod.name":         "k8s_pod_name",
+		"0abc":                 "_abc",
+		"ns:metric_1":          "ns:metric_1",
+		"a-b c":                "a_b_c",
+	} {
+		if s := otelNameToPrometheus(name); s != expected {
+			t.Errorf("otelNameToPrometheus(%q) = %q, want %q", name, s, expected)
+		}
+	}
+}
@@ -46,6 +46,9 @@ type ExtMetrics struct {
 	Telegraf      *Metricsor
 	Prometheus    *Metricsor
 	MetaflowStats *Metricsor
+	OTelMetrics   *Metricsor
+	OTelLogs      *Metricsor
+	RemoteWrite   *RemoteWriteServer
 }
 
 type Metricsor struct {
@@ -54,9 +57,11 @@ type Metricsor struct {
 	PlatformDataEnabled bool
 	PlatformDatas       []*grpc.PlatformInfoTable
 	Writer              *dbwriter.ExtMetricsWriter
+	DecodeQueues        queue.MultiQueueWriter
 }
 
-func NewExtMetrics(config *config.Config, recv *receiver.Receiver) (*ExtMetrics, error) {
+// otlpEnabled为true时才创建OTLP指标及日志的解析, 其数据由ingester的OTLP接收器写入解析队列
+func NewExtMetrics(config *config.Config, recv *receiver.Receiver, otlpEnabled bool) (*ExtMetrics, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_EXTMETRICS_QUEUE)
 	controllers := make([]net.IP, len(config.Base.ControllerIPs))
 	for i, ipString := range config.Base.ControllerIPs {
@@ -78,11 +83,30 @@ func NewExtMetrics(config *config.Config, recv *receiver.Receiver) (*ExtMetrics,
 	if err != nil {
 		return nil, err
 	}
+	var otelMetrics, otelLogs *Metricsor
+	if otlpEnabled {
+		otelMetrics, err = NewMetricsor(datatype.MESSAGE_TYPE_OTEL_METRICS, dbwriter.EXT_METRICS_DB, config, controllers, manager, recv, true)
+		if err != nil {
+			return nil, err
+		}
+		otelLogs, err = NewMetricsor(datatype.MESSAGE_TYPE_OTEL_LOGS, dbwriter.EXT_METRICS_DB, config, controllers, manager, recv, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var remoteWrite *RemoteWriteServer
+	if config.RemoteWrite.Enabled {
+		// remote_write请求与agent转发的prometheus数据共用解析队列
+		remoteWrite = NewRemoteWriteServer(&config.RemoteWrite, prometheus.DecodeQueues, config.DecoderQueueCount)
+	}
 	return &ExtMetrics{
 		Config:        config,
 		Telegraf:      telegraf,
 		Prometheus:    prometheus,
 		MetaflowStats: deepflowStats,
+		OTelMetrics:   otelMetrics,
+		OTelLogs:      otelLogs,
+		RemoteWrite:   remoteWrite,
 	}, nil
 }
 
@@ -95,9 +119,19 @@ func NewMetricsor(msgType datatype.MessageType, db string, config *config.Config
 		1,
 		libqueue.OptionFlushIndicator(3*time.Second),
 		libqueue.OptionRelease(func(p interface{}) { receiver.ReleaseRecvBuffer(p.(*receiver.RecvBuffer)) }))
-	recv.RegistHandler(msgType, decodeQueues, queueCount)
+	// 内部消息类型的数据不从采集器接收
+	if !msgType.IsInternal() {
+		recv.RegistHandler(msgType, decodeQueues, queueCount)
+	}
 
-	metricsWriter, err := dbwriter.NewExtMetricsWriter(msgType, db, config)
+	var metricsWriter *dbwriter.ExtMetricsWriter
+	var appLogWriter *dbwriter.AppLogWriter
+	var err error
+	if msgType == datatype.MESSAGE_TYPE_OTEL_LOGS {
+		appLogWriter, err = dbwriter.NewAppLogWriter(config)
+	} else {
+		metricsWriter, err = dbwriter.NewExtMetricsWriter(msgType, db, config)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +150,7 @@ func NewMetricsor(msgType datatype.MessageType, db string, config *config.Config
 			platformDatas[i],
 			queue.QueueReader(decodeQueues.FixedMultiQueue[i]),
 			metricsWriter,
+			appLogWriter,
 			config,
 		)
 	}
@@ -124,6 +159,7 @@ func NewMetricsor(msgType datatype.MessageType, db string, config *config.Config
 		Decoders:            decoders,
 		PlatformDataEnabled: platformDataEnabled,
 		PlatformDatas:       platformDatas,
+		DecodeQueues:        decodeQueues,
 	}, nil
 }
 
@@ -151,11 +187,25 @@ func (s *ExtMetrics) Start() {
 	s.Telegraf.Start()
 	s.Prometheus.Start()
 	s.MetaflowStats.Start()
+	if s.OTelMetrics != nil {
+		s.OTelMetrics.Start()
+		s.OTelLogs.Start()
+	}
+	if s.RemoteWrite != nil {
+		s.RemoteWrite.Start()
+	}
 }
 
 func (s *ExtMetrics) Close() error {
 	s.Telegraf.Close()
 	s.Prometheus.Close()
 	s.MetaflowStats.Close()
+	if s.OTelMetrics != nil {
+		s.OTelMetrics.Close()
+		s.OTelLogs.Close()
+	}
+	if s.RemoteWrite != nil {
+		s.RemoteWrite.Close()
+	}
 	return nil
 }
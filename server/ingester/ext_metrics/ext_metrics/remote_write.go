@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ext_metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/gorilla/mux"
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowys/deepflow/server/ingester/common"
+	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/config"
+	"github.com/deepflowys/deepflow/server/libs/codec"
+	"github.com/deepflowys/deepflow/server/libs/queue"
+	"github.com/deepflowys/deepflow/server/libs/receiver"
+	"github.com/deepflowys/deepflow/server/libs/stats"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("ext_metrics")
+
+const (
+	REMOTE_WRITE_PATH = "/api/v1/prom/write"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+type RemoteWriteCounter struct {
+	RequestCount int64 `statsd:"request-count"`
+	RequestBytes int64 `statsd:"request-bytes"`
+	ErrorCount   int64 `statsd:"err-count"`
+}
+
+// RemoteWriteServer 接收prometheus/otel-collector等直接发送的remote_write请求，
+// 请求体与deepflow-agent转发的MESSAGE_TYPE_PROMETHEUS数据格式相同，封装后放入prometheus的解析队列，
+// 由decoder统一解析并写入ext_metrics, 请求中的metadata不写入, 在decoder的metadata-count中统计
+type RemoteWriteServer struct {
+	server      *http.Server
+	port        int
+	maxBodySize int64
+	queues      queue.MultiQueueWriter
+	queueCount  int
+	queueCursor uint32
+
+	counter *RemoteWriteCounter
+	utils.Closable
+}
+
+func NewRemoteWriteServer(cfg *config.RemoteWriteConfig, queues queue.MultiQueueWriter, queueCount int) *RemoteWriteServer {
+	s := &RemoteWriteServer{
+		server: &http.Server{
+			Addr:    ":" + strconv.Itoa(cfg.ListenPort),
+			Handler: mux.NewRouter(),
+		},
+		port:        cfg.ListenPort,
+		maxBodySize: int64(cfg.MaxBodySize),
+		queues:      queues,
+		queueCount:  queueCount,
+		counter:     &RemoteWriteCounter{},
+	}
+	s.server.Handler.(*mux.Router).HandleFunc(REMOTE_WRITE_PATH, s.remoteWrite).Methods("POST")
+	return s
+}
+
+func (s *RemoteWriteServer) GetCounter() interface{} {
+	counter := &RemoteWriteCounter{}
+	counter.RequestCount = atomic.SwapInt64(&s.counter.RequestCount, 0)
+	counter.RequestBytes = atomic.SwapInt64(&s.counter.RequestBytes, 0)
+	counter.ErrorCount = atomic.SwapInt64(&s.counter.ErrorCount, 0)
+	return counter
+}
+
+func (s *RemoteWriteServer) remoteWrite(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize+1)
+	compressed, err := readLimited(r.Body, s.maxBodySize)
+	if err != nil {
+		atomic.AddInt64(&s.counter.ErrorCount, 1)
+		if errors.Is(err, errBodyTooLarge) {
+			http.Error(w, fmt.Sprintf("request body exceeds max-body-size %d", s.maxBodySize), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	// 仅校验snappy头部，完整的解析在decoder中进行，返回4xx避免发送方重试错误的数据
+	if _, err := snappy.DecodedLen(compressed); err != nil {
+		atomic.AddInt64(&s.counter.ErrorCount, 1)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	atomic.AddInt64(&s.counter.RequestCount, 1)
+	atomic.AddInt64(&s.counter.RequestBytes, int64(len(compressed)))
+
+	encoder := codec.AcquireSimpleEncoder()
+	encoder.WriteBytes(compressed)
+	data := encoder.Bytes()
+	buffer := receiver.AcquireRecvBuffer(len(data))
+	buffer.End = copy(buffer.Buffer, data)
+	codec.ReleaseSimpleEncoder(encoder)
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		buffer.IP = net.ParseIP(host)
+	}
+
+	index := atomic.AddUint32(&s.queueCursor, 1) % uint32(s.queueCount)
+	if err := s.queues.Put(queue.HashKey(index), buffer); err != nil {
+		receiver.ReleaseRecvBuffer(buffer)
+		atomic.AddInt64(&s.counter.ErrorCount, 1)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readLimited 最多读取maxSize字节, 超过时返回errBodyTooLarge
+func readLimited(reader io.Reader, maxSize int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+func (s *RemoteWriteServer) Start() {
+	common.RegisterCountableForIngester("prometheus_remote_write", s, stats.OptionStatTags{"port": strconv.Itoa(s.port)})
+	go func() {
+		if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Errorf("prometheus remote write ListenAndServe() failed: %v", err)
+		}
+	}()
+	log.Infof("prometheus remote write server listen on %s", s.server.Addr)
+}
+
+func (s *RemoteWriteServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	s.Closable.Close()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Errorf("prometheus remote write Shutdown() failed: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ext_metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+
+	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/config"
+	"github.com/deepflowys/deepflow/server/libs/queue"
+)
+
+type testQueues struct {
+	items []interface{}
+}
+
+func (q *testQueues) Put(key queue.HashKey, items ...interface{}) error {
+	q.items = append(q.items, items...)
+	return nil
+}
+
+func (q *testQueues) Puts(keys []queue.HashKey, items []interface{}) error {
+	q.items = append(q.items, items...)
+	return nil
+}
+
+func (q *testQueues) Len(key queue.HashKey) int {
+	return len(q.items)
+}
+
+func (q *testQueues) Close() error {
+	return nil
+}
+
+func TestRemoteWriteMaxBodySize(t *testing.T) {
+	queues := &testQueues{}
+	s := NewRemoteWriteServer(&config.RemoteWriteConfig{ListenPort: config.DefaultRemoteWritePort, MaxBodySize: 64}, queues, 1)
+
+	body := snappy.Encode(nil, bytes.Repeat([]byte{1}, 32))
+	w := httptest.NewRecorder()
+	s.remoteWrite(w, httptest.NewRequest(http.MethodPost, REMOTE_WRITE_PATH, bytes.NewReader(body)))
+	if w.Code != http.StatusNoContent || len(queues.items) != 1 {
+		t.Errorf("remote write status = %d, queued %d", w.Code, len(queues.items))
+	}
+
+	// 超过限制时返回413, 不放入队列
+	body = make([]byte, 65)
+	w = httptest.NewRecorder()
+	s.remoteWrite(w, httptest.NewRequest(http.MethodPost, REMOTE_WRITE_PATH, bytes.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge || len(queues.items) != 1 {
+		t.Errorf("remote write status = %d, queued %d", w.Code, len(queues.items))
+	}
+
+	// 未知长度的请求体超过限制
+	req := httptest.NewRequest(http.MethodPost, REMOTE_WRITE_PATH, bytes.NewReader(body))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	s.remoteWrite(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || s.counter.ErrorCount != 2 {
+		t.Errorf("remote write status = %d, error count %d", w.Code, s.counter.ErrorCount)
+	}
+}
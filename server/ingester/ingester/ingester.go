@@ -27,6 +27,7 @@ import (
 
 	"github.com/deepflowys/deepflow/server/ingester/ckmonitor"
 	"github.com/deepflowys/deepflow/server/ingester/datasource"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
 	"github.com/deepflowys/deepflow/server/libs/debug"
 	"github.com/deepflowys/deepflow/server/libs/logger"
 	"github.com/deepflowys/deepflow/server/libs/pool"
@@ -48,6 +49,7 @@ import (
 	extmetricscfg "github.com/deepflowys/deepflow/server/ingester/ext_metrics/config"
 	"github.com/deepflowys/deepflow/server/ingester/ext_metrics/ext_metrics"
 	"github.com/deepflowys/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowys/deepflow/server/ingester/pkg/ckwriter"
 	rozecfg "github.com/deepflowys/deepflow/server/ingester/roze/config"
 	"github.com/deepflowys/deepflow/server/ingester/roze/roze"
 	streamcfg "github.com/deepflowys/deepflow/server/ingester/stream/config"
@@ -105,6 +107,10 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 	}
 	stats.SetDFRemote(net.JoinHostPort("127.0.0.1", strconv.Itoa(int(cfg.ListenPort))))
 
+	if cfg.CKWriterSpill.Enabled {
+		ckwriter.SetSpill(cfg.CKWriterSpill.Directory, int64(cfg.CKWriterSpill.MaxSize)<<20)
+	}
+
 	dropletConfig := dropletcfg.Load(cfg, configPath)
 	bytes, _ = yaml.Marshal(dropletConfig)
 	log.Infof("droplet config:\n%s", string(bytes))
@@ -151,12 +157,18 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 		// 写流日志数据
 		stream, err := stream.NewStream(streamConfig, receiver)
 		checkError(err)
-		stream.Start()
-		closers = append(closers, stream)
 
 		// 写ext_metrics数据
-		extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver)
+		extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver, stream.OTLPReceiver != nil)
 		checkError(err)
+
+		// OTLP接收器收到的指标及日志由ext_metrics解析
+		if stream.OTLPReceiver != nil {
+			stream.OTLPReceiver.RegisterQueues(datatype.MESSAGE_TYPE_OTEL_METRICS, extMetrics.OTelMetrics.DecodeQueues, extMetricsConfig.DecoderQueueCount)
+			stream.OTLPReceiver.RegisterQueues(datatype.MESSAGE_TYPE_OTEL_LOGS, extMetrics.OTelLogs.DecodeQueues, extMetricsConfig.DecoderQueueCount)
+		}
+		stream.Start()
+		closers = append(closers, stream)
 		extMetrics.Start()
 		closers = append(closers, extMetrics)
 
@@ -30,6 +30,7 @@ import (
 	"github.com/deepflowys/deepflow/server/ingester/droplet/queue"
 	"github.com/deepflowys/deepflow/server/ingester/ingesterctl"
 	"github.com/deepflowys/deepflow/server/ingester/ingesterctl/rpc"
+	"github.com/deepflowys/deepflow/server/ingester/pkg/ckwriter"
 	"github.com/deepflowys/deepflow/server/ingester/roze/roze"
 	"github.com/deepflowys/deepflow/server/ingester/stream/stream"
 	"github.com/deepflowys/deepflow/server/libs/debug"
@@ -62,6 +63,7 @@ func RegisterIngesterCommand(root *cobra.Command) {
 	ingesterCmd.AddCommand(profiler.RegisterProfilerCommand())
 	ingesterCmd.AddCommand(debug.RegisterLogLevelCommand())
 	ingesterCmd.AddCommand(RegisterTimeConvertCommand())
+	ingesterCmd.AddCommand(debug.ClientRegisterSimple(ckwriter.CMD_CKWRITER_SPILL, debug.CmdHelper{"ckwriterSpill [filter]", "show ckwriter spill queue of each table"}, nil))
 
 	dropletCmd.AddCommand(queue.RegisterCommand(ingesterctl.INGESTERCTL_QUEUE, []string{
 		"1-receiver-to-statsd",
@@ -59,6 +59,7 @@ type CKWriter struct {
 	dataQueues queue.FixedMultiQueue
 	counters   []Counter
 	putCounter int
+	spill      *SpillQueue // 未开启落盘时为nil
 
 	wg   sync.WaitGroup
 	exit bool
@@ -155,6 +156,11 @@ func NewCKWriter(primaryAddr, secondaryAddr, user, password, counterName string,
 	}
 
 	name := fmt.Sprintf("%s-%s", table.Database, table.LocalName)
+	spill, err := acquireSpillQueue(name, primaryAddr, user, password)
+	if err != nil {
+		// 落盘目录不可用时不影响写入, 写失败的数据仍直接丢弃
+		log.Warningf("create ckwriter spill queue for %s failed: %s", name, err)
+	}
 	dataQueues := queue.NewOverwriteQueues(
 		name, queue.HashKey(queueCount), queueSize,
 		queue.OptionFlushIndicator(time.Second),
@@ -164,6 +170,8 @@ func NewCKWriter(primaryAddr, secondaryAddr, user, password, counterName string,
 	return &CKWriter{
 		primaryAddr:   primaryAddr,
 		secondaryAddr: secondaryAddr,
+		user:          user,
+		password:      password,
 		table:         table,
 		queueCount:    queueCount,
 		queueSize:     queueSize,
@@ -176,6 +184,7 @@ func NewCKWriter(primaryAddr, secondaryAddr, user, password, counterName string,
 		conns:      conns,
 		dataQueues: dataQueues,
 		counters:   make([]Counter, queueCount),
+		spill:      spill,
 	}, nil
 }
 
@@ -268,13 +277,23 @@ func (w *CKWriter) Write(queueID int, items []CKItem) {
 
 			// 写失败重连后重试一次, 规避偶尔写失败问题
 			if err = w.writeItems(queueID, items); err != nil {
-				log.Warningf("retry write table(%s.%s) failed, drop(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+				if w.spill != nil {
+					log.Warningf("retry write table(%s.%s) failed, spill(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+				} else {
+					log.Warningf("retry write table(%s.%s) failed, drop(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+				}
 			} else {
 				log.Infof("retry write table(%s.%s) success, write(%d) items", w.table.Database, w.table.LocalName, len(items))
 			}
 		}
 		if err != nil {
 			w.counters[queueID].WriteFailedCount += int64(len(items))
+			// 落盘后由SpillQueue在clickhouse恢复后重放
+			if w.spill != nil {
+				if err := w.spill.Put(w.prepare, items); err != nil && w.counters[queueID].WriteFailedCount == int64(len(items)) {
+					log.Warningf("spill table(%s.%s) failed, drop(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+				}
+			}
 		} else {
 			w.counters[queueID].WriteSuccessCount += int64(len(items))
 		}
@@ -346,6 +365,9 @@ func (w *CKWriter) Close() {
 	for _, c := range w.counters {
 		c.Close()
 	}
+	if w.spill != nil {
+		releaseSpillQueue(w.spill)
+	}
 
 	log.Infof("ckwriter %s closed", w.name)
 }
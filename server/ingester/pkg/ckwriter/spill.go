@@ -0,0 +1,358 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/deepflowys/deepflow/server/ingester/common"
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
+	"github.com/deepflowys/deepflow/server/libs/codec"
+	"github.com/deepflowys/deepflow/server/libs/debug"
+	"github.com/deepflowys/deepflow/server/libs/stats"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+)
+
+const (
+	CMD_CKWRITER_SPILL = 36
+
+	SPILL_FILE_SUFFIX     = ".blk"
+	SPILL_TMP_FILE_SUFFIX = ".tmp"
+	SPILL_REPLAY_INTERVAL = 10 * time.Second
+)
+
+var (
+	spillDirectory string
+	spillMaxBytes  int64
+	spillLock      sync.Mutex
+	spillQueues    = make(map[string]*SpillQueue)
+)
+
+// SetSpill 开启写clickhouse失败时的落盘重放, 需在创建CKWriter之前调用
+// 每个表在directory下有单独的目录, 占用空间超过maxBytes后, 新的写失败数据将被丢弃
+func SetSpill(directory string, maxBytes int64) {
+	spillDirectory = directory
+	spillMaxBytes = maxBytes
+	debug.ServerRegisterSimple(CMD_CKWRITER_SPILL, &spillCommand{})
+	log.Infof("ckwriter spill enabled, directory: %s, max bytes per table: %d", directory, maxBytes)
+}
+
+type SpillCounter struct {
+	SpillCount        int64 `statsd:"spill-count"`         // 落盘的行数
+	SpillDropCount    int64 `statsd:"spill-drop-count"`    // 超过空间限制或落盘失败而丢弃的行数
+	ReplayCount       int64 `statsd:"replay-count"`        // 重放成功的行数
+	ReplayFailedCount int64 `statsd:"replay-failed-count"` // 重放失败的次数
+	CorruptCount      int64 `statsd:"corrupt-count"`       // 无法解析而删除的文件数
+	PendingFiles      int64 `statsd:"pending-files"`
+	PendingBytes      int64 `statsd:"pending-bytes"`
+}
+
+type spillSegment struct {
+	seq  uint64
+	size int64
+}
+
+// SpillQueue 为单个表的落盘队列, 写clickhouse失败的batch以ckdb.RecordBatch编码, 每个batch一个文件,
+// 文件名为递增的序号, 后台按序号顺序重放, 重放成功后删除. 同名的表共用一个SpillQueue
+type SpillQueue struct {
+	name     string
+	dir      string
+	addr     string
+	user     string
+	password string
+	maxBytes int64
+	refs     int
+
+	lock     sync.Mutex
+	segments []spillSegment
+	nextSeq  uint64
+	bytes    int64
+	counter  SpillCounter
+	conn     clickhouse.Conn
+	exit     bool
+	utils.Closable
+}
+
+func acquireSpillQueue(name, addr, user, password string) (*SpillQueue, error) {
+	if spillDirectory == "" {
+		return nil, nil
+	}
+	spillLock.Lock()
+	defer spillLock.Unlock()
+	if q, ok := spillQueues[name]; ok {
+		q.refs++
+		return q, nil
+	}
+	q := &SpillQueue{
+		name:     name,
+		dir:      filepath.Join(spillDirectory, name),
+		addr:     addr,
+		user:     user,
+		password: password,
+		maxBytes: spillMaxBytes,
+		refs:     1,
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	spillQueues[name] = q
+	common.RegisterCountableForIngester("ckwriter_spill", q, stats.OptionStatTags{"table": name})
+	go q.run()
+	return q, nil
+}
+
+func releaseSpillQueue(q *SpillQueue) {
+	spillLock.Lock()
+	defer spillLock.Unlock()
+	q.refs--
+	if q.refs > 0 {
+		return
+	}
+	delete(spillQueues, q.name)
+	q.lock.Lock()
+	q.exit = true
+	q.lock.Unlock()
+	q.Closable.Close()
+}
+
+// load 加载上次运行遗留的文件, 删除未写完的临时文件
+func (q *SpillQueue) load() error {
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), SPILL_TMP_FILE_SUFFIX) {
+			os.Remove(filepath.Join(q.dir, f.Name()))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), SPILL_FILE_SUFFIX), 10, 64)
+		if err != nil || !strings.HasSuffix(f.Name(), SPILL_FILE_SUFFIX) {
+			continue
+		}
+		q.segments = append(q.segments, spillSegment{seq: seq, size: f.Size()})
+		q.bytes += f.Size()
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+	if len(q.segments) > 0 {
+		log.Infof("ckwriter spill %s loaded %d files, %d bytes", q.name, len(q.segments), q.bytes)
+	}
+	return nil
+}
+
+func (q *SpillQueue) segmentPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, SPILL_FILE_SUFFIX))
+}
+
+func (q *SpillQueue) GetCounter() interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	counter := q.counter
+	q.counter = SpillCounter{}
+	counter.PendingFiles = int64(len(q.segments))
+	counter.PendingBytes = q.bytes
+	return &counter
+}
+
+// Put 将写入失败的items编码后落盘, 调用方负责Release items
+func (q *SpillQueue) Put(prepare string, items []CKItem) error {
+	record := ckdb.NewRecordBatch(prepare)
+	block := &ckdb.Block{Batch: record}
+	for _, item := range items {
+		if err := item.WriteBlock(block); err != nil {
+			q.drop(len(items))
+			return fmt.Errorf("item write record block failed: %s", err)
+		}
+		block.ResetIndex()
+	}
+	record.Rows = len(items)
+	encoder := codec.AcquireSimpleEncoder()
+	defer codec.ReleaseSimpleEncoder(encoder)
+	record.Encode(encoder)
+	data := encoder.Bytes()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.bytes+int64(len(data)) > q.maxBytes {
+		q.counter.SpillDropCount += int64(len(items))
+		return fmt.Errorf("spill directory %s is full(%d bytes)", q.dir, q.bytes)
+	}
+	seq := q.nextSeq
+	path := q.segmentPath(seq)
+	// 先写临时文件再重命名, 避免进程退出时留下不完整的文件
+	if err := ioutil.WriteFile(path+SPILL_TMP_FILE_SUFFIX, data, 0644); err != nil {
+		os.Remove(path + SPILL_TMP_FILE_SUFFIX)
+		q.counter.SpillDropCount += int64(len(items))
+		return err
+	}
+	if err := os.Rename(path+SPILL_TMP_FILE_SUFFIX, path); err != nil {
+		os.Remove(path + SPILL_TMP_FILE_SUFFIX)
+		q.counter.SpillDropCount += int64(len(items))
+		return err
+	}
+	q.nextSeq++
+	q.segments = append(q.segments, spillSegment{seq: seq, size: int64(len(data))})
+	q.bytes += int64(len(data))
+	q.counter.SpillCount += int64(len(items))
+	return nil
+}
+
+func (q *SpillQueue) drop(n int) {
+	q.lock.Lock()
+	q.counter.SpillDropCount += int64(n)
+	q.lock.Unlock()
+}
+
+func (q *SpillQueue) run() {
+	ticker := time.NewTicker(SPILL_REPLAY_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		q.lock.Lock()
+		exit := q.exit
+		q.lock.Unlock()
+		if exit {
+			break
+		}
+		q.replay()
+	}
+	if q.conn != nil {
+		q.conn.Close()
+		q.conn = nil
+	}
+}
+
+func (q *SpillQueue) oldest() (spillSegment, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.segments) == 0 || q.exit {
+		return spillSegment{}, false
+	}
+	return q.segments[0], true
+}
+
+func (q *SpillQueue) remove(segment spillSegment, rows int, corrupt bool) {
+	os.Remove(q.segmentPath(segment.seq))
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.segments = q.segments[1:]
+	q.bytes -= segment.size
+	if corrupt {
+		q.counter.CorruptCount++
+	} else {
+		q.counter.ReplayCount += int64(rows)
+	}
+}
+
+// replay 按顺序重放落盘的数据, 遇到写入失败时停止, 等待下次重试
+func (q *SpillQueue) replay() {
+	for {
+		segment, ok := q.oldest()
+		if !ok {
+			return
+		}
+		path := q.segmentPath(segment.seq)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Warningf("read spill file %s failed, drop it: %s", path, err)
+			q.remove(segment, 0, true)
+			continue
+		}
+		decoder := &codec.SimpleDecoder{}
+		decoder.Init(data)
+		record, err := ckdb.DecodeRecordBatch(decoder)
+		if err != nil {
+			log.Warningf("decode spill file %s failed, drop it: %s", path, err)
+			q.remove(segment, 0, true)
+			continue
+		}
+
+		if q.conn == nil {
+			if q.conn, err = clickhouse.Open(&clickhouse.Options{
+				Addr: []string{q.addr},
+				Auth: clickhouse.Auth{
+					Database: "default",
+					Username: q.user,
+					Password: q.password,
+				},
+			}); err != nil {
+				q.replayFailed(err)
+				return
+			}
+		}
+		if err := record.ReplayTo(context.Background(), q.conn); err != nil {
+			q.replayFailed(err)
+			q.conn.Close()
+			q.conn = nil
+			return
+		}
+		q.remove(segment, record.Rows, false)
+		log.Debugf("ckwriter spill %s replay %d items success", q.name, record.Rows)
+	}
+}
+
+func (q *SpillQueue) replayFailed(err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.counter.ReplayFailedCount == 0 {
+		log.Warningf("ckwriter spill %s replay failed, will retry after %s: %s", q.name, SPILL_REPLAY_INTERVAL, err)
+	}
+	q.counter.ReplayFailedCount++
+}
+
+func (q *SpillQueue) String() string {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return fmt.Sprintf("%-48s %-12d %-16d %s", q.name, len(q.segments), q.bytes, q.dir)
+}
+
+type spillCommand struct{}
+
+// HandleSimpleCommand 显示各表落盘队列的状态, arg不为空时按表名过滤
+func (c *spillCommand) HandleSimpleCommand(op uint16, arg string) string {
+	spillLock.Lock()
+	queues := make([]*SpillQueue, 0, len(spillQueues))
+	for name, q := range spillQueues {
+		if arg == "" || strings.Contains(name, arg) {
+			queues = append(queues, q)
+		}
+	}
+	spillLock.Unlock()
+	sort.Slice(queues, func(i, j int) bool { return queues[i].name < queues[j].name })
+
+	lines := []string{fmt.Sprintf("%-48s %-12s %-16s %s", "table", "files", "bytes", "directory")}
+	for _, q := range queues {
+		lines = append(lines, q.String())
+	}
+	return strings.Join(lines, "\n")
+}
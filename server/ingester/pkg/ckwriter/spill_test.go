@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
+	"github.com/deepflowys/deepflow/server/libs/codec"
+)
+
+type testItem struct {
+	value uint64
+}
+
+func (i *testItem) WriteBlock(block *ckdb.Block) error {
+	return block.WriteUInt64(i.value)
+}
+
+func (i *testItem) Release() {}
+
+func TestSpillQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckwriter-spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := &SpillQueue{name: "db-table", dir: dir, maxBytes: 1 << 20}
+	if err := q.load(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := q.Put("INSERT INTO db.`table` (value) VALUES (?)", []CKItem{&testItem{uint64(i)}, &testItem{uint64(i + 10)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ioutil.WriteFile(q.segmentPath(100)+SPILL_TMP_FILE_SUFFIX, []byte("partial"), 0644)
+
+	// 重启后按序号加载, 并删除临时文件
+	loaded := &SpillQueue{name: "db-table", dir: dir, maxBytes: 1 << 20}
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.segments) != 3 || loaded.bytes != q.bytes || loaded.nextSeq != 3 {
+		t.Fatalf("load() segments=%v bytes=%d nextSeq=%d", loaded.segments, loaded.bytes, loaded.nextSeq)
+	}
+	if _, err := os.Stat(q.segmentPath(100) + SPILL_TMP_FILE_SUFFIX); !os.IsNotExist(err) {
+		t.Error("tmp file should be removed")
+	}
+	data, err := ioutil.ReadFile(loaded.segmentPath(loaded.segments[1].seq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := &codec.SimpleDecoder{}
+	decoder.Init(data)
+	record, err := ckdb.DecodeRecordBatch(decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Rows != 2 {
+		t.Errorf("record rows = %d, want 2", record.Rows)
+	}
+
+	full := &SpillQueue{name: "db-table", dir: dir, maxBytes: q.bytes}
+	full.load()
+	if err := full.Put("INSERT INTO db.`table` (value) VALUES (?)", []CKItem{&testItem{1}}); err == nil || full.counter.SpillDropCount != 1 {
+		t.Errorf("put to full spill queue should be dropped, err=%v", err)
+	}
+}
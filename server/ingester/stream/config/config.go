@@ -17,10 +17,12 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/deepflowys/deepflow/server/ingester/config"
+	"github.com/deepflowys/deepflow/server/ingester/stream/common"
 
 	logging "github.com/op/go-logging"
 	yaml "gopkg.in/yaml.v2"
@@ -34,23 +36,82 @@ const (
 	DefaultDecoderQueueSize  = 10000
 	DefaultBrokerQueueSize   = 10000
 	DefaultFlowLogTTL        = 3
+	DefaultOTLPGrpcPort      = 4317
+	DefaultOTLPHttpPort      = 4318
+	DefaultOTLPMaxRecvSize   = 16 << 20
+	DefaultExporterQueueSize = 100000
 )
 
+const (
+	QUOTA_BY_NONE    = ""
+	QUOTA_BY_VTAP    = "vtap"
+	QUOTA_BY_SERVICE = "service"
+)
+
+// ThrottlePolicy 流日志限速时的采样策略, 默认对所有数据做均匀采样
+type ThrottlePolicy struct {
+	TraceConsistent bool   `yaml:"trace-consistent"` // 按trace_id一致性采样, 同一trace的数据整体保留或丢弃
+	KeepError       bool   `yaml:"keep-error"`       // 异常(服务端异常, 客户端异常)及超时(无响应)的数据单独配额, 优先保留
+	QuotaBy         string `yaml:"quota-by"`         // 按采集器(vtap)或服务(service)平分限速配额, 为空时不区分
+}
+
 type FlowLogTTL struct {
 	L4FlowLog int `yaml:"l4-flow-log"`
 	L7FlowLog int `yaml:"l7-flow-log"`
 	L4Packet  int `yaml:"l4-packet"`
 }
 
+// OTLPReceiverConfig 直接接收OTLP trace数据的服务配置
+type OTLPReceiverConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	GrpcPort       int  `yaml:"grpc-port"`
+	HttpPort       int  `yaml:"http-port"`
+	MaxRecvMsgSize int  `yaml:"max-recv-msg-size"`
+}
+
+const (
+	EXPORT_FORMAT_JSON     = "json"
+	EXPORT_FORMAT_PROTOBUF = "protobuf"
+
+	FILTER_OPERATOR_EQ  = "="
+	FILTER_OPERATOR_NEQ = "!="
+)
+
+// KafkaFilter 按列值过滤导出的数据, 列值以字符串形式与values比较
+type KafkaFilter struct {
+	Column   string   `yaml:"column"`
+	Operator string   `yaml:"operator"` // '='时列值在values中才导出, '!='时列值不在values中才导出
+	Values   []string `yaml:"values"`
+}
+
+type KafkaTopic struct {
+	Name    string        `yaml:"name"`
+	Table   string        `yaml:"table"`   // l4_flow_log 或 l7_flow_log
+	Format  string        `yaml:"format"`  // json 或 protobuf(google.protobuf.Struct)
+	Columns []string      `yaml:"columns"` // 导出的列, 为空时导出所有列
+	Filters []KafkaFilter `yaml:"filters"` // 所有过滤条件都满足时才导出
+}
+
+// KafkaExporterConfig 将写入clickhouse的流日志同时导出到kafka
+type KafkaExporterConfig struct {
+	Enabled   bool         `yaml:"enabled"`
+	Brokers   []string     `yaml:"brokers"`
+	QueueSize int          `yaml:"queue-size"`
+	Topics    []KafkaTopic `yaml:"topics"`
+}
+
 type Config struct {
 	Base              *config.Config
 	CKWriterConfig    config.CKWriterConfig `yaml:"flowlog-ck-writer"`
 	Throttle          int                   `yaml:"throttle"`
 	L4Throttle        int                   `yaml:"l4-throttle"`
 	L7Throttle        int                   `yaml:"l7-throttle"`
+	ThrottlePolicy    ThrottlePolicy        `yaml:"throttle-policy"`
 	FlowLogTTL        FlowLogTTL            `yaml:"flow-log-ttl"`
 	DecoderQueueCount int                   `yaml:"decoder-queue-count"`
 	DecoderQueueSize  int                   `yaml:"decoder-queue-size"`
+	OTLPReceiver      OTLPReceiverConfig    `yaml:"otlp-receiver"`
+	KafkaExporter     KafkaExporterConfig   `yaml:"kafka-exporter"`
 }
 type StreamConfig struct {
 	Stream Config `yaml:"ingester"`
@@ -73,6 +134,53 @@ func (c *Config) Validate() error {
 		c.FlowLogTTL.L4Packet = DefaultFlowLogTTL
 	}
 
+	switch c.ThrottlePolicy.QuotaBy {
+	case QUOTA_BY_NONE, QUOTA_BY_VTAP, QUOTA_BY_SERVICE:
+	default:
+		log.Warningf("throttle-policy.quota-by '%s' is invalid, should be '%s' or '%s', ignore it", c.ThrottlePolicy.QuotaBy, QUOTA_BY_VTAP, QUOTA_BY_SERVICE)
+		c.ThrottlePolicy.QuotaBy = QUOTA_BY_NONE
+	}
+
+	if c.OTLPReceiver.GrpcPort == 0 {
+		c.OTLPReceiver.GrpcPort = DefaultOTLPGrpcPort
+	}
+	if c.OTLPReceiver.HttpPort == 0 {
+		c.OTLPReceiver.HttpPort = DefaultOTLPHttpPort
+	}
+	if c.OTLPReceiver.MaxRecvMsgSize <= 0 {
+		c.OTLPReceiver.MaxRecvMsgSize = DefaultOTLPMaxRecvSize
+	}
+
+	if c.KafkaExporter.QueueSize <= 0 {
+		c.KafkaExporter.QueueSize = DefaultExporterQueueSize
+	}
+	if c.KafkaExporter.Enabled {
+		if len(c.KafkaExporter.Brokers) == 0 {
+			return fmt.Errorf("kafka-exporter.brokers is empty")
+		}
+		for i := range c.KafkaExporter.Topics {
+			topic := &c.KafkaExporter.Topics[i]
+			if topic.Name == "" {
+				return fmt.Errorf("kafka-exporter.topics[%d].name is empty", i)
+			}
+			if id := common.FlowLogNameToID(topic.Table); id != common.L4_FLOW_ID && id != common.L7_FLOW_ID {
+				return fmt.Errorf("kafka-exporter topic '%s' table '%s' is invalid, should be '%s' or '%s'", topic.Name, topic.Table, common.L4_FLOW_ID, common.L7_FLOW_ID)
+			}
+			switch topic.Format {
+			case "":
+				topic.Format = EXPORT_FORMAT_JSON
+			case EXPORT_FORMAT_JSON, EXPORT_FORMAT_PROTOBUF:
+			default:
+				return fmt.Errorf("kafka-exporter topic '%s' format '%s' is invalid, should be '%s' or '%s'", topic.Name, topic.Format, EXPORT_FORMAT_JSON, EXPORT_FORMAT_PROTOBUF)
+			}
+			for _, filter := range topic.Filters {
+				if filter.Operator != FILTER_OPERATOR_EQ && filter.Operator != FILTER_OPERATOR_NEQ {
+					return fmt.Errorf("kafka-exporter topic '%s' filter operator '%s' is invalid, should be '%s' or '%s'", topic.Name, filter.Operator, FILTER_OPERATOR_EQ, FILTER_OPERATOR_NEQ)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -85,6 +193,8 @@ func Load(base *config.Config, path string) *Config {
 			DecoderQueueSize:  DefaultDecoderQueueSize,
 			CKWriterConfig:    config.CKWriterConfig{QueueCount: 1, QueueSize: 1000000, BatchSize: 512000, FlushTimeout: 10},
 			FlowLogTTL:        FlowLogTTL{DefaultFlowLogTTL, DefaultFlowLogTTL, DefaultFlowLogTTL},
+			OTLPReceiver:      OTLPReceiverConfig{Enabled: false, GrpcPort: DefaultOTLPGrpcPort, HttpPort: DefaultOTLPHttpPort, MaxRecvMsgSize: DefaultOTLPMaxRecvSize},
+			KafkaExporter:     KafkaExporterConfig{QueueSize: DefaultExporterQueueSize},
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -35,8 +35,14 @@ const (
 	DefaultPartition = ckdb.TimeFuncHour
 )
 
+// Exporter 在流日志写入clickhouse的同时将其导出到外部系统, Put中不应阻塞
+type Exporter interface {
+	Put(index int, items ...interface{})
+}
+
 type FlowLogWriter struct {
 	ckwriters []*ckwriter.CKWriter
+	exporter  Exporter
 }
 
 func newFlowLogTable(id common.FlowLogID, columns []*ckdb.Column, engine ckdb.EngineType, cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
@@ -100,7 +106,15 @@ func NewFlowLogWriter(addr, user, password, cluster, storagePolicy string, ckWri
 	}, nil
 }
 
+func (w *FlowLogWriter) SetExporter(exporter Exporter) {
+	w.exporter = exporter
+}
+
 func (w *FlowLogWriter) Put(index int, items ...interface{}) {
+	// 需在写入ckwriter前导出, 否则数据可能已被ckwriter回收
+	if w.exporter != nil {
+		w.exporter.Put(index, items...)
+	}
 	w.ckwriters[index].Put(items...)
 }
 
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"compress/zlib"
 	"io/ioutil"
+	"net"
 	"strconv"
 
 	"github.com/golang/protobuf/proto"
@@ -63,6 +64,8 @@ type Counter struct {
 	L7NoSQLDropCount  int64 `statsd:"l7-nosql-drop-count"`
 	L7RPCCount        int64 `statsd:"l7-rpc-count"`
 	L7RPCDropCount    int64 `statsd:"l7-rpc-drop-count"`
+	L7GRPCCount       int64 `statsd:"l7-grpc-count"`
+	L7GRPCDropCount   int64 `statsd:"l7-grpc-drop-count"`
 	L7MQCount         int64 `statsd:"l7-mq-count"`
 	L7MQDropCount     int64 `statsd:"l7-mq-drop-count"`
 	OTelCount         int64 `statsd:"otel-count"`
@@ -138,9 +141,9 @@ func (d *Decoder) Run() {
 			case datatype.MESSAGE_TYPE_TAGGEDFLOW:
 				d.handleTaggedFlow(decoder, pbTaggedFlow)
 			case datatype.MESSAGE_TYPE_OPENTELEMETRY:
-				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, false)
+				d.handleOpenTelemetry(recvBytes.VtapID, recvBytes.IP, decoder, pbTracesData, false)
 			case datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED:
-				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, true)
+				d.handleOpenTelemetry(recvBytes.VtapID, recvBytes.IP, decoder, pbTracesData, true)
 			case datatype.MESSAGE_TYPE_PACKETSEQUENCE:
 				d.handleL4Packet(recvBytes.VtapID, decoder)
 			default:
@@ -195,7 +198,7 @@ func decompressOpenTelemetry(compressed []byte) ([]byte, error) {
 	return ioutil.ReadAll(reader)
 }
 
-func (d *Decoder) handleOpenTelemetry(vtapID uint16, decoder *codec.SimpleDecoder, pbTracesData *v1.TracesData, compressed bool) {
+func (d *Decoder) handleOpenTelemetry(vtapID uint16, srcIP net.IP, decoder *codec.SimpleDecoder, pbTracesData *v1.TracesData, compressed bool) {
 	var err error
 	for !decoder.IsEnd() {
 		pbTracesData.Reset()
@@ -215,16 +218,16 @@ func (d *Decoder) handleOpenTelemetry(vtapID uint16, decoder *codec.SimpleDecode
 			d.counter.ErrorCount++
 			return
 		}
-		d.sendOpenMetetry(vtapID, pbTracesData)
+		d.sendOpenMetetry(vtapID, srcIP, pbTracesData)
 	}
 }
 
-func (d *Decoder) sendOpenMetetry(vtapID uint16, tracesData *v1.TracesData) {
+func (d *Decoder) sendOpenMetetry(vtapID uint16, srcIP net.IP, tracesData *v1.TracesData) {
 	if d.debugEnabled {
 		log.Debugf("decoder %d vtap %d recv otel: %s", d.index, vtapID, tracesData)
 	}
 	d.counter.OTelCount++
-	ls := jsonify.OTelTracesDataToL7Loggers(vtapID, tracesData, d.platformData)
+	ls := jsonify.OTelTracesDataToL7Loggers(vtapID, srcIP, tracesData, d.platformData)
 	for _, l := range ls {
 		l.AddReferenceCount()
 		if !d.throttler.Send(l) {
@@ -275,6 +278,8 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 	d.counter.L7Count++
 	drop := int64(0)
 	l := jsonify.ProtoLogToL7Logger(proto, d.platformData)
+	// 以jsonify处理后的协议计数, 带有grpc-status的HTTP2会被识别为gRPC
+	l7Protocol := datatype.L7Protocol(l.L7Protocol)
 	l.AddReferenceCount()
 	if !d.throttler.Send(l) {
 		d.counter.L7DropCount++
@@ -285,7 +290,7 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 	}
 	proto.Release()
 
-	switch datatype.L7Protocol(proto.Base.Head.Proto) {
+	switch l7Protocol {
 	case datatype.L7_PROTOCOL_HTTP_1, datatype.L7_PROTOCOL_HTTP_2, datatype.L7_PROTOCOL_HTTP_1_TLS, datatype.L7_PROTOCOL_HTTP_2_TLS:
 		d.counter.L7HTTPCount++
 		d.counter.L7HTTPDropCount += drop
@@ -295,13 +300,16 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 	case datatype.L7_PROTOCOL_MYSQL, datatype.L7_PROTOCOL_POSTGRE:
 		d.counter.L7SQLCount++
 		d.counter.L7SQLDropCount += drop
-	case datatype.L7_PROTOCOL_REDIS:
+	case datatype.L7_PROTOCOL_REDIS, datatype.L7_PROTOCOL_MONGODB, datatype.L7_PROTOCOL_MEMCACHED:
 		d.counter.L7NoSQLCount++
 		d.counter.L7NoSQLDropCount += drop
-	case datatype.L7_PROTOCOL_DUBBO:
+	case datatype.L7_PROTOCOL_GRPC:
+		d.counter.L7GRPCCount++
+		d.counter.L7GRPCDropCount += drop
+	case datatype.L7_PROTOCOL_DUBBO, datatype.L7_PROTOCOL_SOFARPC:
 		d.counter.L7RPCCount++
 		d.counter.L7RPCDropCount += drop
-	case datatype.L7_PROTOCOL_KAFKA:
+	case datatype.L7_PROTOCOL_KAFKA, datatype.L7_PROTOCOL_AMQP, datatype.L7_PROTOCOL_NATS:
 		fallthrough
 	case datatype.L7_PROTOCOL_MQTT:
 		d.counter.L7MQCount++
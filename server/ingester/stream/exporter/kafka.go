@@ -0,0 +1,376 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	logging "github.com/op/go-logging"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	ingestercommon "github.com/deepflowys/deepflow/server/ingester/common"
+	"github.com/deepflowys/deepflow/server/ingester/stream/common"
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/ingester/stream/jsonify"
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
+	"github.com/deepflowys/deepflow/server/libs/queue"
+	"github.com/deepflowys/deepflow/server/libs/stats"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("stream.exporter")
+
+const (
+	KAFKA_CLIENT_ID = "deepflow-ingester"
+	QUEUE_BATCH     = 1024
+)
+
+// ExportItem 为写入clickhouse的流日志, 导出期间通过引用计数保证数据不被回收
+type ExportItem interface {
+	WriteBlock(block *ckdb.Block) error
+	AddReferenceCount()
+	Release()
+}
+
+type Counter struct {
+	ExportCount int64 `statsd:"export-count"` // 发送到kafka的条数
+	FilterCount int64 `statsd:"filter-count"` // 被过滤条件丢弃的条数
+	ErrorCount  int64 `statsd:"error-count"`  // 序列化或发送失败的条数
+	utils.Closable
+}
+
+func (c *Counter) GetCounter() interface{} {
+	return &Counter{
+		ExportCount: atomic.SwapInt64(&c.ExportCount, 0),
+		FilterCount: atomic.SwapInt64(&c.FilterCount, 0),
+		ErrorCount:  atomic.SwapInt64(&c.ErrorCount, 0),
+	}
+}
+
+type filter struct {
+	index  int
+	equal  bool
+	values map[string]bool
+}
+
+type topicExporter struct {
+	config  *config.KafkaTopic
+	columns []*ckdb.Column
+	indexes []int // 导出列在表所有列中的下标
+	filters []filter
+	counter *Counter
+}
+
+type tableExporter struct {
+	id      common.FlowLogID
+	columns []*ckdb.Column
+	topics  []*topicExporter
+	queue   *queue.OverwriteQueue
+}
+
+// KafkaExporter 将写入clickhouse的流日志按topic配置过滤、选择列后序列化为json或protobuf发送到kafka
+type KafkaExporter struct {
+	producer sarama.AsyncProducer
+	tables   [common.FLOWLOG_ID_MAX]*tableExporter
+	topics   []*topicExporter
+
+	wg   sync.WaitGroup
+	exit int32 // 非0时各导出协程退出, 通过atomic读写
+}
+
+func tableColumns(id common.FlowLogID) []*ckdb.Column {
+	switch id {
+	case common.L4_FLOW_ID:
+		return jsonify.FlowLoggerColumns()
+	case common.L7_FLOW_ID:
+		return jsonify.L7LoggerColumns()
+	}
+	return nil
+}
+
+func NewKafkaExporter(cfg *config.KafkaExporterConfig) (*KafkaExporter, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = KAFKA_CLIENT_ID
+	saramaConfig.Producer.Return.Errors = true
+	producer, err := sarama.NewAsyncProducer(cfg.Brokers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newKafkaExporter(cfg, producer)
+	if err != nil {
+		producer.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func newKafkaExporter(cfg *config.KafkaExporterConfig, producer sarama.AsyncProducer) (*KafkaExporter, error) {
+	e := &KafkaExporter{producer: producer}
+	for i := range cfg.Topics {
+		topicConfig := &cfg.Topics[i]
+		id := common.FlowLogNameToID(topicConfig.Table)
+		columns := tableColumns(id)
+		if columns == nil {
+			return nil, fmt.Errorf("kafka exporter topic '%s' table '%s' is not supported", topicConfig.Name, topicConfig.Table)
+		}
+		topic, err := newTopicExporter(topicConfig, columns)
+		if err != nil {
+			return nil, err
+		}
+		if e.tables[id] == nil {
+			e.tables[id] = &tableExporter{
+				id:      id,
+				columns: columns,
+				queue: queue.NewOverwriteQueue(
+					"kafka-exporter-"+id.String(), cfg.QueueSize,
+					queue.OptionFlushIndicator(time.Second),
+					queue.OptionRelease(func(p interface{}) { p.(ExportItem).Release() }),
+					ingestercommon.QUEUE_STATS_MODULE_INGESTER),
+			}
+		}
+		e.tables[id].topics = append(e.tables[id].topics, topic)
+		e.topics = append(e.topics, topic)
+		ingestercommon.RegisterCountableForIngester("kafka_exporter", topic.counter, stats.OptionStatTags{"topic": topic.config.Name, "table": id.String()})
+	}
+	return e, nil
+}
+
+func newTopicExporter(cfg *config.KafkaTopic, columns []*ckdb.Column) (*topicExporter, error) {
+	indexOf := make(map[string]int, len(columns))
+	for i, column := range columns {
+		indexOf[column.Name] = i
+	}
+	topic := &topicExporter{config: cfg, counter: &Counter{}}
+	if len(cfg.Columns) == 0 {
+		topic.columns = columns
+		for i := range columns {
+			topic.indexes = append(topic.indexes, i)
+		}
+	}
+	for _, name := range cfg.Columns {
+		index, ok := indexOf[name]
+		if !ok {
+			return nil, fmt.Errorf("kafka exporter topic '%s' column '%s' not found in table '%s'", cfg.Name, name, cfg.Table)
+		}
+		topic.columns = append(topic.columns, columns[index])
+		topic.indexes = append(topic.indexes, index)
+	}
+	for _, f := range cfg.Filters {
+		index, ok := indexOf[f.Column]
+		if !ok {
+			return nil, fmt.Errorf("kafka exporter topic '%s' filter column '%s' not found in table '%s'", cfg.Name, f.Column, cfg.Table)
+		}
+		values := make(map[string]bool, len(f.Values))
+		for _, v := range f.Values {
+			values[v] = true
+		}
+		topic.filters = append(topic.filters, filter{index: index, equal: f.Operator == config.FILTER_OPERATOR_EQ, values: values})
+	}
+	return topic, nil
+}
+
+// Put 实现dbwriter.Exporter, 未配置topic的表直接忽略
+func (e *KafkaExporter) Put(index int, items ...interface{}) {
+	if index < 0 || index >= len(e.tables) || e.tables[index] == nil {
+		return
+	}
+	exportItems := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if exportItem, ok := item.(ExportItem); ok {
+			exportItem.AddReferenceCount()
+			exportItems = append(exportItems, exportItem)
+		}
+	}
+	if len(exportItems) > 0 {
+		e.tables[index].queue.Put(exportItems...)
+	}
+}
+
+func (e *KafkaExporter) Start() {
+	for _, table := range e.tables {
+		if table != nil {
+			e.wg.Add(1)
+			go e.run(table)
+		}
+	}
+	go e.handleErrors()
+}
+
+func (e *KafkaExporter) Close() error {
+	atomic.StoreInt32(&e.exit, 1)
+	e.wg.Wait()
+	for _, table := range e.tables {
+		if table != nil {
+			table.queue.Close()
+		}
+	}
+	for _, topic := range e.topics {
+		topic.counter.Close()
+	}
+	return e.producer.Close()
+}
+
+func (e *KafkaExporter) handleErrors() {
+	for err := range e.producer.Errors() {
+		topic, ok := err.Msg.Metadata.(*topicExporter)
+		if !ok {
+			continue
+		}
+		if atomic.AddInt64(&topic.counter.ErrorCount, 1) == 1 {
+			log.Warningf("kafka exporter send to topic %s failed: %s", topic.config.Name, err.Err)
+		}
+	}
+}
+
+func (e *KafkaExporter) run(table *tableExporter) {
+	defer e.wg.Done()
+	items := make([]interface{}, QUEUE_BATCH)
+	for atomic.LoadInt32(&e.exit) == 0 {
+		n := table.queue.Gets(items)
+		for i := 0; i < n; i++ {
+			if item, ok := items[i].(ExportItem); ok {
+				e.export(table, item)
+				item.Release()
+			}
+			items[i] = nil
+		}
+	}
+}
+
+func (e *KafkaExporter) export(table *tableExporter, item ExportItem) {
+	record := ckdb.NewRecordBatch("")
+	if err := item.WriteBlock(&ckdb.Block{Batch: record}); err != nil {
+		for _, topic := range table.topics {
+			if atomic.AddInt64(&topic.counter.ErrorCount, 1) == 1 {
+				log.Warningf("kafka exporter record %s failed: %s", table.id, err)
+			}
+		}
+		return
+	}
+	row := record.Row(0)
+	for i, v := range row {
+		if i < len(table.columns) {
+			row[i] = exportValue(v, table.columns[i].Type)
+		}
+	}
+
+	for _, topic := range table.topics {
+		if !topic.match(row) {
+			atomic.AddInt64(&topic.counter.FilterCount, 1)
+			continue
+		}
+		value, err := topic.encode(row)
+		if err != nil {
+			if atomic.AddInt64(&topic.counter.ErrorCount, 1) == 1 {
+				log.Warningf("kafka exporter encode topic %s failed: %s", topic.config.Name, err)
+			}
+			continue
+		}
+		e.producer.Input() <- &sarama.ProducerMessage{
+			Topic:    topic.config.Name,
+			Value:    sarama.ByteEncoder(value),
+			Metadata: topic,
+		}
+		atomic.AddInt64(&topic.counter.ExportCount, 1)
+	}
+}
+
+func (t *topicExporter) match(row []interface{}) bool {
+	for _, f := range t.filters {
+		if f.values[valueString(row[f.index])] != f.equal {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *topicExporter) encode(row []interface{}) ([]byte, error) {
+	fields := make(map[string]interface{}, len(t.indexes))
+	for i, index := range t.indexes {
+		fields[t.columns[i].Name] = row[index]
+	}
+	if t.config.Format == config.EXPORT_FORMAT_PROTOBUF {
+		s, err := structpb.NewStruct(fields)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(s)
+	}
+	return json.Marshal(fields)
+}
+
+// exportValue 将写入clickhouse的列值转换为通用类型: IP转为字符串, 时间按列精度转为unix时间戳,
+// 可为空的列取指针指向的值或nil, 数组转为[]interface{}以便protobuf序列化.
+// structpb不支持8/16位整数, 统一扩展为int64/uint64
+func exportValue(v interface{}, columnType ckdb.ColumnType) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case net.IP:
+		return value.String()
+	case time.Time:
+		switch columnType {
+		case ckdb.DateTime64ms:
+			return value.UnixNano() / int64(time.Millisecond)
+		case ckdb.DateTime64us:
+			return value.UnixNano() / int64(time.Microsecond)
+		default:
+			return value.Unix()
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return exportValue(rv.Elem().Interface(), columnType)
+	case reflect.Slice:
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = exportValue(rv.Index(i).Interface(), columnType)
+		}
+		return values
+	case reflect.Int8, reflect.Int16:
+		return rv.Int()
+	case reflect.Uint8, reflect.Uint16:
+		return rv.Uint()
+	}
+	return v
+}
+
+func valueString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/deepflowys/deepflow/server/ingester/stream/common"
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/ingester/stream/jsonify"
+	"github.com/deepflowys/deepflow/server/libs/ckdb"
+)
+
+func newL7Logger(domain string, code int32) *jsonify.L7Logger {
+	l := jsonify.AcquireL7Logger()
+	l.IP40 = 0x0a000001
+	l.IsIPv4 = true
+	l.L7ProtocolStr = "HTTP"
+	l.RequestDomain = domain
+	l.ResponseCode = &code
+	l.AttributeNames = []string{"user"}
+	return l
+}
+
+func TestKafkaExporterEncode(t *testing.T) {
+	cfg := &config.KafkaExporterConfig{
+		QueueSize: 16,
+		Topics: []config.KafkaTopic{
+			{
+				Name:    "l7-json",
+				Table:   common.L7_FLOW_ID.String(),
+				Format:  config.EXPORT_FORMAT_JSON,
+				Columns: []string{"ip4_0", "request_domain", "response_code", "attribute_names"},
+				Filters: []config.KafkaFilter{{Column: "request_domain", Operator: config.FILTER_OPERATOR_NEQ, Values: []string{"health"}}},
+			},
+			{
+				Name:    "l7-pb",
+				Table:   common.L7_FLOW_ID.String(),
+				Format:  config.EXPORT_FORMAT_PROTOBUF,
+				Columns: []string{"l7_protocol_str", "response_code"},
+				Filters: []config.KafkaFilter{{Column: "response_code", Operator: config.FILTER_OPERATOR_EQ, Values: []string{"500"}}},
+			},
+		},
+	}
+	producer := mocks.NewAsyncProducer(t, nil)
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		value, _ := msg.Value.Encode()
+		var fields map[string]interface{}
+		if msg.Topic != "l7-json" || json.Unmarshal(value, &fields) != nil {
+			t.Errorf("unexpected message %s: %s", msg.Topic, value)
+			return nil
+		}
+		if fields["ip4_0"] != "10.0.0.1" || fields["request_domain"] != "example.com" || fields["response_code"] != float64(200) || len(fields) != 4 {
+			t.Errorf("json message = %v", fields)
+		}
+		return nil
+	})
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != "l7-json" {
+			t.Errorf("unexpected topic %s", msg.Topic)
+		}
+		return nil
+	})
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		value, _ := msg.Value.Encode()
+		fields := &structpb.Struct{}
+		if err := proto.Unmarshal(value, fields); err != nil || msg.Topic != "l7-pb" {
+			t.Errorf("unexpected message %s: %v", msg.Topic, err)
+			return nil
+		}
+		if fields.Fields["l7_protocol_str"].GetStringValue() != "HTTP" || fields.Fields["response_code"].GetNumberValue() != 500 {
+			t.Errorf("protobuf message = %v", fields)
+		}
+		return nil
+	})
+
+	e, err := newKafkaExporter(cfg, producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := e.tables[common.L7_FLOW_ID]
+	for _, l := range []*jsonify.L7Logger{newL7Logger("example.com", 200), newL7Logger("health", 200), newL7Logger("example.com", 500)} {
+		e.Put(int(common.L7_FLOW_ID), l)
+		if l.GetReferenceCount() != 2 {
+			t.Errorf("reference count after Put = %d, want 2", l.GetReferenceCount())
+		}
+		item := table.queue.Get().(*jsonify.L7Logger)
+		e.export(table, item)
+		item.Release()
+		l.Release()
+	}
+	// 未配置topic的表直接忽略
+	e.Put(int(common.L4_FLOW_ID), jsonify.AcquireFlowLogger())
+
+	jsonCounter, pbCounter := table.topics[0].counter, table.topics[1].counter
+	if jsonCounter.ExportCount != 2 || jsonCounter.FilterCount != 1 || pbCounter.ExportCount != 1 || pbCounter.FilterCount != 2 {
+		t.Errorf("counters = %+v %+v", jsonCounter, pbCounter)
+	}
+	if err := producer.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKafkaExporterProtobufAllColumns(t *testing.T) {
+	cfg := &config.KafkaExporterConfig{
+		QueueSize: 16,
+		Topics: []config.KafkaTopic{
+			{Name: "l4-pb", Table: common.L4_FLOW_ID.String(), Format: config.EXPORT_FORMAT_PROTOBUF},
+			{Name: "l7-pb", Table: common.L7_FLOW_ID.String(), Format: config.EXPORT_FORMAT_PROTOBUF},
+		},
+	}
+	checkMessage := func(topic string, columnCount int, check func(fields map[string]*structpb.Value)) func(*sarama.ProducerMessage) error {
+		return func(msg *sarama.ProducerMessage) error {
+			value, _ := msg.Value.Encode()
+			s := &structpb.Struct{}
+			if err := proto.Unmarshal(value, s); err != nil || msg.Topic != topic {
+				t.Errorf("unexpected message %s: %v", msg.Topic, err)
+				return nil
+			}
+			if len(s.Fields) != columnCount {
+				t.Errorf("topic %s exports %d columns, want %d", topic, len(s.Fields), columnCount)
+			}
+			check(s.Fields)
+			return nil
+		}
+	}
+	producer := mocks.NewAsyncProducer(t, nil)
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(checkMessage("l4-pb", len(jsonify.FlowLoggerColumns()), func(fields map[string]*structpb.Value) {
+		if fields["protocol"].GetNumberValue() != 6 || fields["server_port"].GetNumberValue() != 80 ||
+			fields["tap_type"].GetNumberValue() != 3 || fields["vtap_id"].GetNumberValue() != 1 {
+			t.Errorf("l4 protobuf message = %v", fields)
+		}
+	}))
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(checkMessage("l7-pb", len(jsonify.L7LoggerColumns()), func(fields map[string]*structpb.Value) {
+		if fields["protocol"].GetNumberValue() != 6 || fields["client_port"].GetNumberValue() != 12345 ||
+			fields["l7_protocol"].GetNumberValue() != 20 || fields["response_status"].GetNumberValue() != 3 ||
+			fields["is_ipv4"].GetNumberValue() != 1 || fields["vtap_id"].GetNumberValue() != 1 {
+			t.Errorf("l7 protobuf message = %v", fields)
+		}
+	}))
+
+	e, err := newKafkaExporter(cfg, producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := jsonify.AcquireFlowLogger()
+	f.Protocol = 6
+	f.ServerPort = 80
+	f.TapType = 3
+	f.VtapID = 1
+	e.Put(int(common.L4_FLOW_ID), f)
+	f.Release()
+
+	l := newL7Logger("example.com", 500)
+	l.Protocol = 6
+	l.ClientPort = 12345
+	l.L7Protocol = 20
+	l.ResponseStatus = 3
+	l.VtapID = 1
+	e.Put(int(common.L7_FLOW_ID), l)
+	l.Release()
+
+	for _, id := range []common.FlowLogID{common.L4_FLOW_ID, common.L7_FLOW_ID} {
+		table := e.tables[id]
+		item := table.queue.Get().(ExportItem)
+		e.export(table, item)
+		item.Release()
+	}
+	for _, topic := range e.topics {
+		if topic.counter.ExportCount != 1 || topic.counter.ErrorCount != 0 {
+			t.Errorf("topic %s counter = %+v", topic.config.Name, topic.counter)
+		}
+	}
+	if err := producer.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExportValue(t *testing.T) {
+	code := uint16(404)
+	for _, c := range []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{uint8(1), uint64(1)},
+		{uint16(2), uint64(2)},
+		{int8(-3), int64(-3)},
+		{int16(-4), int64(-4)},
+		{&code, uint64(404)},
+		{[]uint16{1, 2}, []interface{}{uint64(1), uint64(2)}},
+		{uint32(5), uint32(5)},
+	} {
+		v := exportValue(c.value, ckdb.UInt16)
+		if !reflect.DeepEqual(v, c.expected) {
+			t.Errorf("exportValue(%v) = %#v, want %#v", c.value, v, c.expected)
+		}
+		if _, err := structpb.NewValue(v); err != nil {
+			t.Errorf("exportValue(%v) is not supported by protobuf: %s", c.value, err)
+		}
+	}
+}
+
+func TestKafkaExporterConfig(t *testing.T) {
+	cfg := &config.KafkaExporterConfig{
+		QueueSize: 16,
+		Topics:    []config.KafkaTopic{{Name: "l4", Table: common.L4_FLOW_ID.String(), Columns: []string{"no_such_column"}}},
+	}
+	if _, err := newKafkaExporter(cfg, nil); err == nil {
+		t.Error("unknown column should fail")
+	}
+	cfg.Topics[0].Columns = nil
+	cfg.Topics[0].Table = common.L4_PACKET_ID.String()
+	if _, err := newKafkaExporter(cfg, nil); err == nil {
+		t.Error("unsupported table should fail")
+	}
+}
+
+func TestKafkaExporterMockBroker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("l7", 0, broker.BrokerID()),
+		// sarama默认的kafka版本(1.0.0)使用v3的ProduceRequest
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3),
+	})
+
+	cfg := &config.KafkaExporterConfig{
+		Brokers:   []string{broker.Addr()},
+		QueueSize: 16,
+		Topics:    []config.KafkaTopic{{Name: "l7", Table: common.L7_FLOW_ID.String(), Format: config.EXPORT_FORMAT_JSON}},
+	}
+	e, err := NewKafkaExporter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Start()
+	l := newL7Logger("example.com", 200)
+	l.IP60 = net.ParseIP("::1")
+	e.Put(int(common.L7_FLOW_ID), l)
+	l.Release()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !hasProduceRequest(broker) {
+		if time.Now().After(deadline) {
+			t.Fatal("no produce request received by broker")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+	if counter := e.topics[0].counter; counter.ExportCount != 1 || counter.ErrorCount != 0 {
+		t.Errorf("counter = %+v", counter)
+	}
+}
+
+func hasProduceRequest(broker *sarama.MockBroker) bool {
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			return true
+		}
+	}
+	return false
+}
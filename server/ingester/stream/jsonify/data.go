@@ -1291,6 +1291,23 @@ func (f *FlowLogger) EndTime() time.Duration {
 	return time.Duration(f.FlowInfo.EndTime) * time.Second
 }
 
+// 以下方法实现throttler.Sampleable, 流日志没有trace及服务信息
+func (f *FlowLogger) GetVtapID() uint16 {
+	return f.FlowInfo.VtapID
+}
+
+func (f *FlowLogger) GetTraceID() string {
+	return ""
+}
+
+func (f *FlowLogger) GetServiceName() string {
+	return ""
+}
+
+func (f *FlowLogger) GetResponseStatus() uint8 {
+	return f.FlowInfo.Status
+}
+
 func (f *FlowLogger) String() string {
 	return fmt.Sprintf("flow: %+v\n", *f)
 }
@@ -50,7 +50,7 @@ func TestJsonify(t *testing.T) {
 }
 
 func TestZeroToNull(t *testing.T) {
-	pf := grpc.NewPlatformInfoTable(nil, 0, "", "", "", nil)
+	pf := grpc.NewPlatformInfoTable(nil, 0, 0, "", "", "", nil)
 	taggedFlow := pb.TaggedFlow{
 		Flow: &pb.Flow{
 			FlowKey:        &pb.FlowKey{},
@@ -60,7 +60,7 @@ func TestZeroToNull(t *testing.T) {
 		},
 	}
 
-	flow := TaggedFlowToLogger(&taggedFlow, 0, pf)
+	flow := TaggedFlowToLogger(&taggedFlow, pf)
 
 	flowByte, _ := json.Marshal(flow)
 
@@ -76,7 +76,7 @@ func TestZeroToNull(t *testing.T) {
 	if flow.EndTime() != 0 {
 		t.Error("flow endtime should be 0")
 	}
-	flow.String()
+	_ = flow.String()
 	flow.Release()
 }
 
@@ -106,17 +106,17 @@ func TestProtoLogToHTTPLogger(t *testing.T) {
 	appData.Base.VtapId = 123
 	appData.Base.EndTime = uint64(10 * time.Microsecond)
 	appData.Base.Head.Proto = uint32(datatype.L7_PROTOCOL_HTTP_1)
-	appData.Http = &pb.HttpInfo{}
+	appData.Req = &pb.L7Request{}
 
-	pf := grpc.NewPlatformInfoTable(nil, 0, "", "", "", nil)
-	httpData := ProtoLogToL7Logger(appData, 0, pf).(*L7Logger)
+	pf := grpc.NewPlatformInfoTable(nil, 0, 0, "", "", "", nil)
+	httpData := ProtoLogToL7Logger(appData, pf)
 	if httpData.VtapID != 123 {
 		t.Errorf("expect 123, result %v", httpData.VtapID)
 	}
 	if httpData.EndTime() != 10*time.Microsecond {
 		t.Errorf("expect 10000000, result %v", httpData.EndTime())
 	}
-	httpData.String()
+	_ = httpData.String()
 	httpData.Release()
 }
 
@@ -129,16 +129,16 @@ func TestProtoLogToDNSLogger(t *testing.T) {
 	appData.Base.TapType = 3
 	appData.Base.EndTime = uint64(10 * time.Microsecond)
 	appData.Base.Head.Proto = uint32(datatype.L7_PROTOCOL_DNS)
-	appData.Dns = &pb.DnsInfo{}
+	appData.Req = &pb.L7Request{}
 
-	pf := grpc.NewPlatformInfoTable(nil, 0, "", "", "", nil)
-	dnsData := ProtoLogToL7Logger(appData, 0, pf).(*L7Logger)
+	pf := grpc.NewPlatformInfoTable(nil, 0, 0, "", "", "", nil)
+	dnsData := ProtoLogToL7Logger(appData, pf)
 	if dnsData.TapType != 3 {
 		t.Errorf("expect 3, result %v", dnsData.TapType)
 	}
 	if dnsData.EndTime() != 10*time.Microsecond {
 		t.Errorf("expect 10000000, result %v", dnsData.EndTime())
 	}
-	dnsData.String()
+	_ = dnsData.String()
 	dnsData.Release()
 }
@@ -450,3 +450,135 @@ func GetMQTTV5ExceptionDesc(errCode uint16) string {
 	}
 	return ""
 }
+
+// AMQP 0-9-1 reply-code
+var amqpExceptionDescs = map[uint16]string{
+	311: "content-too-large",
+	312: "no-route",
+	313: "no-consumers",
+	320: "connection-forced",
+	402: "invalid-path",
+	403: "access-refused",
+	404: "not-found",
+	405: "resource-locked",
+	406: "precondition-failed",
+	501: "frame-error",
+	502: "syntax-error",
+	503: "command-invalid",
+	504: "channel-error",
+	505: "unexpected-frame",
+	506: "resource-error",
+	530: "not-allowed",
+	540: "not-implemented",
+	541: "internal-error",
+}
+
+func GetAMQPExceptionDesc(errCode uint16) string {
+	return amqpExceptionDescs[errCode]
+}
+
+// memcached二进制协议的响应状态码
+var memcachedExceptionDescs = map[uint16]string{
+	0x01: "Key not found",
+	0x02: "Key exists",
+	0x03: "Value too large",
+	0x04: "Invalid arguments",
+	0x05: "Item not stored",
+	0x06: "Incr/Decr on non-numeric value",
+	0x07: "The vbucket belongs to another server",
+	0x08: "Authentication error",
+	0x09: "Authentication continue",
+	0x81: "Unknown command",
+	0x82: "Out of memory",
+	0x83: "Not supported",
+	0x84: "Internal error",
+	0x85: "Busy",
+	0x86: "Temporary failure",
+}
+
+func GetMemcachedExceptionDesc(errCode uint16) string {
+	return memcachedExceptionDescs[errCode]
+}
+
+// SOFARPC Bolt协议的响应状态
+var sofaRpcExceptionDescs = []string{
+	0:  "SUCCESS",
+	1:  "ERROR",
+	2:  "SERVER_EXCEPTION",
+	3:  "UNKNOWN",
+	4:  "SERVER_THREADPOOL_BUSY",
+	5:  "ERROR_COMM",
+	6:  "NO_PROCESSOR",
+	7:  "TIMEOUT",
+	8:  "CLIENT_SEND_ERROR",
+	9:  "CODEC_EXCEPTION",
+	16: "CONNECTION_CLOSED",
+	17: "SERVER_SERIAL_EXCEPTION",
+	18: "SERVER_DESERIAL_EXCEPTION",
+}
+
+func GetSofaRpcExceptionDesc(errCode uint16) string {
+	if errCode > 0 && int(errCode) < len(sofaRpcExceptionDescs) {
+		return sofaRpcExceptionDescs[errCode]
+	}
+	return ""
+}
+
+// MongoDB常见的错误码, 其他错误码使用响应中的errmsg
+var mongoDBExceptionDescs = map[int32]string{
+	1:     "InternalError",
+	2:     "BadValue",
+	4:     "NoSuchKey",
+	6:     "HostUnreachable",
+	7:     "HostNotFound",
+	9:     "FailedToParse",
+	11:    "UserNotFound",
+	13:    "Unauthorized",
+	18:    "AuthenticationFailed",
+	26:    "NamespaceNotFound",
+	43:    "CursorNotFound",
+	48:    "NamespaceExists",
+	50:    "MaxTimeMSExpired",
+	59:    "CommandNotFound",
+	89:    "NetworkTimeout",
+	91:    "ShutdownInProgress",
+	112:   "WriteConflict",
+	121:   "DocumentValidationFailure",
+	189:   "PrimarySteppedDown",
+	10107: "NotWritablePrimary",
+	11000: "DuplicateKey",
+	11600: "InterruptedAtShutdown",
+	13435: "NotPrimaryNoSecondaryOk",
+}
+
+func GetMongoDBExceptionDesc(errCode int32) string {
+	return mongoDBExceptionDescs[errCode]
+}
+
+// gRPC status code, 参考: https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+var grpcExceptionDescs = []string{
+	0:  "OK",
+	1:  "CANCELLED",
+	2:  "UNKNOWN",
+	3:  "INVALID_ARGUMENT",
+	4:  "DEADLINE_EXCEEDED",
+	5:  "NOT_FOUND",
+	6:  "ALREADY_EXISTS",
+	7:  "PERMISSION_DENIED",
+	8:  "RESOURCE_EXHAUSTED",
+	9:  "FAILED_PRECONDITION",
+	10: "ABORTED",
+	11: "OUT_OF_RANGE",
+	12: "UNIMPLEMENTED",
+	13: "INTERNAL",
+	14: "UNAVAILABLE",
+	15: "DATA_LOSS",
+	16: "UNAUTHENTICATED",
+}
+
+func GetGRPCExceptionDesc(errCode uint16) string {
+	if errCode > 0 && int(errCode) < len(grpcExceptionDescs) {
+		return grpcExceptionDescs[errCode]
+	}
+	return ""
+}
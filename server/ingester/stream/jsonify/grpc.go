@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonify
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+)
+
+const (
+	GRPC_STATUS_OK                  = 0
+	GRPC_STATUS_CANCELLED           = 1
+	GRPC_STATUS_UNKNOWN             = 2
+	GRPC_STATUS_INVALID_ARGUMENT    = 3
+	GRPC_STATUS_DEADLINE_EXCEEDED   = 4
+	GRPC_STATUS_NOT_FOUND           = 5
+	GRPC_STATUS_ALREADY_EXISTS      = 6
+	GRPC_STATUS_PERMISSION_DENIED   = 7
+	GRPC_STATUS_RESOURCE_EXHAUSTED  = 8
+	GRPC_STATUS_FAILED_PRECONDITION = 9
+	GRPC_STATUS_ABORTED             = 10
+	GRPC_STATUS_OUT_OF_RANGE        = 11
+	GRPC_STATUS_UNIMPLEMENTED       = 12
+	GRPC_STATUS_INTERNAL            = 13
+	GRPC_STATUS_UNAVAILABLE         = 14
+	GRPC_STATUS_DATA_LOSS           = 15
+	GRPC_STATUS_UNAUTHENTICATED     = 16
+)
+
+const GRPC_METHOD = "POST" // gRPC请求的HTTP method总是POST
+
+// 采集器或应用以属性上报的grpc-status及grpc-message
+var (
+	grpcStatusAttributes  = []string{"grpc-status", "grpc_status", "rpc.grpc.status_code"}
+	grpcMessageAttributes = []string{"grpc-message", "grpc_message"}
+)
+
+// grpcCodeToResponseStatus 调用方引起的错误(参数、权限、认证等)为客户端异常, 其余错误为服务端异常
+func grpcCodeToResponseStatus(code int32) uint8 {
+	switch code {
+	case GRPC_STATUS_OK:
+		return datatype.STATUS_OK
+	case GRPC_STATUS_CANCELLED, GRPC_STATUS_INVALID_ARGUMENT, GRPC_STATUS_NOT_FOUND,
+		GRPC_STATUS_ALREADY_EXISTS, GRPC_STATUS_PERMISSION_DENIED, GRPC_STATUS_FAILED_PRECONDITION,
+		GRPC_STATUS_OUT_OF_RANGE, GRPC_STATUS_UNAUTHENTICATED:
+		return datatype.STATUS_CLIENT_ERROR
+	default:
+		return datatype.STATUS_SERVER_ERROR
+	}
+}
+
+// httpCodeToGRPCCode 没有grpc-status时, 由HTTP状态码推断gRPC状态码,
+// 参考: https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func httpCodeToGRPCCode(code int32) int32 {
+	switch code {
+	case 400:
+		return GRPC_STATUS_INTERNAL
+	case 401:
+		return GRPC_STATUS_UNAUTHENTICATED
+	case 403:
+		return GRPC_STATUS_PERMISSION_DENIED
+	case 404:
+		return GRPC_STATUS_UNIMPLEMENTED
+	case 429, 502, 503, 504:
+		return GRPC_STATUS_UNAVAILABLE
+	default:
+		return GRPC_STATUS_UNKNOWN
+	}
+}
+
+// splitGRPCPath 将gRPC的path(/package.Service/Method)拆分为服务名(package.Service)和方法名
+func splitGRPCPath(path string) (string, string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	index := strings.LastIndexByte(path, '/')
+	if index <= 0 || index == len(path)-1 {
+		return "", "", false
+	}
+	return path[:index], path[index+1:], true
+}
+
+func (h *L7Logger) getAttribute(names []string) (string, bool) {
+	for i, name := range h.AttributeNames {
+		for _, n := range names {
+			if name == n && i < len(h.AttributeValues) {
+				return h.AttributeValues[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+func (h *L7Logger) isGRPC() bool {
+	switch datatype.L7Protocol(h.L7Protocol) {
+	case datatype.L7_PROTOCOL_GRPC:
+		return true
+	case datatype.L7_PROTOCOL_HTTP_2, datatype.L7_PROTOCOL_HTTP_2_TLS:
+		// 未识别为gRPC的HTTP2, 若带有grpc-status也按gRPC处理
+		_, ok := h.getAttribute(grpcStatusAttributes)
+		return ok
+	}
+	return false
+}
+
+// fillGRPC 处理gRPC的请求及响应:
+//   - request_domain为服务名, request_resource为方法名, endpoint为完整的path
+//   - grpc-status写入response_code并据此计算response_status, 没有grpc-status且inferFromHTTP时由非200的HTTP状态码推断,
+//     HTTP 200且没有grpc-status时(调用结果在trailer中)保持原有的response_code及response_status不变
+//   - grpc-message写入response_exception, 没有时使用状态码的描述
+func (h *L7Logger) fillGRPC(path, message string, inferFromHTTP bool) {
+	h.L7Protocol = uint8(datatype.L7_PROTOCOL_GRPC)
+	h.L7ProtocolStr = datatype.L7_PROTOCOL_GRPC.String()
+	if service, method, ok := splitGRPCPath(path); ok {
+		h.RequestDomain = service
+		h.RequestResource = method
+		h.Endpoint = "/" + service + "/" + method
+	}
+	if h.RequestType == "" {
+		h.RequestType = GRPC_METHOD
+	}
+	if h.Type == uint8(datatype.MSG_T_REQUEST) {
+		return
+	}
+
+	if m, ok := h.getAttribute(grpcMessageAttributes); ok && message == "" {
+		message = m
+	}
+	if status, ok := h.getAttribute(grpcStatusAttributes); ok {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return
+		}
+		h.responseCode = int32(code)
+	} else if inferFromHTTP && h.ResponseCode != nil && h.responseCode != 200 {
+		h.responseCode = httpCodeToGRPCCode(h.responseCode)
+	} else {
+		return
+	}
+	h.ResponseCode = &h.responseCode
+	h.ResponseStatus = grpcCodeToResponseStatus(h.responseCode)
+	if h.ResponseStatus == datatype.STATUS_OK {
+		h.ResponseException = ""
+	} else if message != "" {
+		h.ResponseException = message
+	} else {
+		h.ResponseException = GetGRPCExceptionDesc(uint16(h.responseCode))
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonify
+
+import (
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+)
+
+func TestSplitGRPCPath(t *testing.T) {
+	for _, c := range []struct {
+		path    string
+		service string
+		method  string
+		ok      bool
+	}{
+		{"/helloworld.Greeter/SayHello", "helloworld.Greeter", "SayHello", true},
+		{"grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check", true},
+		{"/a.b/c/Method", "a.b/c", "Method", true},
+		{"/helloworld.Greeter/", "", "", false},
+		{"/SayHello", "", "", false},
+		{"", "", "", false},
+	} {
+		service, method, ok := splitGRPCPath(c.path)
+		if service != c.service || method != c.method || ok != c.ok {
+			t.Errorf("splitGRPCPath(%q) = %q, %q, %v, want %q, %q, %v", c.path, service, method, ok, c.service, c.method, c.ok)
+		}
+	}
+}
+
+func TestGRPCCodeToResponseStatus(t *testing.T) {
+	for code, status := range map[int32]uint8{
+		GRPC_STATUS_OK:                datatype.STATUS_OK,
+		GRPC_STATUS_INVALID_ARGUMENT:  datatype.STATUS_CLIENT_ERROR,
+		GRPC_STATUS_NOT_FOUND:         datatype.STATUS_CLIENT_ERROR,
+		GRPC_STATUS_UNAUTHENTICATED:   datatype.STATUS_CLIENT_ERROR,
+		GRPC_STATUS_UNKNOWN:           datatype.STATUS_SERVER_ERROR,
+		GRPC_STATUS_DEADLINE_EXCEEDED: datatype.STATUS_SERVER_ERROR,
+		GRPC_STATUS_INTERNAL:          datatype.STATUS_SERVER_ERROR,
+		GRPC_STATUS_UNAVAILABLE:       datatype.STATUS_SERVER_ERROR,
+		100:                           datatype.STATUS_SERVER_ERROR,
+	} {
+		if s := grpcCodeToResponseStatus(code); s != status {
+			t.Errorf("grpcCodeToResponseStatus(%d) = %d, want %d", code, s, status)
+		}
+	}
+}
+
+func newGRPCResponse(httpCode int32) *L7Logger {
+	h := &L7Logger{}
+	h.Type = uint8(datatype.MSG_T_RESPONSE)
+	h.L7Protocol = uint8(datatype.L7_PROTOCOL_HTTP_2)
+	h.responseCode = httpCode
+	h.ResponseCode = &h.responseCode
+	h.ResponseStatus = datatype.STATUS_OK
+	return h
+}
+
+func TestFillGRPC(t *testing.T) {
+	// 请求只处理path
+	h := &L7Logger{}
+	h.Type = uint8(datatype.MSG_T_REQUEST)
+	h.fillGRPC("/helloworld.Greeter/SayHello", "", true)
+	if h.RequestDomain != "helloworld.Greeter" || h.RequestResource != "SayHello" || h.Endpoint != "/helloworld.Greeter/SayHello" ||
+		h.RequestType != GRPC_METHOD || h.L7ProtocolStr != datatype.L7_PROTOCOL_GRPC.String() || h.ResponseCode != nil {
+		t.Errorf("request = %+v", h)
+	}
+
+	// grpc-status及grpc-message
+	h = newGRPCResponse(200)
+	h.AttributeNames = []string{"grpc-status", "grpc-message"}
+	h.AttributeValues = []string{"5", "user not found"}
+	if !h.isGRPC() {
+		t.Error("http2 with grpc-status should be grpc")
+	}
+	h.fillGRPC("/user.User/Get", "", true)
+	if h.ResponseCode == nil || *h.ResponseCode != GRPC_STATUS_NOT_FOUND ||
+		h.ResponseStatus != datatype.STATUS_CLIENT_ERROR || h.ResponseException != "user not found" {
+		t.Errorf("response with grpc-status = %+v", h)
+	}
+
+	// HTTP 200且没有grpc-status时保持原有响应码
+	h = newGRPCResponse(200)
+	h.fillGRPC("/user.User/Get", "", true)
+	if h.ResponseCode == nil || *h.ResponseCode != 200 || h.ResponseStatus != datatype.STATUS_OK || h.ResponseException != "" {
+		t.Errorf("response without grpc-status = %+v", h)
+	}
+
+	// 非200的HTTP状态码推断gRPC状态码
+	h = newGRPCResponse(503)
+	h.fillGRPC("/user.User/Get", "", true)
+	if h.ResponseCode == nil || *h.ResponseCode != GRPC_STATUS_UNAVAILABLE || h.ResponseStatus != datatype.STATUS_SERVER_ERROR ||
+		h.ResponseException != GetGRPCExceptionDesc(GRPC_STATUS_UNAVAILABLE) {
+		t.Errorf("response with http 503 = %+v", h)
+	}
+
+	// OTel不由HTTP状态码推断, 且保留rpc.method
+	h = newGRPCResponse(503)
+	h.RequestType = "Get"
+	h.fillGRPC("/user.User/Get", "", false)
+	if *h.ResponseCode != 503 || h.ResponseStatus != datatype.STATUS_OK || h.RequestType != "Get" {
+		t.Errorf("otel response = %+v", h)
+	}
+}
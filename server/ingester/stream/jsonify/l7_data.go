@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/deepflowys/deepflow/server/ingester/flow_tag"
@@ -295,7 +296,7 @@ func L7LoggerColumns() []*ckdb.Column {
 	l7Columns = append(l7Columns, ckdb.NewColumn("_id", ckdb.UInt64).SetCodec(ckdb.CodecDoubleDelta))
 	l7Columns = append(l7Columns, L7BaseColumns()...)
 	l7Columns = append(l7Columns,
-		ckdb.NewColumn("l7_protocol", ckdb.UInt8).SetIndex(ckdb.IndexNone).SetComment("0:未知 1:其他, 20:http1, 21:http2, 40:dubbo, 60:mysql, 80:redis, 100:kafka, 101:mqtt, 120:dns"),
+		ckdb.NewColumn("l7_protocol", ckdb.UInt8).SetIndex(ckdb.IndexNone).SetComment("0:未知 1:其他, 20:http1, 21:http2, 40:dubbo, 43:sofarpc, 60:mysql, 80:redis, 81:mongodb, 82:memcached, 100:kafka, 101:mqtt, 102:amqp, 104:nats, 120:dns"),
 		ckdb.NewColumn("l7_protocol_str", ckdb.LowCardinalityString).SetIndex(ckdb.IndexNone).SetComment("应用协议"),
 		ckdb.NewColumn("version", ckdb.LowCardinalityString).SetComment("协议版本"),
 		ckdb.NewColumn("type", ckdb.UInt8).SetIndex(ckdb.IndexNone).SetComment("日志类型, 0:请求, 1:响应, 2:会话"),
@@ -526,6 +527,19 @@ func (h *L7Logger) fillL7Log(l *pb.AppProtoLogsData) {
 		h.TraceId = l.TraceInfo.TraceId
 	}
 
+	if h.isGRPC() {
+		// 采集器识别的gRPC, path在endpoint中, 否则与HTTP2相同, path在request_resource中
+		path := h.Endpoint
+		if !strings.HasPrefix(path, "/") {
+			path = h.RequestResource
+		}
+		message := ""
+		if l.Resp != nil {
+			message = l.Resp.Exception
+		}
+		h.fillGRPC(path, message, true)
+	}
+
 	// 处理内置协议特殊情况
 	switch datatype.L7Protocol(h.L7Protocol) {
 	case datatype.L7_PROTOCOL_KAFKA:
@@ -560,7 +574,20 @@ func (h *L7Logger) fillExceptionDesc(l *pb.AppProtoLogsData) {
 		} else {
 			h.ResponseException = GetMQTTV5ExceptionDesc(uint16(code))
 		}
-	case datatype.L7_PROTOCOL_MYSQL, datatype.L7_PROTOCOL_REDIS:
+	case datatype.L7_PROTOCOL_AMQP:
+		h.ResponseException = GetAMQPExceptionDesc(uint16(code))
+	case datatype.L7_PROTOCOL_SOFARPC:
+		h.ResponseException = GetSofaRpcExceptionDesc(uint16(code))
+	case datatype.L7_PROTOCOL_MEMCACHED:
+		// 文本协议没有状态码, 使用ERROR, CLIENT_ERROR, SERVER_ERROR后的错误信息
+		if h.ResponseException = l.Resp.Exception; h.ResponseException == "" {
+			h.ResponseException = GetMemcachedExceptionDesc(uint16(code))
+		}
+	case datatype.L7_PROTOCOL_MONGODB:
+		if h.ResponseException = l.Resp.Exception; h.ResponseException == "" {
+			h.ResponseException = GetMongoDBExceptionDesc(code)
+		}
+	case datatype.L7_PROTOCOL_MYSQL, datatype.L7_PROTOCOL_REDIS, datatype.L7_PROTOCOL_NATS:
 		fallthrough
 	default:
 		h.ResponseException = l.Resp.Exception
@@ -579,6 +606,23 @@ func (h *L7Logger) String() string {
 	return fmt.Sprintf("L7Log: %+v\n", *h)
 }
 
+// 以下方法实现throttler.Sampleable
+func (h *L7Logger) GetVtapID() uint16 {
+	return h.L7Base.VtapID
+}
+
+func (h *L7Logger) GetTraceID() string {
+	return h.TraceId
+}
+
+func (h *L7Logger) GetServiceName() string {
+	return h.ServiceName
+}
+
+func (h *L7Logger) GetResponseStatus() uint8 {
+	return h.ResponseStatus
+}
+
 func (b *L7Base) Fill(log *pb.AppProtoLogsData, platformData *grpc.PlatformInfoTable) {
 	l := log.Base
 	// 网络层
@@ -33,7 +33,8 @@ import (
 	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
-func OTelTracesDataToL7Loggers(vtapID uint16, l *v1.TracesData, platformData *grpc.PlatformInfoTable) []*L7Logger {
+// srcIP为数据发送方的ip, 当vtapID为0(非采集器发送)时用于查询平台信息
+func OTelTracesDataToL7Loggers(vtapID uint16, srcIP net.IP, l *v1.TracesData, platformData *grpc.PlatformInfoTable) []*L7Logger {
 	ret := []*L7Logger{}
 	for _, resourceSpan := range l.GetResourceSpans() {
 		var resAttributes []*v11.KeyValue
@@ -43,18 +44,18 @@ func OTelTracesDataToL7Loggers(vtapID uint16, l *v1.TracesData, platformData *gr
 		}
 		for _, scopeSpan := range resourceSpan.GetScopeSpans() {
 			for _, span := range scopeSpan.GetSpans() {
-				ret = append(ret, spanToL7Logger(vtapID, span, resAttributes, platformData))
+				ret = append(ret, spanToL7Logger(vtapID, srcIP, span, resAttributes, platformData))
 			}
 		}
 	}
 	return ret
 }
 
-func spanToL7Logger(vtapID uint16, span *v1.Span, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable) *L7Logger {
+func spanToL7Logger(vtapID uint16, srcIP net.IP, span *v1.Span, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable) *L7Logger {
 	h := AcquireL7Logger()
 	h._id = genID(uint32(span.EndTimeUnixNano/uint64(time.Second)), &L7LogCounter, vtapID)
 	h.VtapID = vtapID
-	h.FillOTel(span, resAttributes, platformData)
+	h.FillOTel(span, resAttributes, srcIP, platformData)
 	return h
 }
 
@@ -301,7 +302,7 @@ func (h *L7Logger) fillAttributes(spanAttributes, resAttributes []*v11.KeyValue,
 	h.MetricsValues = metricsValues
 }
 
-func (h *L7Logger) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable) {
+func (h *L7Logger) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, srcIP net.IP, platformData *grpc.PlatformInfoTable) {
 	// OTel data net protocol always set to TCP
 	h.Protocol = uint8(layers.IPProtocolTCP)
 	h.Type = uint8(datatype.MSG_T_SESSION)
@@ -320,6 +321,10 @@ func (h *L7Logger) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, platformD
 	}
 
 	h.fillAttributes(l.GetAttributes(), resAttributes, l.GetLinks())
+	// 非采集器发送的数据, 若没有通过app.host.ip获取到应用的IP, 则使用发送方的IP
+	if h.VtapID == 0 && srcIP != nil && !h.hasHostIP() {
+		h.fillHostIP(srcIP)
+	}
 	// 优先匹配http的响应码
 	if h.responseCode != 0 {
 		h.ResponseStatus = httpCodeToResponseStatus(h.responseCode)
@@ -340,11 +345,63 @@ func (h *L7Logger) FillOTel(l *v1.Span, resAttributes []*v11.KeyValue, platformD
 			}
 		}
 	}
-	h.L7Base.KnowledgeGraph.FillOTel(h, platformData)
+	if h.isGRPC() {
+		// rpc.service, rpc.method分别写入了request_resource, request_type(保留为方法名); 没有时span名称一般为package.Service/Method
+		path := h.Endpoint
+		if h.RequestResource != "" && h.RequestType != "" {
+			path = h.RequestResource + "/" + h.RequestType
+		}
+		message := ""
+		if l.Status != nil {
+			message = l.Status.Message
+		}
+		h.fillGRPC(path, message, false)
+	}
+	h.L7Base.KnowledgeGraph.FillOTel(h, srcIP, platformData)
+}
+
+func (h *L7Logger) hasHostIP() bool {
+	if h.TapSide == "c-app" {
+		return h.IP40 != 0 || h.IP60 != nil
+	}
+	return h.IP41 != 0 || h.IP61 != nil
+}
+
+func (h *L7Logger) fillHostIP(ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		// 对端IP为IPv6时无法同时记录IPv4的本端IP
+		if !h.IsIPv4 {
+			return
+		}
+		if h.TapSide == "c-app" {
+			h.IP40 = utils.IpToUint32(ip4)
+		} else {
+			h.IP41 = utils.IpToUint32(ip4)
+		}
+	} else {
+		if h.IsIPv4 && (h.IP40 != 0 || h.IP41 != 0) {
+			return
+		}
+		h.IsIPv4 = false
+		if h.TapSide == "c-app" {
+			h.IP60 = ip
+		} else {
+			h.IP61 = ip
+		}
+	}
 }
 
-func (k *KnowledgeGraph) FillOTel(l *L7Logger, platformData *grpc.PlatformInfoTable) {
-	k.L3EpcID0 = platformData.QueryVtapEpc0(uint32(l.VtapID))
+func (k *KnowledgeGraph) FillOTel(l *L7Logger, srcIP net.IP, platformData *grpc.PlatformInfoTable) {
+	if l.VtapID == 0 && srcIP != nil {
+		// 没有vtap id时, 以发送方的ip查询epc
+		if ip4 := srcIP.To4(); ip4 != nil {
+			k.L3EpcID0 = platformData.QueryIPEpc(true, utils.IpToUint32(ip4), nil)
+		} else {
+			k.L3EpcID0 = platformData.QueryIPEpc(false, 0, srcIP)
+		}
+	} else {
+		k.L3EpcID0 = platformData.QueryVtapEpc0(uint32(l.VtapID))
+	}
 	k.L3EpcID1 = platformData.QueryVtapEpc1(uint32(l.VtapID), l.IsIPv4, l.IP41, l.IP61)
 	k.fill(
 		platformData,
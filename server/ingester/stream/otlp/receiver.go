@@ -0,0 +1,383 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	logging "github.com/op/go-logging"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/deepflowys/deepflow/server/ingester/common"
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/libs/codec"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+	"github.com/deepflowys/deepflow/server/libs/queue"
+	"github.com/deepflowys/deepflow/server/libs/receiver"
+	"github.com/deepflowys/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("stream.otlp")
+
+const (
+	TRACES_PATH  = "/v1/traces"
+	METRICS_PATH = "/v1/metrics"
+	LOGS_PATH    = "/v1/logs"
+
+	CONTENT_TYPE_PROTOBUF = "application/x-protobuf"
+	CONTENT_TYPE_JSON     = "application/json"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+type Counter struct {
+	GrpcCount  int64 `statsd:"grpc-count"`
+	HttpCount  int64 `statsd:"http-count"`
+	InBytes    int64 `statsd:"in-bytes"`
+	ErrorCount int64 `statsd:"err-count"`
+	DropCount  int64 `statsd:"drop-count"`
+}
+
+type decodeQueues struct {
+	queues queue.MultiQueueWriter
+	count  int
+	cursor uint32
+}
+
+// Receiver 直接接收OTLP/gRPC及OTLP/HTTP的trace, metrics, logs数据,
+// 转换为TracesData/MetricsData/LogsData的pb编码后, 放入对应消息类型的解析队列:
+//   - trace: MESSAGE_TYPE_OPENTELEMETRY, 与采集器转发的数据统一处理
+//   - metrics: MESSAGE_TYPE_OTEL_METRICS, 写入ext_metrics
+//   - logs: MESSAGE_TYPE_OTEL_LOGS, 写入application_log
+//
+// 未注册解析队列的数据类型, gRPC返回Unimplemented, HTTP返回404
+type Receiver struct {
+	config     *config.OTLPReceiverConfig
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	queues     map[datatype.MessageType]*decodeQueues
+
+	counter *Counter
+	utils.Closable
+}
+
+func NewReceiver(cfg *config.OTLPReceiverConfig) *Receiver {
+	r := &Receiver{
+		config:     cfg,
+		grpcServer: grpc.NewServer(grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize)),
+		httpServer: &http.Server{
+			Addr:    ":" + strconv.Itoa(cfg.HttpPort),
+			Handler: mux.NewRouter(),
+		},
+		queues:  make(map[datatype.MessageType]*decodeQueues),
+		counter: &Counter{},
+	}
+	coltracepb.RegisterTraceServiceServer(r.grpcServer, &traceService{receiver: r})
+	colmetricspb.RegisterMetricsServiceServer(r.grpcServer, &metricsService{receiver: r})
+	collogspb.RegisterLogsServiceServer(r.grpcServer, &logsService{receiver: r})
+	router := r.httpServer.Handler.(*mux.Router)
+	router.HandleFunc(TRACES_PATH, r.httpHandler(datatype.MESSAGE_TYPE_OPENTELEMETRY, func() proto.Message { return &coltracepb.ExportTraceServiceRequest{} }, &coltracepb.ExportTraceServiceResponse{})).Methods("POST")
+	router.HandleFunc(METRICS_PATH, r.httpHandler(datatype.MESSAGE_TYPE_OTEL_METRICS, func() proto.Message { return &colmetricspb.ExportMetricsServiceRequest{} }, &colmetricspb.ExportMetricsServiceResponse{})).Methods("POST")
+	router.HandleFunc(LOGS_PATH, r.httpHandler(datatype.MESSAGE_TYPE_OTEL_LOGS, func() proto.Message { return &collogspb.ExportLogsServiceRequest{} }, &collogspb.ExportLogsServiceResponse{})).Methods("POST")
+	return r
+}
+
+// RegisterQueues 注册消息类型对应的解析队列, 需在Start前调用
+func (r *Receiver) RegisterQueues(msgType datatype.MessageType, queues queue.MultiQueueWriter, queueCount int) {
+	r.queues[msgType] = &decodeQueues{queues: queues, count: queueCount}
+}
+
+func (r *Receiver) GetCounter() interface{} {
+	counter := &Counter{}
+	counter.GrpcCount = atomic.SwapInt64(&r.counter.GrpcCount, 0)
+	counter.HttpCount = atomic.SwapInt64(&r.counter.HttpCount, 0)
+	counter.InBytes = atomic.SwapInt64(&r.counter.InBytes, 0)
+	counter.ErrorCount = atomic.SwapInt64(&r.counter.ErrorCount, 0)
+	counter.DropCount = atomic.SwapInt64(&r.counter.DropCount, 0)
+	return counter
+}
+
+type traceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	receiver *Receiver
+}
+
+// Export 实现OTLP/gRPC的TraceService
+func (s *traceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if err := s.receiver.grpcExport(ctx, datatype.MESSAGE_TYPE_OPENTELEMETRY, req); err != nil {
+		return nil, err
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	receiver *Receiver
+}
+
+// Export 实现OTLP/gRPC的MetricsService
+func (s *metricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	if err := s.receiver.grpcExport(ctx, datatype.MESSAGE_TYPE_OTEL_METRICS, req); err != nil {
+		return nil, err
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+type logsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	receiver *Receiver
+}
+
+// Export 实现OTLP/gRPC的LogsService
+func (s *logsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if err := s.receiver.grpcExport(ctx, datatype.MESSAGE_TYPE_OTEL_LOGS, req); err != nil {
+		return nil, err
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// grpcExport Export*ServiceRequest与对应的TracesData/MetricsData/LogsData的pb编码相同, 直接编码后放入队列
+func (r *Receiver) grpcExport(ctx context.Context, msgType datatype.MessageType, req proto.Message) error {
+	atomic.AddInt64(&r.counter.GrpcCount, 1)
+	if r.queues[msgType] == nil {
+		return status.Errorf(codes.Unimplemented, "%s is not enabled", msgType)
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		atomic.AddInt64(&r.counter.ErrorCount, 1)
+		return err
+	}
+	var srcIP net.IP
+	if p, ok := peer.FromContext(ctx); ok {
+		srcIP = addrToIP(p.Addr.String())
+	}
+	return r.put(msgType, data, srcIP)
+}
+
+func (r *Receiver) httpHandler(msgType datatype.MessageType, newRequest func() proto.Message, response proto.Message) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if r.queues[msgType] == nil {
+			http.NotFound(w, req)
+			return
+		}
+		r.httpExport(w, req, msgType, newRequest, response)
+	}
+}
+
+func (r *Receiver) httpExport(w http.ResponseWriter, req *http.Request, msgType datatype.MessageType, newRequest func() proto.Message, response proto.Message) {
+	atomic.AddInt64(&r.counter.HttpCount, 1)
+	// 与gRPC的MaxRecvMsgSize一致, 压缩前后的数据均不能超过max-recv-msg-size
+	maxSize := int64(r.config.MaxRecvMsgSize)
+	req.Body = http.MaxBytesReader(w, req.Body, maxSize+1)
+	body, err := readLimited(req.Body, maxSize)
+	if err == nil && req.Header.Get("Content-Encoding") == "gzip" {
+		var gzipReader *gzip.Reader
+		gzipReader, err = gzip.NewReader(bytes.NewReader(body))
+		if err == nil {
+			body, err = readLimited(gzipReader, maxSize)
+			gzipReader.Close()
+		}
+	}
+	if err != nil {
+		atomic.AddInt64(&r.counter.ErrorCount, 1)
+		if errors.Is(err, errBodyTooLarge) {
+			http.Error(w, fmt.Sprintf("request body exceeds max-recv-msg-size %d", maxSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	var data []byte
+	switch contentType {
+	case CONTENT_TYPE_PROTOBUF:
+		data = body
+	case CONTENT_TYPE_JSON:
+		data, err = jsonToProtobuf(body, newRequest())
+		if err != nil {
+			atomic.AddInt64(&r.counter.ErrorCount, 1)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		atomic.AddInt64(&r.counter.ErrorCount, 1)
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err := r.put(msgType, data, addrToIP(req.RemoteAddr)); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	var resp []byte
+	if contentType == CONTENT_TYPE_JSON {
+		resp, _ = protojson.Marshal(response)
+	} else {
+		resp, _ = proto.Marshal(response)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// readLimited 最多读取maxSize字节, 超过时返回errBodyTooLarge
+func readLimited(reader io.Reader, maxSize int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// put 将pb编码的数据按与采集器相同的格式封装, 放入消息类型对应的解析队列
+func (r *Receiver) put(msgType datatype.MessageType, data []byte, srcIP net.IP) error {
+	queues := r.queues[msgType]
+	atomic.AddInt64(&r.counter.InBytes, int64(len(data)))
+	encoder := codec.AcquireSimpleEncoder()
+	encoder.WriteBytes(data)
+	buffer := receiver.AcquireRecvBuffer(len(encoder.Bytes()))
+	buffer.End = copy(buffer.Buffer, encoder.Bytes())
+	codec.ReleaseSimpleEncoder(encoder)
+	buffer.IP = srcIP
+
+	index := atomic.AddUint32(&queues.cursor, 1) % uint32(queues.count)
+	if err := queues.queues.Put(queue.HashKey(index), buffer); err != nil {
+		receiver.ReleaseRecvBuffer(buffer)
+		atomic.AddInt64(&r.counter.DropCount, 1)
+		return err
+	}
+	return nil
+}
+
+func addrToIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// OTLP/JSON中traceId/spanId/parentSpanId使用hex编码, 与protojson的bytes类型默认的base64编码不同, 需要先转换
+// 参考: https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#json-protobuf-encoding
+func jsonToProtobuf(body []byte, req proto.Message) ([]byte, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	if err := hexIDsToBase64(m); err != nil {
+		return nil, err
+	}
+	converted, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(converted, req); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(req)
+}
+
+func hexIDsToBase64(v interface{}) error {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if k == "traceId" || k == "spanId" || k == "parentSpanId" {
+				if s, ok := child.(string); ok {
+					id, err := hex.DecodeString(s)
+					if err != nil {
+						return fmt.Errorf("invalid %s %q: %s", k, s, err)
+					}
+					value[k] = base64.StdEncoding.EncodeToString(id)
+				}
+				continue
+			}
+			if err := hexIDsToBase64(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if err := hexIDsToBase64(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Receiver) Start() {
+	common.RegisterCountableForIngester("otlp-receiver", r)
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(r.config.GrpcPort))
+	if err != nil {
+		log.Errorf("otlp grpc listen on port %d failed: %s", r.config.GrpcPort, err)
+	} else {
+		go func() {
+			if err := r.grpcServer.Serve(listener); err != nil {
+				log.Errorf("otlp grpc Serve() failed: %v", err)
+			}
+		}()
+		log.Infof("otlp grpc receiver listen on %d", r.config.GrpcPort)
+	}
+	go func() {
+		if err := r.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Errorf("otlp http ListenAndServe() failed: %v", err)
+		}
+	}()
+	log.Infof("otlp http receiver listen on %s", r.httpServer.Addr)
+}
+
+func (r *Receiver) Close() error {
+	r.Closable.Close()
+	r.grpcServer.GracefulStop()
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if err := r.httpServer.Shutdown(ctx); err != nil {
+		log.Errorf("otlp http Shutdown() failed: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+)
+
+func TestJsonToProtobuf(t *testing.T) {
+	body := []byte(`{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]},
+		"scopeSpans":[{"spans":[{"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","parentSpanId":"",
+		"name":"GET /","kind":2,"startTimeUnixNano":"1544712660000000000","endTimeUnixNano":"1544712661000000000"}]}]}]}`)
+	data, err := jsonToProtobuf(body, &coltracepb.ExportTraceServiceRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracesData := &v1.TracesData{}
+	if err := proto.Unmarshal(data, tracesData); err != nil {
+		t.Fatal(err)
+	}
+	span := tracesData.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if !bytes.Equal(span.TraceId, []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}) {
+		t.Errorf("traceId = %x", span.TraceId)
+	}
+	if len(span.ParentSpanId) != 0 || span.Name != "GET /" || span.EndTimeUnixNano != 1544712661000000000 {
+		t.Errorf("span = %v", span)
+	}
+
+	if _, err := jsonToProtobuf([]byte(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"xyz"}]}]}]}`), &coltracepb.ExportTraceServiceRequest{}); err == nil {
+		t.Error("invalid hex trace id should fail")
+	}
+
+	body = []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"1544712660000000000",
+		"severityNumber":9,"body":{"stringValue":"hello"},"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174"}]}]}]}`)
+	data, err = jsonToProtobuf(body, &collogspb.ExportLogsServiceRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logsData := &logsv1.LogsData{}
+	if err := proto.Unmarshal(data, logsData); err != nil {
+		t.Fatal(err)
+	}
+	record := logsData.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if len(record.SpanId) != 8 || record.Body.GetStringValue() != "hello" || record.SeverityNumber != logsv1.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("log record = %v", record)
+	}
+}
+
+func TestHttpExportMaxSize(t *testing.T) {
+	r := &Receiver{config: &config.OTLPReceiverConfig{MaxRecvMsgSize: 100}, counter: &Counter{}}
+	export := func(body []byte, gzipped bool) int {
+		req := httptest.NewRequest("POST", TRACES_PATH, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		if gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		w := httptest.NewRecorder()
+		r.httpExport(w, req, datatype.MESSAGE_TYPE_OPENTELEMETRY, func() proto.Message { return &coltracepb.ExportTraceServiceRequest{} }, &coltracepb.ExportTraceServiceResponse{})
+		return w.Code
+	}
+	compress := func(data []byte) []byte {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.Write(data)
+		writer.Close()
+		return buf.Bytes()
+	}
+
+	// 未超过限制时继续校验Content-Type
+	if code := export(make([]byte, 100), false); code != http.StatusUnsupportedMediaType {
+		t.Errorf("body within limit: code = %d", code)
+	}
+	if code := export(make([]byte, 101), false); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body exceeds limit: code = %d", code)
+	}
+	// 压缩后很小但解压后超过限制
+	compressed := compress(make([]byte, 10000))
+	if len(compressed) > 100 {
+		t.Fatalf("compressed size %d", len(compressed))
+	}
+	if code := export(compressed, true); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("decompressed body exceeds limit: code = %d", code)
+	}
+	if code := export(compress(make([]byte, 100)), true); code != http.StatusUnsupportedMediaType {
+		t.Errorf("decompressed body within limit: code = %d", code)
+	}
+}
@@ -31,7 +31,9 @@ import (
 	"github.com/deepflowys/deepflow/server/ingester/stream/config"
 	"github.com/deepflowys/deepflow/server/ingester/stream/dbwriter"
 	"github.com/deepflowys/deepflow/server/ingester/stream/decoder"
+	"github.com/deepflowys/deepflow/server/ingester/stream/exporter"
 	"github.com/deepflowys/deepflow/server/ingester/stream/geo"
+	"github.com/deepflowys/deepflow/server/ingester/stream/otlp"
 	"github.com/deepflowys/deepflow/server/ingester/stream/throttler"
 	"github.com/deepflowys/deepflow/server/libs/datatype"
 	"github.com/deepflowys/deepflow/server/libs/debug"
@@ -52,6 +54,8 @@ type Stream struct {
 	OtelLogger           *Logger
 	OtelCompressedLogger *Logger
 	L4PacketLogger       *Logger
+	OTLPReceiver         *otlp.Receiver
+	KafkaExporter        *exporter.KafkaExporter
 }
 
 type Logger struct {
@@ -59,6 +63,7 @@ type Logger struct {
 	Decoders      []*decoder.Decoder
 	PlatformDatas []*grpc.PlatformInfoTable
 	FlowLogWriter *dbwriter.FlowLogWriter
+	DecodeQueues  queue.MultiQueueWriter
 }
 
 func NewStream(config *config.Config, recv *receiver.Receiver) (*Stream, error) {
@@ -79,6 +84,14 @@ func NewStream(config *config.Config, recv *receiver.Receiver) (*Stream, error)
 	if err != nil {
 		return nil, err
 	}
+	var kafkaExporter *exporter.KafkaExporter
+	if config.KafkaExporter.Enabled {
+		kafkaExporter, err = exporter.NewKafkaExporter(&config.KafkaExporter)
+		if err != nil {
+			return nil, err
+		}
+		flowLogWriter.SetExporter(kafkaExporter)
+	}
 	l4FlowLogger := NewL4FlowLogger(config, controllers, manager, recv, flowLogWriter)
 	flowTagWriter, err := flow_tag.NewFlowTagWriter(common.FLOW_LOG_DB, common.FLOW_LOG_DB, config.FlowLogTTL.L7FlowLog, dbwriter.DefaultPartition, config.Base, &config.CKWriterConfig)
 	if err != nil {
@@ -88,6 +101,12 @@ func NewStream(config *config.Config, recv *receiver.Receiver) (*Stream, error)
 	otelLogger := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY, config, controllers, manager, recv, flowLogWriter, common.L7_FLOW_ID, flowTagWriter)
 	otelCompressedLogger := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED, config, controllers, manager, recv, flowLogWriter, common.L7_FLOW_ID, flowTagWriter)
 	l4PacketLogger := NewLogger(datatype.MESSAGE_TYPE_PACKETSEQUENCE, config, nil, manager, recv, flowLogWriter, common.L4_PACKET_ID, nil)
+	var otlpReceiver *otlp.Receiver
+	if config.OTLPReceiver.Enabled {
+		otlpReceiver = otlp.NewReceiver(&config.OTLPReceiver)
+		// 直接接收的OTLP trace数据与采集器转发的OpenTelemetry数据共用解析队列
+		otlpReceiver.RegisterQueues(datatype.MESSAGE_TYPE_OPENTELEMETRY, otelLogger.DecodeQueues, config.DecoderQueueCount)
+	}
 	return &Stream{
 		StreamConfig:         config,
 		L4FlowLogger:         l4FlowLogger,
@@ -95,6 +114,8 @@ func NewStream(config *config.Config, recv *receiver.Receiver) (*Stream, error)
 		OtelLogger:           otelLogger,
 		OtelCompressedLogger: otelCompressedLogger,
 		L4PacketLogger:       l4PacketLogger,
+		OTLPReceiver:         otlpReceiver,
+		KafkaExporter:        kafkaExporter,
 	}, nil
 }
 
@@ -110,6 +131,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, controllers
 	recv.RegistHandler(msgType, decodeQueues, queueCount)
 	throttle := config.Throttle / queueCount
 
+	policy := throttler.NewSamplingPolicy(config.ThrottlePolicy)
 	throttlers := make([]*throttler.ThrottlingQueue, queueCount)
 	decoders := make([]*decoder.Decoder, queueCount)
 	platformDatas := make([]*grpc.PlatformInfoTable, queueCount)
@@ -118,6 +140,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, controllers
 			throttle,
 			flowLogWriter,
 			int(flowLogId),
+			policy,
 		)
 		if controllers != nil {
 			platformDatas[i] = grpc.NewPlatformInfoTable(controllers, int(config.Base.ControllerPort), config.Base.GrpcBufferSize, "stream-"+datatype.MessageTypeString[msgType]+"-"+strconv.Itoa(i), "", config.Base.NodeIP, nil)
@@ -139,6 +162,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, controllers
 		Decoders:      decoders,
 		PlatformDatas: platformDatas,
 		FlowLogWriter: flowLogWriter,
+		DecodeQueues:  decodeQueues,
 	}
 }
 
@@ -161,6 +185,7 @@ func NewL4FlowLogger(config *config.Config, controllers []net.IP, manager *dropl
 		throttle = config.L4Throttle / queueCount
 	}
 
+	policy := throttler.NewSamplingPolicy(config.ThrottlePolicy)
 	throttlers := make([]*throttler.ThrottlingQueue, queueCount)
 	decoders := make([]*decoder.Decoder, queueCount)
 	platformDatas := make([]*grpc.PlatformInfoTable, queueCount)
@@ -170,6 +195,7 @@ func NewL4FlowLogger(config *config.Config, controllers []net.IP, manager *dropl
 			throttle,
 			flowLogWriter,
 			int(common.L4_FLOW_ID),
+			policy,
 		)
 		platformDatas[i] = grpc.NewPlatformInfoTable(controllers, int(config.Base.ControllerPort), config.Base.GrpcBufferSize, "stream-l4-log-"+strconv.Itoa(i), "", config.Base.NodeIP, nil)
 		if i == 0 {
@@ -212,6 +238,7 @@ func NewL7FlowLogger(config *config.Config, controllers []net.IP, manager *dropl
 		throttle = config.L7Throttle / queueCount
 	}
 
+	policy := throttler.NewSamplingPolicy(config.ThrottlePolicy)
 	throttlers := make([]*throttler.ThrottlingQueue, queueCount)
 
 	platformDatas := make([]*grpc.PlatformInfoTable, queueCount)
@@ -221,6 +248,7 @@ func NewL7FlowLogger(config *config.Config, controllers []net.IP, manager *dropl
 			throttle,
 			flowLogWriter,
 			int(common.L7_FLOW_ID),
+			policy,
 		)
 		platformDatas[i] = grpc.NewPlatformInfoTable(controllers, int(config.Base.ControllerPort), config.Base.GrpcBufferSize, "stream-l7-log-"+strconv.Itoa(i), "", config.Base.NodeIP, nil)
 		decoders[i] = decoder.NewDecoder(
@@ -261,11 +289,17 @@ func (l *Logger) Close() {
 }
 
 func (s *Stream) Start() {
+	if s.KafkaExporter != nil {
+		s.KafkaExporter.Start()
+	}
 	s.L4FlowLogger.Start()
 	s.L7FlowLogger.Start()
 	s.L4PacketLogger.Start()
 	s.OtelLogger.Start()
 	s.OtelCompressedLogger.Start()
+	if s.OTLPReceiver != nil {
+		s.OTLPReceiver.Start()
+	}
 }
 
 func (s *Stream) Close() error {
@@ -274,5 +308,11 @@ func (s *Stream) Close() error {
 	s.L4PacketLogger.Close()
 	s.OtelLogger.Close()
 	s.OtelCompressedLogger.Close()
+	if s.OTLPReceiver != nil {
+		s.OTLPReceiver.Close()
+	}
+	if s.KafkaExporter != nil {
+		s.KafkaExporter.Close()
+	}
 	return nil
 }
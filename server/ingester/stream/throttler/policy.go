@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package throttler
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+)
+
+// Sampleable 由流日志实现, 提供采样策略所需的信息
+type Sampleable interface {
+	GetVtapID() uint16
+	GetTraceID() string
+	GetServiceName() string
+	GetResponseStatus() uint8
+}
+
+// SamplingPolicy 决定ThrottlingQueue中每条数据的采样方式:
+// 数据按Key分桶, 各桶平分限速配额; AlwaysKeep的数据进入单独的桶, 配额与总限速相同;
+// 每个桶在一个周期内保留Priority数值最小的数据
+type SamplingPolicy interface {
+	Key(item interface{}) string
+	Priority(item interface{}) uint64
+	AlwaysKeep(item interface{}) bool
+}
+
+type policy struct {
+	config config.ThrottlePolicy
+}
+
+// NewSamplingPolicy 根据配置创建采样策略, 默认配置下等同于对所有数据做均匀的蓄水池采样
+func NewSamplingPolicy(cfg config.ThrottlePolicy) SamplingPolicy {
+	return &policy{config: cfg}
+}
+
+func (p *policy) Key(item interface{}) string {
+	s, ok := item.(Sampleable)
+	if !ok {
+		return ""
+	}
+	switch p.config.QuotaBy {
+	case config.QUOTA_BY_VTAP:
+		return strconv.Itoa(int(s.GetVtapID()))
+	case config.QUOTA_BY_SERVICE:
+		return s.GetServiceName()
+	}
+	return ""
+}
+
+// Priority 使用随机数时, 保留最小的N个等价于蓄水池采样; 使用trace_id的hash时, 各ingester的选择一致
+func (p *policy) Priority(item interface{}) uint64 {
+	if p.config.TraceConsistent {
+		if s, ok := item.(Sampleable); ok {
+			if traceID := s.GetTraceID(); traceID != "" {
+				h := fnv.New64a()
+				h.Write([]byte(traceID))
+				return h.Sum64()
+			}
+		}
+	}
+	return rand.Uint64()
+}
+
+func (p *policy) AlwaysKeep(item interface{}) bool {
+	if !p.config.KeepError {
+		return false
+	}
+	s, ok := item.(Sampleable)
+	if !ok {
+		return false
+	}
+	switch s.GetResponseStatus() {
+	case datatype.STATUS_SERVER_ERROR, datatype.STATUS_CLIENT_ERROR, datatype.STATUS_NOT_EXIST:
+		return true
+	}
+	return false
+}
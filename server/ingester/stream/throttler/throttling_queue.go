@@ -17,9 +17,10 @@
 package throttler
 
 import (
-	"math/rand"
+	"container/heap"
 	"time"
 
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
 	"github.com/deepflowys/deepflow/server/ingester/stream/dbwriter"
 )
 
@@ -31,32 +32,129 @@ type throttleItem interface {
 	Release()
 }
 
+type sampleItem struct {
+	priority uint64
+	item     interface{}
+}
+
+// reservoir 保留priority最小的capacity条数据, 使用大顶堆实现
+type reservoir struct {
+	capacity int
+	items    []sampleItem
+}
+
+func (r *reservoir) Len() int           { return len(r.items) }
+func (r *reservoir) Less(i, j int) bool { return r.items[i].priority > r.items[j].priority }
+func (r *reservoir) Swap(i, j int)      { r.items[i], r.items[j] = r.items[j], r.items[i] }
+func (r *reservoir) Push(x interface{}) { r.items = append(r.items, x.(sampleItem)) }
+func (r *reservoir) Pop() interface{} {
+	n := len(r.items)
+	x := r.items[n-1]
+	r.items[n-1] = sampleItem{}
+	r.items = r.items[:n-1]
+	return x
+}
+
+// add 返回数据是否被保留, 被替换或未被保留的数据会被Release
+func (r *reservoir) add(priority uint64, item interface{}) bool {
+	if len(r.items) < r.capacity {
+		heap.Push(r, sampleItem{priority, item})
+		return true
+	}
+	if r.capacity == 0 || priority >= r.items[0].priority {
+		release(item)
+		return false
+	}
+	release(r.items[0].item)
+	r.items[0] = sampleItem{priority, item}
+	heap.Fix(r, 0)
+	return true
+}
+
+func release(item interface{}) {
+	if tItem, ok := item.(throttleItem); ok {
+		tItem.Release()
+	}
+}
+
 type ThrottlingQueue struct {
 	flowLogWriter *dbwriter.FlowLogWriter
 	index         int
+	policy        SamplingPolicy
 
-	Throttle        int
-	lastFlush       int64
-	periodCount     int
-	periodEmitCount int
+	Throttle  int
+	lastFlush int64
 
+	keepBucket  *reservoir
+	buckets     map[string]*reservoir
+	lastKeys    int // 上个周期的桶数, 用于计算每个桶的配额
+	emitItems   []interface{}
 	sampleItems []interface{}
+	emitCount   int
 }
 
-func NewThrottlingQueue(throttle int, flowLogWriter *dbwriter.FlowLogWriter, index int) *ThrottlingQueue {
+func NewThrottlingQueue(throttle int, flowLogWriter *dbwriter.FlowLogWriter, index int, policy SamplingPolicy) *ThrottlingQueue {
 	thq := &ThrottlingQueue{
 		Throttle:      throttle * THROTTLE_BUCKET,
 		flowLogWriter: flowLogWriter,
 		index:         index,
+		policy:        policy,
+		buckets:       make(map[string]*reservoir),
+		lastKeys:      1,
+	}
+	if thq.policy == nil {
+		thq.policy = NewSamplingPolicy(config.ThrottlePolicy{})
 	}
-	thq.sampleItems = make([]interface{}, thq.Throttle)
+	thq.keepBucket = &reservoir{capacity: thq.Throttle}
 	return thq
 }
 
 func (thq *ThrottlingQueue) flush() {
-	if thq.periodEmitCount > 0 {
-		thq.flowLogWriter.Put(thq.index, thq.sampleItems[:thq.periodEmitCount]...)
+	thq.emitItems = thq.emitItems[:0]
+	for _, r := range append([]*reservoir{thq.keepBucket}, thq.bucketList()...) {
+		for i := range r.items {
+			thq.emitItems = append(thq.emitItems, r.items[i].item)
+			r.items[i] = sampleItem{}
+		}
+		r.items = r.items[:0]
+	}
+	if len(thq.emitItems) > 0 {
+		thq.flowLogWriter.Put(thq.index, thq.emitItems...)
+	}
+	for i := range thq.emitItems {
+		thq.emitItems[i] = nil
+	}
+	if len(thq.buckets) > 0 {
+		thq.lastKeys = len(thq.buckets)
+	}
+	thq.buckets = make(map[string]*reservoir, thq.lastKeys)
+}
+
+func (thq *ThrottlingQueue) bucketList() []*reservoir {
+	buckets := make([]*reservoir, 0, len(thq.buckets))
+	for _, r := range thq.buckets {
+		buckets = append(buckets, r)
+	}
+	return buckets
+}
+
+// bucket 获取数据所属的桶, 各桶配额为限速除以桶数, 桶数取上个周期的桶数与当前周期已有桶数的较大值,
+// 避免新出现大量的桶时总数据量远超限速
+func (thq *ThrottlingQueue) bucket(key string) *reservoir {
+	r, ok := thq.buckets[key]
+	if !ok {
+		keys := thq.lastKeys
+		if len(thq.buckets)+1 > keys {
+			keys = len(thq.buckets) + 1
+		}
+		capacity := thq.Throttle / keys
+		if capacity == 0 && thq.Throttle > 0 {
+			capacity = 1
+		}
+		r = &reservoir{capacity: capacity}
+		thq.buckets[key] = r
 	}
+	return r
 }
 
 func (thq *ThrottlingQueue) Send(flow interface{}) bool {
@@ -64,41 +162,26 @@ func (thq *ThrottlingQueue) Send(flow interface{}) bool {
 	if now/THROTTLE_BUCKET != thq.lastFlush/THROTTLE_BUCKET {
 		thq.flush()
 		thq.lastFlush = now
-		thq.periodCount = 0
-		thq.periodEmitCount = 0
 	}
 	if flow == nil {
 		return false
 	}
 
-	// Reservoir Sampling
-	thq.periodCount++
-	if thq.periodEmitCount < thq.Throttle {
-		thq.sampleItems[thq.periodEmitCount] = flow
-		thq.periodEmitCount++
-		return true
-	} else {
-		r := rand.Intn(thq.periodCount)
-		if r < thq.Throttle {
-			if tItem, ok := thq.sampleItems[r].(throttleItem); ok {
-				tItem.Release()
-			}
-			thq.sampleItems[r] = flow
-		} else {
-			if tItem, ok := flow.(throttleItem); ok {
-				tItem.Release()
-			}
-		}
-		return false
+	if thq.policy.AlwaysKeep(flow) {
+		return thq.keepBucket.add(thq.policy.Priority(flow), flow)
 	}
+	return thq.bucket(thq.policy.Key(flow)).add(thq.policy.Priority(flow), flow)
 }
 
 func (thq *ThrottlingQueue) SendWithoutThrottling(flow interface{}) bool {
-	thq.sampleItems[thq.periodEmitCount] = flow
-	thq.periodEmitCount++
-	if thq.periodEmitCount == thq.Throttle || flow == nil {
-		thq.flowLogWriter.Put(thq.index, thq.sampleItems[:thq.periodEmitCount]...)
-		thq.periodEmitCount = 0
+	if thq.sampleItems == nil {
+		thq.sampleItems = make([]interface{}, thq.Throttle)
+	}
+	thq.sampleItems[thq.emitCount] = flow
+	thq.emitCount++
+	if thq.emitCount == thq.Throttle || flow == nil {
+		thq.flowLogWriter.Put(thq.index, thq.sampleItems[:thq.emitCount]...)
+		thq.emitCount = 0
 	}
 	return true
 }
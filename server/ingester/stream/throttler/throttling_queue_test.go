@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package throttler
+
+import (
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/ingester/stream/config"
+	"github.com/deepflowys/deepflow/server/libs/datatype"
+)
+
+type testItem struct {
+	vtapID   uint16
+	traceID  string
+	service  string
+	status   uint8
+	released bool
+}
+
+func (i *testItem) GetVtapID() uint16        { return i.vtapID }
+func (i *testItem) GetTraceID() string       { return i.traceID }
+func (i *testItem) GetServiceName() string   { return i.service }
+func (i *testItem) GetResponseStatus() uint8 { return i.status }
+func (i *testItem) Release()                 { i.released = true }
+
+func TestReservoir(t *testing.T) {
+	r := &reservoir{capacity: 2}
+	items := []*testItem{{}, {}, {}, {}}
+	for i, priority := range []uint64{30, 10, 20, 40} {
+		r.add(priority, items[i])
+	}
+	if len(r.items) != 2 || r.items[0].priority != 20 {
+		t.Fatalf("reservoir items = %v", r.items)
+	}
+	if !items[0].released || items[1].released || items[2].released || !items[3].released {
+		t.Errorf("released = %v %v %v %v", items[0].released, items[1].released, items[2].released, items[3].released)
+	}
+}
+
+func TestSamplingPolicy(t *testing.T) {
+	p := NewSamplingPolicy(config.ThrottlePolicy{TraceConsistent: true, KeepError: true, QuotaBy: config.QUOTA_BY_VTAP})
+	a := &testItem{vtapID: 1, traceID: "5b8efff798038103d269b633813fc60c"}
+	b := &testItem{vtapID: 2, traceID: "5b8efff798038103d269b633813fc60c", status: datatype.STATUS_SERVER_ERROR}
+	if p.Priority(a) != p.Priority(b) {
+		t.Error("spans of the same trace should have the same priority")
+	}
+	if p.Key(a) == p.Key(b) {
+		t.Error("items of different vtaps should use different quota keys")
+	}
+	if p.AlwaysKeep(a) || !p.AlwaysKeep(b) {
+		t.Error("only error items should be always kept")
+	}
+
+	p = NewSamplingPolicy(config.ThrottlePolicy{})
+	if p.Key(a) != "" || p.AlwaysKeep(b) {
+		t.Error("default policy should not split quota or keep errors")
+	}
+}
+
+func TestThrottlingQueueBucket(t *testing.T) {
+	thq := NewThrottlingQueue(10, nil, 0, nil)
+	thq.lastKeys = 4
+	if c := thq.bucket("a").capacity; c != 25 {
+		t.Errorf("bucket capacity = %d, want 25", c)
+	}
+	for _, key := range []string{"b", "c", "d"} {
+		thq.bucket(key)
+	}
+	if c := thq.bucket("e").capacity; c != 20 {
+		t.Errorf("new bucket capacity = %d, want 20", c)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClickHouse节点发现方式
+const (
+	DISCOVERY_KUBERNETES = "kubernetes" // 通过Kubernetes Endpoints发现
+	DISCOVERY_STATIC     = "static"     // 使用配置中的静态地址列表
+	DISCOVERY_DNS        = "dns"        // 解析域名获取所有节点地址
+)
+
+type Endpoint struct {
+	Host string
+	Port uint16
+}
+
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+func IsValidDiscovery(discovery string) bool {
+	switch discovery {
+	case DISCOVERY_KUBERNETES, DISCOVERY_STATIC, DISCOVERY_DNS:
+		return true
+	}
+	return false
+}
+
+// ResolveEndpoints 获取非Kubernetes环境下的ClickHouse节点地址，返回结果按地址排序
+//   - static: endpoints为host[:port]列表，未指定端口时使用defaultPort
+//   - dns: 解析host得到所有节点IP，端口均为defaultPort
+func ResolveEndpoints(discovery string, endpoints []string, host string, defaultPort int) ([]Endpoint, error) {
+	if defaultPort <= 0 || defaultPort > 65535 {
+		return nil, fmt.Errorf("invalid clickhouse port %d", defaultPort)
+	}
+	var result []Endpoint
+	switch discovery {
+	case DISCOVERY_STATIC:
+		if len(endpoints) == 0 {
+			return nil, errors.New("clickhouse endpoints is empty in static discovery")
+		}
+		for _, e := range endpoints {
+			endpoint, err := parseEndpoint(e, defaultPort)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, endpoint)
+		}
+	case DISCOVERY_DNS:
+		if host == "" {
+			return nil, errors.New("clickhouse host is empty in dns discovery")
+		}
+		addrs, err := net.LookupHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("lookup clickhouse host %s failed: %s", host, err)
+		}
+		for _, addr := range addrs {
+			result = append(result, Endpoint{Host: addr, Port: uint16(defaultPort)})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported clickhouse discovery %s", discovery)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Host == result[j].Host {
+			return result[i].Port < result[j].Port
+		}
+		return result[i].Host < result[j].Host
+	})
+	return result, nil
+}
+
+func parseEndpoint(s string, defaultPort int) (Endpoint, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Endpoint{}, errors.New("clickhouse endpoint is empty")
+	}
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		// 未指定端口，或为不带[]的IPv6地址
+		return Endpoint{Host: strings.Trim(s, "[]"), Port: uint16(defaultPort)}, nil
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return Endpoint{}, fmt.Errorf("invalid clickhouse endpoint %s", s)
+	}
+	return Endpoint{Host: host, Port: uint16(port)}, nil
+}
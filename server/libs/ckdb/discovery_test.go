@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveStaticEndpoints(t *testing.T) {
+	endpoints, err := ResolveEndpoints(DISCOVERY_STATIC, []string{"10.1.1.2:9001", "10.1.1.1", "[fd00::1]:9000", "fd00::2"}, "", 9000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Endpoint{
+		{Host: "10.1.1.1", Port: 9000},
+		{Host: "10.1.1.2", Port: 9001},
+		{Host: "fd00::1", Port: 9000},
+		{Host: "fd00::2", Port: 9000},
+	}
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Errorf("expected %v, got %v", expected, endpoints)
+	}
+
+	if _, err := ResolveEndpoints(DISCOVERY_STATIC, []string{"10.1.1.1:abc"}, "", 9000); err == nil {
+		t.Error("expected error for invalid port")
+	}
+	if _, err := ResolveEndpoints(DISCOVERY_STATIC, nil, "", 9000); err == nil {
+		t.Error("expected error for empty endpoints")
+	}
+}
+
+func TestResolveDNSEndpoints(t *testing.T) {
+	endpoints, err := ResolveEndpoints(DISCOVERY_DNS, nil, "127.0.0.1", 9000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Endpoint{{Host: "127.0.0.1", Port: 9000}}
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Errorf("expected %v, got %v", expected, endpoints)
+	}
+}
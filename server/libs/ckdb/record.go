@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/deepflowys/deepflow/server/libs/codec"
+)
+
+const (
+	RECORD_BATCH_MAGIC   = 0x434b5350 // "CKSP"
+	RECORD_BATCH_VERSION = 1
+)
+
+// Block各Write*函数写入列的数据类型, 编码时以下标+1作为类型标识, 只能在末尾追加
+var recordColumnTypes = []reflect.Type{
+	reflect.TypeOf([]uint8(nil)),
+	reflect.TypeOf([]uint16(nil)),
+	reflect.TypeOf([]uint32(nil)),
+	reflect.TypeOf([]uint64(nil)),
+	reflect.TypeOf([]int8(nil)),
+	reflect.TypeOf([]int16(nil)),
+	reflect.TypeOf([]int32(nil)),
+	reflect.TypeOf([]int64(nil)),
+	reflect.TypeOf([]float64(nil)),
+	reflect.TypeOf([]string(nil)),
+	reflect.TypeOf([]net.IP(nil)),
+	reflect.TypeOf([]time.Time(nil)),
+	reflect.TypeOf([]*uint8(nil)),
+	reflect.TypeOf([]*uint16(nil)),
+	reflect.TypeOf([]*uint32(nil)),
+	reflect.TypeOf([]*uint64(nil)),
+	reflect.TypeOf([]*int8(nil)),
+	reflect.TypeOf([]*int16(nil)),
+	reflect.TypeOf([]*int32(nil)),
+	reflect.TypeOf([]*int64(nil)),
+	reflect.TypeOf([]*float64(nil)),
+	reflect.TypeOf([][]byte(nil)),
+	reflect.TypeOf([][]string(nil)),
+	reflect.TypeOf([][]uint16(nil)),
+	reflect.TypeOf([][]uint32(nil)),
+	reflect.TypeOf([][]uint64(nil)),
+	reflect.TypeOf([][]int64(nil)),
+	reflect.TypeOf([][]float64(nil)),
+}
+
+var (
+	recordTypeIDs = make(map[reflect.Type]uint8, len(recordColumnTypes))
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+func init() {
+	for i, t := range recordColumnTypes {
+		recordTypeIDs[t] = uint8(i + 1)
+	}
+}
+
+type recordColumn struct {
+	values []interface{}
+}
+
+func (c *recordColumn) Append(v interface{}) error {
+	if _, ok := recordTypeIDs[reflect.TypeOf(v)]; !ok {
+		return fmt.Errorf("unsupported record column type %T", v)
+	}
+	c.values = append(c.values, v)
+	return nil
+}
+
+// RecordBatch 实现driver.Batch, 记录Block写入各列的数据而不发送,
+// 用于将写入clickhouse失败的数据编码后持久化, 之后通过Replay按原顺序写入新的Batch
+type RecordBatch struct {
+	Prepare string // 写入时使用的INSERT语句, 包含列名, 表结构增加列后仍可重放
+	Rows    int
+	columns []*recordColumn
+}
+
+func NewRecordBatch(prepare string) *RecordBatch {
+	return &RecordBatch{Prepare: prepare}
+}
+
+func (b *RecordBatch) Abort() error {
+	return nil
+}
+
+func (b *RecordBatch) Append(v ...interface{}) error {
+	return errors.New("RecordBatch only supports Column().Append()")
+}
+
+func (b *RecordBatch) AppendStruct(v interface{}) error {
+	return errors.New("RecordBatch only supports Column().Append()")
+}
+
+func (b *RecordBatch) Column(i int) driver.BatchColumn {
+	for len(b.columns) <= i {
+		b.columns = append(b.columns, &recordColumn{})
+	}
+	return b.columns[i]
+}
+
+func (b *RecordBatch) Send() error {
+	return nil
+}
+
+// Replay 将记录的数据按原顺序写入batch, 调用方负责Send
+func (b *RecordBatch) Replay(batch driver.Batch) error {
+	for i, column := range b.columns {
+		for _, v := range column.values {
+			if err := batch.Column(i).Append(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Row 返回第row行各列的值, 各列Append的slice被依次展开, 列中不存在该行时为nil
+func (b *RecordBatch) Row(row int) []interface{} {
+	values := make([]interface{}, len(b.columns))
+	for i, column := range b.columns {
+		offset := row
+		for _, v := range column.values {
+			slice := reflect.ValueOf(v)
+			if offset < slice.Len() {
+				values[i] = slice.Index(offset).Interface()
+				break
+			}
+			offset -= slice.Len()
+		}
+	}
+	return values
+}
+
+// ReplayTo 使用conn重新执行Prepare并写入记录的数据
+func (b *RecordBatch) ReplayTo(ctx context.Context, conn driver.Conn) error {
+	batch, err := conn.PrepareBatch(ctx, b.Prepare)
+	if err != nil {
+		return err
+	}
+	if err := b.Replay(batch); err != nil {
+		batch.Abort()
+		return err
+	}
+	return batch.Send()
+}
+
+func (b *RecordBatch) Encode(encoder *codec.SimpleEncoder) {
+	encoder.WriteU32(RECORD_BATCH_MAGIC)
+	encoder.WriteU8(RECORD_BATCH_VERSION)
+	encoder.WriteBytes([]byte(b.Prepare))
+	encoder.WriteU32(uint32(b.Rows))
+	encoder.WriteU32(uint32(len(b.columns)))
+	for _, column := range b.columns {
+		encoder.WriteU32(uint32(len(column.values)))
+		for _, v := range column.values {
+			encoder.WriteU8(recordTypeIDs[reflect.TypeOf(v)])
+			encodeRecordValue(encoder, reflect.ValueOf(v))
+		}
+	}
+}
+
+func DecodeRecordBatch(decoder *codec.SimpleDecoder) (*RecordBatch, error) {
+	if decoder.ReadU32() != RECORD_BATCH_MAGIC {
+		return nil, errors.New("invalid record batch magic")
+	}
+	if version := decoder.ReadU8(); version != RECORD_BATCH_VERSION {
+		return nil, fmt.Errorf("unsupported record batch version %d", version)
+	}
+	b := &RecordBatch{Prepare: string(decoder.ReadBytes())}
+	b.Rows = int(decoder.ReadU32())
+	columnCount := int(decoder.ReadU32())
+	for i := 0; i < columnCount && !decoder.Failed(); i++ {
+		valueCount := int(decoder.ReadU32())
+		column := &recordColumn{}
+		for j := 0; j < valueCount && !decoder.Failed(); j++ {
+			typeID := int(decoder.ReadU8())
+			if typeID == 0 || typeID > len(recordColumnTypes) {
+				return nil, fmt.Errorf("unknown record column type %d", typeID)
+			}
+			v, err := decodeRecordValue(decoder, recordColumnTypes[typeID-1])
+			if err != nil {
+				return nil, err
+			}
+			column.values = append(column.values, v.Interface())
+		}
+		b.columns = append(b.columns, column)
+	}
+	if decoder.Failed() {
+		return nil, errors.New("record batch decode failed")
+	}
+	return b, nil
+}
+
+func encodeRecordValue(encoder *codec.SimpleEncoder, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Uint8:
+		encoder.WriteU8(uint8(v.Uint()))
+	case reflect.Uint16:
+		encoder.WriteU16(uint16(v.Uint()))
+	case reflect.Uint32:
+		encoder.WriteU32(uint32(v.Uint()))
+	case reflect.Uint64:
+		encoder.WriteU64(v.Uint())
+	case reflect.Int8:
+		encoder.WriteU8(uint8(v.Int()))
+	case reflect.Int16:
+		encoder.WriteU16(uint16(v.Int()))
+	case reflect.Int32:
+		encoder.WriteU32(uint32(v.Int()))
+	case reflect.Int64:
+		encoder.WriteU64(uint64(v.Int()))
+	case reflect.Float64:
+		encoder.WriteU64(math.Float64bits(v.Float()))
+	case reflect.String:
+		encoder.WriteBytes([]byte(v.String()))
+	case reflect.Ptr:
+		encoder.WriteBool(!v.IsNil())
+		if !v.IsNil() {
+			encodeRecordValue(encoder, v.Elem())
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			encoder.WriteBytes(v.Bytes())
+			return
+		}
+		encoder.WriteU32(uint32(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			encodeRecordValue(encoder, v.Index(i))
+		}
+	case reflect.Struct:
+		// 仅time.Time
+		encoder.WriteU64(uint64(v.Interface().(time.Time).UnixNano()))
+	}
+}
+
+func decodeRecordValue(decoder *codec.SimpleDecoder, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Uint8:
+		v.SetUint(uint64(decoder.ReadU8()))
+	case reflect.Uint16:
+		v.SetUint(uint64(decoder.ReadU16()))
+	case reflect.Uint32:
+		v.SetUint(uint64(decoder.ReadU32()))
+	case reflect.Uint64:
+		v.SetUint(decoder.ReadU64())
+	case reflect.Int8:
+		v.SetInt(int64(int8(decoder.ReadU8())))
+	case reflect.Int16:
+		v.SetInt(int64(int16(decoder.ReadU16())))
+	case reflect.Int32:
+		v.SetInt(int64(int32(decoder.ReadU32())))
+	case reflect.Int64:
+		v.SetInt(int64(decoder.ReadU64()))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(decoder.ReadU64()))
+	case reflect.String:
+		v.SetString(string(decoder.ReadBytes()))
+	case reflect.Ptr:
+		if decoder.ReadBool() {
+			elem, err := decodeRecordValue(decoder, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Set(reflect.New(t.Elem()))
+			v.Elem().Set(elem)
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			bytes := decoder.ReadBytes()
+			// ReadBytes返回的是decoder中buffer的引用, 需要复制
+			v.Set(reflect.MakeSlice(t, len(bytes), len(bytes)))
+			reflect.Copy(v, reflect.ValueOf(bytes))
+			return v, nil
+		}
+		n := int(decoder.ReadU32())
+		// 每个元素至少占用1字节, 避免数据损坏时分配过大的内存
+		if n > len(decoder.Bytes())-decoder.Offset() {
+			return v, fmt.Errorf("invalid record array length %d", n)
+		}
+		v.Set(reflect.MakeSlice(t, 0, n))
+		for i := 0; i < n && !decoder.Failed(); i++ {
+			elem, err := decodeRecordValue(decoder, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+	case reflect.Struct:
+		if t == timeType {
+			v.Set(reflect.ValueOf(time.Unix(0, int64(decoder.ReadU64()))))
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckdb
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deepflowys/deepflow/server/libs/codec"
+)
+
+func TestRecordBatchCodec(t *testing.T) {
+	code := int32(404)
+	record := NewRecordBatch("INSERT INTO flow_log.`l7_flow_log` (time,ip6,response_code,tag_names) VALUES (?,?,?,?)")
+	block := &Block{Batch: record}
+	for i := 0; i < 2; i++ {
+		block.WriteDateTime(1665912411)
+		block.WriteIPv6(net.ParseIP("2001:db8::1"))
+		if i == 0 {
+			block.WriteInt32Nullable(&code)
+		} else {
+			block.WriteInt32Nullable(nil)
+		}
+		block.WriteArrayString([]string{"host", "job"})
+		block.ResetIndex()
+	}
+	record.Rows = 2
+
+	encoder := &codec.SimpleEncoder{}
+	record.Encode(encoder)
+	decoder := &codec.SimpleDecoder{}
+	decoder.Init(encoder.Bytes())
+	decoded, err := DecodeRecordBatch(decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Prepare != record.Prepare || decoded.Rows != 2 || len(decoded.columns) != 4 {
+		t.Fatalf("DecodeRecordBatch() = %+v", decoded)
+	}
+	for i, column := range record.columns {
+		for j, v := range column.values {
+			got := decoded.columns[i].values[j]
+			if tm, ok := v.([]time.Time); ok {
+				if !tm[0].Equal(got.([]time.Time)[0]) {
+					t.Errorf("column %d row %d = %v, want %v", i, j, got, v)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("column %d row %d = %#v, want %#v", i, j, got, v)
+			}
+		}
+	}
+
+	row := record.Row(0)
+	if len(row) != 4 || *row[2].(*int32) != code || !reflect.DeepEqual(row[3], []string{"host", "job"}) {
+		t.Errorf("Row(0) = %#v", row)
+	}
+	if row = record.Row(1); row[2].(*int32) != nil {
+		t.Errorf("Row(1) = %#v", row)
+	}
+	if row = record.Row(2); row[0] != nil {
+		t.Errorf("Row(2) = %#v", row)
+	}
+
+	decoder.Init(encoder.Bytes()[:len(encoder.Bytes())-3])
+	if _, err := DecodeRecordBatch(decoder); err == nil {
+		t.Error("decode truncated record batch should fail")
+	}
+	if err := record.Column(0).Append([]interface{}{1}); err == nil {
+		t.Error("append unsupported type should fail")
+	}
+}
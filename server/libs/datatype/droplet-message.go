@@ -49,6 +49,16 @@ const (
 	MESSAGE_TYPE_MAX
 )
 
+// ingester内部使用的消息类型, 仅用于区分OTLP接收器直接写入的解析队列, 不会从采集器接收,
+// 取值在采集器的消息类型范围之外, 不占用采集器的消息类型
+const (
+	MESSAGE_TYPE_OTEL_METRICS MessageType = 128 + iota
+	MESSAGE_TYPE_OTEL_LOGS
+	MESSAGE_TYPE_INTERNAL_MAX
+
+	MESSAGE_TYPE_INTERNAL_START = MESSAGE_TYPE_OTEL_METRICS
+)
+
 var MessageTypeString = [MESSAGE_TYPE_MAX]string{
 	MESSAGE_TYPE_COMPRESS: "pcap",
 	MESSAGE_TYPE_SYSLOG:   "syslog",
@@ -66,13 +76,26 @@ var MessageTypeString = [MESSAGE_TYPE_MAX]string{
 	MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED: "open_telemetry_compressed",
 }
 
+var internalMessageTypeString = [MESSAGE_TYPE_INTERNAL_MAX - MESSAGE_TYPE_INTERNAL_START]string{
+	MESSAGE_TYPE_OTEL_METRICS - MESSAGE_TYPE_INTERNAL_START: "open_telemetry_metrics",
+	MESSAGE_TYPE_OTEL_LOGS - MESSAGE_TYPE_INTERNAL_START:    "open_telemetry_logs",
+}
+
 func (m MessageType) String() string {
 	if m < MESSAGE_TYPE_MAX {
 		return MessageTypeString[m]
 	}
+	if m.IsInternal() {
+		return internalMessageTypeString[m-MESSAGE_TYPE_INTERNAL_START]
+	}
 	return "unknown message"
 }
 
+// IsInternal 是否为ingester内部使用的消息类型
+func (m MessageType) IsInternal() bool {
+	return m >= MESSAGE_TYPE_INTERNAL_START && m < MESSAGE_TYPE_INTERNAL_MAX
+}
+
 type MessageHeaderType uint8
 
 const (
@@ -152,11 +152,16 @@ const (
 	L7_PROTOCOL_HTTP_2_TLS L7Protocol = 23
 	L7_PROTOCOL_DUBBO      L7Protocol = 40
 	L7_PROTOCOL_GRPC       L7Protocol = 41
+	L7_PROTOCOL_SOFARPC    L7Protocol = 43
 	L7_PROTOCOL_MYSQL      L7Protocol = 60
 	L7_PROTOCOL_POSTGRE    L7Protocol = 61
 	L7_PROTOCOL_REDIS      L7Protocol = 80
+	L7_PROTOCOL_MONGODB    L7Protocol = 81
+	L7_PROTOCOL_MEMCACHED  L7Protocol = 82
 	L7_PROTOCOL_KAFKA      L7Protocol = 100
 	L7_PROTOCOL_MQTT       L7Protocol = 101
+	L7_PROTOCOL_AMQP       L7Protocol = 102
+	L7_PROTOCOL_NATS       L7Protocol = 104
 	L7_PROTOCOL_DNS        L7Protocol = 120
 )
 
@@ -612,6 +617,16 @@ func (p L7Protocol) String() string {
 		formatted = "kafka"
 	case L7_PROTOCOL_MQTT:
 		formatted = "mqtt"
+	case L7_PROTOCOL_SOFARPC:
+		formatted = "sofarpc"
+	case L7_PROTOCOL_MONGODB:
+		formatted = "mongodb"
+	case L7_PROTOCOL_MEMCACHED:
+		formatted = "memcached"
+	case L7_PROTOCOL_AMQP:
+		formatted = "amqp"
+	case L7_PROTOCOL_NATS:
+		formatted = "nats"
 	case L7_PROTOCOL_OTHER:
 		formatted = "other"
 	default:
@@ -632,6 +647,11 @@ var L7ProtocolStringMap = map[string]L7Protocol{
 	L7_PROTOCOL_GRPC.String():       L7_PROTOCOL_GRPC,
 	L7_PROTOCOL_KAFKA.String():      L7_PROTOCOL_KAFKA,
 	L7_PROTOCOL_MQTT.String():       L7_PROTOCOL_MQTT,
+	L7_PROTOCOL_SOFARPC.String():    L7_PROTOCOL_SOFARPC,
+	L7_PROTOCOL_MONGODB.String():    L7_PROTOCOL_MONGODB,
+	L7_PROTOCOL_MEMCACHED.String():  L7_PROTOCOL_MEMCACHED,
+	L7_PROTOCOL_AMQP.String():       L7_PROTOCOL_AMQP,
+	L7_PROTOCOL_NATS.String():       L7_PROTOCOL_NATS,
 	L7_PROTOCOL_OTHER.String():      L7_PROTOCOL_OTHER,
 	L7_PROTOCOL_UNKNOWN.String():    L7_PROTOCOL_UNKNOWN,
 }
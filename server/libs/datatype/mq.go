@@ -140,3 +140,146 @@ func (i *MqttInfo) Merge(r interface{}) {
 		i.RespMsgSize = mqtt.RespMsgSize
 	}
 }
+
+var amqpInfoPool = pool.NewLockFreePool(func() interface{} {
+	return new(AmqpInfo)
+})
+
+func AcquireAmqpInfo() *AmqpInfo {
+	return amqpInfoPool.Get().(*AmqpInfo)
+}
+
+func ReleaseAmqpInfo(d *AmqpInfo) {
+	*d = AmqpInfo{}
+	amqpInfoPool.Put(d)
+}
+
+// AmqpInfo 为AMQP 0-9-1(RabbitMQ)的method帧信息
+type AmqpInfo struct {
+	Channel uint16
+	Method  string // class.method, 例如: basic.publish, queue.declare
+
+	// request
+	Vhost      string
+	Exchange   string
+	RoutingKey string
+	Queue      string
+	ReqMsgSize int32
+
+	// response
+	ReplyCode   uint16 // channel.close, connection.close及basic.return中的reply-code
+	ReplyText   string
+	RespMsgSize int32
+}
+
+func (i *AmqpInfo) WriteToPB(p *pb.AppProtoLogsData, msgType LogMessageType) {
+	p.Version = "0-9-1"
+
+	p.ReqLen, p.RespLen = -1, -1
+	if msgType == MSG_T_REQUEST || msgType == MSG_T_SESSION {
+		resource := i.Queue
+		if resource == "" {
+			resource = i.RoutingKey
+		}
+		p.Req = &pb.L7Request{
+			ReqType:  i.Method,
+			Domain:   i.Vhost,
+			Resource: resource,
+			Endpoint: i.Exchange,
+		}
+		if i.Channel != 0 {
+			p.ExtInfo = &pb.ExtendedInfo{
+				RequestId: uint32(i.Channel),
+			}
+		}
+		p.ReqLen = i.ReqMsgSize
+	}
+
+	if msgType == MSG_T_RESPONSE || msgType == MSG_T_SESSION {
+		p.Resp.Code = int32(i.ReplyCode)
+		p.Resp.Exception = i.ReplyText
+		if p.Resp.Code == 0 {
+			p.Resp.Code = L7PROTOCOL_LOG_RESP_CODE_NONE
+		}
+		p.RespLen = i.RespMsgSize
+	}
+}
+
+func (i *AmqpInfo) String() string {
+	return fmt.Sprintf("%#v", i)
+}
+
+func (i *AmqpInfo) Merge(r interface{}) {
+	if amqp, ok := r.(*AmqpInfo); ok {
+		i.ReplyCode = amqp.ReplyCode
+		i.ReplyText = amqp.ReplyText
+		i.RespMsgSize = amqp.RespMsgSize
+	}
+}
+
+var natsInfoPool = pool.NewLockFreePool(func() interface{} {
+	return new(NatsInfo)
+})
+
+func AcquireNatsInfo() *NatsInfo {
+	return natsInfoPool.Get().(*NatsInfo)
+}
+
+func ReleaseNatsInfo(d *NatsInfo) {
+	*d = NatsInfo{}
+	natsInfoPool.Put(d)
+}
+
+// NatsInfo 为NATS客户端协议的信息, 请求为PUB, SUB, CONNECT等, 响应为+OK或-ERR
+type NatsInfo struct {
+	Op string // 例如: PUB, HPUB, SUB, MSG, CONNECT
+
+	// request
+	Subject    string
+	ReplyTo    string
+	QueueGroup string
+	ServerName string // INFO中的server_name
+	ReqMsgSize int32
+
+	// response
+	Error       string // -ERR后的错误信息
+	RespMsgSize int32
+}
+
+func (i *NatsInfo) WriteToPB(p *pb.AppProtoLogsData, msgType LogMessageType) {
+	p.ReqLen, p.RespLen = -1, -1
+	if msgType == MSG_T_REQUEST || msgType == MSG_T_SESSION {
+		p.Req = &pb.L7Request{
+			ReqType:  i.Op,
+			Domain:   i.ServerName,
+			Resource: i.Subject,
+			Endpoint: i.QueueGroup,
+		}
+		if i.ReplyTo != "" {
+			p.ExtInfo = &pb.ExtendedInfo{
+				AttributeNames:  []string{"nats_reply_to"},
+				AttributeValues: []string{i.ReplyTo},
+			}
+		}
+		p.ReqLen = i.ReqMsgSize
+	}
+
+	if msgType == MSG_T_RESPONSE || msgType == MSG_T_SESSION {
+		p.Resp.Exception = i.Error
+		if p.Resp.Code == 0 {
+			p.Resp.Code = L7PROTOCOL_LOG_RESP_CODE_NONE
+		}
+		p.RespLen = i.RespMsgSize
+	}
+}
+
+func (i *NatsInfo) String() string {
+	return fmt.Sprintf("%#v", i)
+}
+
+func (i *NatsInfo) Merge(r interface{}) {
+	if nats, ok := r.(*NatsInfo); ok {
+		i.Error = nats.Error
+		i.RespMsgSize = nats.RespMsgSize
+	}
+}
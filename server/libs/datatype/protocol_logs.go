@@ -216,6 +216,16 @@ func ReleaseAppProtoLogsData(d *AppProtoLogsData) {
 		ReleaseKafkaInfo(d.Detail.(*KafkaInfo))
 	case L7_PROTOCOL_MQTT:
 		ReleaseMqttInfo(d.Detail.(*MqttInfo))
+	case L7_PROTOCOL_SOFARPC:
+		ReleaseSofaRpcInfo(d.Detail.(*SofaRpcInfo))
+	case L7_PROTOCOL_MONGODB:
+		ReleaseMongoDBInfo(d.Detail.(*MongoDBInfo))
+	case L7_PROTOCOL_MEMCACHED:
+		ReleaseMemcachedInfo(d.Detail.(*MemcachedInfo))
+	case L7_PROTOCOL_AMQP:
+		ReleaseAmqpInfo(d.Detail.(*AmqpInfo))
+	case L7_PROTOCOL_NATS:
+		ReleaseNatsInfo(d.Detail.(*NatsInfo))
 	}
 
 	*d = zeroAppProtoLogsData
@@ -323,6 +333,26 @@ func (l *AppProtoLogsData) WriteToPB(p *pb.AppProtoLogsData) {
 		if mqtt, ok := l.Detail.(*MqttInfo); ok {
 			mqtt.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
 		}
+	case L7_PROTOCOL_SOFARPC:
+		if sofa, ok := l.Detail.(*SofaRpcInfo); ok {
+			sofa.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
+		}
+	case L7_PROTOCOL_MONGODB:
+		if mongo, ok := l.Detail.(*MongoDBInfo); ok {
+			mongo.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
+		}
+	case L7_PROTOCOL_MEMCACHED:
+		if memcached, ok := l.Detail.(*MemcachedInfo); ok {
+			memcached.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
+		}
+	case L7_PROTOCOL_AMQP:
+		if amqp, ok := l.Detail.(*AmqpInfo); ok {
+			amqp.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
+		}
+	case L7_PROTOCOL_NATS:
+		if nats, ok := l.Detail.(*NatsInfo); ok {
+			nats.WriteToPB(p, l.AppProtoLogsBaseInfo.MsgType)
+		}
 	}
 }
 
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datatype
+
+import (
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/libs/datatype/pb"
+)
+
+func TestL7ProtocolString(t *testing.T) {
+	for _, p := range []L7Protocol{L7_PROTOCOL_SOFARPC, L7_PROTOCOL_MONGODB, L7_PROTOCOL_MEMCACHED, L7_PROTOCOL_AMQP, L7_PROTOCOL_NATS} {
+		if p.String() == "unknown" || L7ProtocolStringMap[p.String()] != p {
+			t.Errorf("L7Protocol(%d).String() = %s", p, p.String())
+		}
+	}
+}
+
+func TestNewProtocolsWriteToPB(t *testing.T) {
+	newLog := func(proto L7Protocol, detail ProtoSpecialInfo) *AppProtoLogsData {
+		d := AcquireAppProtoLogsData()
+		d.Proto = proto
+		d.MsgType = MSG_T_SESSION
+		d.Detail = detail
+		return d
+	}
+
+	amqp := AcquireAmqpInfo()
+	amqp.Method, amqp.Vhost, amqp.Exchange, amqp.RoutingKey = "basic.publish", "/", "orders", "order.created"
+	amqp.ReplyCode, amqp.ReplyText = 312, "NO_ROUTE"
+	mongo := AcquireMongoDBInfo()
+	mongo.OpCodeName, mongo.Command, mongo.Database, mongo.Collection = "OP_MSG", "insert", "shop", "orders"
+	mongo.RequestID, mongo.ErrorCode, mongo.ErrorMessage = 7, 11000, "E11000 duplicate key error"
+	memcached := AcquireMemcachedInfo()
+	memcached.Binary, memcached.Command, memcached.Key, memcached.Status = true, "get", "user:1", 0x01
+	nats := AcquireNatsInfo()
+	nats.Op, nats.Subject, nats.ReplyTo, nats.Error = "PUB", "orders.created", "_INBOX.1", "Permissions Violation"
+	sofa := AcquireSofaRpcInfo()
+	sofa.Proto, sofa.TargetService, sofa.MethodName, sofa.RequestID, sofa.RespStatus, sofa.TraceId = 1, "com.example.HelloService:1.0", "sayHello", 9, 7, "0a0fe8ec1665913285001100"
+
+	cases := []struct {
+		log                         *AppProtoLogsData
+		reqType, resource, endpoint string
+		code                        int32
+		exception                   string
+	}{
+		{newLog(L7_PROTOCOL_AMQP, amqp), "basic.publish", "order.created", "orders", 312, "NO_ROUTE"},
+		{newLog(L7_PROTOCOL_MONGODB, mongo), "insert", "orders", "OP_MSG", 11000, "E11000 duplicate key error"},
+		{newLog(L7_PROTOCOL_MEMCACHED, memcached), "get", "user:1", "", 1, ""},
+		{newLog(L7_PROTOCOL_NATS, nats), "PUB", "orders.created", "", L7PROTOCOL_LOG_RESP_CODE_NONE, "Permissions Violation"},
+		{newLog(L7_PROTOCOL_SOFARPC, sofa), "", "sayHello", "com.example.HelloService:1.0/sayHello", 7, ""},
+	}
+	for _, c := range cases {
+		p := &pb.AppProtoLogsData{}
+		c.log.WriteToPB(p)
+		if p.Req == nil || p.Resp == nil {
+			t.Fatalf("%s: request or response not written: %v", c.log.Proto, p)
+		}
+		if p.Req.ReqType != c.reqType || p.Req.Resource != c.resource || p.Req.Endpoint != c.endpoint {
+			t.Errorf("%s: request = %v", c.log.Proto, p.Req)
+		}
+		if p.Resp.Code != c.code || p.Resp.Exception != c.exception {
+			t.Errorf("%s: response = %v", c.log.Proto, p.Resp)
+		}
+		c.log.Release()
+	}
+}
@@ -91,3 +91,79 @@ func (i *DubboInfo) Merge(r interface{}) {
 		i.RespBodyLen = dubbo.RespBodyLen
 	}
 }
+
+var sofaRpcInfoPool = pool.NewLockFreePool(func() interface{} {
+	return new(SofaRpcInfo)
+})
+
+func AcquireSofaRpcInfo() *SofaRpcInfo {
+	return sofaRpcInfoPool.Get().(*SofaRpcInfo)
+}
+
+func ReleaseSofaRpcInfo(d *SofaRpcInfo) {
+	*d = SofaRpcInfo{}
+	sofaRpcInfoPool.Put(d)
+}
+
+// SofaRpcInfo 为SOFARPC(Bolt协议)的请求及响应信息
+type SofaRpcInfo struct {
+	// header
+	Proto     uint8 // Bolt协议版本: 1或2
+	CmdCode   uint16
+	RequestID uint32
+
+	// req
+	ReqBodyLen     int32
+	TargetService  string // sofa_head_target_service, 例如: com.example.HelloService:1.0
+	MethodName     string
+	TraceId        string // rpc_trace_context.sofaTraceId
+	SpanId         string // rpc_trace_context.sofaRpcId
+	ServiceVersion string
+
+	// resp
+	RespStatus  uint16
+	RespBodyLen int32
+}
+
+func (i *SofaRpcInfo) WriteToPB(p *pb.AppProtoLogsData, msgType LogMessageType) {
+	if i.Proto != 0 {
+		p.Version = fmt.Sprintf("bolt-v%d", i.Proto)
+	}
+	if i.TraceId != "" {
+		p.TraceInfo = &pb.TraceInfo{
+			TraceId: i.TraceId,
+			SpanId:  i.SpanId,
+		}
+	}
+
+	p.ReqLen, p.RespLen = -1, -1
+	if msgType == MSG_T_REQUEST || msgType == MSG_T_SESSION {
+		p.Req = &pb.L7Request{
+			Domain:   i.TargetService,
+			Resource: i.MethodName,
+			Endpoint: i.TargetService + "/" + i.MethodName,
+		}
+
+		p.ExtInfo = &pb.ExtendedInfo{
+			RequestId:  i.RequestID,
+			RpcService: i.TargetService,
+		}
+		p.ReqLen = i.ReqBodyLen
+	}
+
+	if msgType == MSG_T_RESPONSE || msgType == MSG_T_SESSION {
+		p.Resp.Code = int32(i.RespStatus)
+		p.RespLen = i.RespBodyLen
+	}
+}
+
+func (i *SofaRpcInfo) String() string {
+	return fmt.Sprintf("%#v", i)
+}
+
+func (i *SofaRpcInfo) Merge(r interface{}) {
+	if sofa, ok := r.(*SofaRpcInfo); ok {
+		i.RespStatus = sofa.RespStatus
+		i.RespBodyLen = sofa.RespBodyLen
+	}
+}
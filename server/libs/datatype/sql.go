@@ -152,3 +152,146 @@ func (i *RedisInfo) Merge(r interface{}) {
 		i.Error = redis.Error
 	}
 }
+
+var mongoDBInfoPool = pool.NewLockFreePool(func() interface{} {
+	return new(MongoDBInfo)
+})
+
+func AcquireMongoDBInfo() *MongoDBInfo {
+	return mongoDBInfoPool.Get().(*MongoDBInfo)
+}
+
+func ReleaseMongoDBInfo(d *MongoDBInfo) {
+	*d = MongoDBInfo{}
+	mongoDBInfoPool.Put(d)
+}
+
+type MongoDBInfo struct {
+	// header
+	RequestID  int32
+	ResponseTo int32
+	OpCode     uint32
+	OpCodeName string // 例如: OP_MSG, OP_QUERY
+
+	// request
+	Command    string // 命令名称, 例如: find, insert, aggregate
+	Database   string
+	Collection string
+	ReqLen     int32
+
+	// response
+	ErrorCode    int32 // 响应中的code字段, ok为1时为0
+	ErrorMessage string
+	RespLen      int32
+}
+
+func (i *MongoDBInfo) WriteToPB(p *pb.AppProtoLogsData, msgType LogMessageType) {
+	p.ReqLen, p.RespLen = -1, -1
+	if msgType == MSG_T_REQUEST || msgType == MSG_T_SESSION {
+		p.Req = &pb.L7Request{
+			ReqType:  i.Command,
+			Domain:   i.Database,
+			Resource: i.Collection,
+			Endpoint: i.OpCodeName,
+		}
+		if i.RequestID != 0 {
+			p.ExtInfo = &pb.ExtendedInfo{
+				RequestId: uint32(i.RequestID),
+			}
+		}
+		p.ReqLen = i.ReqLen
+	}
+
+	if msgType == MSG_T_RESPONSE || msgType == MSG_T_SESSION {
+		p.Resp.Code = i.ErrorCode
+		p.Resp.Exception = i.ErrorMessage
+		if p.Resp.Code == 0 {
+			p.Resp.Code = L7PROTOCOL_LOG_RESP_CODE_NONE
+		}
+		p.RespLen = i.RespLen
+	}
+}
+
+func (i *MongoDBInfo) String() string {
+	return fmt.Sprintf("%#v", i)
+}
+
+func (i *MongoDBInfo) Merge(r interface{}) {
+	if mongo, ok := r.(*MongoDBInfo); ok {
+		i.ResponseTo = mongo.ResponseTo
+		i.ErrorCode = mongo.ErrorCode
+		i.ErrorMessage = mongo.ErrorMessage
+		i.RespLen = mongo.RespLen
+	}
+}
+
+var memcachedInfoPool = pool.NewLockFreePool(func() interface{} {
+	return new(MemcachedInfo)
+})
+
+func AcquireMemcachedInfo() *MemcachedInfo {
+	return memcachedInfoPool.Get().(*MemcachedInfo)
+}
+
+func ReleaseMemcachedInfo(d *MemcachedInfo) {
+	*d = MemcachedInfo{}
+	memcachedInfoPool.Put(d)
+}
+
+type MemcachedInfo struct {
+	Binary bool   // 二进制协议或文本协议
+	Opaque uint32 // 二进制协议请求与响应一致的opaque
+
+	// request
+	Command string // 命令类型不包括参数, 例如: get, set, incr
+	Key     string // 多个key时以空格分隔
+	ReqLen  int32
+
+	// response
+	Status  uint16 // 二进制协议的响应状态码, 0为成功
+	Error   string // 文本协议的ERROR, CLIENT_ERROR, SERVER_ERROR响应
+	RespLen int32
+}
+
+func (i *MemcachedInfo) WriteToPB(p *pb.AppProtoLogsData, msgType LogMessageType) {
+	if i.Binary {
+		p.Version = "binary"
+	} else {
+		p.Version = "text"
+	}
+
+	p.ReqLen, p.RespLen = -1, -1
+	if msgType == MSG_T_REQUEST || msgType == MSG_T_SESSION {
+		p.Req = &pb.L7Request{
+			ReqType:  i.Command,
+			Resource: i.Key,
+		}
+		if i.Opaque != 0 {
+			p.ExtInfo = &pb.ExtendedInfo{
+				RequestId: i.Opaque,
+			}
+		}
+		p.ReqLen = i.ReqLen
+	}
+
+	if msgType == MSG_T_RESPONSE || msgType == MSG_T_SESSION {
+		p.Resp.Code = int32(i.Status)
+		p.Resp.Exception = i.Error
+		if p.Resp.Code == 0 {
+			p.Resp.Code = L7PROTOCOL_LOG_RESP_CODE_NONE
+		}
+		p.RespLen = i.RespLen
+	}
+}
+
+func (i *MemcachedInfo) String() string {
+	return fmt.Sprintf("%#v", i)
+}
+
+func (i *MemcachedInfo) Merge(r interface{}) {
+	if memcached, ok := r.(*MemcachedInfo); ok {
+		i.Status = memcached.Status
+		i.Error = memcached.Error
+		i.RespLen = memcached.RespLen
+	}
+}
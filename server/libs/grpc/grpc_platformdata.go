@@ -136,6 +136,7 @@ type PlatformInfoTable struct {
 
 	podNameInfos map[string][]*PodInfo
 	vtapIdInfos  map[uint32]*VtapInfo
+	vtapIpInfos  map[string]*VtapInfo
 
 	peerConnections map[int32][]int32
 
@@ -277,6 +278,7 @@ func NewPlatformInfoTable(ips []net.IP, port, rpcMaxMsgSize int, moduleName, pca
 
 		podNameInfos:    make(map[string][]*PodInfo),
 		vtapIdInfos:     make(map[uint32]*VtapInfo),
+		vtapIpInfos:     make(map[string]*VtapInfo),
 		peerConnections: make(map[int32][]int32),
 		ctlIP:           nodeIP,
 	}
@@ -1298,17 +1300,37 @@ func (t *PlatformInfoTable) QueryVtapEpc1(vtapId uint32, isIPv4 bool, ip41 uint3
 	return t.findEpcInWan(isIPv4, ip41, ip61)
 }
 
+// 没有vtap id时(如直接发送至ingester的OTLP数据), 使用发送方的ip计算epc:
+// 1. 发送方为采集器所在的主机时, 使用采集器的epc
+// 2. 否则直接使用ip去查wan ip
+func (t *PlatformInfoTable) QueryIPEpc(isIPv4 bool, ip4 uint32, ip6 net.IP) int32 {
+	var ip string
+	if isIPv4 {
+		ip = utils.IpFromUint32(ip4).String()
+	} else {
+		ip = ip6.String()
+	}
+	if vtapInfo, ok := t.vtapIpInfos[ip]; ok {
+		return int32(vtapInfo.EpcId)
+	}
+	return t.findEpcInWan(isIPv4, ip4, ip6)
+}
+
 func (t *PlatformInfoTable) updateVtapIps(vtapIps []*trident.VtapIp) {
 	vtapIdInfos := make(map[uint32]*VtapInfo)
+	vtapIpInfos := make(map[string]*VtapInfo)
 	for _, vtapIp := range vtapIps {
-		vtapIdInfos[vtapIp.GetVtapId()] = &VtapInfo{
+		vtapInfo := &VtapInfo{
 			VtapId:       vtapIp.GetVtapId(),
 			EpcId:        vtapIp.GetEpcId(),
 			Ip:           vtapIp.GetIp(),
 			PodClusterId: vtapIp.GetPodClusterId(),
 		}
+		vtapIdInfos[vtapInfo.VtapId] = vtapInfo
+		vtapIpInfos[vtapInfo.Ip] = vtapInfo
 	}
 	t.vtapIdInfos = vtapIdInfos
+	t.vtapIpInfos = vtapIpInfos
 }
 
 func (t *PlatformInfoTable) vtapsString() string {
@@ -41,6 +41,9 @@ type QuerierConfig struct {
 	Clickhouse   Clickhouse `yaml:clickhouse`
 	Language     string     `default:"en" yaml:"language"`
 	OtelEndpoint string     `default:"http://${K8S_NODE_IP_FOR_DEEPFLOW}:38086/api/v1/otel/trace" yaml:"otel-endpoint"`
+	Prometheus   Prometheus `yaml:"prometheus"`
+	Cache        Cache      `yaml:"cache"`
+	Limits       Limits     `yaml:"limits"`
 }
 
 type Clickhouse struct {
@@ -50,6 +53,48 @@ type Clickhouse struct {
 	Port           int    `default:"9000" yaml:"port"`
 	Timeout        int    `default:"60" yaml:"timeout"`
 	ConnectTimeout int    `default:"2" yaml:"connect-timeout"`
+	// 多副本部署时的ClickHouse地址列表, 格式为host:port, 配置后不再使用host和port
+	Endpoints           []string `yaml:"endpoints"`
+	HealthCheckInterval int      `default:"10" yaml:"health-check-interval"`
+}
+
+type Prometheus struct {
+	QueryTimeout  int `default:"60" yaml:"query-timeout"`
+	MaxSamples    int `default:"50000000" yaml:"max-samples"`
+	LookbackDelta int `default:"300" yaml:"lookback-delta"`
+}
+
+type Cache struct {
+	Enabled   bool `default:"false" yaml:"enabled"`
+	TTL       int  `default:"300" yaml:"ttl"`
+	MaxMemory int  `default:"256" yaml:"max-memory"`
+	DataDelay int  `default:"120" yaml:"data-delay"`
+}
+
+// Limits 单个查询的限制, 为0时不限制
+type Limits struct {
+	MaxTimeSpan      int `default:"0" yaml:"max-time-span"`
+	MaxResultRows    int `default:"0" yaml:"max-result-rows"`
+	MaxBytesToRead   int `default:"0" yaml:"max-bytes-to-read"`
+	MaxExecutionTime int `default:"0" yaml:"max-execution-time"`
+}
+
+// Settings 返回下推到ClickHouse的查询限制
+func (l *Limits) Settings() map[string]interface{} {
+	settings := make(map[string]interface{})
+	if l.MaxResultRows > 0 {
+		settings["max_result_rows"] = l.MaxResultRows
+		settings["result_overflow_mode"] = "throw"
+	}
+	if l.MaxBytesToRead > 0 {
+		settings["max_bytes_to_read"] = l.MaxBytesToRead
+		settings["read_overflow_mode"] = "throw"
+	}
+	if l.MaxExecutionTime > 0 {
+		settings["max_execution_time"] = l.MaxExecutionTime
+		settings["timeout_overflow_mode"] = "throw"
+	}
+	return settings
 }
 
 func (c *Config) expendEnv() {
@@ -18,11 +18,13 @@ package clickhouse
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/deepflowys/deepflow/server/libs/utils"
 
+	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/tag"
 	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/view"
 )
@@ -37,15 +39,21 @@ func (c *Callback) Format(m *view.Model) {
 }
 
 func TimeFill(args []interface{}) func(columns []interface{}, values []interface{}) (newValues []interface{}) {
-	// group by time时的补点
+	// group by time时的补点, args[1]为可选的序列字段, 存在时按序列分别补点
 	return func(columns []interface{}, values []interface{}) (newValues []interface{}) {
 		m := args[0].(*view.Model)
+		var seriesColumns []string
+		if len(args) > 1 {
+			seriesColumns = args[1].([]string)
+		}
 		var timeFieldIndex int
-		// 取出time字段对应的下标
+		var seriesIndexes []int
+		// 取出time字段和序列字段对应的下标
 		for i, column := range columns {
 			if column.(string) == strings.Trim(m.Time.Alias, "`") {
 				timeFieldIndex = i
-				break
+			} else if common.IsValueInSliceString(column.(string), seriesColumns) {
+				seriesIndexes = append(seriesIndexes, i)
 			}
 		}
 		// start和end取整
@@ -67,44 +75,71 @@ func TimeFill(args []interface{}) func(columns []interface{}, values []interface
 			log.Errorf("Callback Time Fill Error: intervalLength(%d) < 1", intervalLength)
 			return []interface{}{}
 		}
-		newValues = make([]interface{}, intervalLength)
-		// 将查询数据结果写入newValues切片
+		// 按序列字段的值拆分, 保持序列首次出现的顺序
+		var seriesKeys []string
+		seriesValues := map[string][]interface{}{}
 		for _, value := range values {
 			record := value.([]interface{})
-			// 获取record在补点切片中的位置
-			var timeIndex int
-			if orderby == "asc" {
-				timeIndex = (record[timeFieldIndex].(int) - start) / m.Time.Interval
-			} else {
-				timeIndex = (end - record[timeFieldIndex].(int)) / m.Time.Interval
+			key := ""
+			for _, index := range seriesIndexes {
+				key += fmt.Sprintf("%v\x00", record[index])
 			}
-			if timeIndex >= intervalLength || timeIndex < 0 {
-				continue
+			if _, ok := seriesValues[key]; !ok {
+				seriesKeys = append(seriesKeys, key)
 			}
-			newValues[timeIndex] = value
+			seriesValues[key] = append(seriesValues[key], value)
 		}
-		var timestamp int
-		// 针对newValues中缺少的时间点进行补点
-		for i, value := range newValues {
-			if value == nil {
-				newValue := make([]interface{}, len(columns))
-				if m.Time.Fill != "null" {
-					for i := range newValue {
-						if intField, err := strconv.Atoi(m.Time.Fill); err == nil {
-							newValue[i] = int(intField)
-						} else {
-							newValue[i] = m.Time.Fill
-						}
-					}
-				}
+		if len(seriesKeys) == 0 {
+			seriesKeys = append(seriesKeys, "")
+		}
+		for _, key := range seriesKeys {
+			filledValues := make([]interface{}, intervalLength)
+			// 将查询数据结果写入filledValues切片
+			for _, value := range seriesValues[key] {
+				record := value.([]interface{})
+				// 获取record在补点切片中的位置
+				var timeIndex int
 				if orderby == "asc" {
-					timestamp = start + i*m.Time.Interval
+					timeIndex = (record[timeFieldIndex].(int) - start) / m.Time.Interval
 				} else {
-					timestamp = end - i*m.Time.Interval
+					timeIndex = (end - record[timeFieldIndex].(int)) / m.Time.Interval
+				}
+				if timeIndex >= intervalLength || timeIndex < 0 {
+					continue
+				}
+				filledValues[timeIndex] = value
+			}
+			var timestamp int
+			// 针对filledValues中缺少的时间点进行补点
+			for i, value := range filledValues {
+				if value == nil {
+					newValue := make([]interface{}, len(columns))
+					if m.Time.Fill != "null" {
+						for i := range newValue {
+							if intField, err := strconv.Atoi(m.Time.Fill); err == nil {
+								newValue[i] = int(intField)
+							} else {
+								newValue[i] = m.Time.Fill
+							}
+						}
+					}
+					if orderby == "asc" {
+						timestamp = start + i*m.Time.Interval
+					} else {
+						timestamp = end - i*m.Time.Interval
+					}
+					newValue[timeFieldIndex] = timestamp
+					// 补点携带所属序列的字段值
+					if len(seriesValues[key]) > 0 {
+						record := seriesValues[key][0].([]interface{})
+						for _, index := range seriesIndexes {
+							newValue[index] = record[index]
+						}
+					}
+					filledValues[i] = newValue
 				}
-				newValue[timeFieldIndex] = timestamp
-				newValues[i] = newValue
 			}
+			newValues = append(newValues, filledValues...)
 		}
 		return newValues
 	}
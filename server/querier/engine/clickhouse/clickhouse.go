@@ -45,6 +45,10 @@ type CHEngine struct {
 	Table         string
 	DataSource    string
 	asTagMap      map[string]string
+	rollupTable   *Table // 未指定DataSource时待自动选择数据源的表
+	groupColumns  []string
+	selectColumns []string
+	topK          *TopK
 	ColumnSchemas []*client.ColumnSchema
 	View          *view.View
 	Context       context.Context
@@ -122,10 +126,59 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (map[string][]interf
 		}
 		return results, debug.Get(), nil
 	}
+	chClient, params, shift, err := e.prepareQuery(sql, query_uuid, debug)
+	if err != nil {
+		return nil, debug.Get(), err
+	}
+	if shift != nil {
+		// 偏移时间段的时间范围不随查询的时间范围替换, 不能增量复用缓存
+		params.Cache.TimeColumn = ""
+	}
+	rst, err := chClient.DoQuery(params)
+	if err != nil {
+		return nil, debug.Get(), err
+	}
+	return rst, debug.Get(), err
+}
+
+// ExecuteQueryRows 与ExecuteQuery相同地转换sql, 但在读取ClickHouse结果的每一行时回调handle,
+// 不在内存中保留整个结果, 仅支持不需要callback处理结果且不含show/shift的查询
+func (e *CHEngine) ExecuteQueryRows(args *common.QuerierParams, handle client.RowHandler) (map[string]interface{}, error) {
+	debug := &client.Debug{
+		IP:        config.Cfg.Clickhouse.Host,
+		QueryUUID: args.QueryUUID,
+	}
+	chClient, params, shift, err := e.prepareQuery(args.Sql, args.QueryUUID, debug)
+	if err != nil {
+		return debug.Get(), err
+	}
+	if shift != nil || len(params.Callbacks) > 0 {
+		return debug.Get(), fmt.Errorf("sql %s can not be executed row by row", args.Sql)
+	}
+	err = chClient.QueryRows(params, handle)
+	return debug.Get(), err
+}
+
+// prepareQuery 将sql转换为ClickHouse的sql, 返回用于执行查询的client及查询参数
+func (e *CHEngine) prepareQuery(sql, query_uuid string, debug *client.Debug) (*client.Client, *client.QueryParams, *Shift, error) {
+	// Shift/Delta/Ratio拆分为当前时间段和偏移时间段的查询
+	shift, err := NewShift(sql)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, nil, err
+	}
+	if shift != nil {
+		sql = shift.CurrentSql
+	}
+	parser := parse.Parser{Engine: e}
 	err = parser.ParseSQL(sql)
 	if err != nil {
 		log.Error(err)
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	debug.Datasource = e.SelectDatasource()
+	if err = e.checkTimeSpan(); err != nil {
+		return nil, nil, nil, err
 	}
 	for _, stmt := range e.Statements {
 		stmt.Format(e.Model)
@@ -134,9 +187,16 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (map[string][]interf
 	// 使用Model生成View
 	e.View = view.NewView(e.Model)
 	chSql := e.ToSQLString()
+	if shift != nil {
+		chSql, err = shift.ToSQLString(e, chSql)
+		if err != nil {
+			log.Error(err)
+			return nil, nil, nil, err
+		}
+	}
 	callbacks := e.View.GetCallbacks()
 	debug.Sql = chSql
-	chClient := client.Client{
+	chClient := &client.Client{
 		Host:     config.Cfg.Clickhouse.Host,
 		Port:     config.Cfg.Clickhouse.Port,
 		UserName: config.Cfg.Clickhouse.User,
@@ -154,12 +214,48 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (map[string][]interf
 		Callbacks:       callbacks,
 		QueryUUID:       query_uuid,
 		ColumnSchemaMap: ColumnSchemaMap,
+		Cache:           e.CacheParams(),
+		Settings:        config.Cfg.Limits.Settings(),
 	}
-	rst, err := chClient.DoQuery(params)
-	if err != nil {
-		return nil, debug.Get(), err
+	if e.topK != nil {
+		params.HiddenColumns = []string{TOPK_FLAG}
 	}
-	return rst, debug.Get(), err
+	return chClient, params, shift, nil
+}
+
+// checkTimeSpan 检查查询的时间范围是否超过限制, flow_tag中的表不按时间查询
+func (e *CHEngine) checkTimeSpan() error {
+	maxTimeSpan := int64(config.Cfg.Limits.MaxTimeSpan)
+	if maxTimeSpan <= 0 || e.DB == "flow_tag" {
+		return nil
+	}
+	if span := e.Model.Time.TimeEnd - e.Model.Time.TimeStart; span > maxTimeSpan {
+		return fmt.Errorf("query time span %ds exceeds the limit %ds, please narrow the time range", span, maxTimeSpan)
+	}
+	return nil
+}
+
+// CacheParams 生成查询结果缓存的参数, 只有使用time()分组、未使用limit和TopK且只按时间排序的查询可以增量复用
+func (e *CHEngine) CacheParams() *client.CacheParams {
+	m := e.Model
+	params := &client.CacheParams{
+		TimeStart: m.Time.TimeStart,
+		TimeEnd:   m.Time.TimeEnd,
+		Interval:  m.Time.Interval,
+	}
+	if m.Time.Interval <= 0 || m.Time.WindowSize > 1 || m.Time.Alias == "" || m.Limit.Limit != "" || e.topK != nil {
+		return params
+	}
+	timeColumn := strings.Trim(m.Time.Alias, "`")
+	for _, node := range m.Orders.Orders {
+		order := node.(*view.Order)
+		if strings.Trim(order.SortBy, "`") != timeColumn {
+			return params
+		}
+		params.OrderDesc = strings.ToLower(order.OrderBy) == "desc"
+	}
+	params.TimeColumn = timeColumn
+	return params
 }
 
 func (e *CHEngine) ParseShowSql(sql string) (map[string][]interface{}, []string, bool, error) {
@@ -221,6 +317,7 @@ func (e *CHEngine) Init() {
 
 func (e *CHEngine) TransSelect(tags sqlparser.SelectExprs) error {
 	e.asTagMap = make(map[string]string)
+	e.selectColumns = nil
 	for _, tag := range tags {
 		err := e.parseSelect(tag)
 		if err != nil {
@@ -228,6 +325,7 @@ func (e *CHEngine) TransSelect(tags sqlparser.SelectExprs) error {
 		}
 		item, ok := tag.(*sqlparser.AliasedExpr)
 		if ok {
+			e.selectColumns = append(e.selectColumns, selectName(item))
 			as := chCommon.ParseAlias(item.As)
 			colName, ok := item.Expr.(*sqlparser.ColName)
 			if ok {
@@ -292,7 +390,11 @@ func (e *CHEngine) TransFrom(froms sqlparser.TableExprs) error {
 				}
 				e.Model.Time.DatasourceInterval = interval
 			} else {
-				e.AddTable(fmt.Sprintf("%s.`%s`", e.DB, table))
+				stmt := &Table{Value: fmt.Sprintf("%s.`%s`", e.DB, table)}
+				e.Statements = append(e.Statements, stmt)
+				if e.DB == "flow_metrics" {
+					e.rollupTable = stmt
+				}
 			}
 			virtualTableFilter, ok := GetVirtualTableFilter(e.DB, e.Table)
 			if ok {
@@ -327,6 +429,19 @@ func (e *CHEngine) TransOrderBy(orders sqlparser.OrderBy) error {
 }
 
 func (e *CHEngine) TransLimit(limit *sqlparser.Limit) error {
+	// LIMIT TopK(N)不作为limit下推, 而是将查询包装为子查询, 在clickhouse中取前N个分组并合并其余分组
+	if function, ok := limit.Rowcount.(*sqlparser.FuncExpr); ok && sqlparser.String(function.Name) == FUNCTION_TOPK {
+		if limit.Offset != nil {
+			return errors.New("TopK does not support offset")
+		}
+		topK, err := e.parseTopK(function)
+		if err != nil {
+			return err
+		}
+		e.topK = topK
+		e.Statements = append(e.Statements, topK)
+		return nil
+	}
 	e.Model.Limit.Limit = sqlparser.String(limit.Rowcount)
 	if limit.Offset != nil {
 		e.Model.Limit.Offset = sqlparser.String(limit.Offset)
@@ -346,6 +461,9 @@ func (e *CHEngine) ToSQLString() string {
 	}
 	// View生成clickhouse-sql
 	chSql := e.View.ToString()
+	if e.topK != nil {
+		chSql = e.topK.ToSQLString(chSql)
+	}
 	return chSql
 }
 
@@ -382,6 +500,9 @@ func (e *CHEngine) parseGroupBy(group sqlparser.Expr) error {
 		if err != nil {
 			return err
 		}
+		if e.asTagMap[groupTag] != "time" {
+			e.groupColumns = append(e.groupColumns, strings.Trim(groupTag, "`"))
+		}
 		_, ok := e.asTagMap[groupTag]
 		if !ok {
 			err := e.AddTag(groupTag, "")
@@ -183,6 +183,13 @@ var (
 	}, {
 		input:  "select request from l7_flow_log where Enum(tap_side) like 'xxx' limit 0, 50",
 		output: "SELECT if(type IN [0, 2],1,0) AS `request` FROM flow_log.`l7_flow_log` PREWHERE (tap_side IN (SELECT value FROM flow_tag.string_enum_map WHERE name ilike 'xxx' and tag_name='tap_side')) LIMIT 0, 50",
+	}, {
+		input:  "select Enum(pod_group_type_0) from l7_flow_log where Enum(pod_group_type_0)=6 limit 0, 50",
+		output: "WITH dictGetOrDefault(flow_tag.int_enum_map, 'name', ('pod_group_type',toUInt64(dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(pod_group_id_0))))), dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(pod_group_id_0)))) AS `Enum(pod_group_type_0)` SELECT `Enum(pod_group_type_0)` FROM flow_log.`l7_flow_log` PREWHERE (toUInt64(pod_group_id_0) IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type IN (SELECT value FROM flow_tag.int_enum_map WHERE name = 6 and tag_name='pod_group_type')) OR dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(pod_group_id_0))) = toUInt64(6)) LIMIT 0, 50",
+	}, {
+		input:  "select pod_group_type from vtap_flow_port where pod_group_type=6",
+		output: "SELECT dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(pod_group_id))) AS `pod_group_type` FROM flow_metrics.`vtap_flow_port` WHERE (toUInt64(pod_group_id) IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type = 6))",
+		db:     "flow_metrics",
 	}, {
 		input:  "select Histogram(Sum(byte),10) AS histo from l4_flow_log",
 		output: "SELECT histogram(10)(`_sum_byte_tx+byte_rx`) AS `histo` FROM (SELECT SUM(byte_tx+byte_rx) AS `_sum_byte_tx+byte_rx` FROM flow_log.`l4_flow_log`)",
@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CACHE_TIME_START = "${cache_time_start}"
+	CACHE_TIME_END   = "${cache_time_end}"
+
+	CACHE_HIT     = "hit"
+	CACHE_MISS    = "miss"
+	CACHE_PARTIAL = "partial"
+)
+
+var QueryCache *ResultCache
+
+// CacheParams 描述查询的时间范围, 用于按time()的粒度增量复用缓存的结果
+type CacheParams struct {
+	TimeStart  int64
+	TimeEnd    int64
+	Interval   int
+	TimeColumn string // time()的别名, 为空时只复用SQL完全相同的结果
+	OrderDesc  bool
+}
+
+type queryResult struct {
+	columns []interface{}
+	schemas []interface{}
+	values  []interface{}
+}
+
+// copy 复制结果的每一行, 避免callback修改缓存中的数据
+func (r *queryResult) copy() *queryResult {
+	values := make([]interface{}, len(r.values))
+	for i, value := range r.values {
+		values[i] = append([]interface{}{}, value.([]interface{})...)
+	}
+	return &queryResult{
+		columns: append([]interface{}{}, r.columns...),
+		schemas: r.schemas,
+		values:  values,
+	}
+}
+
+func (r *queryResult) size() int {
+	size := 0
+	for _, value := range r.values {
+		for _, v := range value.([]interface{}) {
+			// interface本身占用16字节
+			size += 16
+			if s, ok := v.(string); ok {
+				size += len(s)
+			}
+		}
+	}
+	return size
+}
+
+type cacheEntry struct {
+	key       string
+	sql       string
+	result    *queryResult
+	timeStart int64
+	timeEnd   int64
+	stableEnd int64 // 该时间及之前的数据已写入完成, 可以增量复用
+	expireAt  time.Time
+	size      int
+}
+
+// ResultCache 以ClickHouse SQL为key的查询结果缓存, 按LRU淘汰
+// 对于按time()分组的查询, key中的时间范围被替换为占位符, 时间范围变化后
+// 只查询缓存中不存在或未写入完成的时间段, 再与缓存中完整的时间段合并
+type ResultCache struct {
+	sync.Mutex
+	ttl       time.Duration
+	maxSize   int
+	dataDelay int64
+	size      int
+	lru       *list.List
+	entries   map[string]*list.Element
+}
+
+func NewResultCache(ttl time.Duration, maxSize int, dataDelay time.Duration) *ResultCache {
+	return &ResultCache{
+		ttl:       ttl,
+		maxSize:   maxSize,
+		dataDelay: int64(dataDelay / time.Second),
+		lru:       list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// Purge 清空缓存, 返回清除的条目数
+func (r *ResultCache) Purge() int {
+	r.Lock()
+	defer r.Unlock()
+	count := len(r.entries)
+	r.lru.Init()
+	r.entries = make(map[string]*list.Element)
+	r.size = 0
+	return count
+}
+
+func (r *ResultCache) get(key string, now time.Time) *cacheEntry {
+	r.Lock()
+	defer r.Unlock()
+	element, ok := r.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cacheEntry)
+	if now.After(entry.expireAt) {
+		r.remove(element)
+		return nil
+	}
+	r.lru.MoveToFront(element)
+	return entry
+}
+
+func (r *ResultCache) put(entry *cacheEntry) {
+	if entry.size > r.maxSize {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if element, ok := r.entries[entry.key]; ok {
+		r.remove(element)
+	}
+	r.entries[entry.key] = r.lru.PushFront(entry)
+	r.size += entry.size
+	for r.size > r.maxSize {
+		r.remove(r.lru.Back())
+	}
+}
+
+func (r *ResultCache) remove(element *list.Element) {
+	entry := r.lru.Remove(element).(*cacheEntry)
+	delete(r.entries, entry.key)
+	r.size -= entry.size
+}
+
+// cacheKey 将sql中的时间范围替换为占位符, 第二个返回值表示是否可以增量查询
+func cacheKey(sql string, p *CacheParams) (string, bool) {
+	if p.TimeColumn == "" || p.Interval <= 0 || p.TimeStart >= p.TimeEnd {
+		return sql, false
+	}
+	start, end := strconv.FormatInt(p.TimeStart, 10), strconv.FormatInt(p.TimeEnd, 10)
+	if strings.Count(sql, start) != 1 || strings.Count(sql, end) != 1 {
+		return sql, false
+	}
+	return strings.NewReplacer(start, CACHE_TIME_START, end, CACHE_TIME_END).Replace(sql), true
+}
+
+// query 优先使用缓存的结果, fetch用于查询缓存中不存在的时间段
+func (r *ResultCache) query(debug *Debug, sql string, p *CacheParams, fetch func(sql string) (*queryResult, error)) (*queryResult, error) {
+	key, incremental := cacheKey(sql, p)
+	now := time.Now()
+	entry := r.get(key, now)
+	if entry != nil && entry.sql == sql {
+		debug.Cache = CACHE_HIT
+		return entry.result.copy(), nil
+	}
+	var result *queryResult
+	var err error
+	if entry != nil && incremental {
+		result, err = r.merge(debug, entry, p, fetch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if result == nil {
+		debug.Cache = CACHE_MISS
+		result, err = fetch(sql)
+		if err != nil {
+			return nil, err
+		}
+	}
+	stableEnd := now.Unix() - r.dataDelay
+	if stableEnd > p.TimeEnd {
+		stableEnd = p.TimeEnd
+	}
+	r.put(&cacheEntry{
+		key:       key,
+		sql:       sql,
+		result:    result,
+		timeStart: p.TimeStart,
+		timeEnd:   p.TimeEnd,
+		stableEnd: stableEnd,
+		expireAt:  now.Add(r.ttl),
+		size:      result.size(),
+	})
+	return result.copy(), nil
+}
+
+// merge 复用entry中完整的时间段, 只查询其之前和之后的时间段, 无法复用时返回nil
+func (r *ResultCache) merge(debug *Debug, entry *cacheEntry, p *CacheParams, fetch func(sql string) (*queryResult, error)) (*queryResult, error) {
+	timeIndex := -1
+	for i, column := range entry.result.columns {
+		if column == p.TimeColumn {
+			timeIndex = i
+			break
+		}
+	}
+	if timeIndex < 0 || len(entry.result.values) == 0 {
+		return nil, nil
+	}
+	first, ok := entry.result.values[0].([]interface{})[timeIndex].(int)
+	if !ok {
+		return nil, nil
+	}
+	// time()的分组边界可能受时区影响, 以缓存中已有的分组时间推算
+	interval := int64(p.Interval)
+	offset := ((int64(first) % interval) + interval) % interval
+	alignDown := func(t int64) int64 {
+		return t - ((t-offset)%interval+interval)%interval
+	}
+	// 缓存中完整的分组为[reuseStart, reuseEnd), 开始时间之前的分组只包含部分数据
+	reuseStart := entry.timeStart
+	if p.TimeStart > reuseStart {
+		reuseStart = p.TimeStart
+	}
+	if aligned := alignDown(reuseStart); aligned != reuseStart {
+		reuseStart = aligned + interval
+	}
+	reuseEnd := entry.stableEnd + 1
+	if p.TimeEnd+1 < reuseEnd {
+		reuseEnd = p.TimeEnd + 1
+	}
+	reuseEnd = alignDown(reuseEnd)
+	if reuseStart >= reuseEnd {
+		return nil, nil
+	}
+
+	var reused []interface{}
+	for _, value := range entry.result.values {
+		t, ok := value.([]interface{})[timeIndex].(int)
+		if !ok {
+			return nil, nil
+		}
+		if int64(t) >= reuseStart && int64(t) < reuseEnd {
+			reused = append(reused, value)
+		}
+	}
+	var head, tail *queryResult
+	var err error
+	if p.TimeStart < reuseStart {
+		head, err = fetch(replaceTimeRange(entry.key, p.TimeStart, reuseStart-1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if reuseEnd <= p.TimeEnd {
+		tail, err = fetch(replaceTimeRange(entry.key, reuseEnd, p.TimeEnd))
+		if err != nil {
+			return nil, err
+		}
+	}
+	parts := []*queryResult{head, {values: reused}, tail}
+	if p.OrderDesc {
+		parts[0], parts[2] = tail, head
+	}
+	result := &queryResult{columns: entry.result.columns, schemas: entry.result.schemas}
+	for _, part := range parts {
+		if part == nil {
+			continue
+		}
+		if part.columns != nil && len(part.columns) != len(result.columns) {
+			return nil, nil
+		}
+		result.values = append(result.values, part.values...)
+	}
+	debug.Cache = fmt.Sprintf("%s, reused %d rows in [%d, %d)", CACHE_PARTIAL, len(reused), reuseStart, reuseEnd)
+	return result, nil
+}
+
+func replaceTimeRange(key string, start, end int64) string {
+	return strings.NewReplacer(CACHE_TIME_START, strconv.FormatInt(start, 10), CACHE_TIME_END, strconv.FormatInt(end, 10)).Replace(key)
+}
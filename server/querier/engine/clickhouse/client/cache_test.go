@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCacheSql = "SELECT toUnixTimestamp(`_time`) AS `time`, COUNT(1) AS `count` FROM flow_metrics.`vtap_flow_port` WHERE time >= %d AND time <= %d GROUP BY `time`"
+
+// fakeFetch 模拟按60秒分组的查询, count为分组内落在时间范围中的秒数
+func fakeFetch(sqls *[]string) func(sql string) (*queryResult, error) {
+	return func(sql string) (*queryResult, error) {
+		*sqls = append(*sqls, sql)
+		var start, end int64
+		if _, err := fmt.Sscanf(sql[strings.Index(sql, "WHERE"):], "WHERE time >= %d AND time <= %d", &start, &end); err != nil {
+			return nil, err
+		}
+		result := &queryResult{columns: []interface{}{"time", "count"}}
+		for bucket := start - start%60; bucket <= end; bucket += 60 {
+			from, to := bucket, bucket+59
+			if from < start {
+				from = start
+			}
+			if to > end {
+				to = end
+			}
+			result.values = append(result.values, []interface{}{int(bucket), int(to - from + 1)})
+		}
+		return result, nil
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	cache := NewResultCache(time.Minute, 1<<20, 0)
+	now := time.Now().Unix()
+	start, end := now-3600-15, now-600-15
+	var sqls []string
+	query := func(start, end int64) (*queryResult, *Debug) {
+		debug := &Debug{}
+		params := &CacheParams{TimeStart: start, TimeEnd: end, Interval: 60, TimeColumn: "time"}
+		result, err := cache.query(debug, fmt.Sprintf(testCacheSql, start, end), params, fakeFetch(&sqls))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, debug
+	}
+
+	if _, debug := query(start, end); debug.Cache != CACHE_MISS {
+		t.Errorf("first query cache = %s, want %s", debug.Cache, CACHE_MISS)
+	}
+	if _, debug := query(start, end); debug.Cache != CACHE_HIT || len(sqls) != 1 {
+		t.Errorf("repeated query cache = %s, sqls = %d", debug.Cache, len(sqls))
+	}
+
+	// 时间范围整体后移, 只查询头部和尾部
+	sqls = nil
+	result, debug := query(start+30, end+300)
+	if !strings.HasPrefix(debug.Cache, CACHE_PARTIAL) || len(sqls) != 2 {
+		t.Fatalf("shifted query cache = %s, sqls = %v", debug.Cache, sqls)
+	}
+	expected, _ := fakeFetch(&sqls)(fmt.Sprintf(testCacheSql, start+30, end+300))
+	if !reflect.DeepEqual(result.values, expected.values) {
+		t.Errorf("merged values = %v, want %v", result.values, expected.values)
+	}
+
+	// callback修改返回的结果不影响缓存
+	result.values[0].([]interface{})[1] = -1
+	if result, _ := query(start+30, end+300); result.values[0].([]interface{})[1] == -1 {
+		t.Error("cached values modified by caller")
+	}
+
+	if count := cache.Purge(); count != 1 {
+		t.Errorf("Purge() = %d, want 1", count)
+	}
+	if _, debug := query(start+30, end+300); debug.Cache != CACHE_MISS {
+		t.Errorf("query after purge cache = %s, want %s", debug.Cache, CACHE_MISS)
+	}
+}
+
+func TestResultCacheEvict(t *testing.T) {
+	cache := NewResultCache(time.Minute, 100, 0)
+	var sqls []string
+	for i := int64(0); i < 3; i++ {
+		params := &CacheParams{TimeStart: i, TimeEnd: 59}
+		cache.query(&Debug{}, fmt.Sprintf(testCacheSql, i, 59), params, fakeFetch(&sqls))
+	}
+	// 每个结果占用32字节
+	if len(cache.entries) != 3 || cache.size != 96 {
+		t.Errorf("entries = %d, size = %d", len(cache.entries), cache.size)
+	}
+	params := &CacheParams{TimeStart: 0, TimeEnd: 100}
+	cache.query(&Debug{}, fmt.Sprintf(testCacheSql, 0, 100), params, fakeFetch(&sqls))
+	if len(cache.entries) != 2 || cache.size != 96 {
+		t.Errorf("after evict entries = %d, size = %d", len(cache.entries), cache.size)
+	}
+}
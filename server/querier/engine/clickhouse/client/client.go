@@ -20,7 +20,7 @@ import (
 	"context"
 	//"database/sql"
 	"fmt"
-	_ "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/jmoiron/sqlx"
 	//"github.com/k0kubun/pp"
 	"github.com/deepflowys/deepflow/server/querier/statsd"
@@ -38,6 +38,9 @@ type QueryParams struct {
 	Callbacks       []func(columns []interface{}, values []interface{}) []interface{}
 	QueryUUID       string
 	ColumnSchemaMap map[string]*ColumnSchema
+	Cache           *CacheParams           // 为nil时不使用查询结果缓存
+	Settings        map[string]interface{} // 下推到ClickHouse的查询设置, 如查询限制
+	HiddenColumns   []string               // 仅供callback使用的列, 执行callback后从结果中删除
 }
 
 type Client struct {
@@ -49,6 +52,7 @@ type Client struct {
 	DB         string
 	Context    context.Context
 	Debug      *Debug
+	settings   map[string]interface{}
 }
 
 func (c *Client) init(query_uuid string) error {
@@ -78,73 +82,200 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) DoQuery(params *QueryParams) (map[string][]interface{}, error) {
+	if Replicas != nil {
+		return Replicas.query(c, func() (map[string][]interface{}, error) {
+			return c.doQuery(params)
+		})
+	}
+	return c.doQuery(params)
+}
+
+func (c *Client) doQuery(params *QueryParams) (map[string][]interface{}, error) {
 	sqlstr, callbacks, query_uuid, columnSchemaMap := params.Sql, params.Callbacks, params.QueryUUID, params.ColumnSchemaMap
 	err := c.init(query_uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer c.Close()
-	start := time.Now()
-	var rows *sqlx.Rows
-	if c.Context != nil {
-		rows, err = c.connection.QueryxContext(c.Context, sqlstr)
+	c.settings = params.Settings
+	var res *queryResult
+	if params.Cache != nil && QueryCache != nil {
+		res, err = QueryCache.query(c.Debug, sqlstr, params.Cache, func(sql string) (*queryResult, error) {
+			return c.query(sql, columnSchemaMap)
+		})
 	} else {
-		rows, err = c.connection.Queryx(sqlstr)
+		res, err = c.query(sqlstr, columnSchemaMap)
+	}
+	c.Debug.Sql = sqlstr
+	if err != nil {
+		return nil, err
 	}
+	values := res.values
+	for _, callback := range callbacks {
+		values = callback(res.columns, values)
+	}
+	columns, schemas := res.columns, res.schemas
+	if len(params.HiddenColumns) > 0 {
+		columns, schemas, values = hideColumns(params.HiddenColumns, columns, schemas, values)
+	}
+	result := make(map[string][]interface{})
+	result["columns"] = columns
+	result["schemas"] = schemas
+	result["values"] = values
+	return result, nil
+}
+
+// hideColumns 删除结果中的指定列, 返回新的切片, 不修改可能被缓存的原结果
+func hideColumns(hidden []string, columns, schemas, values []interface{}) ([]interface{}, []interface{}, []interface{}) {
+	hiddenSet := make(map[string]bool, len(hidden))
+	for _, name := range hidden {
+		hiddenSet[name] = true
+	}
+	var keepIndexes []int
+	newColumns := make([]interface{}, 0, len(columns))
+	newSchemas := make([]interface{}, 0, len(schemas))
+	for i, column := range columns {
+		if name, ok := column.(string); ok && hiddenSet[name] {
+			continue
+		}
+		keepIndexes = append(keepIndexes, i)
+		newColumns = append(newColumns, column)
+		if i < len(schemas) {
+			newSchemas = append(newSchemas, schemas[i])
+		}
+	}
+	if len(keepIndexes) == len(columns) {
+		return columns, schemas, values
+	}
+	newValues := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		record := value.([]interface{})
+		newRecord := make([]interface{}, 0, len(keepIndexes))
+		for _, i := range keepIndexes {
+			newRecord = append(newRecord, record[i])
+		}
+		newValues = append(newValues, newRecord)
+	}
+	return newColumns, newSchemas, newValues
+}
+
+// RowHandler 逐行处理查询结果, 返回错误时停止读取
+type RowHandler func(columns []interface{}, row []interface{}) error
+
+// QueryRows 执行查询并在读取每一行时回调handle, 不在内存中保留整个结果,
+// 因此不使用查询结果缓存, 也不执行params中的callback
+func (c *Client) QueryRows(params *QueryParams, handle RowHandler) error {
+	if Replicas != nil {
+		handled := false
+		_, err := Replicas.query(c, func() (map[string][]interface{}, error) {
+			err := c.queryRows(params, func(columns []interface{}, row []interface{}) error {
+				handled = true
+				return handle(columns, row)
+			})
+			// 已经返回了部分结果时不能在其它副本上重试
+			if err != nil && handled {
+				return nil, &noRetryError{err}
+			}
+			return nil, err
+		})
+		return err
+	}
+	return c.queryRows(params, handle)
+}
+
+func (c *Client) queryRows(params *QueryParams, handle RowHandler) error {
+	err := c.init(params.QueryUUID)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	c.settings = params.Settings
+	res := &queryResult{}
+	return c.scan(params.Sql, params.ColumnSchemaMap, res, func(row []interface{}) error {
+		return handle(res.columns, row)
+	})
+}
+
+// query 执行sqlstr并返回未经过callback处理的结果
+func (c *Client) query(sqlstr string, columnSchemaMap map[string]*ColumnSchema) (*queryResult, error) {
+	res := &queryResult{}
+	err := c.scan(sqlstr, columnSchemaMap, res, func(row []interface{}) error {
+		res.values = append(res.values, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// scan 执行sqlstr, 将列名及列信息写入res, 并逐行回调转换类型后的数据
+func (c *Client) scan(sqlstr string, columnSchemaMap map[string]*ColumnSchema, res *queryResult, handle func(row []interface{}) error) error {
+	start := time.Now()
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = clickhouse.Context(ctx, clickhouse.WithQueryID(c.Debug.QueryUUID), clickhouse.WithSettings(c.settings))
+	running := runningQueries.add(c, sqlstr, start)
+	defer runningQueries.remove(running)
+	rows, err := c.connection.QueryxContext(ctx, sqlstr)
 
 	c.Debug.Sql = sqlstr
 	if err != nil {
 		log.Errorf("query clickhouse Error: %s, sql: %s, query_uuid: %s", err, sqlstr, c.Debug.QueryUUID)
 		c.Debug.Error = fmt.Sprintf("%s", err)
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 	columns, err := rows.ColumnTypes()
 	resColumns := len(columns)
 	if err != nil {
 		c.Debug.Error = fmt.Sprintf("%s", err)
-		return nil, err
+		return err
 	}
-	result := make(map[string][]interface{})
-	var columnNames []interface{}
 	var columnTypes []string
-	var columnSchemas []interface{}
 	// 获取列名和列类型
 	for _, column := range columns {
-		columnNames = append(columnNames, column.Name())
+		res.columns = append(res.columns, column.Name())
 		columnTypes = append(columnTypes, column.DatabaseTypeName())
 		if schema, ok := columnSchemaMap[column.Name()]; ok {
-			columnSchemas = append(columnSchemas, schema.ToMap())
+			res.schemas = append(res.schemas, schema.ToMap())
 		} else {
-			columnSchemas = append(columnSchemas, NewColumnSchema(column.Name()).ToMap())
+			res.schemas = append(res.schemas, NewColumnSchema(column.Name()).ToMap())
 		}
 	}
-	result["columns"] = columnNames
-	result["schemas"] = columnSchemas
-	var values []interface{}
 	resSize := 0
+	resRows := 0
 	for rows.Next() {
 		// row, err := rows.SliceScan()
 		var row []interface{}
 		row, err = sqlx.SliceScan(rows)
 		if err != nil {
 			c.Debug.Error = fmt.Sprintf("%s", err)
-			return nil, err
+			return err
 		}
 		var record []interface{}
 		for i, rawValue := range row {
 			value, err := TransType(columnTypes[i], rawValue)
 			if err != nil {
 				c.Debug.Error = fmt.Sprintf("%s", err)
-				return nil, err
+				return err
 			}
 			resSize += int(unsafe.Sizeof(value))
 			record = append(record, value)
 		}
-		values = append(values, record)
+		resRows++
+		if err := handle(record); err != nil {
+			c.Debug.Error = fmt.Sprintf("%s", err)
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.Debug.Error = fmt.Sprintf("%s", err)
+		return err
 	}
 	queryTime := time.Since(start)
-	resRows := len(values)
 	statsd.QuerierCounter.WriteCk(
 		&statsd.ClickhouseCounter{
 			ResponseSize: uint64(resSize),
@@ -152,12 +283,8 @@ func (c *Client) DoQuery(params *QueryParams) (map[string][]interface{}, error)
 			ColumnCount:  uint64(resColumns),
 		},
 	)
-	c.Debug.QueryTime = int64(queryTime)
-	for _, callback := range callbacks {
-		values = callback(columnNames, values)
-	}
-	result["values"] = values
+	c.Debug.QueryTime += int64(queryTime)
 	log.Debugf("sql: %s, query_uuid: %s", sqlstr, c.Debug.QueryUUID)
 	log.Infof("res_rows: %v, res_columns: %v, res_size: %v", resRows, resColumns, resSize)
-	return result, nil
+	return nil
 }
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHideColumns(t *testing.T) {
+	columns := []interface{}{"time", "ip", "__hidden__", "byte"}
+	schemas := []interface{}{"s_time", "s_ip", "s_hidden", "s_byte"}
+	values := []interface{}{
+		[]interface{}{60, "a", 1, 10},
+		[]interface{}{120, "b", 0, 20},
+	}
+	newColumns, newSchemas, newValues := hideColumns([]string{"__hidden__"}, columns, schemas, values)
+	if !reflect.DeepEqual(newColumns, []interface{}{"time", "ip", "byte"}) || !reflect.DeepEqual(newSchemas, []interface{}{"s_time", "s_ip", "s_byte"}) {
+		t.Errorf("hideColumns() columns = %v, schemas = %v", newColumns, newSchemas)
+	}
+	wantValues := []interface{}{
+		[]interface{}{60, "a", 10},
+		[]interface{}{120, "b", 20},
+	}
+	if !reflect.DeepEqual(newValues, wantValues) {
+		t.Errorf("hideColumns() values = %v, want %v", newValues, wantValues)
+	}
+	// 原结果可能被缓存, 不能被修改
+	if len(columns) != 4 || len(values[0].([]interface{})) != 4 {
+		t.Errorf("hideColumns() modified the original result")
+	}
+}
@@ -21,11 +21,14 @@ import (
 )
 
 type Debug struct {
-	IP        string
-	Sql       string
-	QueryTime int64
-	QueryUUID string
-	Error     string
+	IP         string
+	Sql        string
+	QueryTime  int64
+	QueryUUID  string
+	Error      string
+	Datasource string // 自动选择数据源的结果
+	Cache      string // 查询结果缓存的命中情况
+	Retry      string // 在其他副本重试前失败的副本及错误
 }
 
 func (s *Debug) Get() map[string]interface{} {
@@ -35,12 +38,15 @@ func (s *Debug) Get() map[string]interface{} {
 		"query_time": fmt.Sprintf("%.9fs", float64(s.QueryTime)/1e9),
 		"query_uuid": s.QueryUUID,
 		"error":      s.Error,
+		"datasource": s.Datasource,
+		"cache":      s.Cache,
+		"retry":      s.Retry,
 	}
 }
 
 func (s *Debug) String() string {
 	return fmt.Sprintf(
-		"| ip: %s | sql: %s | query_time: %.9fs | query_uuid: %s | error: %s |",
-		s.IP, s.Sql, float64(s.QueryTime)/1e9, s.QueryUUID, s.Error,
+		"| ip: %s | sql: %s | query_time: %.9fs | query_uuid: %s | error: %s | datasource: %s | cache: %s | retry: %s |",
+		s.IP, s.Sql, float64(s.QueryTime)/1e9, s.QueryUUID, s.Error, s.Datasource, s.Cache, s.Retry,
 	)
 }
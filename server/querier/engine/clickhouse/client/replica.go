@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+const DEFAULT_HEALTH_CHECK_INTERVAL = 10 * time.Second
+
+// Replicas 配置了多个ClickHouse副本时不为nil, DoQuery在健康的副本中选择并发查询数最少的执行
+var Replicas *ReplicaPool
+
+type Replica struct {
+	Host     string
+	Port     int
+	inFlight int32
+	healthy  int32
+}
+
+func (r *Replica) String() string {
+	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+}
+
+func (r *Replica) InFlight() int {
+	return int(atomic.LoadInt32(&r.inFlight))
+}
+
+func (r *Replica) Healthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *Replica) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&r.healthy, 1)
+	} else {
+		atomic.StoreInt32(&r.healthy, 0)
+	}
+}
+
+type ReplicaPool struct {
+	replicas       []*Replica
+	userName       string
+	password       string
+	checkInterval  time.Duration
+	connectTimeout time.Duration
+	stop           chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewReplicaPool endpoints的格式为host:port, 未指定端口时使用defaultPort
+func NewReplicaPool(endpoints []string, defaultPort int, userName, password string, checkInterval, connectTimeout time.Duration) (*ReplicaPool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("clickhouse endpoints is empty")
+	}
+	if checkInterval <= 0 {
+		checkInterval = DEFAULT_HEALTH_CHECK_INTERVAL
+	}
+	p := &ReplicaPool{
+		userName:       userName,
+		password:       password,
+		checkInterval:  checkInterval,
+		connectTimeout: connectTimeout,
+		stop:           make(chan struct{}),
+	}
+	for _, endpoint := range endpoints {
+		host, port := endpoint, defaultPort
+		if h, portStr, err := net.SplitHostPort(endpoint); err == nil {
+			host = h
+			if port, err = strconv.Atoi(portStr); err != nil {
+				return nil, fmt.Errorf("invalid clickhouse endpoint %s: %s", endpoint, err)
+			}
+		}
+		// 启动时认为所有副本都是健康的, 由健康检查更新
+		p.replicas = append(p.replicas, &Replica{Host: host, Port: port, healthy: 1})
+	}
+	return p, nil
+}
+
+func (p *ReplicaPool) Replicas() []*Replica {
+	return p.replicas
+}
+
+func (p *ReplicaPool) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.check()
+			}
+		}
+	}()
+}
+
+func (p *ReplicaPool) Close() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+func (p *ReplicaPool) check() {
+	for _, replica := range p.replicas {
+		err := p.ping(replica)
+		if err != nil && replica.Healthy() {
+			log.Warningf("clickhouse replica %s is unhealthy: %s", replica, err)
+		} else if err == nil && !replica.Healthy() {
+			log.Infof("clickhouse replica %s is healthy", replica)
+		}
+		replica.setHealthy(err == nil)
+	}
+}
+
+func (p *ReplicaPool) ping(replica *Replica) error {
+	url := fmt.Sprintf("clickhouse://%s:%s@%s/", p.userName, p.password, replica)
+	conn, err := sqlx.Open("clickhouse", url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), p.connectTimeout)
+	defer cancel()
+	return conn.PingContext(ctx)
+}
+
+// pick 选择并发查询数最少的健康副本, 没有健康副本时在所有副本中选择, exclude为已失败的副本
+func (p *ReplicaPool) pick(exclude *Replica) *Replica {
+	var selected *Replica
+	for _, healthyOnly := range []bool{true, false} {
+		for _, replica := range p.replicas {
+			if replica == exclude || (healthyOnly && !replica.Healthy()) {
+				continue
+			}
+			if selected == nil || replica.InFlight() < selected.InFlight() {
+				selected = replica
+			}
+		}
+		if selected != nil {
+			return selected
+		}
+	}
+	return nil
+}
+
+// retryable 连接失败等非ClickHouse返回的错误可以在其他副本重试
+func retryable(ctx context.Context, err error) bool {
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	var noRetry *noRetryError
+	if errors.As(err, &noRetry) {
+		return false
+	}
+	var exception *clickhouse.Exception
+	return !errors.As(err, &exception)
+}
+
+// noRetryError 表示查询失败但不能在其它副本上重试, 如流式查询已经返回了部分结果
+type noRetryError struct {
+	err error
+}
+
+func (e *noRetryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *noRetryError) Unwrap() error {
+	return e.err
+}
+
+// query 在选择的副本上执行查询, 失败时在另一个副本上重试一次
+func (p *ReplicaPool) query(c *Client, do func() (map[string][]interface{}, error)) (map[string][]interface{}, error) {
+	var failed *Replica
+	var result map[string][]interface{}
+	var err error
+	for i := 0; i < 2; i++ {
+		replica := p.pick(failed)
+		if replica == nil {
+			break
+		}
+		c.Host, c.Port = replica.Host, replica.Port
+		if c.Debug != nil {
+			c.Debug.IP = replica.Host
+			c.Debug.Error = ""
+		}
+		atomic.AddInt32(&replica.inFlight, 1)
+		result, err = do()
+		atomic.AddInt32(&replica.inFlight, -1)
+		if err == nil || !retryable(c.Context, err) {
+			return result, err
+		}
+		log.Warningf("query clickhouse replica %s failed: %s", replica, err)
+		replica.setHealthy(false)
+		if c.Debug != nil {
+			c.Debug.Retry = fmt.Sprintf("%s: %s", replica.Host, err)
+		}
+		failed = replica
+	}
+	return result, err
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+func TestReplicaPool(t *testing.T) {
+	p, err := NewReplicaPool([]string{"ck-0:9001", "ck-1", "[::1]:9002"}, 9000, "default", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas := p.Replicas()
+	if replicas[0].String() != "ck-0:9001" || replicas[1].String() != "ck-1:9000" || replicas[2].String() != "[::1]:9002" {
+		t.Fatalf("replicas = %v", replicas)
+	}
+	if _, err := NewReplicaPool([]string{"ck-0:abc"}, 9000, "default", "", 0, 0); err == nil {
+		t.Error("invalid port should fail")
+	}
+
+	replicas[0].inFlight = 2
+	replicas[1].inFlight = 1
+	replicas[2].inFlight = 3
+	if r := p.pick(nil); r != replicas[1] {
+		t.Errorf("pick() = %s, want %s", r, replicas[1])
+	}
+	replicas[1].setHealthy(false)
+	if r := p.pick(nil); r != replicas[0] {
+		t.Errorf("pick() with unhealthy = %s, want %s", r, replicas[0])
+	}
+	if r := p.pick(replicas[0]); r != replicas[2] {
+		t.Errorf("pick(exclude) = %s, want %s", r, replicas[2])
+	}
+	replicas[0].setHealthy(false)
+	replicas[2].setHealthy(false)
+	if r := p.pick(replicas[0]); r != replicas[1] {
+		t.Errorf("pick() without healthy = %s, want %s", r, replicas[1])
+	}
+}
+
+func TestReplicaPoolRetry(t *testing.T) {
+	p, _ := NewReplicaPool([]string{"ck-0", "ck-1"}, 9000, "default", "", 0, 0)
+	c := &Client{Debug: &Debug{}}
+	var hosts []string
+	_, err := p.query(c, func() (map[string][]interface{}, error) {
+		hosts = append(hosts, c.Host)
+		if len(hosts) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return map[string][]interface{}{}, nil
+	})
+	if err != nil || len(hosts) != 2 || hosts[0] == hosts[1] || c.Debug.IP != hosts[1] || c.Debug.Retry == "" {
+		t.Errorf("retry hosts = %v, debug = %+v, err = %v", hosts, c.Debug, err)
+	}
+	if p.Replicas()[0].Healthy() == p.Replicas()[1].Healthy() {
+		t.Error("failed replica should be marked unhealthy")
+	}
+
+	// ClickHouse返回的错误不重试
+	hosts = nil
+	_, err = p.query(c, func() (map[string][]interface{}, error) {
+		hosts = append(hosts, c.Host)
+		return nil, &clickhouse.Exception{Code: 62, Message: "syntax error"}
+	})
+	if err == nil || len(hosts) != 1 {
+		t.Errorf("exception hosts = %v, err = %v", hosts, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if retryable(ctx, errors.New("canceled")) {
+		t.Error("canceled query should not retry")
+	}
+}
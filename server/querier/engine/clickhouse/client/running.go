@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var runningQueries = &runningQuerySet{queries: make(map[*RunningQuery]struct{})}
+
+// RunningQuery 正在ClickHouse中执行的查询
+type RunningQuery struct {
+	QueryUUID string
+	Sql       string
+	Host      string
+	Port      int
+	StartTime time.Time
+	userName  string
+	password  string
+}
+
+func (q *RunningQuery) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"query_uuid": q.QueryUUID,
+		"sql":        q.Sql,
+		"host":       q.Host,
+		"start_time": q.StartTime.Unix(),
+		"elapsed":    fmt.Sprintf("%.3fs", time.Since(q.StartTime).Seconds()),
+	}
+}
+
+type runningQuerySet struct {
+	sync.Mutex
+	queries map[*RunningQuery]struct{}
+}
+
+func (s *runningQuerySet) add(c *Client, sql string, start time.Time) *RunningQuery {
+	q := &RunningQuery{
+		QueryUUID: c.Debug.QueryUUID,
+		Sql:       sql,
+		Host:      c.Host,
+		Port:      c.Port,
+		StartTime: start,
+		userName:  c.UserName,
+		password:  c.Password,
+	}
+	s.Lock()
+	s.queries[q] = struct{}{}
+	s.Unlock()
+	return q
+}
+
+func (s *runningQuerySet) remove(q *RunningQuery) {
+	s.Lock()
+	delete(s.queries, q)
+	s.Unlock()
+}
+
+// RunningQueries 返回正在执行的查询, 按开始时间排序
+func RunningQueries() []*RunningQuery {
+	runningQueries.Lock()
+	queries := make([]*RunningQuery, 0, len(runningQueries.queries))
+	for q := range runningQueries.queries {
+		queries = append(queries, q)
+	}
+	runningQueries.Unlock()
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].StartTime.Before(queries[j].StartTime)
+	})
+	return queries
+}
+
+// KillQuery 在执行查询的ClickHouse上对queryUUID执行KILL QUERY, 返回终止的查询数
+func KillQuery(queryUUID string) (int, error) {
+	killed := 0
+	hosts := make(map[string]bool)
+	for _, q := range RunningQueries() {
+		if q.QueryUUID != queryUUID {
+			continue
+		}
+		killed++
+		host := fmt.Sprintf("%s:%d", q.Host, q.Port)
+		if hosts[host] {
+			continue
+		}
+		hosts[host] = true
+		if err := q.kill(); err != nil {
+			return killed, err
+		}
+	}
+	return killed, nil
+}
+
+func (q *RunningQuery) kill() error {
+	url := fmt.Sprintf("clickhouse://%s:%s@%s:%d/", q.userName, q.password, q.Host, q.Port)
+	conn, err := sqlx.Open("clickhouse", url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sql := fmt.Sprintf("KILL QUERY WHERE query_id = '%s' ASYNC", escapeString(q.QueryUUID))
+	if _, err = conn.Exec(sql); err != nil {
+		log.Errorf("kill query %s on %s:%d failed: %s", q.QueryUUID, q.Host, q.Port, err)
+		return err
+	}
+	log.Infof("kill query %s on %s:%d", q.QueryUUID, q.Host, q.Port)
+	return nil
+}
+
+func escapeString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunningQueries(t *testing.T) {
+	c := &Client{Host: "ck-0", Port: 9000, Debug: &Debug{QueryUUID: "uuid-1"}}
+	now := time.Now()
+	first := runningQueries.add(c, "SELECT 1", now.Add(-time.Second))
+	c.Debug.QueryUUID = "uuid-2"
+	second := runningQueries.add(c, "SELECT 2", now)
+
+	queries := RunningQueries()
+	if len(queries) != 2 || queries[0] != first || queries[1] != second {
+		t.Fatalf("RunningQueries() = %v", queries)
+	}
+	if m := first.ToMap(); m["query_uuid"] != "uuid-1" || m["sql"] != "SELECT 1" || m["host"] != "ck-0" {
+		t.Errorf("ToMap() = %v", m)
+	}
+	if killed, err := KillQuery("uuid-3"); killed != 0 || err != nil {
+		t.Errorf("KillQuery(not running) = %d, %v", killed, err)
+	}
+
+	runningQueries.remove(first)
+	runningQueries.remove(second)
+	if queries := RunningQueries(); len(queries) != 0 {
+		t.Errorf("RunningQueries() after remove = %v", queries)
+	}
+	if s := escapeString(`a'b\c`); s != `a\'b\\c` {
+		t.Errorf("escapeString() = %s", s)
+	}
+}
@@ -115,8 +115,26 @@ func ParseResponse(response *http.Response) (map[string]interface{}, error) {
 	return result, err
 }
 
+// Datasource 对应controller /v1/data-sources/ 返回的数据源
+type Datasource struct {
+	Name                      string
+	Interval                  int // 单位: 秒
+	RetentionTime             int // 单位: 天
+	SummableMetricsOperator   string
+	UnSummableMetricsOperator string
+}
+
 func GetDatasources(db string, table string) ([]string, error) {
 	var datasources []string
+	infos, err := GetDatasourceInfos(db, table)
+	for _, info := range infos {
+		datasources = append(datasources, info.Name)
+	}
+	return datasources, err
+}
+
+func GetDatasourceInfos(db string, table string) ([]*Datasource, error) {
+	var datasources []*Datasource
 	switch db {
 	case "flow_metrics":
 		var tsdbType string
@@ -146,8 +164,18 @@ func GetDatasources(db string, table string) ([]string, error) {
 		if body["DATA"] == nil || len(body["DATA"].([]interface{})) < 1 {
 			return datasources, errors.New(fmt.Sprintf("get datasources error, url: %s, response: '%v'", url, body))
 		}
-		for _, datasource := range body["DATA"].([]interface{}) {
-			datasources = append(datasources, datasource.(map[string]interface{})["NAME"].(string))
+		for _, data := range body["DATA"].([]interface{}) {
+			datasource := data.(map[string]interface{})
+			info := &Datasource{Name: datasource["NAME"].(string)}
+			if interval, ok := datasource["INTERVAL"].(float64); ok {
+				info.Interval = int(interval)
+			}
+			if retentionTime, ok := datasource["RETENTION_TIME"].(float64); ok {
+				info.RetentionTime = int(retentionTime)
+			}
+			info.SummableMetricsOperator, _ = datasource["SUMMABLE_METRICS_OPERATOR"].(string)
+			info.UnSummableMetricsOperator, _ = datasource["UNSUMMABLE_METRICS_OPERATOR"].(string)
+			datasources = append(datasources, info)
 		}
 	default:
 		return datasources, nil
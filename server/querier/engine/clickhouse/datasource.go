@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	chCommon "github.com/deepflowys/deepflow/server/querier/engine/clickhouse/common"
+)
+
+const (
+	DATASOURCE_SUMMABLE_OPERATOR   = "Sum"
+	DATASOURCE_UNSUMMABLE_OPERATOR = "Avg"
+	// 数据源列表的缓存时长, 自动选择数据源时每个查询都需要数据源列表, 缓存以避免每次都请求controller
+	DATASOURCE_CACHE_TTL = 30 * time.Second
+)
+
+var datasourceInfos = newDatasourceCache(DATASOURCE_CACHE_TTL, chCommon.GetDatasourceInfos)
+
+type datasourceCacheEntry struct {
+	datasources []*chCommon.Datasource
+	expireAt    time.Time
+}
+
+// datasourceCache 按db和table缓存controller返回的数据源列表, 请求失败时不缓存
+type datasourceCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	fetch   func(db, table string) ([]*chCommon.Datasource, error)
+	entries map[string]*datasourceCacheEntry
+}
+
+func newDatasourceCache(ttl time.Duration, fetch func(db, table string) ([]*chCommon.Datasource, error)) *datasourceCache {
+	return &datasourceCache{
+		ttl:     ttl,
+		fetch:   fetch,
+		entries: make(map[string]*datasourceCacheEntry),
+	}
+}
+
+func (c *datasourceCache) get(db, table string, now time.Time) ([]*chCommon.Datasource, error) {
+	key := db + "." + table
+	c.Lock()
+	entry, ok := c.entries[key]
+	c.Unlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.datasources, nil
+	}
+	// 请求controller时不持有锁, 避免controller无响应时阻塞其他查询
+	datasources, err := c.fetch(db, table)
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	c.entries[key] = &datasourceCacheEntry{datasources: datasources, expireAt: now.Add(c.ttl)}
+	c.Unlock()
+	return datasources, nil
+}
+
+// SelectDatasource 未指定DataSource时, 根据查询的时间范围和time()粒度自动选择数据源,
+// 返回选择结果的描述, 用于debug输出
+func (e *CHEngine) SelectDatasource() string {
+	if e.DataSource != "" {
+		return e.DataSource
+	}
+	if e.rollupTable == nil {
+		return ""
+	}
+	datasources, err := datasourceInfos.get(e.DB, e.Table, time.Now())
+	if err != nil {
+		log.Warning(err)
+		return fmt.Sprintf("auto select failed: %s", err)
+	}
+	datasource, reason := SelectDatasource(datasources, e.Model.Time.Interval, e.Model.Time.TimeStart, e.Model.Time.TimeEnd, time.Now().Unix())
+	if datasource == nil {
+		return reason
+	}
+	e.DataSource = datasource.Name
+	e.rollupTable.Value = fmt.Sprintf("%s.`%s.%s`", e.DB, e.Table, datasource.Name)
+	e.Model.Time.DatasourceInterval = datasource.Interval
+	return reason
+}
+
+// SelectDatasource 在满足以下条件的数据源中选择粒度最粗的:
+//   - 粒度能整除time()的粒度, 未使用time()时不超过查询的时间范围
+//   - 查询的开始时间在数据源的保留时长内
+//   - 聚合方式与原始数据一致, 避免Max/Min聚合的数据源改变指标含义
+func SelectDatasource(datasources []*chCommon.Datasource, interval int, timeStart, timeEnd, now int64) (*chCommon.Datasource, string) {
+	var selected *chCommon.Datasource
+	for _, datasource := range datasources {
+		if datasource.Interval <= 0 {
+			continue
+		}
+		if datasource.SummableMetricsOperator != "" && datasource.SummableMetricsOperator != DATASOURCE_SUMMABLE_OPERATOR {
+			continue
+		}
+		if datasource.UnSummableMetricsOperator != "" && datasource.UnSummableMetricsOperator != DATASOURCE_UNSUMMABLE_OPERATOR {
+			continue
+		}
+		if interval > 0 {
+			if interval%datasource.Interval != 0 {
+				continue
+			}
+		} else if timeEnd-timeStart < int64(datasource.Interval) {
+			continue
+		}
+		if timeStart < now-int64(datasource.RetentionTime)*86400 {
+			continue
+		}
+		if selected == nil || datasource.Interval > selected.Interval {
+			selected = datasource
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Sprintf("auto select failed: no datasource matches interval %d and time range [%d, %d]", interval, timeStart, timeEnd)
+	}
+	return selected, fmt.Sprintf("%s (auto selected, interval: %ds, retention_time: %dd)", selected.Name, selected.Interval, selected.RetentionTime)
+}
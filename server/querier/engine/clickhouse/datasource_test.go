@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	chCommon "github.com/deepflowys/deepflow/server/querier/engine/clickhouse/common"
+)
+
+func TestSelectDatasource(t *testing.T) {
+	datasources := []*chCommon.Datasource{
+		{Name: "1s", Interval: 1, RetentionTime: 1},
+		{Name: "1m", Interval: 60, RetentionTime: 7, SummableMetricsOperator: "Sum", UnSummableMetricsOperator: "Avg"},
+		{Name: "1h", Interval: 3600, RetentionTime: 30, SummableMetricsOperator: "Sum", UnSummableMetricsOperator: "Avg"},
+		{Name: "1d", Interval: 86400, RetentionTime: 365, SummableMetricsOperator: "Sum", UnSummableMetricsOperator: "Avg"},
+		{Name: "1h_max", Interval: 3600, RetentionTime: 365, SummableMetricsOperator: "Max", UnSummableMetricsOperator: "Max"},
+	}
+	now := int64(1700000000)
+	day := int64(86400)
+	cases := []struct {
+		interval  int
+		timeStart int64
+		want      string
+	}{
+		{interval: 60, timeStart: now - 3600, want: "1m"},
+		{interval: 3600, timeStart: now - 3600, want: "1h"},
+		{interval: 86400, timeStart: now - 29*day, want: "1d"},
+		{interval: 3600, timeStart: now - 60*day, want: ""},
+		{interval: 10, timeStart: now - 3600, want: "1s"},
+		{interval: 10, timeStart: now - 2*day, want: ""},
+		{interval: 0, timeStart: now - 600, want: "1m"},
+		{interval: 0, timeStart: now - 10*day, want: "1d"},
+	}
+	for _, c := range cases {
+		selected, reason := SelectDatasource(datasources, c.interval, c.timeStart, now, now)
+		got := ""
+		if selected != nil {
+			got = selected.Name
+		}
+		if got != c.want {
+			t.Errorf("SelectDatasource(%d, %d) = %q (%s), want %q", c.interval, c.timeStart, got, reason, c.want)
+		}
+	}
+}
+
+func TestDatasourceCache(t *testing.T) {
+	fetched := 0
+	failed := false
+	cache := newDatasourceCache(time.Minute, func(db, table string) ([]*chCommon.Datasource, error) {
+		fetched++
+		if failed {
+			return nil, errors.New("controller unavailable")
+		}
+		return []*chCommon.Datasource{{Name: "1m", Interval: 60}}, nil
+	})
+	now := time.Unix(1700000000, 0)
+	for _, offset := range []time.Duration{0, 30 * time.Second} {
+		datasources, err := cache.get("flow_metrics", "vtap_flow_port", now.Add(offset))
+		if err != nil || len(datasources) != 1 || datasources[0].Name != "1m" {
+			t.Fatalf("get() = %v, %v", datasources, err)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("datasources fetched %d times within ttl, want 1", fetched)
+	}
+	if _, err := cache.get("flow_metrics", "vtap_app_port", now); err != nil || fetched != 2 {
+		t.Errorf("another table should be fetched separately, fetched: %d, err: %v", fetched, err)
+	}
+
+	// 过期后重新请求, 请求失败时不缓存
+	failed = true
+	if _, err := cache.get("flow_metrics", "vtap_flow_port", now.Add(time.Minute)); err == nil || fetched != 3 {
+		t.Errorf("expired entry should be fetched again, fetched: %d, err: %v", fetched, err)
+	}
+	failed = false
+	if _, err := cache.get("flow_metrics", "vtap_flow_port", now.Add(time.Minute)); err != nil || fetched != 4 {
+		t.Errorf("failed fetch should not be cached, fetched: %d, err: %v", fetched, err)
+	}
+}
@@ -650,6 +650,11 @@ func (f *WhereFunction) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string
 		if !ok {
 			right = view.Expr{Value: f.Value}
 		} else {
+			// 非数据表字段的枚举tag(如pod_group_type), 取值比较时使用其default翻译
+			tagColumn := tagName
+			if tagDefault, ok := tag.GetTag(tagName, db, table, "default"); ok && tagDefault.TagTranslator != "" {
+				tagColumn = tagDefault.TagTranslator
+			}
 			whereFilter := tagItem.WhereTranslator
 			if strings.ToLower(opName) == "like" || strings.ToLower(opName) == "not like" {
 				f.Value = strings.ReplaceAll(f.Value, "*", "%")
@@ -673,12 +678,12 @@ func (f *WhereFunction) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string
 					intValue, err := strconv.Atoi(strings.Trim(f.Value, "'"))
 					if err == nil {
 						// when value type is int, add toUInt64() function
-						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagName + " = " + "toUInt64(" + strconv.Itoa(intValue) + ")"
+						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagColumn + " = " + "toUInt64(" + strconv.Itoa(intValue) + ")"
 					} else {
 						whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName)
 					}
 				} else {
-					whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagName + " = " + f.Value
+					whereFilter = fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + " OR " + tagColumn + " = " + f.Value
 				}
 			case "!=":
 				//when enum function operator is '!=', add 'and tag != xxx'
@@ -686,12 +691,12 @@ func (f *WhereFunction) Trans(expr sqlparser.Expr, w *Where, asTagMap map[string
 					intValue, err := strconv.Atoi(strings.Trim(f.Value, "'"))
 					if err == nil {
 						// when value type is int, add toUInt64() function
-						whereFilter = "not(" + fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + ") AND " + tagName + " != " + "toUInt64(" + strconv.Itoa(intValue) + ")"
+						whereFilter = "not(" + fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + ") AND " + tagColumn + " != " + "toUInt64(" + strconv.Itoa(intValue) + ")"
 					} else {
 						whereFilter = "not(" + fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + ")"
 					}
 				} else {
-					whereFilter = "not(" + fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + ") AND " + tagName + " != " + f.Value
+					whereFilter = "not(" + fmt.Sprintf(tagItem.WhereTranslator, "=", f.Value, enumFileName) + ") AND " + tagColumn + " != " + f.Value
 				}
 			default:
 				whereFilter = fmt.Sprintf(tagItem.WhereTranslator, opName, f.Value, enumFileName)
@@ -43,7 +43,8 @@ var METRICS_FUNCTIONS = []string{
 	view.FUNCTION_PCTL, view.FUNCTION_PCTL_EXACT, view.FUNCTION_SPREAD,
 	view.FUNCTION_RSPREAD, view.FUNCTION_STDDEV, view.FUNCTION_APDEX,
 	view.FUNCTION_UNIQ, view.FUNCTION_UNIQ_EXACT, view.FUNCTION_PERCENTAG,
-	view.FUNCTION_PERSECOND, view.FUCNTION_HISTOGRAM,
+	view.FUNCTION_PERSECOND, view.FUCNTION_HISTOGRAM, view.FUNCTION_SHIFT,
+	view.FUNCTION_DELTA, view.FUNCTION_RATIO,
 }
 
 var METRICS_FUNCTIONS_MAP = map[string]*Function{
@@ -62,6 +63,9 @@ var METRICS_FUNCTIONS_MAP = map[string]*Function{
 	view.FUNCTION_PERCENTAG:  NewFunction(view.FUNCTION_PERCENTAG, FUNCTION_TYPE_MATH, nil, "%", 0),
 	view.FUNCTION_PERSECOND:  NewFunction(view.FUNCTION_PERSECOND, FUNCTION_TYPE_MATH, nil, "$unit/s", 0),
 	view.FUCNTION_HISTOGRAM:  NewFunction(view.FUCNTION_HISTOGRAM, FUNCTION_TYPE_MATH, nil, "", 1),
+	view.FUNCTION_SHIFT:      NewFunction(view.FUNCTION_SHIFT, FUNCTION_TYPE_MATH, nil, "$unit", 1),
+	view.FUNCTION_DELTA:      NewFunction(view.FUNCTION_DELTA, FUNCTION_TYPE_MATH, nil, "$unit", 1),
+	view.FUNCTION_RATIO:      NewFunction(view.FUNCTION_RATIO, FUNCTION_TYPE_MATH, nil, "", 1),
 }
 
 func GetFunctionDescriptions() (map[string][]interface{}, error) {
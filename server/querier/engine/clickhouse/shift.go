@@ -0,0 +1,347 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xwb1989/sqlparser"
+
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/view"
+	"github.com/deepflowys/deepflow/server/querier/parse"
+)
+
+const SHIFT_CURRENT_ALIAS = "cur"
+
+// 偏移量的单位, 如'30m', '1d', '1w', 无单位时为秒
+var SHIFT_OFFSET_UNITS = map[byte]int64{
+	's': 1,
+	'm': 60,
+	'h': 3600,
+	'd': 86400,
+	'w': 604800,
+}
+
+func ParseShiftOffset(offset string) (int64, error) {
+	offset = strings.Trim(offset, "'")
+	if offset == "" {
+		return 0, errors.New("shift offset is empty")
+	}
+	unit := int64(1)
+	if u, ok := SHIFT_OFFSET_UNITS[offset[len(offset)-1]]; ok {
+		unit = u
+		offset = offset[:len(offset)-1]
+	}
+	n, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid shift offset '%s'", offset)
+	}
+	return n * unit, nil
+}
+
+type shiftedQuery struct {
+	alias   string
+	offset  int64
+	stmt    *sqlparser.Select
+	metrics map[string]string // 指标表达式 -> 列名
+}
+
+// Shift 同比/环比查询
+// Shift(metric, '1d')返回偏移后时间段的指标, Delta为当前值与偏移值的差, Ratio为当前值与偏移值的比值
+// 查询被拆分为当前时间段和各偏移时间段的子查询, 按time()分组和group by的tag做LEFT JOIN, 生成一条ClickHouse SQL
+type Shift struct {
+	CurrentSql string // 去掉Shift/Delta/Ratio后的当前时间段查询
+
+	timeAlias string
+	groups    []string
+	columns   []string
+	orderBy   string
+	limit     string
+	shifted   []*shiftedQuery
+}
+
+func isShiftFunction(expr sqlparser.Expr) (*sqlparser.FuncExpr, bool) {
+	function, ok := expr.(*sqlparser.FuncExpr)
+	if !ok {
+		return nil, false
+	}
+	switch function.Name.String() {
+	case view.FUNCTION_SHIFT, view.FUNCTION_DELTA, view.FUNCTION_RATIO:
+		return function, true
+	}
+	return nil, false
+}
+
+func selectName(expr *sqlparser.AliasedExpr) string {
+	if !expr.As.IsEmpty() {
+		return strings.Trim(expr.As.String(), "`")
+	}
+	if colName, ok := expr.Expr.(*sqlparser.ColName); ok {
+		return strings.Trim(sqlparser.String(colName), "`")
+	}
+	return sqlparser.String(expr.Expr)
+}
+
+// NewShift 解析sql中的Shift/Delta/Ratio, 不包含时返回nil
+func NewShift(sql string) (*Shift, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, nil
+	}
+	pStmt, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return nil, nil
+	}
+	hasShift := false
+	for _, expr := range pStmt.SelectExprs {
+		if item, ok := expr.(*sqlparser.AliasedExpr); ok {
+			if _, ok := isShiftFunction(item.Expr); ok {
+				hasShift = true
+				break
+			}
+		}
+	}
+	if !hasShift {
+		return nil, nil
+	}
+
+	s := &Shift{}
+	names := make(map[string]*sqlparser.AliasedExpr)
+	for _, expr := range pStmt.SelectExprs {
+		item, ok := expr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, errors.New("shift query does not support select *")
+		}
+		if _, ok := isShiftFunction(item.Expr); ok {
+			continue
+		}
+		name := selectName(item)
+		names[name] = item
+		if function, ok := item.Expr.(*sqlparser.FuncExpr); ok && function.Name.String() == "time" {
+			s.timeAlias = name
+		}
+	}
+	for _, group := range pStmt.GroupBy {
+		name := strings.Trim(sqlparser.String(group), "`")
+		if _, ok := names[name]; !ok {
+			return nil, fmt.Errorf("shift query requires group by column %s in select", name)
+		}
+		s.groups = append(s.groups, name)
+	}
+	for _, order := range pStmt.OrderBy {
+		colName, ok := order.Expr.(*sqlparser.ColName)
+		if !ok {
+			return nil, fmt.Errorf("shift query only supports order by select alias, got %s", sqlparser.String(order.Expr))
+		}
+		if s.orderBy != "" {
+			s.orderBy += ","
+		}
+		s.orderBy += fmt.Sprintf("`%s` %s", strings.Trim(sqlparser.String(colName), "`"), strings.ToUpper(order.Direction))
+	}
+	if pStmt.Limit != nil {
+		s.limit = fmt.Sprintf("LIMIT %s", sqlparser.String(pStmt.Limit.Rowcount))
+		if pStmt.Limit.Offset != nil {
+			s.limit += fmt.Sprintf(" OFFSET %s", sqlparser.String(pStmt.Limit.Offset))
+		}
+	}
+
+	var currentExprs sqlparser.SelectExprs
+	currentMetrics := make(map[string]string)
+	for _, expr := range pStmt.SelectExprs {
+		item := expr.(*sqlparser.AliasedExpr)
+		name := selectName(item)
+		function, ok := isShiftFunction(item.Expr)
+		if !ok {
+			if item.As.IsEmpty() {
+				if _, ok := item.Expr.(*sqlparser.ColName); !ok {
+					item.As = sqlparser.NewColIdent(name)
+				}
+			}
+			currentExprs = append(currentExprs, item)
+			s.columns = append(s.columns, fmt.Sprintf("%s.`%s` AS `%s`", SHIFT_CURRENT_ALIAS, name, name))
+			continue
+		}
+		if len(function.Exprs) != 2 {
+			return nil, fmt.Errorf("%s requires 2 arguments: metric and offset", function.Name.String())
+		}
+		metric, ok := function.Exprs[0].(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s metric %s", function.Name.String(), sqlparser.String(function.Exprs[0]))
+		}
+		offset, err := ParseShiftOffset(sqlparser.String(function.Exprs[1]))
+		if err != nil {
+			return nil, err
+		}
+		shifted, err := s.getShiftedQuery(sql, offset, names)
+		if err != nil {
+			return nil, err
+		}
+		metricStr := sqlparser.String(metric.Expr)
+		shiftColumn, ok := shifted.metrics[metricStr]
+		if !ok {
+			shiftColumn = fmt.Sprintf("__shift_%d", len(shifted.metrics))
+			shifted.metrics[metricStr] = shiftColumn
+			shifted.stmt.SelectExprs = append(shifted.stmt.SelectExprs, &sqlparser.AliasedExpr{Expr: metric.Expr, As: sqlparser.NewColIdent(shiftColumn)})
+		}
+		shiftValue := fmt.Sprintf("%s.`%s`", shifted.alias, shiftColumn)
+		if function.Name.String() == view.FUNCTION_SHIFT {
+			s.columns = append(s.columns, fmt.Sprintf("%s AS `%s`", shiftValue, name))
+			continue
+		}
+		currentColumn, ok := currentMetrics[metricStr]
+		if !ok {
+			currentColumn = fmt.Sprintf("__current_%d", len(currentMetrics))
+			currentMetrics[metricStr] = currentColumn
+			currentExprs = append(currentExprs, &sqlparser.AliasedExpr{Expr: metric.Expr, As: sqlparser.NewColIdent(currentColumn)})
+		}
+		currentValue := fmt.Sprintf("%s.`%s`", SHIFT_CURRENT_ALIAS, currentColumn)
+		if function.Name.String() == view.FUNCTION_DELTA {
+			s.columns = append(s.columns, fmt.Sprintf("%s - %s AS `%s`", currentValue, shiftValue, name))
+		} else {
+			s.columns = append(s.columns, fmt.Sprintf("%s / %s AS `%s`", currentValue, shiftValue, name))
+		}
+	}
+
+	pStmt.SelectExprs = currentExprs
+	pStmt.OrderBy = nil
+	pStmt.Limit = nil
+	s.CurrentSql = sqlparser.String(pStmt)
+	return s, nil
+}
+
+// getShiftedQuery 返回偏移offset的子查询, 只包含group by的列, where中time的条件向前偏移offset
+func (s *Shift) getShiftedQuery(sql string, offset int64, names map[string]*sqlparser.AliasedExpr) (*shiftedQuery, error) {
+	for _, shifted := range s.shifted {
+		if shifted.offset == offset {
+			return shifted, nil
+		}
+	}
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	pStmt := stmt.(*sqlparser.Select)
+	pStmt.SelectExprs = nil
+	for _, group := range s.groups {
+		item := *names[group]
+		if item.As.IsEmpty() {
+			if _, ok := item.Expr.(*sqlparser.ColName); !ok {
+				item.As = sqlparser.NewColIdent(group)
+			}
+		}
+		pStmt.SelectExprs = append(pStmt.SelectExprs, &item)
+	}
+	if pStmt.Where != nil {
+		pStmt.Where.Expr = shiftTimeFilter(pStmt.Where.Expr, offset)
+	}
+	pStmt.Having = nil
+	pStmt.OrderBy = nil
+	pStmt.Limit = nil
+	shifted := &shiftedQuery{
+		alias:   fmt.Sprintf("shifted_%d", len(s.shifted)),
+		offset:  offset,
+		stmt:    pStmt,
+		metrics: make(map[string]string),
+	}
+	s.shifted = append(s.shifted, shifted)
+	return shifted, nil
+}
+
+func shiftTimeValue(expr sqlparser.Expr, offset int64) sqlparser.Expr {
+	if val, ok := expr.(*sqlparser.SQLVal); ok && val.Type == sqlparser.IntVal {
+		if t, err := strconv.ParseInt(string(val.Val), 10, 64); err == nil {
+			return sqlparser.NewIntVal([]byte(strconv.FormatInt(t-offset, 10)))
+		}
+	}
+	return &sqlparser.BinaryExpr{
+		Left:     &sqlparser.ParenExpr{Expr: expr},
+		Operator: sqlparser.MinusStr,
+		Right:    sqlparser.NewIntVal([]byte(strconv.FormatInt(offset, 10))),
+	}
+}
+
+func isTimeColumn(expr sqlparser.Expr) bool {
+	colName, ok := expr.(*sqlparser.ColName)
+	return ok && strings.Trim(sqlparser.String(colName), "`") == "time"
+}
+
+func shiftTimeFilter(expr sqlparser.Expr, offset int64) sqlparser.Expr {
+	switch node := expr.(type) {
+	case *sqlparser.AndExpr:
+		node.Left = shiftTimeFilter(node.Left, offset)
+		node.Right = shiftTimeFilter(node.Right, offset)
+	case *sqlparser.OrExpr:
+		node.Left = shiftTimeFilter(node.Left, offset)
+		node.Right = shiftTimeFilter(node.Right, offset)
+	case *sqlparser.ParenExpr:
+		node.Expr = shiftTimeFilter(node.Expr, offset)
+	case *sqlparser.NotExpr:
+		node.Expr = shiftTimeFilter(node.Expr, offset)
+	case *sqlparser.ComparisonExpr:
+		if isTimeColumn(node.Left) {
+			node.Right = shiftTimeValue(node.Right, offset)
+		}
+	case *sqlparser.RangeCond:
+		if isTimeColumn(node.Left) {
+			node.From = shiftTimeValue(node.From, offset)
+			node.To = shiftTimeValue(node.To, offset)
+		}
+	}
+	return expr
+}
+
+// ToSQLString 将当前时间段的ClickHouse SQL与各偏移时间段的子查询JOIN为一条SQL
+func (s *Shift) ToSQLString(e *CHEngine, currentSql string) (string, error) {
+	buf := strings.Builder{}
+	buf.WriteString("SELECT ")
+	buf.WriteString(strings.Join(s.columns, ", "))
+	buf.WriteString(fmt.Sprintf(" FROM (%s) AS %s", currentSql, SHIFT_CURRENT_ALIAS))
+	for _, shifted := range s.shifted {
+		engine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context}
+		engine.Init()
+		parser := parse.Parser{Engine: engine}
+		if err := parser.ParseSQL(sqlparser.String(shifted.stmt)); err != nil {
+			return "", err
+		}
+		if len(s.groups) == 0 {
+			buf.WriteString(fmt.Sprintf(" CROSS JOIN (%s) AS %s", engine.ToSQLString(), shifted.alias))
+			continue
+		}
+		buf.WriteString(fmt.Sprintf(" LEFT JOIN (%s) AS %s ON ", engine.ToSQLString(), shifted.alias))
+		for i, group := range s.groups {
+			if i > 0 {
+				buf.WriteString(" AND ")
+			}
+			buf.WriteString(fmt.Sprintf("%s.`%s` = %s.`%s`", SHIFT_CURRENT_ALIAS, group, shifted.alias, group))
+			if group == s.timeAlias {
+				buf.WriteString(fmt.Sprintf(" + %d", shifted.offset))
+			}
+		}
+	}
+	if s.orderBy != "" {
+		buf.WriteString(" ORDER BY ")
+		buf.WriteString(s.orderBy)
+	}
+	if s.limit != "" {
+		buf.WriteString(" ")
+		buf.WriteString(s.limit)
+	}
+	return buf.String(), nil
+}
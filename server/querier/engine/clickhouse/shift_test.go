@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/querier/parse"
+)
+
+func TestParseShiftOffset(t *testing.T) {
+	for offset, want := range map[string]int64{"'90'": 90, "'30m'": 1800, "'1h'": 3600, "'1d'": 86400, "'2w'": 1209600} {
+		if got, err := ParseShiftOffset(offset); err != nil || got != want {
+			t.Errorf("ParseShiftOffset(%s) = %d, %v, want %d", offset, got, err, want)
+		}
+	}
+	for _, offset := range []string{"''", "'-1d'", "'1y'", "'d'"} {
+		if _, err := ParseShiftOffset(offset); err == nil {
+			t.Errorf("ParseShiftOffset(%s) should fail", offset)
+		}
+	}
+}
+
+func shiftSQL(t *testing.T, db, sql string) string {
+	s, err := NewShift(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := CHEngine{DB: db}
+	e.Init()
+	parser := parse.Parser{Engine: &e}
+	if err := parser.ParseSQL(s.CurrentSql); err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.ToSQLString(&e, e.ToSQLString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestShift(t *testing.T) {
+	Load()
+	if s, err := NewShift("select Sum(byte) from vtap_flow_port"); s != nil || err != nil {
+		t.Errorf("NewShift(without shift) = %v, %v", s, err)
+	}
+	if _, err := NewShift("select Shift(Sum(byte), '1d') as s from vtap_flow_port group by pod"); err == nil {
+		t.Error("group by column not in select should fail")
+	}
+
+	out := shiftSQL(t, "flow_metrics", "select Delta(Sum(byte), '1h') as d from vtap_flow_port where time>=1600000000 and time<=1600003600")
+	want := "SELECT cur.`__current_0` - shifted_0.`__shift_0` AS `d` FROM (SELECT SUM(byte) AS `__current_0` FROM flow_metrics.`vtap_flow_port` WHERE `time` >= 1600000000 AND `time` <= 1600003600) AS cur CROSS JOIN (SELECT SUM(byte) AS `__shift_0` FROM flow_metrics.`vtap_flow_port` WHERE `time` >= 1599996400 AND `time` <= 1600000000) AS shifted_0"
+	if out != want {
+		t.Errorf("Delta() = %s, want %s", out, want)
+	}
+
+	out = shiftSQL(t, "flow_metrics", "select Sum(byte) as sum_byte, Shift(Sum(byte), '1d') as sum_byte_1d, Ratio(Sum(byte), '1w') as r, time(time, 60) as time_60, pod from vtap_flow_port where time>=1600000000 and time<=1600003600 group by time_60, pod order by time_60 desc limit 10")
+	for _, want := range []string{
+		"SELECT cur.`sum_byte` AS `sum_byte`, shifted_0.`__shift_0` AS `sum_byte_1d`, cur.`__current_0` / shifted_1.`__shift_0` AS `r`, cur.`time_60` AS `time_60`, cur.`pod` AS `pod` FROM (",
+		"WHERE `time` >= 1599913600 AND `time` <= 1599917200",
+		"WHERE `time` >= 1599395200 AND `time` <= 1599398800",
+		"ON cur.`time_60` = shifted_0.`time_60` + 86400 AND cur.`pod` = shifted_0.`pod`",
+		"ON cur.`time_60` = shifted_1.`time_60` + 604800 AND cur.`pod` = shifted_1.`pod`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Shift() = %s, should contain %s", out, want)
+		}
+	}
+	if !strings.HasSuffix(out, "ORDER BY `time_60` DESC LIMIT 10") {
+		t.Errorf("Shift() = %s, should order and limit the joined result", out)
+	}
+}
@@ -756,6 +756,27 @@ func GenerateTagResoureMap() map[string]map[string]*Tag {
 			}
 		}
 	}
+	// pod_group_type
+	// 工作负载类型不在流表中, 通过pod_group_map字典由pod_group_id获取
+	for _, suffix := range []string{"", "_0", "_1"} {
+		podGroupIDSuffix := "pod_group_id" + suffix
+		podGroupTypeSuffix := "pod_group_type" + suffix
+		podGroupTypeTranslator := "dictGet(flow_tag.pod_group_map, 'pod_group_type', (toUInt64(" + podGroupIDSuffix + ")))"
+		tagResourceMap[podGroupTypeSuffix] = map[string]*Tag{
+			"default": NewTag(
+				podGroupTypeTranslator,
+				podGroupIDSuffix+"!=0",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type %s %s)",
+				"",
+			),
+			"enum": NewTag(
+				"dictGetOrDefault(flow_tag.int_enum_map, 'name', ('%s',toUInt64("+podGroupTypeTranslator+")), "+podGroupTypeTranslator+")",
+				podGroupIDSuffix+"!=0",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type IN (SELECT value FROM flow_tag.int_enum_map WHERE name %s %s and tag_name='%s'))",
+				"toUInt64("+podGroupIDSuffix+") IN (SELECT id FROM flow_tag.pod_group_map WHERE pod_group_type IN (SELECT value FROM flow_tag.int_enum_map WHERE %s(name,%s) and tag_name='%s'))",
+			),
+		}
+	}
 	// span_kind
 	// nullable int_enum tag do not return default value
 	tagResourceMap["span_kind"] = map[string]*Tag{
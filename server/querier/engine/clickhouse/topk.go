@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xwb1989/sqlparser"
+
+	"github.com/deepflowys/deepflow/server/querier/common"
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/view"
+)
+
+const (
+	FUNCTION_TOPK   = "TopK"
+	TOPK_OTHERS     = "__others__"
+	TOPK_PER_BUCKET = "bucket"
+	TOPK_ALIAS      = "topk"     // 原查询作为子查询时的别名
+	TOPK_FLAG       = "__topk__" // 是否为前N个分组, 仅供callback使用, 不返回给调用方
+)
+
+// 合并为__others__时各指标在clickhouse中的计算方式, 不在其中的指标合并后为null
+var TOPK_AGGREGATORS = map[string]string{
+	view.FUNCTION_SUM:       "sum",
+	view.FUNCTION_COUNT:     "sum",
+	view.FUNCTION_PERSECOND: "sum",
+	view.FUNCTION_MAX:       "max",
+	view.FUNCTION_MIN:       "min",
+}
+
+// TopK 按排序字段保留前N个分组, 其余分组合并为一行__others__
+// 例: SELECT Sum(byte) AS b, pod_service FROM vtap_flow_edge_port GROUP BY pod_service ORDER BY b DESC LIMIT TopK(10)
+// TopK(10, 'bucket')表示在每个时间点分别取前N个分组
+// 排名及合并均在clickhouse中完成, 见ToSQLString, callback仅将合并行的分组字段改写为__others__
+type TopK struct {
+	N            int
+	PerBucket    bool
+	Columns      []string          // 查询返回的字段, 按select中的顺序
+	GroupColumns []string          // 除time外的分组字段
+	TimeColumn   string            // time()的别名, 未使用time()时为空
+	OrderColumn  string            // 用于排名的字段, 取第一个排序字段
+	Desc         bool              // 是否取最大的N个
+	Orders       []string          // 原查询的排序, 用于外层查询
+	Aggregators  map[string]string // 指标字段及其合并方式
+	Fill         string            // time()的补点值, 需要在合并后按分组补点
+}
+
+func (e *CHEngine) parseTopK(expr *sqlparser.FuncExpr) (*TopK, error) {
+	if len(expr.Exprs) < 1 || len(expr.Exprs) > 2 {
+		return nil, fmt.Errorf("function: %s not support, usage: TopK(N[, '%s'])", sqlparser.String(expr), TOPK_PER_BUCKET)
+	}
+	n, err := strconv.Atoi(sqlparser.String(expr.Exprs[0]))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid TopK number %s", sqlparser.String(expr.Exprs[0]))
+	}
+	topK := &TopK{
+		N:            n,
+		Columns:      e.selectColumns,
+		GroupColumns: e.groupColumns,
+		TimeColumn:   strings.Trim(e.Model.Time.Alias, "`"),
+		Aggregators:  map[string]string{},
+	}
+	if len(expr.Exprs) > 1 {
+		if strings.Trim(sqlparser.String(expr.Exprs[1]), "'") != TOPK_PER_BUCKET {
+			return nil, fmt.Errorf("invalid TopK mode %s", sqlparser.String(expr.Exprs[1]))
+		}
+		if topK.TimeColumn == "" {
+			return nil, errors.New("TopK per bucket requires time() in select")
+		}
+		topK.PerBucket = true
+	}
+	if len(topK.GroupColumns) == 0 {
+		return nil, errors.New("TopK requires group by tags")
+	}
+	if len(e.Model.Orders.Orders) == 0 {
+		return nil, errors.New("TopK requires order by a metric")
+	}
+	order := e.Model.Orders.Orders[0].(*view.Order)
+	topK.OrderColumn = strings.Trim(order.SortBy, "`")
+	topK.Desc = strings.ToLower(order.OrderBy) != "asc"
+	if !common.IsValueInSliceString(topK.OrderColumn, topK.Columns) {
+		return nil, fmt.Errorf("TopK requires order by a selected metric, got %s", order.SortBy)
+	}
+	for _, node := range e.Model.Orders.Orders {
+		topK.Orders = append(topK.Orders, node.ToString())
+	}
+	for as, name := range e.asTagMap {
+		if aggregator, ok := TOPK_AGGREGATORS[name]; ok {
+			topK.Aggregators[strings.Trim(as, "`")] = aggregator
+		}
+	}
+	// 补点在合并__others__之后按分组进行, 见Format
+	topK.Fill = e.Model.Time.Fill
+	e.Model.Time.Fill = ""
+	return topK, nil
+}
+
+func (t *TopK) Format(m *view.Model) {
+	m.AddCallback(t.Callback)
+	if t.Fill != "" && m.Time.Interval > 0 {
+		m.Time.Fill = t.Fill
+		m.AddCallback(TimeFill([]interface{}{m, t.GroupColumns}))
+	}
+}
+
+// ToSQLString 将原查询作为子查询, 在clickhouse中计算前N个分组, 其余分组按时间点合并为一行
+// 例: SELECT topk.`time_60` AS `time_60`, any(topk.`ip_0`) AS `ip_0`, sum(topk.`sum_byte`) AS `sum_byte`,
+// (topk.`ip_0`) IN (SELECT `ip_0` FROM (...) GROUP BY `ip_0` ORDER BY sum(`sum_byte`) DESC LIMIT 10) AS `__topk__`
+// FROM (...) AS topk GROUP BY topk.`time_60`, `__topk__`, if(`__topk__`, topk.`ip_0`, defaultValueOfArgumentType(topk.`ip_0`))
+// 前N个分组在每个时间点上只有一行, 因此按分组字段聚合后结果不变
+func (t *TopK) ToSQLString(sql string) string {
+	column := func(name string) string {
+		return fmt.Sprintf("%s.`%s`", TOPK_ALIAS, name)
+	}
+	quote := func(names []string) []string {
+		quoted := make([]string, 0, len(names))
+		for _, name := range names {
+			quoted = append(quoted, fmt.Sprintf("`%s`", name))
+		}
+		return quoted
+	}
+	direction := "ASC"
+	if t.Desc {
+		direction = "DESC"
+	}
+
+	// 前N个分组
+	var keys []string
+	var topSql string
+	if t.PerBucket {
+		keys = append([]string{t.TimeColumn}, t.GroupColumns...)
+		topSql = fmt.Sprintf(
+			"SELECT %s FROM (%s) ORDER BY `%s` %s LIMIT %d BY `%s`",
+			strings.Join(quote(keys), ", "), sql, t.OrderColumn, direction, t.N, t.TimeColumn,
+		)
+	} else {
+		keys = t.GroupColumns
+		topSql = fmt.Sprintf(
+			"SELECT %s FROM (%s) GROUP BY %s ORDER BY sum(`%s`) %s LIMIT %d",
+			strings.Join(quote(keys), ", "), sql, strings.Join(quote(keys), ", "), t.OrderColumn, direction, t.N,
+		)
+	}
+	var keyColumns []string
+	for _, key := range keys {
+		keyColumns = append(keyColumns, column(key))
+	}
+
+	var columns, groups []string
+	for _, name := range t.Columns {
+		if name == t.TimeColumn {
+			columns = append(columns, fmt.Sprintf("%s AS `%s`", column(name), name))
+			groups = append(groups, column(name))
+		} else if aggregator, ok := t.Aggregators[name]; ok {
+			columns = append(columns, fmt.Sprintf("%s(%s) AS `%s`", aggregator, column(name), name))
+		} else {
+			columns = append(columns, fmt.Sprintf("any(%s) AS `%s`", column(name), name))
+		}
+	}
+	columns = append(columns, fmt.Sprintf("(%s) IN (%s) AS `%s`", strings.Join(keyColumns, ", "), topSql, TOPK_FLAG))
+	groups = append(groups, fmt.Sprintf("`%s`", TOPK_FLAG))
+	for _, name := range t.GroupColumns {
+		groups = append(groups, fmt.Sprintf(
+			"if(`%s`, %s, defaultValueOfArgumentType(%s))", TOPK_FLAG, column(name), column(name),
+		))
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString("SELECT ")
+	buf.WriteString(strings.Join(columns, ", "))
+	buf.WriteString(fmt.Sprintf(" FROM (%s) AS %s", sql, TOPK_ALIAS))
+	buf.WriteString(" GROUP BY ")
+	buf.WriteString(strings.Join(groups, ", "))
+	if len(t.Orders) > 0 {
+		buf.WriteString(" ORDER BY ")
+		buf.WriteString(strings.Join(t.Orders, ", "))
+	}
+	return buf.String()
+}
+
+// Callback 将合并行的分组字段改写为__others__, 无法合并的指标改写为null
+func (t *TopK) Callback(columns []interface{}, values []interface{}) []interface{} {
+	flagIndex := -1
+	for i, column := range columns {
+		if column.(string) == TOPK_FLAG {
+			flagIndex = i
+			break
+		}
+	}
+	if flagIndex < 0 {
+		log.Warningf("TopK callback skipped: column %s not found", TOPK_FLAG)
+		return values
+	}
+	for _, value := range values {
+		record := value.([]interface{})
+		if topKFlag(record[flagIndex]) {
+			continue
+		}
+		for i, column := range columns {
+			name := column.(string)
+			if i == flagIndex || name == t.TimeColumn {
+				continue
+			}
+			if common.IsValueInSliceString(name, t.GroupColumns) {
+				record[i] = TOPK_OTHERS
+			} else if _, ok := t.Aggregators[name]; !ok {
+				record[i] = nil
+			}
+		}
+	}
+	return values
+}
+
+func topKFlag(value interface{}) bool {
+	switch v := value.(type) {
+	case uint8:
+		return v != 0
+	case int:
+		return v != 0
+	case bool:
+		return v
+	}
+	return false
+}
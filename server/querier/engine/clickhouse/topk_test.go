@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/view"
+	"github.com/deepflowys/deepflow/server/querier/parse"
+)
+
+func TestParseTopK(t *testing.T) {
+	Load()
+	e := CHEngine{DB: "flow_metrics"}
+	e.Init()
+	parser := parse.Parser{Engine: &e}
+	err := parser.ParseSQL("select Sum(byte) as sum_byte, Max(rtt) as max_rtt, time(time, 60, 1, 0) as time_60, ip_0 from vtap_flow_port group by ip_0, time_60 order by sum_byte desc limit TopK(3, 'bucket')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topK := e.topK
+	if topK == nil || topK.N != 3 || !topK.PerBucket || !topK.Desc || topK.OrderColumn != "sum_byte" || topK.TimeColumn != "time_60" || topK.Fill != "0" {
+		t.Fatalf("parseTopK() = %+v", topK)
+	}
+	if !reflect.DeepEqual(topK.GroupColumns, []string{"ip_0"}) {
+		t.Errorf("GroupColumns = %v, want [ip_0]", topK.GroupColumns)
+	}
+	if !reflect.DeepEqual(topK.Columns, []string{"sum_byte", "max_rtt", "time_60", "ip_0"}) {
+		t.Errorf("Columns = %v", topK.Columns)
+	}
+	wantAggregators := map[string]string{"sum_byte": "sum", "max_rtt": "max"}
+	if !reflect.DeepEqual(topK.Aggregators, wantAggregators) {
+		t.Errorf("Aggregators = %v, want %v", topK.Aggregators, wantAggregators)
+	}
+	if e.Model.Limit.Limit != "" || e.CacheParams().TimeColumn != "" {
+		t.Errorf("TopK should not push limit down or reuse cache, limit: %s", e.Model.Limit.Limit)
+	}
+	if sql := e.ToSQLString(); !strings.Contains(sql, "LIMIT 3 BY `time_60`) AS `__topk__` FROM (") {
+		t.Errorf("TopK should be computed in clickhouse, sql: %s", sql)
+	}
+
+	for _, sql := range []string{
+		"select Sum(byte) as sum_byte from vtap_flow_port order by sum_byte desc limit TopK(3)",
+		"select Sum(byte) as sum_byte, ip_0 from vtap_flow_port group by ip_0 limit TopK(3)",
+		"select Sum(byte) as sum_byte, ip_0 from vtap_flow_port group by ip_0 order by sum_byte desc limit TopK(0)",
+		"select Sum(byte) as sum_byte, ip_0 from vtap_flow_port group by ip_0 order by sum_byte desc limit TopK(3, 'bucket')",
+		"select Sum(byte) as sum_byte, ip_0 from vtap_flow_port group by ip_0 order by ip_1 desc limit TopK(3)",
+	} {
+		e := CHEngine{DB: "flow_metrics"}
+		e.Init()
+		parser := parse.Parser{Engine: &e}
+		if err := parser.ParseSQL(sql); err == nil {
+			t.Errorf("ParseSQL(%q) should fail", sql)
+		}
+	}
+}
+
+func TestTopKToSQLString(t *testing.T) {
+	topK := &TopK{
+		N:            2,
+		Columns:      []string{"time", "ip", "byte", "rtt", "uniq"},
+		GroupColumns: []string{"ip"},
+		TimeColumn:   "time",
+		OrderColumn:  "byte",
+		Desc:         true,
+		Orders:       []string{"`byte` desc"},
+		Aggregators:  map[string]string{"byte": "sum", "rtt": "max"},
+	}
+	inner := "SELECT time, ip, byte, rtt, uniq FROM t GROUP BY time, ip"
+	want := "SELECT topk.`time` AS `time`, any(topk.`ip`) AS `ip`, sum(topk.`byte`) AS `byte`, max(topk.`rtt`) AS `rtt`, any(topk.`uniq`) AS `uniq`, " +
+		"(topk.`ip`) IN (SELECT `ip` FROM (" + inner + ") GROUP BY `ip` ORDER BY sum(`byte`) DESC LIMIT 2) AS `__topk__` " +
+		"FROM (" + inner + ") AS topk " +
+		"GROUP BY topk.`time`, `__topk__`, if(`__topk__`, topk.`ip`, defaultValueOfArgumentType(topk.`ip`)) ORDER BY `byte` desc"
+	if got := topK.ToSQLString(inner); got != want {
+		t.Errorf("TopK per query:\n got %s\nwant %s", got, want)
+	}
+
+	topK.PerBucket = true
+	topK.Desc = false
+	got := topK.ToSQLString(inner)
+	wantTop := "(topk.`time`, topk.`ip`) IN (SELECT `time`, `ip` FROM (" + inner + ") ORDER BY `byte` ASC LIMIT 2 BY `time`) AS `__topk__`"
+	if !strings.Contains(got, wantTop) {
+		t.Errorf("TopK per bucket = %s, want contains %s", got, wantTop)
+	}
+}
+
+func TestTopKCallback(t *testing.T) {
+	topK := &TopK{
+		N:            1,
+		GroupColumns: []string{"ip"},
+		TimeColumn:   "time",
+		OrderColumn:  "byte",
+		Desc:         true,
+		Aggregators:  map[string]string{"byte": "sum", "rtt": "max"},
+	}
+	columns := []interface{}{"time", "ip", "byte", "rtt", "uniq", TOPK_FLAG}
+	values := []interface{}{
+		[]interface{}{60, "a", 10, 1.0, 1, uint8(1)},
+		[]interface{}{60, "c", 25, 3.0, 1, uint8(0)},
+		[]interface{}{120, "a", 30, 4.0, 1, uint8(1)},
+		[]interface{}{120, "b", 1, 5.0, 1, uint8(0)},
+	}
+	want := []interface{}{
+		[]interface{}{60, "a", 10, 1.0, 1, uint8(1)},
+		[]interface{}{60, TOPK_OTHERS, 25, 3.0, nil, uint8(0)},
+		[]interface{}{120, "a", 30, 4.0, 1, uint8(1)},
+		[]interface{}{120, TOPK_OTHERS, 1, 5.0, nil, uint8(0)},
+	}
+	if got := topK.Callback(columns, values); !reflect.DeepEqual(got, want) {
+		t.Errorf("TopK callback = %v, want %v", got, want)
+	}
+}
+
+func TestTopKTimeFill(t *testing.T) {
+	m := view.NewModel()
+	m.Time.TimeStart = 60
+	m.Time.TimeEnd = 180
+	m.Time.Interval = 60
+	m.Time.WindowSize = 1
+	m.Time.Alias = "time"
+	topK := &TopK{
+		N:            1,
+		GroupColumns: []string{"ip"},
+		TimeColumn:   "time",
+		OrderColumn:  "byte",
+		Desc:         true,
+		Aggregators:  map[string]string{"byte": "sum"},
+		Fill:         "0",
+	}
+	topK.Format(m)
+	if len(m.Callbacks) != 2 || m.Time.Fill != "0" {
+		t.Fatalf("TopK.Format() callbacks: %d, fill: %s", len(m.Callbacks), m.Time.Fill)
+	}
+	columns := []interface{}{"time", "ip", "byte", TOPK_FLAG}
+	values := []interface{}{
+		[]interface{}{60, "a", 10, uint8(1)},
+		[]interface{}{60, "b", 1, uint8(0)},
+		[]interface{}{120, "a", 20, uint8(1)},
+	}
+	for _, callback := range m.Callbacks {
+		values = callback(columns, values)
+	}
+	want := []interface{}{
+		[]interface{}{60, "a", 10, uint8(1)},
+		[]interface{}{120, "a", 20, uint8(1)},
+		[]interface{}{180, "a", 0, 0},
+		[]interface{}{60, TOPK_OTHERS, 1, uint8(0)},
+		[]interface{}{120, TOPK_OTHERS, 0, 0},
+		[]interface{}{180, TOPK_OTHERS, 0, 0},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("TopK with TimeFill = %v, want %v", values, want)
+	}
+}
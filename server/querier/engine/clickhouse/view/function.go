@@ -46,6 +46,9 @@ const (
 	FUNCTION_PERSECOND   = "PerSecond"
 	FUNCTION_PERCENTAG   = "Percentage"
 	FUCNTION_HISTOGRAM   = "Histogram"
+	FUNCTION_SHIFT       = "Shift"
+	FUNCTION_DELTA       = "Delta"
+	FUNCTION_RATIO       = "Ratio"
 )
 
 // 对外提供的算子与数据库实际算子转换
@@ -3,23 +3,28 @@ package prometheus
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/prometheus/prometheus/prompb"
+	"sort"
 	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
 )
 
 const (
 	prometheusMetricsName = "__name__"
 	extMetricsTagsName    = "tags"
 	extMetricsTimeAlias   = "timestamp"
+
+	// ext_metrics中prometheus的虚拟表名为prometheus.<metric_name>
+	prometheusTablePrefix = "prometheus."
+	// 非prometheus来源的ext_metrics指标在promql中的命名格式为:
+	// ext_metrics__<source>__<table>__<metric>, 例：ext_metrics__influxdb__cpu__usage_idle
+	extMetricsNamePrefix = "ext_metrics__"
+	extMetricsNameSep    = "__"
 )
 
-func PromReaderTransToSQL(req *prompb.ReadRequest) (sql string, err error) {
-	queriers := req.Queries
-	if len(queriers) < 1 {
-		// TODO
-		return "", nil
-	}
-	q := queriers[0]
+// PromQueryTransToSQL 将一个prometheus查询转换为ext_metrics的sql，同时返回查询的指标名
+// 始终查询原始数据，ReadHints不下推，原因见README
+func PromQueryTransToSQL(q *prompb.Query) (sql string, metricsName string, err error) {
 	startTime := q.StartTimestampMs / 1000
 	endTime := q.EndTimestampMs / 1000
 	if q.EndTimestampMs%1000 > 0 {
@@ -27,89 +32,181 @@ func PromReaderTransToSQL(req *prompb.ReadRequest) (sql string, err error) {
 	}
 	timeFilter := fmt.Sprintf("(time >= %d AND time <= %d)", startTime, endTime)
 	filters := []string{timeFilter}
-	metrics := []string{extMetricsTagsName, fmt.Sprintf("toUnixTimestamp(time) AS %s", extMetricsTimeAlias)}
-	metircsName := ""
+	table, field := "", ""
 	// filter
 	for _, matcher := range q.Matchers {
 		// __name__为metrics
 		if matcher.Name == prometheusMetricsName {
-			metircsName = matcher.Value
-			metrics = append(metrics, fmt.Sprintf("metrics.%s", metircsName))
+			if matcher.Type != prompb.LabelMatcher_EQ {
+				return "", "", fmt.Errorf("unsupported match type %v for %s", matcher.Type, prometheusMetricsName)
+			}
+			metricsName = matcher.Value
+			table, field = MetricsNameToTable(metricsName)
 			continue
 		}
-		op := ""
+		op, value := "", escapeString(matcher.Value)
 		switch matcher.Type {
 		case prompb.LabelMatcher_EQ:
 			op = "="
 		case prompb.LabelMatcher_NEQ:
 			op = "!="
 		case prompb.LabelMatcher_RE:
-			op = "regexp"
+			// prometheus的正则是全匹配
+			op = " regexp "
+			value = "^(?:" + value + ")$"
 		case prompb.LabelMatcher_NRE:
-			op = "not regexp"
+			op = " not regexp "
+			value = "^(?:" + value + ")$"
 		default:
-			return "", fmt.Errorf("unknown match type %v", matcher.Type)
+			return "", "", fmt.Errorf("unknown match type %v", matcher.Type)
 		}
-		filters = append(filters, fmt.Sprintf("`tag.%s`%s'%s'", matcher.Name, op, matcher.Value))
+		filters = append(filters, fmt.Sprintf("`tag.%s`%s'%s'", matcher.Name, op, value))
+	}
+	if metricsName == "" {
+		return "", "", fmt.Errorf("metric name (%s) is required", prometheusMetricsName)
+	}
+	metrics := []string{extMetricsTagsName, fmt.Sprintf("toUnixTimestamp(time) AS %s", extMetricsTimeAlias), fmt.Sprintf("`metrics.%s`", field)}
+	sql = fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(metrics, ","), table, strings.Join(filters, " AND "))
+	return sql, metricsName, nil
+}
+
+// MetricsNameToTable 将promql中的指标名转换为ext_metrics的虚拟表名及指标字段名
+func MetricsNameToTable(metricsName string) (table string, field string) {
+	if strings.HasPrefix(metricsName, extMetricsNamePrefix) {
+		name := strings.TrimPrefix(metricsName, extMetricsNamePrefix)
+		sourceIndex := strings.Index(name, extMetricsNameSep)
+		fieldIndex := strings.LastIndex(name, extMetricsNameSep)
+		if sourceIndex > 0 && fieldIndex > sourceIndex {
+			source := name[:sourceIndex]
+			table = name[sourceIndex+len(extMetricsNameSep) : fieldIndex]
+			field = name[fieldIndex+len(extMetricsNameSep):]
+			return fmt.Sprintf("`%s.%s`", source, table), field
+		}
+	}
+	return fmt.Sprintf("`%s%s`", prometheusTablePrefix, metricsName), metricsName
+}
+
+// TableToMetricsName 为MetricsNameToTable的逆过程
+func TableToMetricsName(table, field string) string {
+	if strings.HasPrefix(table, prometheusTablePrefix) {
+		return field
 	}
-	sql = fmt.Sprintf("SELECT %s FROM prometheus.%s WHERE %s", strings.Join(metrics, ","), metircsName, strings.Join(filters, " AND "))
-	return sql, nil
+	return extMetricsNamePrefix + strings.Replace(table, ".", extMetricsNameSep, 1) + extMetricsNameSep + field
+}
+
+func escapeString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `'`, `\'`)
 }
 
-func RespTransToProm(result map[string][]interface{}) (resp *prompb.ReadResponse, err error) {
+func RespTransToProm(metricsName string, result map[string][]interface{}) (resp *prompb.ReadResponse, err error) {
 	resp = &prompb.ReadResponse{
 		Results: []*prompb.QueryResult{{}},
 	}
-	tags := result["columns"]
-	tagIndex := -1
-	metricsIndex := -1
-	timeIndex := -1
-	metricsName := ""
-	for i, tag := range tags {
-		if tag == extMetricsTagsName {
-			tagIndex = i
-		} else if strings.HasPrefix(tag.(string), "metrics.") {
-			metricsIndex = i
-			metricsName = strings.TrimPrefix(tag.(string), "metrics.")
-		} else if tag == extMetricsTimeAlias {
-			timeIndex = i
-		}
-	}
-	if tagIndex < 0 || metricsIndex < 0 || timeIndex < 0 {
-		return nil, fmt.Errorf("tagIndex(%d), metricsIndex(%d), timeIndex(%d) get failed", tagIndex, metricsIndex, timeIndex)
+	indexes, err := newColumnIndexes(result["columns"])
+	if err != nil {
+		return nil, err
 	}
 	tagSeriesMap := map[string]*prompb.TimeSeries{}
 	for _, v := range result["values"] {
-		values := v.([]interface{})
-		tagsJsonStr := values[tagIndex].(string)
+		tagsJsonStr, sample, ok := indexes.parseRow(v.([]interface{}))
+		if !ok {
+			continue
+		}
 		if _, ok := tagSeriesMap[tagsJsonStr]; !ok {
-			// __name__:metricsName
-			pairs := []prompb.Label{prompb.Label{
-				Name:  prometheusMetricsName,
-				Value: metricsName,
-			}}
-			// tag label pair
-			pairs = append(pairs, TagsToLabelPairs(tagsJsonStr)...)
-			series := &prompb.TimeSeries{
-				Labels: pairs,
+			tagSeriesMap[tagsJsonStr] = &prompb.TimeSeries{
+				Labels: seriesLabels(metricsName, tagsJsonStr),
 			}
-			tagSeriesMap[tagsJsonStr] = series
 		}
 		// group by tags
-		tagSeriesMap[tagsJsonStr].Samples = append(
-			tagSeriesMap[tagsJsonStr].Samples, prompb.Sample{
-				Timestamp: int64(values[timeIndex].(int)) * 1000,
-				Value:     *values[metricsIndex].(*float64),
-			},
-		)
-
+		tagSeriesMap[tagsJsonStr].Samples = append(tagSeriesMap[tagsJsonStr].Samples, sample)
 	}
 	for _, series := range tagSeriesMap {
+		// clickhouse返回的数据无序，prometheus要求样本按时间排序
+		samples := series.Samples
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
 		resp.Results[0].Timeseries = append(resp.Results[0].Timeseries, series)
 	}
 	return resp, nil
 }
 
+// columnIndexes 查询结果中tags、指标及时间所在的列
+type columnIndexes struct {
+	tagIndex     int
+	metricsIndex int
+	timeIndex    int
+}
+
+func newColumnIndexes(columns []interface{}) (*columnIndexes, error) {
+	c := &columnIndexes{-1, -1, -1}
+	for i, tag := range columns {
+		if tag == extMetricsTagsName {
+			c.tagIndex = i
+		} else if strings.HasPrefix(tag.(string), "metrics.") {
+			c.metricsIndex = i
+		} else if tag == extMetricsTimeAlias {
+			c.timeIndex = i
+		}
+	}
+	if c.tagIndex < 0 || c.metricsIndex < 0 || c.timeIndex < 0 {
+		return nil, fmt.Errorf("tagIndex(%d), metricsIndex(%d), timeIndex(%d) get failed", c.tagIndex, c.metricsIndex, c.timeIndex)
+	}
+	return c, nil
+}
+
+// parseRow 返回一行数据的tags及样本，指标值为空时返回false
+func (c *columnIndexes) parseRow(values []interface{}) (string, prompb.Sample, bool) {
+	value, ok := toFloat64(values[c.metricsIndex])
+	if !ok {
+		return "", prompb.Sample{}, false
+	}
+	timestamp, ok := toInt64(values[c.timeIndex])
+	if !ok {
+		return "", prompb.Sample{}, false
+	}
+	return values[c.tagIndex].(string), prompb.Sample{Timestamp: timestamp * 1000, Value: value}, true
+}
+
+func seriesLabels(metricsName, tagsJsonStr string) []prompb.Label {
+	// __name__:metricsName
+	pairs := []prompb.Label{prompb.Label{
+		Name:  prometheusMetricsName,
+		Value: metricsName,
+	}}
+	// tag label pair
+	pairs = append(pairs, TagsToLabelPairs(tagsJsonStr)...)
+	// prometheus要求标签按名称排序
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case *float64:
+		if v == nil {
+			return 0, false
+		}
+		return *v, true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
 func TagsToLabelPairs(tagsJsonStr string) []prompb.Label {
 	pairs := []prompb.Label{}
 	m := make(map[string]string)
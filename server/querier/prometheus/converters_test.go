@@ -0,0 +1,158 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func TestPromQueryTransToSQL(t *testing.T) {
+	cases := []struct {
+		query  *prompb.Query
+		output string
+	}{{
+		query: &prompb.Query{
+			StartTimestampMs: 1665912411000,
+			EndTimestampMs:   1665912711500,
+			Matchers: []*prompb.LabelMatcher{
+				{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "node_cpu"},
+				{Type: prompb.LabelMatcher_EQ, Name: "job", Value: "node's"},
+				{Type: prompb.LabelMatcher_RE, Name: "mode", Value: "idle|user"},
+			},
+		},
+		output: "SELECT tags,toUnixTimestamp(time) AS timestamp,`metrics.node_cpu` FROM `prometheus.node_cpu` WHERE (time >= 1665912411 AND time <= 1665912712) AND `tag.job`='node\\'s' AND `tag.mode` regexp '^(?:idle|user)$'",
+	}, {
+		query: &prompb.Query{
+			StartTimestampMs: 1665912411000,
+			EndTimestampMs:   1665912711000,
+			Matchers: []*prompb.LabelMatcher{
+				{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "ext_metrics__influxdb__cpu__usage_idle"},
+				{Type: prompb.LabelMatcher_NRE, Name: "host", Value: "a.*"},
+			},
+		},
+		output: "SELECT tags,toUnixTimestamp(time) AS timestamp,`metrics.usage_idle` FROM `influxdb.cpu` WHERE (time >= 1665912411 AND time <= 1665912711) AND `tag.host` not regexp '^(?:a.*)$'",
+	}}
+	for _, c := range cases {
+		sql, _, err := PromQueryTransToSQL(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql != c.output {
+			t.Errorf("PromQueryTransToSQL() = %q, want: %q", sql, c.output)
+		}
+	}
+
+	if _, _, err := PromQueryTransToSQL(&prompb.Query{}); err == nil {
+		t.Error("query without metric name should fail")
+	}
+}
+
+func TestPromQueryTransToSQLWithHints(t *testing.T) {
+	matchers := []*prompb.LabelMatcher{
+		{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "node_cpu"},
+	}
+	// ReadHints不下推，始终查询原始数据
+	output := "SELECT tags,toUnixTimestamp(time) AS timestamp,`metrics.node_cpu` FROM `prometheus.node_cpu` WHERE (time >= 1665912411 AND time <= 1665912711)"
+	cases := []struct {
+		hints  *prompb.ReadHints
+		output string
+	}{{
+		hints:  &prompb.ReadHints{StartMs: 1665911760000, StepMs: 60000, Func: "rate", RangeMs: 300000},
+		output: output,
+	}, {
+		hints:  &prompb.ReadHints{StartMs: 1665911760000, StepMs: 60000, Func: "max_over_time", RangeMs: 300000},
+		output: output,
+	}, {
+		hints:  &prompb.ReadHints{StartMs: 1665911760000, StepMs: 60000, Func: "max", By: true, Grouping: []string{"mode"}},
+		output: output,
+	}}
+	for _, c := range cases {
+		sql, _, err := PromQueryTransToSQL(&prompb.Query{
+			StartTimestampMs: 1665912411000,
+			EndTimestampMs:   1665912711000,
+			Matchers:         matchers,
+			Hints:            c.hints,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql != c.output {
+			t.Errorf("PromQueryTransToSQL(%v) = %q, want: %q", c.hints, sql, c.output)
+		}
+	}
+}
+
+func TestRespTransToProm(t *testing.T) {
+	v1, v2 := 1.0, 2.0
+	result := map[string][]interface{}{
+		"columns": {"tags", "timestamp", "metrics.node_cpu"},
+		"values": {
+			[]interface{}{`{"mode":"user"}`, 1665912720, &v2},
+			[]interface{}{`{"mode":"user"}`, 1665912660, &v1},
+			[]interface{}{`{"mode":"idle"}`, 1665912660, (*float64)(nil)},
+		},
+	}
+	resp, err := RespTransToProm("node_cpu", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := resp.Results[0].Timeseries
+	if len(series) != 1 {
+		t.Fatalf("RespTransToProm() returned %d series, want 1", len(series))
+	}
+	labels := series[0].Labels
+	if len(labels) != 2 || labels[0].Name != "__name__" || labels[1].Name != "mode" || labels[1].Value != "user" {
+		t.Errorf("RespTransToProm() labels = %v", labels)
+	}
+	samples := series[0].Samples
+	if len(samples) != 2 || samples[0].Timestamp != 1665912660000 || samples[1].Value != 2 {
+		t.Errorf("RespTransToProm() samples = %v", samples)
+	}
+}
+
+func TestMetricsNameToTable(t *testing.T) {
+	for _, name := range []string{"node_cpu", "ext_metrics__influxdb__cpu__usage_idle", "ext_metrics__influxdb__disk_io__read__bytes"} {
+		table, field := MetricsNameToTable(name)
+		if got := TableToMetricsName(table[1:len(table)-1], field); got != name {
+			t.Errorf("TableToMetricsName(MetricsNameToTable(%q)) = %q", name, got)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	ts, err := ParseTime("1665912411.5", time.Time{})
+	if err != nil || ts.UnixNano() != 1665912411500000000 {
+		t.Errorf("ParseTime unix = %v, %v", ts, err)
+	}
+	ts, err = ParseTime("2022-10-16T09:26:51Z", time.Time{})
+	if err != nil || ts.Unix() != 1665912411 {
+		t.Errorf("ParseTime rfc3339 = %v, %v", ts, err)
+	}
+	if d, err := ParseDuration("15"); err != nil || d != 15*time.Second {
+		t.Errorf("ParseDuration seconds = %v, %v", d, err)
+	}
+	if d, err := ParseDuration("1m"); err != nil || d != time.Minute {
+		t.Errorf("ParseDuration duration = %v, %v", d, err)
+	}
+}
+
+func TestSelectTimeRange(t *testing.T) {
+	now := time.Unix(1665912711, 0)
+	nowMs := int64(1665912711000)
+	cases := []struct {
+		start, end       int64
+		expStart, expEnd int64
+	}{
+		{0, 0, nowMs - time.Hour.Milliseconds(), nowMs},
+		{0, 1665900000000, 1665900000000 - time.Hour.Milliseconds(), 1665900000000},
+		{1665800000000, 0, 1665800000000, nowMs},
+		{1665800000000, 1665900000000, 1665800000000, 1665900000000},
+	}
+	for _, c := range cases {
+		start, end := selectTimeRange(c.start, c.end, now)
+		if start != c.expStart || end != c.expEnd {
+			t.Errorf("selectTimeRange(%d, %d) = (%d, %d), expected (%d, %d)", c.start, c.end, start, end, c.expStart, c.expEnd)
+		}
+	}
+}
@@ -3,18 +3,142 @@ package prometheus
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+
+	logging "github.com/op/go-logging"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+
 	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/client"
 	"github.com/google/uuid"
-	"github.com/prometheus/prometheus/prompb"
 )
 
+var log = logging.MustGetLogger("prometheus")
+
+// 流式响应中单个帧的最大字节数，与prometheus默认的remote-read-max-bytes-in-frame一致
+const maxBytesInFrame = 1024 * 1024
+
+// PromReaderExecute 处理remote_read请求，按请求中queries的顺序逐个返回结果
 func PromReaderExecute(req *prompb.ReadRequest, ctx context.Context) (resp *prompb.ReadResponse, err error) {
-	// promrequest trans to sql
-	sql, err := PromReaderTransToSQL(req)
+	resp = &prompb.ReadResponse{Results: make([]*prompb.QueryResult, 0, len(req.Queries))}
+	for _, q := range req.Queries {
+		result, err := PromQueryExecute(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// PromReaderStreamExecute 以STREAMED_XOR_CHUNKS格式处理remote_read请求，
+// 每个query的结果按ChunkedReadResponse帧写入w，帧中的QueryIndex为query在请求中的下标
+func PromReaderStreamExecute(req *prompb.ReadRequest, ctx context.Context, w io.Writer, flusher http.Flusher) error {
+	writer := remote.NewChunkedWriter(w, flusher)
+	for i, q := range req.Queries {
+		if err := promQueryStream(ctx, q, int64(i), writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promQueryStream 按tags及时间排序查询，每读完一个时间序列即写入帧，不在内存中保留整个查询结果
+func promQueryStream(ctx context.Context, q *prompb.Query, queryIndex int64, writer *remote.ChunkedWriter) error {
+	sql, metricsName, err := PromQueryTransToSQL(q)
+	if err != nil {
+		return err
+	}
+	sql = fmt.Sprintf("%s ORDER BY %s,%s", sql, extMetricsTagsName, extMetricsTimeAlias)
+	streamer := &seriesStreamer{writer: writer, queryIndex: queryIndex, metricsName: metricsName}
+	if err := promExecuteSQLRows(ctx, sql, streamer.handleRow); err != nil {
+		return err
+	}
+	return streamer.flush()
+}
+
+// seriesStreamer 将按tags排序的查询结果逐行合并为时间序列，tags变化时将上一个时间序列写入帧
+type seriesStreamer struct {
+	writer      *remote.ChunkedWriter
+	queryIndex  int64
+	metricsName string
+
+	indexes   *columnIndexes
+	seriesKey string
+	series    *prompb.TimeSeries
+}
+
+func (s *seriesStreamer) handleRow(columns []interface{}, row []interface{}) error {
+	if s.indexes == nil {
+		indexes, err := newColumnIndexes(columns)
+		if err != nil {
+			return err
+		}
+		s.indexes = indexes
+	}
+	tagsJsonStr, sample, ok := s.indexes.parseRow(row)
+	if !ok {
+		return nil
+	}
+	if s.series == nil || tagsJsonStr != s.seriesKey {
+		if err := s.flush(); err != nil {
+			return err
+		}
+		s.series = &prompb.TimeSeries{Labels: seriesLabels(s.metricsName, tagsJsonStr)}
+		s.seriesKey = tagsJsonStr
+	}
+	s.series.Samples = append(s.series.Samples, sample)
+	return nil
+}
+
+func (s *seriesStreamer) flush() error {
+	if s.series == nil {
+		return nil
+	}
+	result := &prompb.QueryResult{Timeseries: []*prompb.TimeSeries{s.series}}
+	s.series = nil
+	chunkSet := storage.NewSeriesSetToChunkSet(remote.FromQueryResult(false, result))
+	_, err := remote.StreamChunkedReadResponses(s.writer, s.queryIndex, chunkSet, nil, maxBytesInFrame)
+	return err
+}
+
+// PromQueryExecute 执行单个prometheus查询，供remote_read及promql引擎使用
+func PromQueryExecute(ctx context.Context, q *prompb.Query) (*prompb.QueryResult, error) {
+	sql, metricsName, err := PromQueryTransToSQL(q)
 	if err != nil {
 		return nil, err
 	}
+	resp, err := promExecuteSQL(ctx, sql, metricsName)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results[0], nil
+}
+
+// promExecuteSQLRows 执行sql并逐行回调结果
+func promExecuteSQLRows(ctx context.Context, sql string, handle client.RowHandler) error {
+	args := common.QuerierParams{
+		DB:         "ext_metrics",
+		Sql:        sql,
+		DataSource: "",
+		Debug:      "false",
+		QueryUUID:  uuid.New().String(),
+		Context:    ctx,
+	}
+	ckEngine := &clickhouse.CHEngine{DB: args.DB, DataSource: args.DataSource, Context: ctx}
+	ckEngine.Init()
+	debug, err := ckEngine.ExecuteQueryRows(&args, handle)
+	if err != nil {
+		log.Errorf("query_uuid: %s, debug: %v, error: %s", args.QueryUUID, debug, err)
+	}
+	return err
+}
+
+func promExecuteSQL(ctx context.Context, sql string, metricsName string) (resp *prompb.ReadResponse, err error) {
 	query_uuid := uuid.New()
 	args := common.QuerierParams{
 		DB:         "ext_metrics",
@@ -24,16 +148,15 @@ func PromReaderExecute(req *prompb.ReadRequest, ctx context.Context) (resp *prom
 		QueryUUID:  query_uuid.String(),
 		Context:    ctx,
 	}
-	ckEngine := &clickhouse.CHEngine{DB: args.DB, DataSource: args.DataSource}
+	ckEngine := &clickhouse.CHEngine{DB: args.DB, DataSource: args.DataSource, Context: ctx}
 	ckEngine.Init()
 	result, debug, err := ckEngine.ExecuteQuery(&args)
 	if err != nil {
-		// TODO
-		fmt.Printf("%v %v", debug, err)
+		log.Errorf("query_uuid: %s, debug: %v, error: %s", args.QueryUUID, debug, err)
 		return nil, err
 	}
 	// response trans to prom resp
-	resp, err = RespTransToProm(result)
+	resp, err = RespTransToProm(metricsName, result)
 	if err != nil {
 		return nil, err
 	}
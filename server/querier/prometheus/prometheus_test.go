@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+func TestSeriesStreamer(t *testing.T) {
+	v1, v2, v3 := 1.0, 2.0, 3.0
+	columns := []interface{}{"tags", "timestamp", "metrics.node_cpu"}
+	rows := [][]interface{}{
+		{`{"mode":"idle"}`, 1665912600, &v1},
+		{`{"mode":"idle"}`, 1665912660, &v2},
+		{`{"mode":"user"}`, 1665912600, (*float64)(nil)},
+		{`{"mode":"user"}`, 1665912660, &v3},
+	}
+	recorder := httptest.NewRecorder()
+	streamer := &seriesStreamer{writer: remote.NewChunkedWriter(recorder, recorder), queryIndex: 1, metricsName: "node_cpu"}
+	for i, row := range rows {
+		if err := streamer.handleRow(columns, row); err != nil {
+			t.Fatal(err)
+		}
+		// 读到下一个时间序列时上一个时间序列已写入
+		if i == 3 && recorder.Body.Len() == 0 {
+			t.Errorf("series idle not flushed before reading series user")
+		}
+	}
+	if err := streamer.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := remote.NewChunkedReader(recorder.Body, maxBytesInFrame, nil)
+	labels := []string{}
+	samples := []int{}
+	for {
+		resp := &prompb.ChunkedReadResponse{}
+		if err := reader.NextProto(resp); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if resp.QueryIndex != 1 {
+			t.Errorf("query index = %d, want 1", resp.QueryIndex)
+		}
+		for _, series := range resp.ChunkedSeries {
+			labels = append(labels, series.Labels[1].Value)
+			n := 0
+			for _, chunk := range series.Chunks {
+				n += int(chunk.MaxTimeMs-chunk.MinTimeMs)/60000 + 1
+			}
+			samples = append(samples, n)
+		}
+	}
+	if len(labels) != 2 || labels[0] != "idle" || labels[1] != "user" || samples[0] != 2 || samples[1] != 1 {
+		t.Errorf("streamed series = %v, samples = %v", labels, samples)
+	}
+}
@@ -0,0 +1,233 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/deepflowys/deepflow/server/querier/config"
+)
+
+var (
+	promEngine     *promql.Engine
+	promEngineOnce sync.Once
+
+	// 与prometheus http api保持一致的默认查询范围
+	MinTime = time.Unix(math.MinInt64/1000+62135596801, 0).UTC()
+	MaxTime = time.Unix(math.MaxInt64/1000-62135596801, 999999999).UTC()
+)
+
+type PromQueryData struct {
+	ResultType parser.ValueType `json:"resultType"`
+	Result     parser.Value     `json:"result"`
+}
+
+func getPromEngine() *promql.Engine {
+	promEngineOnce.Do(func() {
+		cfg := config.Cfg.Prometheus
+		promEngine = promql.NewEngine(promql.EngineOpts{
+			MaxSamples:           cfg.MaxSamples,
+			Timeout:              time.Duration(cfg.QueryTimeout) * time.Second,
+			LookbackDelta:        time.Duration(cfg.LookbackDelta) * time.Second,
+			EnableAtModifier:     true,
+			EnableNegativeOffset: true,
+		})
+	})
+	return promEngine
+}
+
+// PromInstantQueryExecute 对应/api/v1/query
+func PromInstantQueryExecute(ctx context.Context, qs string, ts time.Time) (*PromQueryData, error) {
+	query, err := getPromEngine().NewInstantQuery(&Queryable{}, nil, qs, ts)
+	if err != nil {
+		return nil, err
+	}
+	return execPromQuery(ctx, query)
+}
+
+// PromRangeQueryExecute 对应/api/v1/query_range
+func PromRangeQueryExecute(ctx context.Context, qs string, start, end time.Time, step time.Duration) (*PromQueryData, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("end timestamp must not be before start time")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("zero or negative query resolution step widths are not accepted")
+	}
+	// 与prometheus一致，限制返回的点数
+	if end.Sub(start)/step > 11000 {
+		return nil, fmt.Errorf("exceeded maximum resolution of 11,000 points per timeseries")
+	}
+	query, err := getPromEngine().NewRangeQuery(&Queryable{}, nil, qs, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	return execPromQuery(ctx, query)
+}
+
+func execPromQuery(ctx context.Context, query promql.Query) (*PromQueryData, error) {
+	defer query.Close()
+	res := query.Exec(ctx)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return &PromQueryData{
+		ResultType: res.Value.Type(),
+		Result:     res.Value,
+	}, nil
+}
+
+// PromSeriesExecute 对应/api/v1/series
+func PromSeriesExecute(ctx context.Context, matches []string, start, end time.Time) ([]labels.Labels, error) {
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no match[] parameter provided")
+	}
+	matcherSets, err := parseMatchersParam(matches)
+	if err != nil {
+		return nil, err
+	}
+	q, err := (&Queryable{}).Querier(ctx, timestamp(start), timestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	hints := &storage.SelectHints{Start: timestamp(start), End: timestamp(end), Func: "series"}
+	sets := make([]storage.SeriesSet, 0, len(matcherSets))
+	for _, matchers := range matcherSets {
+		sets = append(sets, q.Select(len(matcherSets) > 1, hints, matchers...))
+	}
+	set := storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+	metrics := []labels.Labels{}
+	for set.Next() {
+		metrics = append(metrics, set.At().Labels())
+	}
+	if err := set.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// PromLabelsExecute 对应/api/v1/labels
+func PromLabelsExecute(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	matcherSets, err := parseMatchersParam(matches)
+	if err != nil {
+		return nil, err
+	}
+	q, err := (&Queryable{}).Querier(ctx, timestamp(start), timestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	if len(matcherSets) == 0 {
+		matcherSets = append(matcherSets, nil)
+	}
+	nameSet := map[string]struct{}{}
+	for _, matchers := range matcherSets {
+		names, _, err := q.LabelNames(matchers...)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			nameSet[name] = struct{}{}
+		}
+	}
+	return sortedKeys(nameSet), nil
+}
+
+// PromLabelValuesExecute 对应/api/v1/label/<label_name>/values
+func PromLabelValuesExecute(ctx context.Context, name string, matches []string, start, end time.Time) ([]string, error) {
+	if !model.LabelNameRE.MatchString(name) {
+		return nil, fmt.Errorf("invalid label name: %q", name)
+	}
+	matcherSets, err := parseMatchersParam(matches)
+	if err != nil {
+		return nil, err
+	}
+	q, err := (&Queryable{}).Querier(ctx, timestamp(start), timestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	if len(matcherSets) == 0 {
+		matcherSets = append(matcherSets, nil)
+	}
+	valueSet := map[string]struct{}{}
+	for _, matchers := range matcherSets {
+		values, _, err := q.LabelValues(name, matchers...)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			valueSet[value] = struct{}{}
+		}
+	}
+	return sortedKeys(valueSet), nil
+}
+
+func parseMatchersParam(matches []string) ([][]*labels.Matcher, error) {
+	matcherSets := make([][]*labels.Matcher, 0, len(matches))
+	for _, s := range matches {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+	return matcherSets, nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func timestamp(t time.Time) int64 {
+	if t.Equal(MinTime) || t.Equal(MaxTime) {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// ParseTime 解析prometheus http api中的时间参数，支持unix时间戳及RFC3339格式
+func ParseTime(s string, defaultTime time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultTime, nil
+	}
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, ns := math.Modf(t)
+		ns = math.Round(ns*1000) / 1000
+		return time.Unix(int64(sec), int64(ns*float64(time.Second))).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// ParseDuration 解析prometheus http api中的step参数，支持秒数及prometheus时长格式
+func ParseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
@@ -0,0 +1,142 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+
+	"github.com/deepflowys/deepflow/server/querier/config"
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/client"
+)
+
+// 未指定起止时间时(如/api/v1/series未带start/end), 查询截至当前时间的最近一段数据, 避免全表扫描
+const defaultSelectRange = time.Hour
+
+// Queryable 实现storage.Queryable接口，供promql引擎从ext_metrics中读取数据
+type Queryable struct{}
+
+func (q *Queryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &Querier{ctx: ctx, mint: mint, maxt: maxt}, nil
+}
+
+type Querier struct {
+	ctx  context.Context
+	mint int64
+	maxt int64
+}
+
+func (q *Querier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	startTime, endTime := q.mint, q.maxt
+	if hints != nil {
+		startTime, endTime = hints.Start, hints.End
+	}
+	startTime, endTime = selectTimeRange(startTime, endTime, time.Now())
+	if hints != nil {
+		rangeHints := *hints
+		rangeHints.Start, rangeHints.End = startTime, endTime
+		hints = &rangeHints
+	}
+	prompbQuery, err := remote.ToQuery(startTime, endTime, matchers, hints)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	result, err := PromQueryExecute(q.ctx, prompbQuery)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	return remote.FromQueryResult(sortSeries, result)
+}
+
+// selectTimeRange 起止时间为0表示未指定, 结束时间缺省为当前时间, 开始时间缺省为结束时间前defaultSelectRange
+func selectTimeRange(start, end int64, now time.Time) (int64, int64) {
+	if end == 0 {
+		end = now.UnixNano() / int64(time.Millisecond)
+	}
+	if start == 0 {
+		start = end - defaultSelectRange.Milliseconds()
+	}
+	return start, end
+}
+
+// LabelValues 从flow_tag.ext_metrics_custom_field(_value)中获取标签值，__name__返回所有指标名
+func (q *Querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	filters := q.customFieldFilters(matchers)
+	var sql string
+	if name == labels.MetricName {
+		filters = append(filters, "field_type='metrics'")
+		sql = fmt.Sprintf("SELECT table, field_name FROM ext_metrics_custom_field WHERE %s GROUP BY table, field_name", strings.Join(filters, " AND "))
+	} else {
+		filters = append(filters, "field_type='tag'", fmt.Sprintf("field_name='%s'", escapeString(name)))
+		sql = fmt.Sprintf("SELECT field_value FROM ext_metrics_custom_field_value WHERE %s GROUP BY field_value", strings.Join(filters, " AND "))
+	}
+	rst, err := q.queryFlowTag(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make([]string, 0, len(rst["values"]))
+	for _, row := range rst["values"] {
+		row := row.([]interface{})
+		if name == labels.MetricName {
+			values = append(values, TableToMetricsName(row[0].(string), row[1].(string)))
+		} else {
+			values = append(values, row[0].(string))
+		}
+	}
+	sort.Strings(values)
+	return values, nil, nil
+}
+
+// LabelNames 获取所有标签名，若指定了指标名则仅返回该指标的标签
+func (q *Querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	filters := append(q.customFieldFilters(matchers), "field_type='tag'")
+	sql := fmt.Sprintf("SELECT field_name FROM ext_metrics_custom_field WHERE %s GROUP BY field_name", strings.Join(filters, " AND "))
+	rst, err := q.queryFlowTag(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := []string{labels.MetricName}
+	for _, row := range rst["values"] {
+		names = append(names, row.([]interface{})[0].(string))
+	}
+	sort.Strings(names)
+	return names, nil, nil
+}
+
+func (q *Querier) Close() error {
+	return nil
+}
+
+func (q *Querier) customFieldFilters(matchers []*labels.Matcher) []string {
+	filters := []string{}
+	if q.mint > 0 {
+		filters = append(filters, fmt.Sprintf("time >= %d", q.mint/1000))
+	}
+	if q.maxt > 0 {
+		filters = append(filters, fmt.Sprintf("time <= %d", (q.maxt+999)/1000))
+	}
+	for _, matcher := range matchers {
+		if matcher.Name == labels.MetricName && matcher.Type == labels.MatchEqual {
+			table, _ := MetricsNameToTable(matcher.Value)
+			filters = append(filters, fmt.Sprintf("table='%s'", escapeString(strings.Trim(table, "`"))))
+		}
+	}
+	return filters
+}
+
+func (q *Querier) queryFlowTag(sql string) (map[string][]interface{}, error) {
+	chClient := client.Client{
+		Host:     config.Cfg.Clickhouse.Host,
+		Port:     config.Cfg.Clickhouse.Port,
+		UserName: config.Cfg.Clickhouse.User,
+		Password: config.Cfg.Clickhouse.Password,
+		DB:       "flow_tag",
+		Context:  q.ctx,
+	}
+	return chClient.DoQuery(&client.QueryParams{Sql: sql})
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/deepflowys/deepflow/server/libs/stats"
 	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/config"
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/client"
 	"github.com/deepflowys/deepflow/server/querier/router"
 	"github.com/deepflowys/deepflow/server/querier/statsd"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -45,6 +46,22 @@ func Start(configPath string) {
 	log.Info("============================== Launching YUNSHAN DeepFlow Querier ==============================")
 	log.Infof("querier config:\n%s", string(bytes))
 
+	// ClickHouse多副本
+	if len(cfg.Clickhouse.Endpoints) > 0 {
+		var err error
+		client.Replicas, err = client.NewReplicaPool(
+			cfg.Clickhouse.Endpoints, cfg.Clickhouse.Port,
+			cfg.Clickhouse.User, cfg.Clickhouse.Password,
+			time.Duration(cfg.Clickhouse.HealthCheckInterval)*time.Second,
+			time.Duration(cfg.Clickhouse.ConnectTimeout)*time.Second,
+		)
+		if err != nil {
+			log.Error(err)
+			os.Exit(0)
+		}
+		client.Replicas.Start()
+	}
+
 	// engine加载数据库tag/metric等信息
 	err := Load()
 	if err != nil {
@@ -56,6 +73,15 @@ func Start(configPath string) {
 	statsd.QuerierCounter = statsd.NewCounter()
 	statsd.RegisterCountableForIngester("querier_count", statsd.QuerierCounter)
 
+	// 查询结果缓存
+	if cfg.Cache.Enabled {
+		client.QueryCache = client.NewResultCache(
+			time.Duration(cfg.Cache.TTL)*time.Second,
+			cfg.Cache.MaxMemory<<20,
+			time.Duration(cfg.Cache.DataDelay)*time.Second,
+		)
+	}
+
 	// init opentelemetry
 	if cfg.OtelEndpoint != "" {
 		log.Info("init opentelemetry")
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2022 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/deepflowys/deepflow/server/querier/prometheus"
+	"github.com/deepflowys/deepflow/server/querier/service"
+)
+
+func promQuery() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		ts, err := prometheus.ParseTime(c.Request.FormValue("time"), time.Now())
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		result, err := service.PromQueryExecute(c.Request.Context(), c.Request.FormValue("query"), ts)
+		PromJsonResponse(c, result, err)
+	})
+}
+
+func promQueryRange() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if c.Request.FormValue("start") == "" || c.Request.FormValue("end") == "" || c.Request.FormValue("step") == "" {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", errors.New("start, end and step are required"))
+			return
+		}
+		start, err := prometheus.ParseTime(c.Request.FormValue("start"), time.Time{})
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		end, err := prometheus.ParseTime(c.Request.FormValue("end"), time.Time{})
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		step, err := prometheus.ParseDuration(c.Request.FormValue("step"))
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		result, err := service.PromQueryRangeExecute(c.Request.Context(), c.Request.FormValue("query"), start, end, step)
+		PromJsonResponse(c, result, err)
+	})
+}
+
+func promSeries() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		start, end, err := parsePromTimeRange(c)
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		result, err := service.PromSeriesExecute(c.Request.Context(), c.Request.Form["match[]"], start, end)
+		PromJsonResponse(c, result, err)
+	})
+}
+
+func promLabels() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		start, end, err := parsePromTimeRange(c)
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		result, err := service.PromLabelsExecute(c.Request.Context(), c.Request.Form["match[]"], start, end)
+		PromJsonResponse(c, result, err)
+	})
+}
+
+func promLabelValues() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		start, end, err := parsePromTimeRange(c)
+		if err != nil {
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+		result, err := service.PromLabelValuesExecute(c.Request.Context(), c.Param("labelName"), c.Request.Form["match[]"], start, end)
+		PromJsonResponse(c, result, err)
+	})
+}
+
+func parsePromTimeRange(c *gin.Context) (start, end time.Time, err error) {
+	if err = c.Request.ParseForm(); err != nil {
+		return
+	}
+	if start, err = prometheus.ParseTime(c.Request.FormValue("start"), prometheus.MinTime); err != nil {
+		return
+	}
+	end, err = prometheus.ParseTime(c.Request.FormValue("end"), prometheus.MaxTime)
+	return
+}
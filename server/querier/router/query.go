@@ -21,17 +21,31 @@ import (
 	"github.com/google/uuid"
 	//"github.com/k0kubun/pp"
 	"io/ioutil"
+	"net/http"
 
 	//logging "github.com/op/go-logging"
 	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/service"
 	"github.com/golang/snappy"
 	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
 )
 
 func QueryRouter(e *gin.Engine) {
 	e.POST("/v1/query/", executeQuery())
+	e.DELETE("/v1/query/cache/", purgeCache())
+	e.GET("/v1/query/", runningQueries())
+	e.DELETE("/v1/query/:query_uuid", killQuery())
 	e.POST("/api/v1/prom/read", promReader())
+
+	// prometheus http api
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		e.Handle(method, "/api/v1/query", promQuery())
+		e.Handle(method, "/api/v1/query_range", promQueryRange())
+		e.Handle(method, "/api/v1/series", promSeries())
+		e.Handle(method, "/api/v1/labels", promLabels())
+	}
+	e.GET("/api/v1/label/:labelName/values", promLabelValues())
 }
 
 func executeQuery() gin.HandlerFunc {
@@ -61,6 +75,26 @@ func executeQuery() gin.HandlerFunc {
 	})
 }
 
+func purgeCache() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		count := service.PurgeCache()
+		JsonResponse(c, map[string]interface{}{"purged": count}, nil, nil)
+	})
+}
+
+func runningQueries() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		JsonResponse(c, service.RunningQueries(), nil, nil)
+	})
+}
+
+func killQuery() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		err := service.KillQuery(c.Param("query_uuid"))
+		JsonResponse(c, nil, nil, err)
+	})
+}
+
 func promReader() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		compressed, _ := ioutil.ReadAll(c.Request.Body)
@@ -74,6 +108,22 @@ func promReader() gin.HandlerFunc {
 			c.JSON(500, err)
 			return
 		}
+		responseType, err := remote.NegotiateResponseType(req.AcceptedResponseTypes)
+		if err != nil {
+			c.JSON(400, err.Error())
+			return
+		}
+		// 客户端支持时，以chunk流的形式返回，避免一次性在内存中构造所有结果
+		if responseType == prompb.ReadRequest_STREAMED_XOR_CHUNKS {
+			c.Header("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+			if err := service.PromReaderStreamExecute(&req, c.Request.Context(), c.Writer, c.Writer); err != nil {
+				// 已经开始写入响应后无法再修改状态码
+				if !c.Writer.Written() {
+					c.JSON(500, err.Error())
+				}
+			}
+			return
+		}
 		//pp.Println(req)
 		resp, err := service.PromReaderExecute(&req, c.Request.Context())
 		//pp.Println(resp)
@@ -20,6 +20,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
 
 	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/service"
@@ -83,3 +85,39 @@ func JsonResponse(c *gin.Context, data interface{}, debug interface{}, err error
 		HttpResponse(c, 200, data, debug, common.SUCCESS, "")
 	}
 }
+
+// PromResponse 兼容prometheus http api的返回格式
+type PromResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+func PromJsonResponse(c *gin.Context, data interface{}, err error) {
+	if err != nil {
+		switch err.(type) {
+		case parser.ParseErrors, *parser.ParseErr:
+			PromErrorResponse(c, http.StatusBadRequest, "bad_data", err)
+		case promql.ErrQueryTimeout:
+			PromErrorResponse(c, http.StatusServiceUnavailable, "timeout", err)
+		case promql.ErrQueryCanceled:
+			PromErrorResponse(c, http.StatusServiceUnavailable, "canceled", err)
+		default:
+			PromErrorResponse(c, http.StatusUnprocessableEntity, "execution", err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, PromResponse{
+		Status: "success",
+		Data:   data,
+	})
+}
+
+func PromErrorResponse(c *gin.Context, httpCode int, errorType string, err error) {
+	c.JSON(httpCode, PromResponse{
+		Status:    "error",
+		ErrorType: errorType,
+		Error:     err.Error(),
+	})
+}
@@ -18,10 +18,17 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
 	"github.com/deepflowys/deepflow/server/querier/common"
 	"github.com/deepflowys/deepflow/server/querier/engine"
 	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowys/deepflow/server/querier/engine/clickhouse/client"
 	"github.com/deepflowys/deepflow/server/querier/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/prompb"
 )
 
@@ -38,6 +45,33 @@ func Execute(args *common.QuerierParams) (result map[string][]interface{}, debug
 	return result, debug, err
 }
 
+// PurgeCache 清空查询结果缓存, 返回清除的条目数
+func PurgeCache() int {
+	if client.QueryCache == nil {
+		return 0
+	}
+	return client.QueryCache.Purge()
+}
+
+func RunningQueries() []interface{} {
+	queries := []interface{}{}
+	for _, q := range client.RunningQueries() {
+		queries = append(queries, q.ToMap())
+	}
+	return queries
+}
+
+func KillQuery(queryUUID string) error {
+	killed, err := client.KillQuery(queryUUID)
+	if err != nil {
+		return NewError(common.SERVER_ERROR, err.Error())
+	}
+	if killed == 0 {
+		return NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("query %s is not running", queryUUID))
+	}
+	return nil
+}
+
 func getDbBy() string {
 	return "clickhouse"
 }
@@ -45,3 +79,27 @@ func getDbBy() string {
 func PromReaderExecute(req *prompb.ReadRequest, ctx context.Context) (resp *prompb.ReadResponse, err error) {
 	return prometheus.PromReaderExecute(req, ctx)
 }
+
+func PromReaderStreamExecute(req *prompb.ReadRequest, ctx context.Context, w io.Writer, flusher http.Flusher) error {
+	return prometheus.PromReaderStreamExecute(req, ctx, w, flusher)
+}
+
+func PromQueryExecute(ctx context.Context, query string, ts time.Time) (*prometheus.PromQueryData, error) {
+	return prometheus.PromInstantQueryExecute(ctx, query, ts)
+}
+
+func PromQueryRangeExecute(ctx context.Context, query string, start, end time.Time, step time.Duration) (*prometheus.PromQueryData, error) {
+	return prometheus.PromRangeQueryExecute(ctx, query, start, end, step)
+}
+
+func PromSeriesExecute(ctx context.Context, matches []string, start, end time.Time) ([]labels.Labels, error) {
+	return prometheus.PromSeriesExecute(ctx, matches, start, end)
+}
+
+func PromLabelsExecute(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	return prometheus.PromLabelsExecute(ctx, matches, start, end)
+}
+
+func PromLabelValuesExecute(ctx context.Context, name string, matches []string, start, end time.Time) ([]string, error) {
+	return prometheus.PromLabelValuesExecute(ctx, name, matches, start, end)
+}